@@ -2,11 +2,16 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/mark3labs/mcphost/internal/auth"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -21,34 +26,43 @@ var authCmd = &cobra.Command{
 
 This command allows you to securely authenticate and manage credentials for various AI providers
 using OAuth flows. Stored credentials take precedence over environment variables.
+It also authorizes mcphost for remote MCP servers that require OAuth.
 
 Available providers:
   - anthropic: Anthropic Claude API (OAuth)
 
 Examples:
   mcphost auth login anthropic
+  mcphost auth login --server calendar
   mcphost auth logout anthropic
   mcphost auth status`,
 }
 
 // authLoginCmd represents the login subcommand for authenticating with AI providers.
 // It handles OAuth flow for supported providers, opening a browser for authentication
-// and securely storing the resulting credentials for future use.
+// and securely storing the resulting credentials for future use. With --server it
+// authorizes mcphost for a remote MCP server of the configuration instead.
 var authLoginCmd = &cobra.Command{
 	Use:   "login [provider]",
-	Short: "Authenticate with an AI provider using OAuth",
+	Short: "Authenticate with an AI provider or MCP server using OAuth",
 	Long: `Authenticate with an AI provider using OAuth flow.
 
 This will open your browser to complete the OAuth authentication process.
 Your credentials will be securely stored and will take precedence over 
 environment variables when making API calls.
 
+With --server, mcphost is authorized for a remote MCP server of your
+configuration instead. Its tokens are stored and refreshed automatically.
+Servers are also authorized when they first ask for it, so this is mostly
+useful before running mcphost non-interactively or to switch accounts.
+
 Available providers:
   - anthropic: Anthropic Claude API (OAuth)
 
-Example:
-  mcphost auth login anthropic`,
-	Args: cobra.ExactArgs(1),
+Examples:
+  mcphost auth login anthropic
+  mcphost auth login --server calendar`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runAuthLogin,
 }
 
@@ -63,12 +77,15 @@ var authLogoutCmd = &cobra.Command{
 This will delete the stored API key for the specified provider. You will need
 to use environment variables or command-line flags for authentication after logout.
 
+With --server, the OAuth tokens of a remote MCP server are deleted instead.
+
 Available providers:
   - anthropic: Anthropic Claude API
 
-Example:
-  mcphost auth logout anthropic`,
-	Args: cobra.ExactArgs(1),
+Examples:
+  mcphost auth logout anthropic
+  mcphost auth logout --server calendar`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runAuthLogout,
 }
 
@@ -88,14 +105,41 @@ Example:
 	RunE: runAuthStatus,
 }
 
+// authServer is the MCP server of the --server flag of login and logout.
+var authServer string
+
 func init() {
 	authCmd.AddCommand(authLoginCmd)
 	authCmd.AddCommand(authLogoutCmd)
 	authCmd.AddCommand(authStatusCmd)
+
+	authLoginCmd.Flags().StringVar(&authServer, "server", "", "authorize mcphost for this remote MCP server instead of a provider")
+	authLogoutCmd.Flags().StringVar(&authServer, "server", "", "remove the tokens of this remote MCP server instead of a provider")
+}
+
+// providerArg returns the provider argument of login or logout, which is
+// required unless --server is given.
+func providerArg(args []string) (string, error) {
+	if authServer != "" {
+		if len(args) > 0 {
+			return "", fmt.Errorf("give either a provider or --server, not both")
+		}
+		return "", nil
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("a provider or --server is required. Available providers: anthropic")
+	}
+	return strings.ToLower(args[0]), nil
 }
 
 func runAuthLogin(cmd *cobra.Command, args []string) error {
-	provider := strings.ToLower(args[0])
+	provider, err := providerArg(args)
+	if err != nil {
+		return err
+	}
+	if authServer != "" {
+		return loginMCPServer(authServer)
+	}
 
 	switch provider {
 	case "anthropic":
@@ -106,7 +150,13 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 }
 
 func runAuthLogout(cmd *cobra.Command, args []string) error {
-	provider := strings.ToLower(args[0])
+	provider, err := providerArg(args)
+	if err != nil {
+		return err
+	}
+	if authServer != "" {
+		return logoutMCPServer(authServer)
+	}
 
 	switch provider {
 	case "anthropic":
@@ -156,8 +206,29 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Remote MCP servers authorized with OAuth
+	if store, err := cm.LoadCredentials(); err == nil && len(store.MCPServers) > 0 {
+		fmt.Println("\nMCP servers:")
+		names := slices.Sorted(maps.Keys(store.MCPServers))
+		for _, name := range names {
+			creds := store.MCPServers[name]
+			status := "✓ Authorized"
+			if creds.AccessToken == "" {
+				status = "✗ Not authorized (client registered)"
+			} else if creds.IsExpired() {
+				status = "⚠️  Token expired"
+				if creds.RefreshToken != "" {
+					status += " (will refresh automatically)"
+				}
+			}
+			fmt.Printf("  %s: %s (%s, stored %s)\n", name, status, creds.ServerURL, creds.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+	}
+
 	fmt.Println("\nTo authenticate with a provider:")
 	fmt.Println("  mcphost auth login anthropic")
+	fmt.Println("To authorize mcphost for a remote MCP server:")
+	fmt.Println("  mcphost auth login --server <name>")
 
 	return nil
 }
@@ -277,3 +348,66 @@ func logoutAnthropic() error {
 
 	return nil
 }
+
+// printAuthorizationURL shows the URL authorizing mcphost for a remote MCP
+// server, for the user to open when the browser does not open by itself.
+func printAuthorizationURL(serverName, authURL string) {
+	fmt.Fprintf(os.Stderr, "\n🔐 MCP server %s requires authorization. Opening your browser...\n", serverName)
+	fmt.Fprintln(os.Stderr, "If the browser doesn't open automatically, please visit this URL:")
+	fmt.Fprintf(os.Stderr, "\n%s\n\n", authURL)
+}
+
+func loginMCPServer(serverName string) error {
+	mcpConfig, err := config.LoadAndValidateConfig()
+	if err != nil {
+		return err
+	}
+	// Server names are lowercased when the configuration is loaded.
+	serverName = strings.ToLower(serverName)
+	serverConfig, ok := mcpConfig.MCPServers[serverName]
+	if !ok {
+		return fmt.Errorf("unknown MCP server: %s", serverName)
+	}
+
+	cm, err := auth.NewCredentialManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize credential manager: %w", err)
+	}
+
+	fmt.Printf("🔐 Starting OAuth authorization with MCP server %s...\n", serverName)
+	if err := tools.AuthorizeServer(context.Background(), serverName, serverConfig, printAuthorizationURL); err != nil {
+		return fmt.Errorf("failed to authorize %s: %w", serverName, err)
+	}
+
+	fmt.Printf("✅ Successfully authorized mcphost for %s!\n", serverName)
+	fmt.Printf("📁 Credentials stored in: %s\n", cm.GetCredentialsPath())
+	fmt.Println("\n🔄 The tokens are refreshed automatically when they expire.")
+
+	return nil
+}
+
+func logoutMCPServer(serverName string) error {
+	cm, err := auth.NewCredentialManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize credential manager: %w", err)
+	}
+
+	serverName = strings.ToLower(serverName)
+	creds, err := cm.GetMCPServerCredentials(serverName)
+	if err != nil {
+		return fmt.Errorf("failed to check authentication status: %w", err)
+	}
+	if creds == nil {
+		fmt.Printf("mcphost is not currently authorized for %s.\n", serverName)
+		return nil
+	}
+
+	if err := cm.RemoveMCPServerCredentials(serverName); err != nil {
+		return fmt.Errorf("failed to remove credentials: %w", err)
+	}
+
+	fmt.Printf("✓ Successfully removed the tokens of %s!\n", serverName)
+	fmt.Println("The server will ask for authorization again on the next connection.")
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+// Exit codes returned by mcphost, so scripts can tell why a run failed.
+const (
+	// ExitError is returned for any failure without a more specific code.
+	ExitError = 1
+	// ExitToolLoop is returned when the agent was stopped because the model
+	// kept repeating the same tool calls.
+	ExitToolLoop = 3
+	// ExitBudgetExceeded is returned when a cost or token budget stopped the
+	// agent.
+	ExitBudgetExceeded = 4
+)
+
+// ExitCode returns the process exit code for err, the error returned by the
+// root command. It returns 0 for a nil error.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, agent.ErrToolLoop):
+		return ExitToolLoop
+	case errors.Is(err, agent.ErrBudgetExceeded):
+		return ExitBudgetExceeded
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("failed to load MCP config"), ExitError},
+		{fmt.Errorf("%w: the same bash call 3 times in a row", agent.ErrToolLoop), ExitToolLoop},
+		{fmt.Errorf("%w: prompt cost budget", agent.ErrBudgetExceeded), ExitBudgetExceeded},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
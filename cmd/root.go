@@ -12,8 +12,10 @@ import (
 	"charm.land/fantasy"
 	"github.com/mark3labs/mcphost/internal/agent"
 	"github.com/mark3labs/mcphost/internal/app"
+	"github.com/mark3labs/mcphost/internal/attachments"
 	"github.com/mark3labs/mcphost/internal/config"
 	"github.com/mark3labs/mcphost/internal/session"
+	"github.com/mark3labs/mcphost/internal/tools"
 	"github.com/mark3labs/mcphost/internal/ui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,6 +32,7 @@ var (
 	promptFlag       string
 	quietFlag        bool
 	noExitFlag       bool
+	attachPaths      []string
 	maxSteps         int
 	streamFlag       bool           // Enable streaming output
 	compactMode      bool           // Enable compact output mode
@@ -52,6 +55,48 @@ var (
 	mainGPU int32
 
 	// Hooks control
+	noHooks bool
+
+	// Tool approval
+	noAutoApprove bool
+
+	// Context compaction
+	compactionThreshold float64
+	compactionKeepTurns int
+
+	// Retries and fallback models
+	fallbackModels []string
+	maxRetries     int
+
+	// Extended thinking
+	thinkingBudget  int
+	reasoningEffort string
+
+	// Prompt caching
+	promptCache string
+
+	// Cost and token budgets
+	maxCost          float64
+	maxTotalTokens   int64
+	maxSessionCost   float64
+	maxSessionTokens int64
+	budgetWarning    float64
+
+	// Tool search
+	toolSearch  bool
+	pinnedTools []string
+
+	// Tool call loop detection
+	maxRepeatedToolCalls int
+
+	// MCP resources
+	resourceTool bool
+
+	// Structured output
+	outputSchemaPath string
+
+	// MCP elicitation
+	elicitationAnswersPath string
 
 	// TLS configuration
 	tlsSkipVerify bool
@@ -79,6 +124,10 @@ func (a *agentUIAdapter) GetLoadedServerNames() []string {
 	return a.agent.GetLoadedServerNames()
 }
 
+func (a *agentUIAdapter) GetServerStatuses() []tools.ServerStatus {
+	return a.agent.GetServerStatuses()
+}
+
 // rootCmd represents the base command when called without any subcommands.
 // This is the main entry point for the MCPHost CLI application, providing
 // an interface to interact with various AI models through a unified interface
@@ -276,6 +325,8 @@ func init() {
 		BoolVar(&quietFlag, "quiet", false, "suppress all output (only works with --prompt)")
 	rootCmd.PersistentFlags().
 		BoolVar(&noExitFlag, "no-exit", false, "prevent non-interactive mode from exiting, show input prompt instead")
+	rootCmd.PersistentFlags().
+		StringArrayVar(&attachPaths, "attach", nil, "attach a file to the prompt: images and PDFs as media, text files inline (repeatable)")
 	rootCmd.PersistentFlags().
 		IntVar(&maxSteps, "max-steps", 0, "maximum number of agent steps (0 for unlimited)")
 	rootCmd.PersistentFlags().
@@ -293,6 +344,26 @@ func init() {
 	flags.StringVar(&providerURL, "provider-url", "", "base URL for the provider API (applies to OpenAI, Anthropic, Ollama, and Google)")
 	flags.StringVar(&providerAPIKey, "provider-api-key", "", "API key for the provider (applies to OpenAI, Anthropic, and Google)")
 	flags.BoolVar(&tlsSkipVerify, "tls-skip-verify", false, "skip TLS certificate verification (WARNING: insecure, use only for self-signed certificates)")
+	flags.BoolVar(&noHooks, "no-hooks", false, "disable all hooks")
+	flags.BoolVar(&noAutoApprove, "no-auto-approve", false, "fail tool calls in non-interactive mode instead of approving them automatically")
+	flags.Float64Var(&compactionThreshold, "compaction-threshold", 0.8, "share of the context window (0-1) at which older messages are summarized automatically (0 to disable)")
+	flags.IntVar(&compactionKeepTurns, "compaction-keep-turns", 2, "number of recent turns kept verbatim when compacting the conversation")
+	flags.StringSliceVar(&fallbackModels, "fallback-models", nil, "models to try in order when the model keeps failing with rate limit, overload or server errors (comma-separated)")
+	flags.IntVar(&maxRetries, "max-retries", 3, "retries of rate limit, overload and server errors per model before falling back (0 to disable)")
+	flags.IntVar(&thinkingBudget, "thinking-budget", 0, "tokens the model may spend on extended thinking (Anthropic, Gemini, OpenRouter; 0 to disable)")
+	flags.StringVar(&reasoningEffort, "reasoning-effort", "", "reasoning effort for reasoning models: minimal, low, medium or high (OpenAI, OpenRouter)")
+	flags.StringVar(&promptCache, "prompt-cache", "auto", "prompt caching for Anthropic models: off, auto or explicit")
+	flags.Float64Var(&maxCost, "max-cost", 0, "most a prompt may cost in US dollars, including tool-calling steps (0 for unlimited)")
+	flags.Int64Var(&maxTotalTokens, "max-total-tokens", 0, "most tokens a prompt may use, including tool-calling steps (0 for unlimited)")
+	flags.Float64Var(&maxSessionCost, "max-session-cost", 0, "most the whole session may cost in US dollars (0 for unlimited)")
+	flags.Int64Var(&maxSessionTokens, "max-session-tokens", 0, "most tokens the whole session may use (0 for unlimited)")
+	flags.Float64Var(&budgetWarning, "budget-warning", agent.DefaultBudgetWarnAt, "share of a cost or token budget (0-1) at which a warning is shown")
+	flags.BoolVar(&toolSearch, "tool-search", false, "send only search_tools, load_tool and pinned tools instead of every tool; the model loads the tools it needs")
+	flags.StringSliceVar(&pinnedTools, "pinned-tools", nil, "tools always sent in tool search mode, as globs over prefixed names such as \"todo__*\" (comma-separated)")
+	flags.IntVar(&maxRepeatedToolCalls, "max-repeated-tool-calls", 3, "identical tool calls in a row after which the model is told it is looping, then stopped (0 to disable)")
+	flags.BoolVar(&resourceTool, "resource-tool", false, "add a read_resource tool that lets the model read the resources of MCP servers")
+	flags.StringVar(&outputSchemaPath, "output-schema", "", "JSON Schema file the final answer must match; only the validated JSON is printed (non-interactive mode)")
+	flags.StringVar(&elicitationAnswersPath, "elicitation-answers", "", "YAML or JSON file answering the input requests of MCP servers by server name (non-interactive mode; default decline them)")
 
 	// Model generation parameters
 	flags.IntVar(&maxTokens, "max-tokens", 4096, "maximum number of tokens in the response")
@@ -325,6 +396,26 @@ func init() {
 	_ = viper.BindPFlag("num-gpu-layers", rootCmd.PersistentFlags().Lookup("num-gpu-layers"))
 	_ = viper.BindPFlag("main-gpu", rootCmd.PersistentFlags().Lookup("main-gpu"))
 	_ = viper.BindPFlag("tls-skip-verify", rootCmd.PersistentFlags().Lookup("tls-skip-verify"))
+	_ = viper.BindPFlag("no-hooks", rootCmd.PersistentFlags().Lookup("no-hooks"))
+	_ = viper.BindPFlag("no-auto-approve", rootCmd.PersistentFlags().Lookup("no-auto-approve"))
+	_ = viper.BindPFlag("compaction-threshold", rootCmd.PersistentFlags().Lookup("compaction-threshold"))
+	_ = viper.BindPFlag("compaction-keep-turns", rootCmd.PersistentFlags().Lookup("compaction-keep-turns"))
+	_ = viper.BindPFlag("fallback-models", rootCmd.PersistentFlags().Lookup("fallback-models"))
+	_ = viper.BindPFlag("max-retries", rootCmd.PersistentFlags().Lookup("max-retries"))
+	_ = viper.BindPFlag("thinking-budget", rootCmd.PersistentFlags().Lookup("thinking-budget"))
+	_ = viper.BindPFlag("reasoning-effort", rootCmd.PersistentFlags().Lookup("reasoning-effort"))
+	_ = viper.BindPFlag("prompt-cache", rootCmd.PersistentFlags().Lookup("prompt-cache"))
+	_ = viper.BindPFlag("max-cost", rootCmd.PersistentFlags().Lookup("max-cost"))
+	_ = viper.BindPFlag("max-total-tokens", rootCmd.PersistentFlags().Lookup("max-total-tokens"))
+	_ = viper.BindPFlag("max-session-cost", rootCmd.PersistentFlags().Lookup("max-session-cost"))
+	_ = viper.BindPFlag("max-session-tokens", rootCmd.PersistentFlags().Lookup("max-session-tokens"))
+	_ = viper.BindPFlag("budget-warning", rootCmd.PersistentFlags().Lookup("budget-warning"))
+	_ = viper.BindPFlag("tool-search", rootCmd.PersistentFlags().Lookup("tool-search"))
+	_ = viper.BindPFlag("pinned-tools", rootCmd.PersistentFlags().Lookup("pinned-tools"))
+	_ = viper.BindPFlag("max-repeated-tool-calls", rootCmd.PersistentFlags().Lookup("max-repeated-tool-calls"))
+	_ = viper.BindPFlag("resource-tool", rootCmd.PersistentFlags().Lookup("resource-tool"))
+	_ = viper.BindPFlag("output-schema", rootCmd.PersistentFlags().Lookup("output-schema"))
+	_ = viper.BindPFlag("elicitation-answers", rootCmd.PersistentFlags().Lookup("elicitation-answers"))
 
 	// Defaults are already set in flag definitions, no need to duplicate in viper
 
@@ -344,6 +435,9 @@ func runNormalMode(ctx context.Context) error {
 	if noExitFlag && promptFlag == "" {
 		return fmt.Errorf("--no-exit flag can only be used with --prompt/-p")
 	}
+	if len(attachPaths) > 0 && promptFlag == "" {
+		return fmt.Errorf("--attach can only be used with --prompt/-p (use @path in interactive mode)")
+	}
 
 	// Set up logging
 	if debugMode {
@@ -365,6 +459,33 @@ func runNormalMode(ctx context.Context) error {
 		}
 	}
 
+	atts, err := attachments.LoadAll(attachPaths)
+	if err != nil {
+		return err
+	}
+
+	// An output schema or elicitation answers from the config file only
+	// apply to --prompt runs.
+	var outputSchema *agent.OutputSchema
+	var elicitationFunc tools.ElicitationFunc
+	quiet := quietFlag
+	if promptFlag != "" {
+		var schemaQuiet bool
+		outputSchema, schemaQuiet, err = SetupOutputSchema(noExitFlag)
+		if err != nil {
+			return err
+		}
+		quiet = quiet || schemaQuiet
+		elicitationFunc, err = SetupElicitationAnswers()
+		if err != nil {
+			return err
+		}
+	} else if outputSchemaPath != "" {
+		return fmt.Errorf("--output-schema can only be used with --prompt/-p")
+	} else if elicitationAnswersPath != "" {
+		return fmt.Errorf("--elicitation-answers can only be used with --prompt/-p")
+	}
+
 	// Update debug mode from viper
 	if viper.GetBool("debug") && !debugMode {
 		debugMode = viper.GetBool("debug")
@@ -373,7 +494,7 @@ func runNormalMode(ctx context.Context) error {
 
 	// Create spinner function for agent creation
 	var spinnerFunc agent.SpinnerFunc
-	if !quietFlag {
+	if !quiet {
 		spinnerFunc = func(fn func() error) error {
 			tempCli, tempErr := ui.NewCLI(viper.GetBool("debug"), viper.GetBool("compact"))
 			if tempErr == nil {
@@ -383,12 +504,32 @@ func runNormalMode(ctx context.Context) error {
 		}
 	}
 
+	// Load hooks before creating the agent so tool hooks are wired in. The
+	// session file (if any) doubles as the transcript path passed to hooks.
+	transcriptPath := sessionPath
+	if transcriptPath == "" {
+		transcriptPath = saveSessionPath
+	}
+	hookExecutor, err := SetupHookExecutor(promptFlag == "" || noExitFlag, transcriptPath)
+	if err != nil {
+		return err
+	}
+	permissionRules, err := SetupPermissions(mcpConfig)
+	if err != nil {
+		return err
+	}
+
 	// Create agent using shared setup (builds ProviderConfig from viper internally).
 	agentResult, err := SetupAgent(ctx, AgentSetupOptions{
 		MCPConfig:         mcpConfig,
 		ShowSpinner:       true,
 		SpinnerFunc:       spinnerFunc,
 		UseBufferedLogger: true,
+		HookExecutor:      hookExecutor,
+		Permissions:       permissionRules,
+		Quiet:             quiet,
+
+		AuthorizationURLFunc: printAuthorizationURL,
 	})
 	if err != nil {
 		return err
@@ -402,7 +543,7 @@ func runNormalMode(ctx context.Context) error {
 	// Create CLI for non-interactive mode only.
 	var cli *ui.CLI
 	if promptFlag != "" {
-		cli, err = SetupCLIForNonInteractive(mcpAgent)
+		cli, err = SetupCLIForNonInteractive(mcpAgent, quiet)
 		if err != nil {
 			return fmt.Errorf("failed to setup CLI: %v", err)
 		}
@@ -451,7 +592,7 @@ func runNormalMode(ctx context.Context) error {
 			sessionManager = session.NewManagerWithSession(loadedSession, saveSessionPath)
 		}
 
-		if !quietFlag && cli != nil {
+		if !quiet && cli != nil {
 			// Create a map of tool call IDs to tool calls for quick lookup
 			toolCallMap := make(map[string]session.ToolCall)
 			for _, sessionMsg := range loadedSession.Messages {
@@ -545,8 +686,13 @@ func runNormalMode(ctx context.Context) error {
 	}
 
 	// Create the app.App instance now that session messages are loaded.
-	appOpts := BuildAppOptions(mcpAgent, mcpConfig, modelName, serverNames, toolNames)
+	appOpts := BuildAppOptions(mcpAgent, mcpConfig, modelName, serverNames, toolNames, quiet)
 	appOpts.SessionManager = sessionManager
+	appOpts.HookExecutor = hookExecutor
+	appOpts.Permissions = permissionRules
+	appOpts.OutputSchema = outputSchema
+	appOpts.ElicitationFunc = elicitationFunc
+	appOpts.OutputRetries = agent.DefaultOutputRetries
 
 	// Create a usage tracker that is shared between the app layer (for recording
 	// usage after each step) and the TUI (for /usage display). For non-interactive
@@ -566,9 +712,22 @@ func runNormalMode(ctx context.Context) error {
 	appInstance := app.New(appOpts, messages)
 	defer appInstance.Close()
 
+	// Every tool call the agent makes goes through the app's approval policy,
+	// and so do the sampling requests of MCP servers, whose usage is tracked.
+	// Their requests for input go through its elicitation policy, and
+	// changes to their tools, resources and prompts, their log messages and
+	// servers connecting after startup are shown.
+	mcpAgent.SetToolApprovalFunc(appInstance.ApproveTool)
+	mcpAgent.SetSamplingApprovalFunc(appInstance.ApproveSampling)
+	mcpAgent.SetSamplingUsageFunc(appInstance.RecordSamplingUsage)
+	mcpAgent.SetElicitationFunc(appInstance.Elicit)
+	mcpAgent.SetListChangedFunc(appInstance.ServerListChanged)
+	mcpAgent.SetLogFunc(appInstance.ServerLog)
+	mcpAgent.SetServerStatusFunc(appInstance.ServerStatusChanged)
+
 	// Check if running in non-interactive mode
 	if promptFlag != "" {
-		return runNonInteractiveModeApp(ctx, appInstance, cli, promptFlag, quietFlag, noExitFlag, modelName, parsedProvider, mcpAgent.GetLoadingMessage(), serverNames, toolNames, usageTracker)
+		return runNonInteractiveModeApp(ctx, appInstance, cli, promptFlag, atts, quiet, noExitFlag, modelName, parsedProvider, mcpAgent.GetLoadingMessage(), serverNames, toolNames, mcpAgent.GetServerStatuses(), mcpAgent.GetResources(), mcpAgent.GetPrompts(), usageTracker)
 	}
 
 	// Quiet mode is not allowed in interactive mode
@@ -576,7 +735,7 @@ func runNormalMode(ctx context.Context) error {
 		return fmt.Errorf("--quiet flag can only be used with --prompt/-p")
 	}
 
-	return runInteractiveModeBubbleTea(ctx, appInstance, modelName, parsedProvider, mcpAgent.GetLoadingMessage(), serverNames, toolNames, usageTracker)
+	return runInteractiveModeBubbleTea(ctx, appInstance, modelName, parsedProvider, mcpAgent.GetLoadingMessage(), serverNames, toolNames, mcpAgent.GetServerStatuses(), mcpAgent.GetResources(), mcpAgent.GetPrompts(), usageTracker)
 }
 
 // runNonInteractiveModeApp executes a single prompt via the app layer and exits,
@@ -590,10 +749,10 @@ func runNormalMode(ctx context.Context) error {
 //
 // When --no-exit is set, after the prompt completes the interactive BubbleTea
 // TUI is started so the user can continue the conversation.
-func runNonInteractiveModeApp(ctx context.Context, appInstance *app.App, cli *ui.CLI, prompt string, quiet, noExit bool, modelName, providerName, loadingMessage string, serverNames, toolNames []string, usageTracker *ui.UsageTracker) error {
+func runNonInteractiveModeApp(ctx context.Context, appInstance *app.App, cli *ui.CLI, prompt string, atts []*attachments.Attachment, quiet, noExit bool, modelName, providerName, loadingMessage string, serverNames, toolNames []string, serverStatuses []tools.ServerStatus, resources []tools.Resource, prompts []tools.Prompt, usageTracker *ui.UsageTracker) error {
 	if quiet {
 		// Quiet mode: no intermediate display, just print final response.
-		if err := appInstance.RunOnce(ctx, prompt); err != nil {
+		if err := appInstance.RunOnce(ctx, prompt, atts...); err != nil {
 			return err
 		}
 	} else if cli != nil {
@@ -602,21 +761,21 @@ func runNonInteractiveModeApp(ctx context.Context, appInstance *app.App, cli *ui
 
 		// Route events through the shared CLI event handler.
 		eventHandler := ui.NewCLIEventHandler(cli, modelName)
-		err := appInstance.RunOnceWithDisplay(ctx, prompt, eventHandler.Handle)
+		err := appInstance.RunOnceWithDisplay(ctx, prompt, eventHandler.Handle, atts...)
 		eventHandler.Cleanup()
 		if err != nil {
 			return err
 		}
 	} else {
 		// No CLI available (shouldn't happen in non-quiet mode, but be safe).
-		if err := appInstance.RunOnce(ctx, prompt); err != nil {
+		if err := appInstance.RunOnce(ctx, prompt, atts...); err != nil {
 			return err
 		}
 	}
 
 	// If --no-exit was requested, hand off to the interactive TUI.
 	if noExit {
-		return runInteractiveModeBubbleTea(ctx, appInstance, modelName, providerName, loadingMessage, serverNames, toolNames, usageTracker)
+		return runInteractiveModeBubbleTea(ctx, appInstance, modelName, providerName, loadingMessage, serverNames, toolNames, serverStatuses, resources, prompts, usageTracker)
 	}
 
 	return nil
@@ -633,7 +792,7 @@ func runNonInteractiveModeApp(ctx context.Context, appInstance *app.App, cli *ui
 //  4. Calls program.Run() which blocks until the user quits (Ctrl+C or /quit).
 //
 // SetupCLI is not used for interactive mode; the TUI (AppModel) handles its own rendering.
-func runInteractiveModeBubbleTea(_ context.Context, appInstance *app.App, modelName, providerName, loadingMessage string, serverNames, toolNames []string, usageTracker *ui.UsageTracker) error {
+func runInteractiveModeBubbleTea(_ context.Context, appInstance *app.App, modelName, providerName, loadingMessage string, serverNames, toolNames []string, serverStatuses []tools.ServerStatus, resources []tools.Resource, prompts []tools.Prompt, usageTracker *ui.UsageTracker) error {
 	// Determine terminal size; fall back gracefully.
 	termWidth, termHeight, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil || termWidth == 0 {
@@ -650,6 +809,9 @@ func runInteractiveModeBubbleTea(_ context.Context, appInstance *app.App, modelN
 		Height:         termHeight,
 		ServerNames:    serverNames,
 		ToolNames:      toolNames,
+		ServerStatuses: serverStatuses,
+		Resources:      resources,
+		Prompts:        prompts,
 		UsageTracker:   usageTracker,
 	})
 
@@ -658,8 +820,13 @@ func runInteractiveModeBubbleTea(_ context.Context, appInstance *app.App, modelN
 
 	program := tea.NewProgram(appModel)
 
-	// Register the program with the app layer so agent events are sent to the TUI.
+	// Register the program with the app layer so agent events are sent to the TUI,
+	// and ask the user before every tool call and sampling request, and for the
+	// input MCP servers request, from now on.
 	appInstance.SetProgram(program)
+	appInstance.SetToolApprovalFunc(app.NewInteractiveApprovalFunc(appInstance))
+	appInstance.SetSamplingApprovalFunc(app.NewInteractiveSamplingApprovalFunc(appInstance))
+	appInstance.SetElicitationFunc(app.NewInteractiveElicitationFunc(appInstance))
 
 	_, runErr := program.Run()
 	return runErr
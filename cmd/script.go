@@ -9,7 +9,9 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/mark3labs/mcphost/internal/agent"
 	"github.com/mark3labs/mcphost/internal/app"
+	"github.com/mark3labs/mcphost/internal/attachments"
 	"github.com/mark3labs/mcphost/internal/config"
 	"github.com/mark3labs/mcphost/internal/ui"
 
@@ -138,6 +140,9 @@ func overrideConfigWithFrontmatter(scriptFile string, variables map[string]strin
 	if scriptConfig.TLSSkipVerify && !flagChanged("tls-skip-verify") {
 		viper.Set("tls-skip-verify", scriptConfig.TLSSkipVerify)
 	}
+	if scriptConfig.OutputSchema != "" && !flagChanged("output-schema") {
+		viper.Set("output-schema", scriptConfig.OutputSchema)
+	}
 }
 
 // parseCustomVariables extracts custom variables from command line arguments
@@ -510,11 +515,38 @@ func runScriptMode(ctx context.Context, mcpConfig *config.Config, prompt string,
 		log.SetFlags(log.LstdFlags | log.Lshortfile)
 	}
 
+	outputSchema, schemaQuiet, err := SetupOutputSchema(noExit)
+	if err != nil {
+		return err
+	}
+	quiet := quietFlag || schemaQuiet
+	elicitationFunc, err := SetupElicitationAnswers()
+	if err != nil {
+		return err
+	}
+	atts, err := attachments.LoadAll(attachPaths)
+	if err != nil {
+		return err
+	}
+
 	// Create agent using shared setup. Script frontmatter values are already
 	// merged into viper by overrideConfigWithFrontmatter (PreRun hook), so
 	// BuildProviderConfig inside SetupAgent reads the correct final values.
+	hookExecutor, err := SetupHookExecutor(false, "")
+	if err != nil {
+		return err
+	}
+	permissionRules, err := SetupPermissions(mcpConfig)
+	if err != nil {
+		return err
+	}
+
 	agentResult, err := SetupAgent(ctx, AgentSetupOptions{
-		MCPConfig: mcpConfig,
+		MCPConfig:            mcpConfig,
+		HookExecutor:         hookExecutor,
+		Permissions:          permissionRules,
+		AuthorizationURLFunc: printAuthorizationURL,
+		Quiet:                quiet,
 	})
 	if err != nil {
 		return err
@@ -526,7 +558,7 @@ func runScriptMode(ctx context.Context, mcpConfig *config.Config, prompt string,
 	parsedProvider, modelName, serverNames, toolNames := CollectAgentMetadata(mcpAgent, mcpConfig)
 
 	// Create CLI display layer.
-	cli, err := SetupCLIForNonInteractive(mcpAgent)
+	cli, err := SetupCLIForNonInteractive(mcpAgent, quiet)
 	if err != nil {
 		return fmt.Errorf("failed to setup CLI: %v", err)
 	}
@@ -534,7 +566,12 @@ func runScriptMode(ctx context.Context, mcpConfig *config.Config, prompt string,
 	DisplayDebugConfig(cli, mcpAgent, mcpConfig, parsedProvider)
 
 	// Build app options.
-	appOpts := BuildAppOptions(mcpAgent, mcpConfig, modelName, serverNames, toolNames)
+	appOpts := BuildAppOptions(mcpAgent, mcpConfig, modelName, serverNames, toolNames, quiet)
+	appOpts.HookExecutor = hookExecutor
+	appOpts.Permissions = permissionRules
+	appOpts.OutputSchema = outputSchema
+	appOpts.ElicitationFunc = elicitationFunc
+	appOpts.OutputRetries = agent.DefaultOutputRetries
 	if cli != nil {
 		if tracker := cli.GetUsageTracker(); tracker != nil {
 			appOpts.UsageTracker = tracker
@@ -544,9 +581,22 @@ func runScriptMode(ctx context.Context, mcpConfig *config.Config, prompt string,
 	appInstance := app.New(appOpts, nil)
 	defer appInstance.Close()
 
-	if quietFlag {
+	// Every tool call the agent makes goes through the app's approval policy,
+	// and so do the sampling requests of MCP servers, whose usage is tracked.
+	// Their requests for input go through its elicitation policy, and
+	// changes to their tools, resources and prompts, their log messages and
+	// servers connecting after startup are shown.
+	mcpAgent.SetToolApprovalFunc(appInstance.ApproveTool)
+	mcpAgent.SetSamplingApprovalFunc(appInstance.ApproveSampling)
+	mcpAgent.SetSamplingUsageFunc(appInstance.RecordSamplingUsage)
+	mcpAgent.SetElicitationFunc(appInstance.Elicit)
+	mcpAgent.SetListChangedFunc(appInstance.ServerListChanged)
+	mcpAgent.SetLogFunc(appInstance.ServerLog)
+	mcpAgent.SetServerStatusFunc(appInstance.ServerStatusChanged)
+
+	if quiet {
 		// Quiet mode: no intermediate display, just print final response.
-		return appInstance.RunOnce(ctx, prompt)
+		return appInstance.RunOnce(ctx, prompt, atts...)
 	}
 
 	// Display user message before running the agent.
@@ -556,7 +606,7 @@ func runScriptMode(ctx context.Context, mcpConfig *config.Config, prompt string,
 
 	// Build an event handler that routes app events to the CLI.
 	eventHandler := ui.NewCLIEventHandler(cli, modelName)
-	err = appInstance.RunOnceWithDisplay(ctx, prompt, eventHandler.Handle)
+	err = appInstance.RunOnceWithDisplay(ctx, prompt, eventHandler.Handle, atts...)
 	eventHandler.Cleanup()
 	return err
 }
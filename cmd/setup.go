@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/mark3labs/mcphost/internal/agent"
 	"github.com/mark3labs/mcphost/internal/app"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/hooks"
 	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/permissions"
 	"github.com/mark3labs/mcphost/internal/tools"
 	"github.com/mark3labs/mcphost/internal/ui"
 	"github.com/spf13/viper"
@@ -34,18 +39,23 @@ func BuildProviderConfig() (*models.ProviderConfig, string, error) {
 	mainGPU := int32(viper.GetInt("main-gpu"))
 
 	cfg := &models.ProviderConfig{
-		ModelString:    viper.GetString("model"),
-		SystemPrompt:   systemPrompt,
-		ProviderAPIKey: viper.GetString("provider-api-key"),
-		ProviderURL:    viper.GetString("provider-url"),
-		MaxTokens:      viper.GetInt("max-tokens"),
-		Temperature:    &temperature,
-		TopP:           &topP,
-		TopK:           &topK,
-		StopSequences:  viper.GetStringSlice("stop-sequences"),
-		NumGPU:         &numGPU,
-		MainGPU:        &mainGPU,
-		TLSSkipVerify:  viper.GetBool("tls-skip-verify"),
+		ModelString:     viper.GetString("model"),
+		SystemPrompt:    systemPrompt,
+		ProviderAPIKey:  viper.GetString("provider-api-key"),
+		ProviderURL:     viper.GetString("provider-url"),
+		MaxTokens:       viper.GetInt("max-tokens"),
+		Temperature:     &temperature,
+		TopP:            &topP,
+		TopK:            &topK,
+		StopSequences:   viper.GetStringSlice("stop-sequences"),
+		NumGPU:          &numGPU,
+		MainGPU:         &mainGPU,
+		TLSSkipVerify:   viper.GetBool("tls-skip-verify"),
+		FallbackModels:  viper.GetStringSlice("fallback-models"),
+		MaxRetries:      viper.GetInt("max-retries"),
+		ThinkingBudget:  viper.GetInt("thinking-budget"),
+		ReasoningEffort: viper.GetString("reasoning-effort"),
+		PromptCache:     viper.GetString("prompt-cache"),
 	}
 
 	return cfg, systemPrompt, nil
@@ -63,6 +73,17 @@ type AgentSetupOptions struct {
 	// UseBufferedLogger captures debug messages for later display (root
 	// non-interactive path). When false a simple logger is used instead.
 	UseBufferedLogger bool
+	// HookExecutor fires PreToolUse/PostToolUse hooks around tool calls
+	// (nil = no tool hooks). See SetupHookExecutor.
+	HookExecutor *hooks.Executor
+	// Permissions holds the allow/ask/deny rules checked before tool calls
+	// (nil = no rules). See SetupPermissions.
+	Permissions *permissions.Ruleset
+	// AuthorizationURLFunc shows the URL authorizing mcphost for a remote MCP
+	// server requiring OAuth (nil = no browser flow). See printAuthorizationURL.
+	AuthorizationURLFunc tools.AuthorizationURLFunc
+	// Quiet suppresses the agent's own output (--quiet, or an output schema).
+	Quiet bool
 }
 
 // AgentSetupResult bundles the created agent and any debug logger so the caller
@@ -100,9 +121,24 @@ func SetupAgent(ctx context.Context, opts AgentSetupOptions) (*AgentSetupResult,
 		MaxSteps:         viper.GetInt("max-steps"),
 		StreamingEnabled: viper.GetBool("stream"),
 		ShowSpinner:      opts.ShowSpinner,
-		Quiet:            quietFlag,
+		Quiet:            opts.Quiet,
 		SpinnerFunc:      opts.SpinnerFunc,
 		DebugLogger:      debugLogger,
+		HookExecutor:     opts.HookExecutor,
+		Permissions:      opts.Permissions,
+		Budget: agent.Budget{
+			MaxCost:          viper.GetFloat64("max-cost"),
+			MaxTokens:        viper.GetInt64("max-total-tokens"),
+			MaxSessionCost:   viper.GetFloat64("max-session-cost"),
+			MaxSessionTokens: viper.GetInt64("max-session-tokens"),
+			WarnAt:           viper.GetFloat64("budget-warning"),
+		},
+		ToolSearch:  viper.GetBool("tool-search"),
+		PinnedTools: viper.GetStringSlice("pinned-tools"),
+
+		MaxRepeatedToolCalls: viper.GetInt("max-repeated-tool-calls"),
+		ResourceTool:         viper.GetBool("resource-tool"),
+		AuthorizationURLFunc: opts.AuthorizationURLFunc,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
@@ -114,6 +150,54 @@ func SetupAgent(ctx context.Context, opts AgentSetupOptions) (*AgentSetupResult,
 	}, nil
 }
 
+// SetupHookExecutor loads the hooks configuration and creates the executor
+// shared by the agent (tool hooks) and the app layer (prompt and stop hooks).
+// Returns nil when hooks are disabled with --no-hooks or none are configured.
+func SetupHookExecutor(interactive bool, transcriptPath string) (*hooks.Executor, error) {
+	if viper.GetBool("no-hooks") {
+		return nil, nil
+	}
+
+	hookConfig, err := hooks.LoadHooksConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hooks config: %w", err)
+	}
+	if len(hookConfig.Hooks) == 0 {
+		return nil, nil
+	}
+	if err := hooks.ValidateHookConfig(hookConfig); err != nil {
+		return nil, fmt.Errorf("invalid hooks config: %w", err)
+	}
+
+	executor := hooks.NewExecutor(hookConfig, rand.Text(), transcriptPath)
+	executor.SetModel(viper.GetString("model"))
+	executor.SetInteractive(interactive)
+	return executor, nil
+}
+
+// SetupPermissions builds the permission rules shared by the agent (which
+// checks them before every tool call) and the app layer (which saves "always
+// allow" answers). Rules come from the "permissions" key of the main config
+// followed by the project file .mcphost/permissions.yml, whose allow rules
+// only apply when they were approved in the prompt (see
+// permissions.ApprovalsFile).
+func SetupPermissions(mcpConfig *config.Config) (*permissions.Ruleset, error) {
+	dataDir, err := models.DataDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	rules, err := permissions.Load(mcpConfig.Permissions, permissions.ProjectFile,
+		filepath.Join(dataDir, permissions.ApprovalsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	for _, rule := range rules.Ignored() {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %q from %s: it was not approved in the approval prompt; add it to your config file to allow it\n",
+			rule.String(), permissions.ProjectFile)
+	}
+	return rules, nil
+}
+
 // CollectAgentMetadata extracts model display info and tool/server name lists
 // from the agent. This is used by both root.go and script.go to populate
 // app.Options and UI setup.
@@ -138,24 +222,87 @@ func CollectAgentMetadata(mcpAgent *agent.Agent, mcpConfig *config.Config) (prov
 
 // BuildAppOptions constructs the app.Options struct from the current state.
 // Both root.go and script.go converge here after agent creation.
-func BuildAppOptions(mcpAgent *agent.Agent, mcpConfig *config.Config, modelName string, serverNames, toolNames []string) app.Options {
+func BuildAppOptions(mcpAgent *agent.Agent, mcpConfig *config.Config, modelName string, serverNames, toolNames []string, quiet bool) app.Options {
 	return app.Options{
-		Agent:            mcpAgent,
-		MCPConfig:        mcpConfig,
-		ModelName:        modelName,
-		ServerNames:      serverNames,
-		ToolNames:        toolNames,
-		StreamingEnabled: viper.GetBool("stream"),
-		Quiet:            quietFlag,
-		Debug:            viper.GetBool("debug"),
-		CompactMode:      viper.GetBool("compact"),
+		Agent:                  mcpAgent,
+		MCPConfig:              mcpConfig,
+		ModelName:              modelName,
+		Provider:               modelProvider(viper.GetString("model")),
+		ServerNames:            serverNames,
+		ToolNames:              toolNames,
+		StreamingEnabled:       viper.GetBool("stream"),
+		Quiet:                  quiet,
+		Debug:                  viper.GetBool("debug"),
+		CompactMode:            viper.GetBool("compact"),
+		ToolApprovalFunc:       nonInteractiveApprovalFunc(),
+		SamplingApprovalFunc:   nonInteractiveSamplingApprovalFunc(),
+		CompactThreshold:       viper.GetFloat64("compaction-threshold"),
+		CompactKeepTurns:       viper.GetInt("compaction-keep-turns"),
+		ContextWindow:          modelContextWindow(viper.GetString("model")),
+		AttachmentsUnsupported: !modelAcceptsAttachments(viper.GetString("model")),
+	}
+}
+
+// lookupModel returns the models registry entry of the given model, or nil
+// when the model is unknown.
+func lookupModel(modelString string) *models.ModelInfo {
+	provider, modelID, err := models.ParseModelString(modelString)
+	if err != nil {
+		return nil
 	}
+	return models.GetGlobalRegistry().LookupModel(provider, modelID)
+}
+
+// modelProvider returns the provider of the given model, or "" when the model
+// string is invalid.
+func modelProvider(modelString string) string {
+	provider, _, _ := models.ParseModelString(modelString)
+	return provider
+}
+
+// modelContextWindow returns the context window size of the given model from
+// the models registry, or 0 when the model is unknown.
+func modelContextWindow(modelString string) int {
+	info := lookupModel(modelString)
+	if info == nil {
+		return 0
+	}
+	return info.Limit.Context
+}
+
+// modelAcceptsAttachments reports whether the given model accepts images and
+// other files according to the models registry. Unknown models are assumed
+// to accept them; the provider reports an error if they do not.
+func modelAcceptsAttachments(modelString string) bool {
+	info := lookupModel(modelString)
+	return info == nil || info.Attachment
+}
+
+// nonInteractiveApprovalFunc returns the tool approval policy used while no one
+// can answer an approval prompt: tool calls are approved automatically unless
+// --no-auto-approve is set, in which case they fail. Interactive mode replaces
+// it with a TUI prompt once the program is running.
+func nonInteractiveApprovalFunc() app.ToolApprovalFunc {
+	if viper.GetBool("no-auto-approve") {
+		return app.RequireApprovalFunc
+	}
+	return app.AutoApproveFunc
+}
+
+// nonInteractiveSamplingApprovalFunc returns the matching policy for sampling
+// requests of MCP servers: nil approves them, and --no-auto-approve fails them.
+func nonInteractiveSamplingApprovalFunc() tools.SamplingApprovalFunc {
+	if viper.GetBool("no-auto-approve") {
+		return app.RequireSamplingApprovalFunc
+	}
+	return nil
 }
 
 // DisplayDebugConfig builds and displays the debug configuration map through
-// the CLI. Shared by root.go (non-interactive) and script.go.
+// the CLI, which is nil in quiet mode. Shared by root.go (non-interactive) and
+// script.go.
 func DisplayDebugConfig(cli *ui.CLI, mcpAgent *agent.Agent, mcpConfig *config.Config, provider string) {
-	if quietFlag || cli == nil || !viper.GetBool("debug") {
+	if cli == nil || !viper.GetBool("debug") {
 		return
 	}
 
@@ -194,6 +341,14 @@ func DisplayDebugConfig(cli *ui.CLI, mcpAgent *agent.Agent, mcpConfig *config.Co
 		for _, name := range mcpAgent.GetLoadedServerNames() {
 			loadedServerSet[name] = true
 		}
+		// Servers still connecting, or lazy ones not started, are not loaded
+		// but have not failed either
+		pendingServerStates := make(map[string]tools.ServerState)
+		for _, status := range mcpAgent.GetServerStatuses() {
+			if status.State == tools.ServerConnecting || status.State == tools.ServerLazy {
+				pendingServerStates[status.Name] = status.State
+			}
+		}
 
 		for name, server := range mcpConfig.MCPServers {
 			serverInfo := map[string]any{
@@ -202,6 +357,8 @@ func DisplayDebugConfig(cli *ui.CLI, mcpAgent *agent.Agent, mcpConfig *config.Co
 			}
 			if loadedServerSet[name] {
 				serverInfo["status"] = "loaded"
+			} else if state, ok := pendingServerStates[name]; ok {
+				serverInfo["status"] = string(state)
 			}
 			if len(server.Command) > 0 {
 				serverInfo["command"] = server.Command
@@ -233,16 +390,46 @@ func DisplayDebugConfig(cli *ui.CLI, mcpAgent *agent.Agent, mcpConfig *config.Co
 	cli.DisplayDebugConfig(debugConfig)
 }
 
+// SetupOutputSchema loads the JSON Schema named by --output-schema (or the
+// output-schema config or frontmatter key). Returns nil when none is set.
+// Structured output only prints the validated JSON, so quiet reports that the
+// run must be quiet; it cannot be combined with --no-exit.
+func SetupOutputSchema(noExit bool) (outputSchema *agent.OutputSchema, quiet bool, err error) {
+	path := viper.GetString("output-schema")
+	if path == "" {
+		return nil, false, nil
+	}
+	if noExit {
+		return nil, false, fmt.Errorf("--output-schema cannot be used with --no-exit")
+	}
+	outputSchema, err = agent.LoadOutputSchema(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return outputSchema, true, nil
+}
+
+// SetupElicitationAnswers loads the answers file named by
+// --elicitation-answers (or the elicitation-answers config key). Returns nil,
+// which declines every request, when none is set.
+func SetupElicitationAnswers() (tools.ElicitationFunc, error) {
+	path := viper.GetString("elicitation-answers")
+	if path == "" {
+		return nil, nil
+	}
+	return app.LoadElicitationAnswers(path)
+}
+
 // SetupCLIForNonInteractive creates the CLI display layer for non-interactive
-// modes (--prompt and script). Returns nil when quiet mode is active.
-func SetupCLIForNonInteractive(mcpAgent *agent.Agent) (*ui.CLI, error) {
+// modes (--prompt and script). Returns nil when quiet is set.
+func SetupCLIForNonInteractive(mcpAgent *agent.Agent, quiet bool) (*ui.CLI, error) {
 	agentAdapter := &agentUIAdapter{agent: mcpAgent}
 	return ui.SetupCLI(&ui.CLISetupOptions{
 		Agent:          agentAdapter,
 		ModelString:    viper.GetString("model"),
 		Debug:          viper.GetBool("debug"),
 		Compact:        viper.GetBool("compact"),
-		Quiet:          quietFlag,
+		Quiet:          quiet,
 		ShowDebug:      false,
 		ProviderAPIKey: viper.GetString("provider-api-key"),
 	})
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// TestSetupOutputSchema verifies that an output schema asks the caller for a
+// quiet run instead of changing --quiet.
+func TestSetupOutputSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(`{"type": "object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { viper.Set("output-schema", "") })
+
+	viper.Set("output-schema", "")
+	if schema, quiet, err := SetupOutputSchema(false); schema != nil || quiet || err != nil {
+		t.Fatalf("Expected no schema without --output-schema, got %v, %v, %v", schema, quiet, err)
+	}
+
+	viper.Set("output-schema", path)
+	schema, quiet, err := SetupOutputSchema(false)
+	if err != nil || schema == nil || !quiet {
+		t.Fatalf("Expected the schema and a quiet run, got %v, %v, %v", schema, quiet, err)
+	}
+	if quietFlag {
+		t.Error("Expected --quiet to be left alone")
+	}
+
+	if _, _, err := SetupOutputSchema(true); err == nil {
+		t.Error("Expected an error with --no-exit")
+	}
+}
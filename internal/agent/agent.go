@@ -3,14 +3,20 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"charm.land/fantasy"
 
+	"github.com/mark3labs/mcphost/internal/attachments"
+	"github.com/mark3labs/mcphost/internal/builtin"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/hooks"
 	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/permissions"
 	"github.com/mark3labs/mcphost/internal/tools"
 )
 
@@ -22,6 +28,24 @@ type AgentConfig struct {
 	MaxSteps         int
 	StreamingEnabled bool
 	DebugLogger      tools.DebugLogger
+	HookExecutor     *hooks.Executor
+	Permissions      *permissions.Ruleset
+	Budget           Budget
+	// ToolSearch sends only the search_tools and load_tool meta-tools, the
+	// PinnedTools and the tools the model loaded instead of every tool.
+	ToolSearch  bool
+	PinnedTools []string
+	// MaxRepeatedToolCalls is the number of identical tool calls in a row
+	// after which the model is told it is looping, and stopped if it goes on.
+	// Short repeating cycles of calls are caught too. 0 disables the check.
+	MaxRepeatedToolCalls int
+	// ResourceTool adds the read_resource tool, which lets the model read the
+	// resources of the MCP servers.
+	ResourceTool bool
+	// AuthorizationURLFunc shows the URL at which the user authorizes
+	// mcphost for a remote MCP server requiring OAuth. Without one, such
+	// servers only connect once authorized with `mcphost auth login`.
+	AuthorizationURLFunc tools.AuthorizationURLFunc
 }
 
 // ToolCallHandler is a function type for handling tool calls as they happen.
@@ -42,6 +66,9 @@ type StreamingResponseHandler func(content string)
 // ToolCallContentHandler is a function type for handling content that accompanies tool calls.
 type ToolCallContentHandler func(content string)
 
+// ReasoningHandler is a function type for handling streaming reasoning ("thinking") deltas.
+type ReasoningHandler func(delta string)
+
 // Agent represents an AI agent with MCP tool integration using the fantasy library.
 // It manages the interaction between an LLM and various tools through the MCP protocol.
 type Agent struct {
@@ -54,6 +81,13 @@ type Agent struct {
 	loadingMessage   string
 	providerType     string
 	streamingEnabled bool
+	nested           bool                        // a sub-agent started by the task tool
+	callOpts         []fantasy.AgentOption       // per-call model settings, shared with sub-agents
+	prepareStep      fantasy.PrepareStepFunction // prepares each step of a call; may be nil
+	promptCache      string                      // prompt cache mode, see models.PromptCacheMode
+	budget           *budgetTracker              // nil without budgets; shared with sub-agents
+	loopGuard        *loopGuard                  // nil without loop detection; sub-agents get their own
+	contextGuard     *contextGuard               // nil when the context window is unknown; sub-agents get their own
 }
 
 // GenerateWithLoopResult contains the result and conversation history from an agent interaction.
@@ -64,46 +98,141 @@ type GenerateWithLoopResult struct {
 	ConversationMessages []fantasy.Message
 	// TotalUsage contains aggregate token usage across all steps
 	TotalUsage fantasy.Usage
+	// StoppedByHook is true when a tool hook returned "continue": false and the loop ended early
+	StoppedByHook bool
+	// HookStopReason is the reason given by the hook that stopped the loop, if any
+	HookStopReason string
+	// StructuredOutput is the validated JSON answer set by GenerateStructuredOutput
+	StructuredOutput json.RawMessage
+	// BudgetExceeded wraps ErrBudgetExceeded when a budget stopped the loop
+	// before the final answer
+	BudgetExceeded error
+	// ToolLoop wraps ErrToolLoop when the model kept repeating the same tool
+	// calls after being told to stop and the loop ended early
+	ToolLoop error
+	// ContextFull wraps ErrContextFull when the next step's request would
+	// have been over the model's context window and the loop ended early
+	ContextFull error
 }
 
 // NewAgent creates a new Agent with MCP tool integration and streaming support.
 func NewAgent(ctx context.Context, agentConfig *AgentConfig) (*Agent, error) {
+	callOpts, err := reasoningCallOptions(agentConfig.ModelConfig)
+	if err != nil {
+		return nil, err
+	}
+	promptCache := models.PromptCacheOff
+	if agentConfig.ModelConfig != nil {
+		if promptCache, err = models.PromptCacheMode(agentConfig.ModelConfig); err != nil {
+			return nil, err
+		}
+	}
+	budget, err := newBudgetTracker(agentConfig.Budget, agentConfig.ModelConfig)
+	if err != nil {
+		return nil, err
+	}
+	guard, err := newLoopGuard(agentConfig.MaxRepeatedToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	contextGuard := newContextGuard(agentConfig.ModelConfig)
+
 	// Create the LLM provider via fantasy
 	providerResult, err := models.CreateProvider(ctx, agentConfig.ModelConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model provider: %v", err)
 	}
 
+	// a is assigned once the agent is built; the task runner is only called
+	// by tool calls, which cannot happen before then.
+	var a *Agent
+
 	// Create and load MCP tools
 	toolManager := tools.NewMCPToolManager()
 	toolManager.SetModel(providerResult.Model)
+	toolManager.SetTaskRunner(func(ctx context.Context, req builtin.TaskRequest) (string, error) {
+		return a.runTask(ctx, req)
+	})
 
 	if agentConfig.DebugLogger != nil {
 		toolManager.SetDebugLogger(agentConfig.DebugLogger)
 	}
 
+	if agentConfig.HookExecutor != nil {
+		toolManager.SetHookExecutor(agentConfig.HookExecutor)
+	}
+
+	if agentConfig.Permissions != nil {
+		toolManager.SetPermissions(agentConfig.Permissions)
+	}
+
+	// Sampling requests of servers are paid from the budgets too
+	if budget != nil {
+		toolManager.SetSamplingBudgetFunc(budget.sessionErr)
+		toolManager.SetSamplingUsageFunc(budget.samplingUsageFunc(nil))
+	}
+	toolManager.SetAuthorizationURLFunc(agentConfig.AuthorizationURLFunc)
+
 	if err := toolManager.LoadTools(ctx, agentConfig.MCPConfig); err != nil {
 		return nil, fmt.Errorf("failed to load MCP tools: %v", err)
 	}
+	if agentConfig.ResourceTool {
+		toolManager.EnableResourceTool()
+	}
 
-	// Build fantasy agent options
-	var agentOpts []fantasy.AgentOption
+	// Build fantasy agent options. Transient provider errors are retried by
+	// the model itself (see models.FallbackModel), so fantasy must not retry.
+	agentOpts := []fantasy.AgentOption{fantasy.WithMaxRetries(0)}
+	agentOpts = append(agentOpts, callOpts...)
 
 	if agentConfig.SystemPrompt != "" {
 		agentOpts = append(agentOpts, fantasy.WithSystemPrompt(agentConfig.SystemPrompt))
 	}
 
-	// Register all MCP tools with the fantasy agent
-	mcpTools := toolManager.GetTools()
+	// Register all MCP tools with the fantasy agent. The active tools are
+	// picked anew for each step, so tools loaded in tool search mode and
+	// tools a server reloaded during the session are sent from the next step
+	// on; sub-agents keep the tools allowed to them.
+	if agentConfig.ToolSearch {
+		toolManager.EnableToolSearch(agentConfig.PinnedTools)
+	}
+	prepareStep := activeToolsStep(toolManager, guard, promptCache, promptCacheStep(promptCache))
+	mcpTools := toolManager.ActiveTools()
 	if len(mcpTools) > 0 {
-		agentOpts = append(agentOpts, fantasy.WithTools(mcpTools...))
+		agentOpts = append(agentOpts, fantasy.WithTools(cacheTools(guard.wrap(mcpTools), promptCache)...))
 	}
 
 	// Set max steps as stop condition
+	var stopConditions []fantasy.StopCondition
 	if agentConfig.MaxSteps > 0 {
-		agentOpts = append(agentOpts, fantasy.WithStopConditions(
-			fantasy.StepCountIs(agentConfig.MaxSteps),
-		))
+		stopConditions = append(stopConditions, fantasy.StepCountIs(agentConfig.MaxSteps))
+	}
+
+	// End the loop once a tool hook has asked to stop with "continue": false
+	if agentConfig.HookExecutor != nil {
+		stopConditions = append(stopConditions, func(_ []fantasy.StepResult) bool {
+			_, stopped := toolManager.HookStop()
+			return stopped
+		})
+	}
+
+	// Stop before a step that would exceed a cost or token budget
+	if budget != nil {
+		stopConditions = append(stopConditions, budget.stopCondition())
+	}
+
+	// Stop once the model keeps looping after being told it is
+	if guard != nil {
+		stopConditions = append(stopConditions, guard.stopCondition())
+	}
+
+	// Stop before a request that would be over the context window
+	if contextGuard != nil {
+		stopConditions = append(stopConditions, contextGuard.stopCondition())
+	}
+
+	if len(stopConditions) > 0 {
+		agentOpts = append(agentOpts, fantasy.WithStopConditions(stopConditions...))
 	}
 
 	// Create the fantasy agent
@@ -117,7 +246,7 @@ func NewAgent(ctx context.Context, agentConfig *AgentConfig) (*Agent, error) {
 		}
 	}
 
-	return &Agent{
+	a = &Agent{
 		toolManager:      toolManager,
 		fantasyAgent:     fantasyAgent,
 		model:            providerResult.Model,
@@ -127,6 +256,39 @@ func NewAgent(ctx context.Context, agentConfig *AgentConfig) (*Agent, error) {
 		loadingMessage:   providerResult.Message,
 		providerType:     providerType,
 		streamingEnabled: agentConfig.StreamingEnabled,
+		callOpts:         callOpts,
+		prepareStep:      prepareStep,
+		promptCache:      promptCache,
+		budget:           budget,
+		loopGuard:        guard,
+		contextGuard:     contextGuard,
+	}
+	return a, nil
+}
+
+// reasoningCallOptions returns the agent options that enable extended
+// thinking as configured in modelConfig, or nil when it is not configured.
+// The thinking budget comes on top of the response's max tokens, since
+// Anthropic requires the budget to be smaller than max_tokens.
+func reasoningCallOptions(modelConfig *models.ProviderConfig) ([]fantasy.AgentOption, error) {
+	if modelConfig == nil {
+		return nil, nil
+	}
+	providerOptions, err := models.ReasoningProviderOptions(modelConfig)
+	if err != nil {
+		return nil, err
+	}
+	if providerOptions == nil {
+		return nil, nil
+	}
+
+	maxTokens := modelConfig.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 4096
+	}
+	return []fantasy.AgentOption{
+		fantasy.WithProviderOptions(providerOptions),
+		fantasy.WithMaxOutputTokens(int64(maxTokens + models.ThinkingBudget(modelConfig))),
 	}, nil
 }
 
@@ -136,7 +298,7 @@ func (a *Agent) GenerateWithLoop(ctx context.Context, messages []fantasy.Message
 	onResponse ResponseHandler, onToolCallContent ToolCallContentHandler,
 ) (*GenerateWithLoopResult, error) {
 	return a.GenerateWithLoopAndStreaming(ctx, messages, onToolCall, onToolExecution, onToolResult,
-		onResponse, onToolCallContent, nil)
+		onResponse, onToolCallContent, nil, nil)
 }
 
 // GenerateWithLoopAndStreaming processes messages using the fantasy agent with streaming and callbacks.
@@ -145,12 +307,24 @@ func (a *Agent) GenerateWithLoop(ctx context.Context, messages []fantasy.Message
 func (a *Agent) GenerateWithLoopAndStreaming(ctx context.Context, messages []fantasy.Message,
 	onToolCall ToolCallHandler, onToolExecution ToolExecutionHandler, onToolResult ToolResultHandler,
 	onResponse ResponseHandler, onToolCallContent ToolCallContentHandler,
-	onStreamingResponse StreamingResponseHandler,
+	onStreamingResponse StreamingResponseHandler, onReasoning ReasoningHandler,
 ) (*GenerateWithLoopResult, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages to send to the model")
+	}
 
-	// Fantasy requires the current user input as Prompt, with prior messages as history.
-	// Extract the last user message text as the prompt, and pass everything before it as Messages.
-	prompt, history := splitPromptAndHistory(messages)
+	// Clear any stop request and tool calls left over from a previous step
+	// and start the prompt's budget. A sub-agent runs inside the parent's
+	// step, so it must not clear a request made there and spends from the
+	// parent's budget.
+	if !a.nested {
+		a.toolManager.ResetHookStop()
+		a.loopGuard.reset()
+		a.contextGuard.reset()
+		if err := a.budget.startPrompt(budgetWarningHandlerFrom(ctx)); err != nil {
+			return nil, err
+		}
+	}
 
 	// Track current tool call info for callbacks
 	var currentToolName string
@@ -160,14 +334,17 @@ func (a *Agent) GenerateWithLoopAndStreaming(ctx context.Context, messages []fan
 	// provided. Fantasy only exposes tool/step callbacks on AgentStreamCall, so
 	// Stream is required to observe tool execution in real time. The non-streaming
 	// Generate path is reserved for the simple case with no callbacks at all.
+	// A budget counts every step in OnStepFinish, so it needs Stream too.
 	hasCallbacks := onToolCall != nil || onToolExecution != nil || onToolResult != nil ||
-		onToolCallContent != nil || onStreamingResponse != nil
+		onToolCallContent != nil || onStreamingResponse != nil || onReasoning != nil
 
-	if a.streamingEnabled || hasCallbacks {
+	if a.streamingEnabled || hasCallbacks || a.budget != nil {
 		// Use fantasy's streaming agent
 		result, err := a.fantasyAgent.Stream(ctx, fantasy.AgentStreamCall{
-			Prompt:   prompt,
-			Messages: history,
+			// The conversation is sent as it is, see conversationPrompt
+			Prompt:      conversationPrompt,
+			Messages:    messages,
+			PrepareStep: conversationStep(a.prepareStep),
 
 			// Text streaming callback
 			OnTextDelta: func(id, text string) error {
@@ -180,6 +357,17 @@ func (a *Agent) GenerateWithLoopAndStreaming(ctx context.Context, messages []fan
 				return nil
 			},
 
+			// Reasoning streaming callback
+			OnReasoningDelta: func(id, text string) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if onReasoning != nil {
+					onReasoning(text)
+				}
+				return nil
+			},
+
 			// Tool call complete - the tool has been parsed and is about to execute
 			OnToolCall: func(tc fantasy.ToolCallContent) error {
 				if ctx.Err() != nil {
@@ -222,6 +410,8 @@ func (a *Agent) GenerateWithLoopAndStreaming(ctx context.Context, messages []fan
 
 			// Step callbacks for content that accompanies tool calls
 			OnStepFinish: func(step fantasy.StepResult) error {
+				// The step is paid for even when the prompt is canceled
+				a.budget.recordStep(step)
 				if ctx.Err() != nil {
 					return ctx.Err()
 				}
@@ -244,13 +434,14 @@ func (a *Agent) GenerateWithLoopAndStreaming(ctx context.Context, messages []fan
 			onResponse(result.Response.Content.Text())
 		}
 
-		return convertAgentResult(result, messages), nil
+		return a.convertAgentResult(result, messages), nil
 	}
 
 	// Non-streaming path with no callbacks — use the simpler Generate call.
 	result, err := a.fantasyAgent.Generate(ctx, fantasy.AgentCall{
-		Prompt:   prompt,
-		Messages: history,
+		Prompt:      conversationPrompt,
+		Messages:    messages,
+		PrepareStep: conversationStep(a.prepareStep),
 	})
 	if err != nil {
 		return nil, err
@@ -263,50 +454,49 @@ func (a *Agent) GenerateWithLoopAndStreaming(ctx context.Context, messages []fan
 
 	_ = currentToolName // satisfy compiler for non-streaming path
 
-	return convertAgentResult(result, messages), nil
+	return a.convertAgentResult(result, messages), nil
 }
 
-// splitPromptAndHistory extracts the last user message as the prompt string,
-// and returns everything before it as conversation history. Fantasy's agent
-// requires the current turn's input as Prompt (string), with prior messages
-// passed separately as Messages (history).
-func splitPromptAndHistory(messages []fantasy.Message) (string, []fantasy.Message) {
-	if len(messages) == 0 {
-		return "", nil
-	}
-
-	// Walk backwards to find the last user message
-	for i := len(messages) - 1; i >= 0; i-- {
-		if messages[i].Role == fantasy.MessageRoleUser {
-			// Extract text from the user message parts
-			var prompt string
-			for _, part := range messages[i].Content {
-				if tp, ok := part.(fantasy.TextPart); ok {
-					prompt = tp.Text
-					break
-				}
-			}
-			// History is everything except this last user message
-			history := make([]fantasy.Message, 0, len(messages)-1)
-			history = append(history, messages[:i]...)
-			history = append(history, messages[i+1:]...)
-			return prompt, history
+// conversationPrompt is the prompt given to fantasy, which requires one and
+// sends it as a user message after the history. conversationStep removes
+// that message again, so the conversation is sent as it is: in order, with
+// every part of its messages, and without a user message at its end when it
+// does not have one.
+const conversationPrompt = "\x00mcphost:conversation"
+
+// conversationStep returns a prepare step function that removes the message
+// fantasy adds for conversationPrompt before next, which may be nil,
+// prepares the rest of the step.
+func conversationStep(next fantasy.PrepareStepFunction) fantasy.PrepareStepFunction {
+	return func(ctx context.Context, opts fantasy.PrepareStepFunctionOptions) (context.Context, fantasy.PrepareStepResult, error) {
+		// The messages belong to fantasy; only change a copy
+		if i := slices.IndexFunc(opts.Messages, isConversationPrompt); i >= 0 {
+			opts.Messages = slices.Delete(slices.Clone(opts.Messages), i, i+1)
 		}
-	}
-
-	// No user message found — use the last message's text as prompt
-	last := messages[len(messages)-1]
-	for _, part := range last.Content {
-		if tp, ok := part.(fantasy.TextPart); ok {
-			return tp.Text, messages[:len(messages)-1]
+		if next == nil {
+			return ctx, fantasy.PrepareStepResult{Messages: opts.Messages}, nil
 		}
+		ctx, result, err := next(ctx, opts)
+		if result.Messages == nil {
+			result.Messages = opts.Messages
+		}
+		return ctx, result, err
 	}
+}
 
-	return "", messages
+// isConversationPrompt reports whether msg is the message fantasy adds for
+// conversationPrompt.
+func isConversationPrompt(msg fantasy.Message) bool {
+	if msg.Role != fantasy.MessageRoleUser || len(msg.Content) != 1 {
+		return false
+	}
+	text, ok := fantasy.AsMessagePart[fantasy.TextPart](msg.Content[0])
+	return ok && text.Text == conversationPrompt
 }
 
-// convertAgentResult converts a fantasy AgentResult to our GenerateWithLoopResult.
-func convertAgentResult(result *fantasy.AgentResult, originalMessages []fantasy.Message) *GenerateWithLoopResult {
+// convertAgentResult converts a fantasy AgentResult to our GenerateWithLoopResult,
+// recording whether a tool hook ended the loop early.
+func (a *Agent) convertAgentResult(result *fantasy.AgentResult, originalMessages []fantasy.Message) *GenerateWithLoopResult {
 	// Collect all conversation messages: original + all step messages
 	var allMessages []fantasy.Message
 	allMessages = append(allMessages, originalMessages...)
@@ -315,10 +505,17 @@ func convertAgentResult(result *fantasy.AgentResult, originalMessages []fantasy.
 		allMessages = append(allMessages, step.Messages...)
 	}
 
+	hookStopReason, stoppedByHook := a.toolManager.HookStop()
+
 	return &GenerateWithLoopResult{
 		FinalResponse:        &result.Response,
 		ConversationMessages: allMessages,
 		TotalUsage:           result.TotalUsage,
+		StoppedByHook:        stoppedByHook,
+		HookStopReason:       hookStopReason,
+		BudgetExceeded:       a.budget.exceededErr(),
+		ToolLoop:             a.loopGuard.loopErr(),
+		ContextFull:          a.contextGuard.contextFullErr(),
 	}
 }
 
@@ -337,12 +534,18 @@ func extractToolResultText(tr fantasy.ToolResultContent) (string, bool) {
 
 	// Get text directly from the Fantasy result type — avoids JSON round-trip.
 	if textResult, ok := tr.Result.(fantasy.ToolResultOutputContentText); ok {
-		// The text typically contains a JSON-encoded MCP CallToolResult
+		// The text may contain a JSON-encoded MCP CallToolResult
 		// (e.g. {"content":[{"type":"text","text":"..."}]}). Extract the
 		// human-readable text from that structure.
 		return extractMCPContentText(textResult.Text), false
 	}
 
+	// Media results carry a text rendering with placeholders for the media;
+	// the base64 data is never shown.
+	if mediaResult, ok := tr.Result.(fantasy.ToolResultOutputContentMedia); ok {
+		return mediaResult.Text, false
+	}
+
 	// Fallback: marshal to JSON for display.
 	resultBytes, err := json.Marshal(tr.Result)
 	if err != nil {
@@ -378,11 +581,123 @@ func extractMCPContentText(result string) string {
 	return result
 }
 
+// GenerateText sends a single prompt to the model, without tools or
+// conversation history, and returns the response. It is used for side tasks
+// such as summarizing the conversation when it is compacted. The call counts
+// towards the budgets, and is refused with an error wrapping
+// ErrBudgetExceeded once a session budget is used up.
+func (a *Agent) GenerateText(ctx context.Context, systemPrompt, prompt string) (*fantasy.Response, error) {
+	if err := a.budget.sessionErr(); err != nil {
+		return nil, err
+	}
+	var msgs fantasy.Prompt
+	if systemPrompt != "" {
+		msgs = append(msgs, fantasy.NewSystemMessage(systemPrompt))
+	}
+	msgs = append(msgs, fantasy.NewUserMessage(prompt))
+	resp, err := a.model.Generate(ctx, fantasy.Call{Prompt: msgs})
+	if err != nil {
+		return nil, err
+	}
+	_ = a.budget.record(resp.Usage)
+	return resp, nil
+}
+
+// SetToolApprovalFunc sets the callback that every tool call must pass before
+// it runs. A nil func lets all tool calls run without asking.
+func (a *Agent) SetToolApprovalFunc(fn tools.ToolApprovalFunc) {
+	a.toolManager.SetToolApprovalFunc(fn)
+}
+
+// SetSamplingApprovalFunc sets the callback that sampling requests of MCP
+// servers must pass before the model answers them.
+func (a *Agent) SetSamplingApprovalFunc(fn tools.SamplingApprovalFunc) {
+	a.toolManager.SetSamplingApprovalFunc(fn)
+}
+
+// SetSamplingUsageFunc sets the callback receiving the token usage of the
+// sampling requests the model answers. The usage counts towards the budgets
+// either way.
+func (a *Agent) SetSamplingUsageFunc(fn tools.SamplingUsageFunc) {
+	if a.budget != nil {
+		fn = a.budget.samplingUsageFunc(fn)
+	}
+	a.toolManager.SetSamplingUsageFunc(fn)
+}
+
+// SetElicitationFunc sets the callback answering the elicitation requests of
+// MCP servers, which ask the user for input. Without one they are declined.
+func (a *Agent) SetElicitationFunc(fn tools.ElicitationFunc) {
+	a.toolManager.SetElicitationFunc(fn)
+}
+
+// SetListChangedFunc sets the callback told when an MCP server's tools,
+// resources or prompts were reloaded because the server changed them.
+func (a *Agent) SetListChangedFunc(fn tools.ListChangedFunc) {
+	a.toolManager.SetListChangedFunc(fn)
+}
+
+// SetLogFunc sets the callback receiving the log messages of MCP servers.
+func (a *Agent) SetLogFunc(fn tools.LogFunc) {
+	a.toolManager.SetLogFunc(fn)
+}
+
+// SetServerStatusFunc sets the callback told when an MCP server becomes
+// ready or fails, including servers still connecting after startup.
+func (a *Agent) SetServerStatusFunc(fn tools.ServerStatusFunc) {
+	a.toolManager.SetServerStatusFunc(fn)
+}
+
 // GetTools returns the list of available tools loaded in the agent.
 func (a *Agent) GetTools() []fantasy.AgentTool {
 	return a.toolManager.GetTools()
 }
 
+// GetResources returns the resources and resource templates of the loaded
+// MCP servers.
+func (a *Agent) GetResources() []tools.Resource {
+	return a.toolManager.Resources()
+}
+
+// ReadResource reads the resource with the given URI from an MCP server and
+// returns its contents as prompt attachments.
+func (a *Agent) ReadResource(ctx context.Context, serverName, uri string) ([]*attachments.Attachment, error) {
+	contents, err := a.toolManager.ReadResource(ctx, serverName, uri)
+	if err != nil {
+		return nil, err
+	}
+	return tools.ResourceAttachments(serverName, contents)
+}
+
+// GetPrompts returns the prompts offered by the loaded MCP servers.
+func (a *Agent) GetPrompts() []tools.Prompt {
+	return a.toolManager.Prompts()
+}
+
+// GetPrompt fills in a prompt of an MCP server with args and returns its
+// messages, ready to be added to the conversation.
+func (a *Agent) GetPrompt(ctx context.Context, serverName, promptName string, args map[string]string) ([]fantasy.Message, error) {
+	return a.toolManager.GetPrompt(ctx, serverName, promptName, args)
+}
+
+// Roots returns the root directories advertised to each MCP server that
+// supports roots, by server name.
+func (a *Agent) Roots() map[string][]string {
+	return a.toolManager.Roots()
+}
+
+// AddRoot adds a directory to the roots of every MCP server and notifies
+// them. It returns the absolute path of the directory.
+func (a *Agent) AddRoot(dir string) (string, error) {
+	return a.toolManager.AddRoot(dir)
+}
+
+// SetWorkingDirectory tells the MCP servers without configured roots that
+// their root is now dir.
+func (a *Agent) SetWorkingDirectory(dir string) {
+	a.toolManager.SetWorkingDirectory(dir)
+}
+
 // GetLoadingMessage returns the loading message from provider creation.
 func (a *Agent) GetLoadingMessage() string {
 	return a.loadingMessage
@@ -393,6 +708,12 @@ func (a *Agent) GetLoadedServerNames() []string {
 	return a.toolManager.GetLoadedServerNames()
 }
 
+// GetServerStatuses returns the startup status of every configured MCP
+// server, sorted by name.
+func (a *Agent) GetServerStatuses() []tools.ServerStatus {
+	return a.toolManager.ServerStatuses()
+}
+
 // GetModel returns the underlying fantasy LanguageModel.
 func (a *Agent) GetModel() fantasy.LanguageModel {
 	return a.model
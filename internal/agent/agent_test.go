@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/mark3labs/mcphost/internal/attachments"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// TestGenerateWithLoop_sendsConversation verifies that the conversation
+// reaches the model as it is, with the files attached to the prompt, in the
+// first step and the steps after a tool call.
+func TestGenerateWithLoop_sendsConversation(t *testing.T) {
+	image := fantasy.FilePart{Filename: "cat.png", Data: []byte("png"), MediaType: "image/png"}
+	conversation := []fantasy.Message{
+		fantasy.NewUserMessage("Hello"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "Hi"}}},
+		fantasy.NewUserMessage("What is in this picture?", image),
+	}
+
+	for _, streaming := range []bool{false, true} {
+		model := &scriptedModel{responses: []fantasy.Response{
+			toolCallResponse(10),
+			{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "A cat"}}, FinishReason: fantasy.FinishReasonStop},
+		}}
+		a := newScriptedAgent(model, nil)
+		a.streamingEnabled = streaming
+
+		result, err := a.GenerateWithLoopAndStreaming(context.Background(), conversation, nil, nil, nil, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("streaming=%v: GenerateWithLoopAndStreaming: %v", streaming, err)
+		}
+		if result.FinalResponse.Content.Text() != "A cat" || len(model.prompts) != 2 {
+			t.Fatalf("streaming=%v: expected 2 calls and the answer, got %d calls and %q",
+				streaming, len(model.prompts), result.FinalResponse.Content.Text())
+		}
+		if got := []fantasy.Message(model.prompts[0]); !reflect.DeepEqual(got, conversation) {
+			t.Errorf("streaming=%v: expected the conversation to be sent as it is, got %+v", streaming, got)
+		}
+		// The next step adds the tool call and its result
+		if got := model.prompts[1]; len(got) != len(conversation)+2 || !reflect.DeepEqual([]fantasy.Message(got[:len(conversation)]), conversation) {
+			t.Errorf("streaming=%v: expected the conversation followed by the tool call, got %+v", streaming, got)
+		}
+	}
+}
+
+func TestGenerateWithLoop_noMessages(t *testing.T) {
+	model := &scriptedModel{}
+	if _, err := newScriptedAgent(model, nil).GenerateWithLoop(context.Background(), nil, nil, nil, nil, nil, nil); err == nil {
+		t.Error("Expected an error without messages")
+	}
+	if model.calls != 0 {
+		t.Errorf("Expected no model call, got %d", model.calls)
+	}
+}
+
+// TestGenerateWithLoop_serverPrompt verifies that the messages of an MCP
+// server prompt reach the model in their order and with all their parts.
+func TestGenerateWithLoop_serverPrompt(t *testing.T) {
+	image := base64.StdEncoding.EncodeToString([]byte("png"))
+	tests := []struct {
+		name     string
+		messages []mcp.PromptMessage
+	}{
+		{
+			name: "multi-part final message",
+			messages: []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Review this diff")),
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Focus on error handling")),
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "file:///a.diff", Text: "+x"})),
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewImageContent(image, "image/png")),
+			},
+		},
+		{
+			name: "trailing assistant message",
+			messages: []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Write a haiku about Go")),
+				mcp.NewPromptMessage(mcp.RoleAssistant, mcp.NewTextContent("Gophers")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversation := tools.PromptMessages(tt.messages)
+			model := &scriptedModel{responses: []fantasy.Response{
+				{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "ok"}}, FinishReason: fantasy.FinishReasonStop},
+			}}
+			a := newScriptedAgent(model, nil)
+
+			if _, err := a.GenerateWithLoopAndStreaming(context.Background(), conversation, nil, nil, nil, nil, nil, nil, nil); err != nil {
+				t.Fatalf("GenerateWithLoopAndStreaming: %v", err)
+			}
+			if got := []fantasy.Message(model.prompts[0]); !reflect.DeepEqual(got, conversation) {
+				t.Errorf("Expected the prompt's messages to be sent as they are, got %+v", got)
+			}
+		})
+	}
+}
+
+// TestReadResource_blobReachesModel verifies that a binary resource
+// mentioned in a prompt is sent to the model as a file.
+func TestReadResource_blobReachesModel(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	srv := server.NewMCPServer("docs", "1.0.0", server.WithToolCapabilities(false), server.WithResourceCapabilities(false, false))
+	srv.AddResource(mcp.NewResource("file:///logo.png", "logo", mcp.WithMIMEType("image/png")),
+		func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.BlobResourceContents{
+				URI: "file:///logo.png", MIMEType: "image/png", Blob: base64.StdEncoding.EncodeToString(png),
+			}}, nil
+		})
+	ts := httptest.NewServer(server.NewStreamableHTTPServer(srv))
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	model := &scriptedModel{responses: []fantasy.Response{
+		{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "A logo"}}, FinishReason: fantasy.FinishReasonStop},
+	}}
+	a := newScriptedAgent(model, nil)
+	cfg := &config.Config{MCPServers: map[string]config.MCPServerConfig{
+		"docs": {Type: "remote", URL: ts.URL + "/mcp"},
+	}}
+	if err := a.toolManager.LoadTools(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = a.toolManager.Close() })
+
+	atts, err := a.ReadResource(ctx, "docs", "file:///logo.png")
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	msg := attachments.NewUserMessage("Describe @docs:file:///logo.png", atts)
+	if _, err := a.GenerateWithLoop(ctx, []fantasy.Message{msg}, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("GenerateWithLoop: %v", err)
+	}
+
+	sent := model.prompts[0][len(model.prompts[0])-1]
+	var files []fantasy.FilePart
+	for _, part := range sent.Content {
+		if file, ok := fantasy.AsMessagePart[fantasy.FilePart](part); ok {
+			files = append(files, file)
+		}
+	}
+	if len(files) != 1 || files[0].MediaType != "image/png" || string(files[0].Data) != string(png) {
+		t.Errorf("Expected the model to receive the image, got %+v", sent.Content)
+	}
+}
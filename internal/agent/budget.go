@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// DefaultBudgetWarnAt is the share of a budget at which a warning is
+// reported when Budget.WarnAt is not set.
+const DefaultBudgetWarnAt = 0.8
+
+// ErrBudgetExceeded is wrapped by the error reported when a cost or token
+// budget stops the agent.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget limits what the agent may spend on model calls, per prompt and per
+// session. A prompt's spending includes all of its tool-calling steps and the
+// sub-agents they start. Costs are computed from the models registry prices
+// of the configured model. Zero fields are not limited.
+type Budget struct {
+	// MaxCost is the most a prompt may cost, in US dollars.
+	MaxCost float64
+	// MaxTokens is the most tokens a prompt may use.
+	MaxTokens int64
+	// MaxSessionCost is the most the session may cost, in US dollars.
+	MaxSessionCost float64
+	// MaxSessionTokens is the most tokens the session may use.
+	MaxSessionTokens int64
+	// WarnAt is the share of a budget, between 0 and 1, at which a warning is
+	// reported. Defaults to DefaultBudgetWarnAt.
+	WarnAt float64
+}
+
+// enabled reports whether any budget is set.
+func (b Budget) enabled() bool {
+	return b.MaxCost > 0 || b.MaxTokens > 0 || b.MaxSessionCost > 0 || b.MaxSessionTokens > 0
+}
+
+// BudgetWarningHandler is called once when the spending of a prompt or the
+// session reaches the warning threshold of one of its budgets.
+type BudgetWarningHandler func(warning string)
+
+type budgetWarningHandlerKey struct{}
+
+// WithBudgetWarningHandler returns a context whose prompts report budget
+// warnings to h.
+func WithBudgetWarningHandler(ctx context.Context, h BudgetWarningHandler) context.Context {
+	return context.WithValue(ctx, budgetWarningHandlerKey{}, h)
+}
+
+// budgetWarningHandlerFrom returns the handler set with WithBudgetWarningHandler.
+func budgetWarningHandlerFrom(ctx context.Context) BudgetWarningHandler {
+	h, _ := ctx.Value(budgetWarningHandlerKey{}).(BudgetWarningHandler)
+	return h
+}
+
+// spending is what model calls have used so far.
+type spending struct {
+	tokens int64
+	cost   float64
+}
+
+func (s *spending) add(o spending) {
+	s.tokens += o.tokens
+	s.cost += o.cost
+}
+
+// budgetCheck compares one kind of spending with its budget.
+type budgetCheck struct {
+	name  string // e.g. "prompt cost"
+	spent float64
+	next  float64 // expected spending of the next step
+	limit float64
+	cost  bool // whether the values are dollars rather than tokens
+}
+
+func (c budgetCheck) format(v float64) string {
+	if c.cost {
+		return fmt.Sprintf("$%.2f", v)
+	}
+	return fmt.Sprintf("%d tokens", int64(v))
+}
+
+// budgetTracker enforces a Budget. It is shared by an agent and its
+// sub-agents, so the steps of both count towards the same prompt.
+type budgetTracker struct {
+	limits   Budget
+	provider string
+	pricing  models.Cost
+
+	mu        sync.Mutex
+	prompt    spending
+	session   spending
+	lastStep  spending
+	exceeded  error
+	warned    map[string]bool
+	onWarning BudgetWarningHandler
+}
+
+// newBudgetTracker returns a tracker enforcing limits for the configured
+// model, or nil when no budget is set. Cost budgets need the model's prices
+// from the models registry.
+func newBudgetTracker(limits Budget, modelConfig *models.ProviderConfig) (*budgetTracker, error) {
+	if !limits.enabled() {
+		return nil, nil
+	}
+	if limits.MaxCost < 0 || limits.MaxTokens < 0 || limits.MaxSessionCost < 0 || limits.MaxSessionTokens < 0 {
+		return nil, errors.New("budgets must not be negative")
+	}
+	if limits.WarnAt < 0 || limits.WarnAt > 1 {
+		return nil, fmt.Errorf("invalid budget warning threshold %v (expected a value between 0 and 1)", limits.WarnAt)
+	}
+	if limits.WarnAt == 0 {
+		limits.WarnAt = DefaultBudgetWarnAt
+	}
+
+	b := &budgetTracker{limits: limits, warned: make(map[string]bool)}
+	if modelConfig != nil {
+		provider, modelID, err := models.ParseModelString(modelConfig.ModelString)
+		if err == nil {
+			b.provider = provider
+			if info := models.GetGlobalRegistry().LookupModel(provider, modelID); info != nil {
+				b.pricing = info.Cost
+			} else if limits.MaxCost > 0 || limits.MaxSessionCost > 0 {
+				return nil, fmt.Errorf("cannot enforce a cost budget: no prices are known for model %s", modelConfig.ModelString)
+			}
+		}
+	}
+	return b, nil
+}
+
+// startPrompt resets the prompt's spending before a new prompt and sets the
+// handler of its warnings. It fails when a session budget is used up.
+func (b *budgetTracker) startPrompt(onWarning BudgetWarningHandler) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prompt = spending{}
+	b.lastStep = spending{}
+	b.exceeded = nil
+	b.onWarning = onWarning
+	b.warned["prompt cost"] = false
+	b.warned["prompt token"] = false
+	return b.usedUpErr()
+}
+
+// sessionErr returns an error wrapping ErrBudgetExceeded when a session
+// budget is used up. Model calls made outside the agent's steps, such as
+// compaction summaries and sampling requests, are refused then.
+func (b *budgetTracker) sessionErr() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usedUpErr()
+}
+
+// usedUpErr returns the error of sessionErr. The caller holds b.mu.
+func (b *budgetTracker) usedUpErr() error {
+	for _, check := range b.checks() {
+		if strings.HasPrefix(check.name, "session") && check.limit > 0 && check.spent >= check.limit {
+			return fmt.Errorf("%w: the %s budget of %s is used up (%s spent)",
+				ErrBudgetExceeded, check.name, check.format(check.limit), check.format(check.spent))
+		}
+	}
+	return nil
+}
+
+// samplingUsageFunc returns a sampling usage callback that records the usage
+// with b, so sampling requests count towards the budgets, and passes it on
+// to fn, which may be nil.
+func (b *budgetTracker) samplingUsageFunc(fn tools.SamplingUsageFunc) tools.SamplingUsageFunc {
+	return func(usage fantasy.Usage) {
+		_ = b.record(usage)
+		if fn != nil {
+			fn(usage)
+		}
+	}
+}
+
+// recordStep records the spending of a finished step. It is called for every
+// step, whichever stop condition ends the loop, and remembers when the next
+// step is expected to exceed a budget. The next step is expected to use at
+// least as much as the last one, since its prompt holds everything the last
+// one's did.
+func (b *budgetTracker) recordStep(step fantasy.StepResult) {
+	err := b.record(step.Usage)
+	// Without tool calls there is no next step to stop
+	if err == nil || len(step.Content.ToolCalls()) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.exceeded = err
+}
+
+// stopCondition returns a fantasy stop condition that stops before a step
+// that is expected to exceed a budget, as recorded by recordStep.
+func (b *budgetTracker) stopCondition() fantasy.StopCondition {
+	return func(_ []fantasy.StepResult) bool {
+		return b.exceededErr() != nil
+	}
+}
+
+// record adds usage to the prompt's and the session's spending and reports
+// warnings. It returns an error wrapping ErrBudgetExceeded when the next step
+// would exceed a budget.
+func (b *budgetTracker) record(usage fantasy.Usage) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	step := spending{
+		tokens: models.UsageTokens(b.provider, usage),
+		cost:   b.pricing.UsageCost(b.provider, usage),
+	}
+	b.prompt.add(step)
+	b.session.add(step)
+	b.lastStep = step
+
+	var exceeded error
+	var warnings []string
+	for _, check := range b.checks() {
+		if check.limit <= 0 {
+			continue
+		}
+		if check.spent+check.next > check.limit && exceeded == nil {
+			exceeded = fmt.Errorf("%w: stopped before the next step would exceed the %s budget of %s (%s spent)",
+				ErrBudgetExceeded, check.name, check.format(check.limit), check.format(check.spent))
+		}
+		if check.spent >= b.limits.WarnAt*check.limit && !b.warned[check.name] {
+			b.warned[check.name] = true
+			warnings = append(warnings, fmt.Sprintf("%s budget: %s of %s used (%.0f%%)",
+				check.name, check.format(check.spent), check.format(check.limit), check.spent/check.limit*100))
+		}
+	}
+	onWarning := b.onWarning
+	b.mu.Unlock()
+
+	if onWarning != nil {
+		for _, warning := range warnings {
+			onWarning(warning)
+		}
+	}
+	return exceeded
+}
+
+// checks lists the budgets with their spending. The caller holds b.mu.
+func (b *budgetTracker) checks() []budgetCheck {
+	return []budgetCheck{
+		{"prompt cost", b.prompt.cost, b.lastStep.cost, b.limits.MaxCost, true},
+		{"prompt token", float64(b.prompt.tokens), float64(b.lastStep.tokens), float64(b.limits.MaxTokens), false},
+		{"session cost", b.session.cost, b.lastStep.cost, b.limits.MaxSessionCost, true},
+		{"session token", float64(b.session.tokens), float64(b.lastStep.tokens), float64(b.limits.MaxSessionTokens), false},
+	}
+}
+
+// exceededErr returns the error describing the budget that stopped the
+// current prompt, or nil.
+func (b *budgetTracker) exceededErr() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exceeded
+}
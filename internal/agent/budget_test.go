@@ -0,0 +1,366 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// scriptedModel is a fantasy.LanguageModel answering each call with the next
+// of its responses; Stream streams them part by part. Calls beyond the
+// script fail.
+type scriptedModel struct {
+	responses []fantasy.Response
+	objects   []*fantasy.ObjectResponse
+	objectErr []error
+	calls     int
+	prompts   []fantasy.Prompt // prompt of each Generate and Stream call
+	objCalls  []fantasy.ObjectCall
+}
+
+func (m *scriptedModel) next() (fantasy.Response, error) {
+	if m.calls >= len(m.responses) {
+		return fantasy.Response{}, errors.New("no more scripted responses")
+	}
+	m.calls++
+	return m.responses[m.calls-1], nil
+}
+
+func (m *scriptedModel) Generate(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	m.prompts = append(m.prompts, call.Prompt)
+	resp, err := m.next()
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (m *scriptedModel) Stream(_ context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	m.prompts = append(m.prompts, call.Prompt)
+	resp, err := m.next()
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		for _, content := range resp.Content {
+			switch c := content.(type) {
+			case fantasy.TextContent:
+				if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextStart, ID: "text"}) ||
+					!yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, ID: "text", Delta: c.Text}) ||
+					!yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: "text"}) {
+					return
+				}
+			case fantasy.ToolCallContent:
+				if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeToolCall, ID: c.ToolCallID, ToolCallName: c.ToolName, ToolCallInput: c.Input}) {
+					return
+				}
+			}
+		}
+		yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish, Usage: resp.Usage, FinishReason: resp.FinishReason})
+	}, nil
+}
+
+func (m *scriptedModel) GenerateObject(_ context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	n := len(m.objCalls)
+	m.objCalls = append(m.objCalls, call)
+	if n < len(m.objectErr) && m.objectErr[n] != nil {
+		return nil, m.objectErr[n]
+	}
+	if n >= len(m.objects) {
+		return nil, errors.New("no more scripted objects")
+	}
+	return m.objects[n], nil
+}
+
+func (m *scriptedModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *scriptedModel) Provider() string { return "test" }
+func (m *scriptedModel) Model() string    { return "scripted" }
+
+// toolCallResponse is a step calling the echo tool that used tokens tokens.
+func toolCallResponse(tokens int64) fantasy.Response {
+	return fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.ToolCallContent{ToolCallID: "1", ToolName: "echo", Input: "{}"}},
+		FinishReason: fantasy.FinishReasonToolCalls,
+		Usage:        fantasy.Usage{InputTokens: tokens},
+	}
+}
+
+// newScriptedAgent returns an agent running model with an echo tool and the
+// given stop conditions.
+func newScriptedAgent(model *scriptedModel, budget *budgetTracker, stopConditions ...fantasy.StopCondition) *Agent {
+	echo := fantasy.NewAgentTool("echo", "Answers with ok",
+		func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return fantasy.NewTextResponse("ok"), nil
+		})
+	return &Agent{
+		toolManager: tools.NewMCPToolManager(),
+		fantasyAgent: fantasy.NewAgent(model, fantasy.WithMaxRetries(0),
+			fantasy.WithTools(echo), fantasy.WithStopConditions(stopConditions...)),
+		model:  model,
+		budget: budget,
+	}
+}
+
+// TestBudget_countsStepsStoppedByOtherConditions verifies that a step is
+// counted even when a stop condition checked before the budget's ends the
+// loop after it.
+func TestBudget_countsStepsStoppedByOtherConditions(t *testing.T) {
+	budget, err := newBudgetTracker(Budget{MaxSessionTokens: 1000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &scriptedModel{responses: []fantasy.Response{toolCallResponse(400)}}
+	a := newScriptedAgent(model, budget, fantasy.StepCountIs(1), budget.stopCondition())
+
+	result, err := a.GenerateWithLoopAndStreaming(context.Background(),
+		[]fantasy.Message{fantasy.NewUserMessage("hi")}, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateWithLoopAndStreaming: %v", err)
+	}
+	if model.calls != 1 {
+		t.Fatalf("Expected the step limit to stop after 1 call, got %d", model.calls)
+	}
+	if budget.prompt.tokens != 400 || budget.session.tokens != 400 {
+		t.Errorf("Expected the step's 400 tokens to be counted, got %d for the prompt and %d for the session",
+			budget.prompt.tokens, budget.session.tokens)
+	}
+	if result.BudgetExceeded != nil {
+		t.Errorf("Expected no budget to be exceeded, got %v", result.BudgetExceeded)
+	}
+
+	// The session budget stops the next prompt before its second step, which
+	// would take the session from 800 to at least 1200 tokens
+	if err := budget.startPrompt(nil); err != nil {
+		t.Fatalf("Expected the session budget to allow another prompt, got %v", err)
+	}
+	model.responses = append(model.responses, toolCallResponse(400), toolCallResponse(400))
+	a = newScriptedAgent(model, budget, budget.stopCondition())
+	result, err = a.GenerateWithLoopAndStreaming(context.Background(),
+		[]fantasy.Message{fantasy.NewUserMessage("again")}, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateWithLoopAndStreaming: %v", err)
+	}
+	if !errors.Is(result.BudgetExceeded, ErrBudgetExceeded) {
+		t.Errorf("Expected the budget to stop the prompt, got %v", result.BudgetExceeded)
+	}
+	if model.calls != 2 || budget.session.tokens != 800 {
+		t.Errorf("Expected one more call and 800 session tokens, got %d calls and %d tokens", model.calls, budget.session.tokens)
+	}
+}
+
+func TestBudgetTracker_record(t *testing.T) {
+	tests := []struct {
+		name         string
+		limits       Budget
+		steps        []fantasy.Usage
+		wantExceeded string // substring of the error of the last step; "" for none
+		wantWarnings []string
+	}{
+		{
+			name:   "within budget",
+			limits: Budget{MaxTokens: 1000},
+			steps:  []fantasy.Usage{{InputTokens: 100, OutputTokens: 50}},
+		},
+		{
+			name:         "warns once at the threshold",
+			limits:       Budget{MaxTokens: 10000},
+			steps:        []fantasy.Usage{{InputTokens: 8000}, {InputTokens: 100}},
+			wantWarnings: []string{"prompt token budget: 8000 tokens of 10000 tokens used (80%)"},
+		},
+		{
+			name:         "custom threshold",
+			limits:       Budget{MaxTokens: 10000, WarnAt: 0.5},
+			steps:        []fantasy.Usage{{InputTokens: 3000}, {InputTokens: 2000}},
+			wantWarnings: []string{"prompt token budget: 5000 tokens of 10000 tokens used (50%)"},
+		},
+		{
+			name:         "next step would exceed",
+			limits:       Budget{MaxTokens: 1000},
+			steps:        []fantasy.Usage{{InputTokens: 300}, {InputTokens: 400}},
+			wantExceeded: "prompt token budget of 1000 tokens (700 tokens spent)",
+		},
+		{
+			name:         "session budget",
+			limits:       Budget{MaxSessionTokens: 500},
+			steps:        []fantasy.Usage{{InputTokens: 200}, {InputTokens: 200}},
+			wantExceeded: "session token budget of 500 tokens",
+			wantWarnings: []string{"session token budget: 400 tokens of 500 tokens used (80%)"},
+		},
+		{
+			name:         "cost budget",
+			limits:       Budget{MaxCost: 1},
+			steps:        []fantasy.Usage{{InputTokens: 100000, OutputTokens: 20000}},
+			wantExceeded: "prompt cost budget of $1.00 ($0.60 spent)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newBudgetTracker(tt.limits, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.pricing = models.Cost{Input: 3, Output: 15}
+			var warnings []string
+			if err := b.startPrompt(func(w string) { warnings = append(warnings, w) }); err != nil {
+				t.Fatal(err)
+			}
+
+			var exceeded error
+			for _, usage := range tt.steps {
+				exceeded = b.record(usage)
+			}
+			if tt.wantExceeded == "" && exceeded != nil {
+				t.Errorf("Expected no error, got %v", exceeded)
+			}
+			if tt.wantExceeded != "" && (!errors.Is(exceeded, ErrBudgetExceeded) || !strings.Contains(exceeded.Error(), tt.wantExceeded)) {
+				t.Errorf("Expected an error about %q, got %v", tt.wantExceeded, exceeded)
+			}
+			if !slices.Equal(warnings, tt.wantWarnings) {
+				t.Errorf("Expected warnings %q, got %q", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestBudgetTracker_checks(t *testing.T) {
+	b, err := newBudgetTracker(Budget{MaxCost: 2, MaxTokens: 1000, MaxSessionCost: 20, MaxSessionTokens: 5000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.pricing = models.Cost{Input: 3, Output: 15}
+	b.session = spending{tokens: 2000, cost: 4}
+	_ = b.record(fantasy.Usage{InputTokens: 100000})
+	_ = b.record(fantasy.Usage{InputTokens: 200000})
+
+	want := []budgetCheck{
+		{"prompt cost", 0.9, 0.6, 2, true},
+		{"prompt token", 300000, 200000, 1000, false},
+		{"session cost", 4.9, 0.6, 20, true},
+		{"session token", 302000, 200000, 5000, false},
+	}
+	got := b.checks()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d checks, got %d", len(want), len(got))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.name != w.name || g.cost != w.cost || g.limit != w.limit ||
+			math.Abs(g.spent-w.spent) > 1e-9 || math.Abs(g.next-w.next) > 1e-9 {
+			t.Errorf("Expected check %+v, got %+v", w, g)
+		}
+	}
+}
+
+func TestBudgetTracker_startPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Budget
+		session spending
+		wantErr string // "" for none
+	}{
+		{
+			name:   "fresh session",
+			limits: Budget{MaxSessionTokens: 1000},
+		},
+		{
+			name:    "session budget left",
+			limits:  Budget{MaxSessionTokens: 1000},
+			session: spending{tokens: 999},
+		},
+		{
+			name:    "session token budget used up",
+			limits:  Budget{MaxSessionTokens: 1000},
+			session: spending{tokens: 1000},
+			wantErr: "the session token budget of 1000 tokens is used up (1000 tokens spent)",
+		},
+		{
+			name:    "session cost budget used up",
+			limits:  Budget{MaxSessionCost: 5},
+			session: spending{cost: 5.5},
+			wantErr: "the session cost budget of $5.00 is used up ($5.50 spent)",
+		},
+		{
+			name:    "prompt budgets start over",
+			limits:  Budget{MaxTokens: 100},
+			session: spending{tokens: 100000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newBudgetTracker(tt.limits, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.session = tt.session
+			b.prompt = spending{tokens: 50, cost: 1}
+			b.lastStep = spending{tokens: 10}
+			b.exceeded = ErrBudgetExceeded
+			b.warned["prompt token"] = true
+			b.warned["session token"] = true
+
+			err = b.startPrompt(nil)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (!errors.Is(err, ErrBudgetExceeded) || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Expected an error about %q, got %v", tt.wantErr, err)
+			}
+			if b.prompt != (spending{}) || b.lastStep != (spending{}) || b.exceeded != nil {
+				t.Errorf("Expected the prompt's spending to be reset, got %+v, %+v, %v", b.prompt, b.lastStep, b.exceeded)
+			}
+			// Prompt warnings are given again; session warnings only once
+			if b.warned["prompt token"] || !b.warned["session token"] {
+				t.Errorf("Expected only the prompt warnings to be reset, got %v", b.warned)
+			}
+		})
+	}
+}
+
+// TestBudget_sideCalls verifies that compaction summaries and sampling
+// requests count towards the session budget and are refused once it is used
+// up.
+func TestBudget_sideCalls(t *testing.T) {
+	budget, err := newBudgetTracker(Budget{MaxSessionTokens: 1000}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &scriptedModel{responses: []fantasy.Response{
+		{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "summary"}}, Usage: fantasy.Usage{InputTokens: 600}},
+	}}
+	a := newScriptedAgent(model, budget)
+
+	if _, err := a.GenerateText(context.Background(), "", "Summarize"); err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	var sampled fantasy.Usage
+	budget.samplingUsageFunc(func(usage fantasy.Usage) { sampled = usage })(fantasy.Usage{InputTokens: 400})
+	if sampled.InputTokens != 400 {
+		t.Errorf("Expected the sampling usage to be passed on, got %+v", sampled)
+	}
+	if budget.session.tokens != 1000 {
+		t.Errorf("Expected 1000 session tokens spent, got %d", budget.session.tokens)
+	}
+
+	if err := budget.sessionErr(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected sampling to be refused, got %v", err)
+	}
+	if _, err := a.GenerateText(context.Background(), "", "Summarize"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected the summary to be refused, got %v", err)
+	}
+	if model.calls != 1 {
+		t.Errorf("Expected 1 model call, got %d", model.calls)
+	}
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"maps"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// cachedTool marks the end of the tool definitions as a cache breakpoint.
+// The wrapped tool is shared with sub-agents, so its own provider options are
+// left untouched.
+type cachedTool struct {
+	fantasy.AgentTool
+}
+
+// ProviderOptions returns the tool's provider options plus a cache breakpoint.
+func (t cachedTool) ProviderOptions() fantasy.ProviderOptions {
+	return withCacheBreakpoint(t.AgentTool.ProviderOptions())
+}
+
+// cacheTools returns tools with a cache breakpoint on the last one, so the
+// whole list of tool definitions is cached. Tools are sent before the system
+// prompt, so this breakpoint covers the longest stable prefix.
+func cacheTools(tools []fantasy.AgentTool, mode string) []fantasy.AgentTool {
+	if mode == models.PromptCacheOff || len(tools) == 0 {
+		return tools
+	}
+	cached := append([]fantasy.AgentTool(nil), tools...)
+	cached[len(cached)-1] = cachedTool{cached[len(cached)-1]}
+	return cached
+}
+
+// promptCacheStep returns a prepare step function that sets cache breakpoints
+// on the system prompt and, in auto mode, on the latest message of each step.
+// The rolling breakpoint lets the next step, and the next prompt, read the
+// conversation so far from the cache. It returns nil when caching is off.
+func promptCacheStep(mode string) fantasy.PrepareStepFunction {
+	if mode == models.PromptCacheOff {
+		return nil
+	}
+	return func(ctx context.Context, opts fantasy.PrepareStepFunctionOptions) (context.Context, fantasy.PrepareStepResult, error) {
+		if len(opts.Messages) == 0 {
+			return ctx, fantasy.PrepareStepResult{}, nil
+		}
+		// The messages belong to the caller; only change a copy
+		messages := append([]fantasy.Message(nil), opts.Messages...)
+		if messages[0].Role == fantasy.MessageRoleSystem {
+			messages[0].ProviderOptions = withCacheBreakpoint(messages[0].ProviderOptions)
+		}
+		if last := len(messages) - 1; mode == models.PromptCacheAuto && last > 0 {
+			messages[last].ProviderOptions = withCacheBreakpoint(messages[last].ProviderOptions)
+		}
+		return ctx, fantasy.PrepareStepResult{Messages: messages}, nil
+	}
+}
+
+// withCacheBreakpoint returns a copy of opts with a cache breakpoint added.
+func withCacheBreakpoint(opts fantasy.ProviderOptions) fantasy.ProviderOptions {
+	merged := maps.Clone(opts)
+	if merged == nil {
+		merged = fantasy.ProviderOptions{}
+	}
+	maps.Copy(merged, models.CacheBreakpoint())
+	return merged
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// ErrContextFull is wrapped by the error reported when the agent stopped
+// because the next step's request would not fit in the model's context
+// window.
+var ErrContextFull = errors.New("context window full")
+
+// contextGuard stops the agent between steps before it sends a request that
+// is over the model's context window. The next request holds everything the
+// last one did, plus the last step's answer and tool results, so its size is
+// estimated from the last step's usage and the length of its tool results.
+type contextGuard struct {
+	window   int64
+	provider string
+
+	mu      sync.Mutex
+	fullErr error
+}
+
+// newContextGuard returns a guard for the context window of the model in
+// modelConfig according to the models registry, or nil when it is unknown.
+func newContextGuard(modelConfig *models.ProviderConfig) *contextGuard {
+	if modelConfig == nil {
+		return nil
+	}
+	provider, modelID, err := models.ParseModelString(modelConfig.ModelString)
+	if err != nil {
+		return nil
+	}
+	info := models.GetGlobalRegistry().LookupModel(provider, modelID)
+	if info == nil || info.Limit.Context <= 0 {
+		return nil
+	}
+	return &contextGuard{window: int64(info.Limit.Context), provider: provider}
+}
+
+// fork returns a new guard for the same window, or nil when g is nil.
+func (g *contextGuard) fork() *contextGuard {
+	if g == nil {
+		return nil
+	}
+	return &contextGuard{window: g.window, provider: g.provider}
+}
+
+// reset forgets why the previous run stopped.
+func (g *contextGuard) reset() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.fullErr = nil
+}
+
+// stopCondition returns a fantasy stop condition that ends the run when the
+// next step's request is expected to be over the context window.
+func (g *contextGuard) stopCondition() fantasy.StopCondition {
+	return func(steps []fantasy.StepResult) bool {
+		if len(steps) == 0 {
+			return false
+		}
+		step := steps[len(steps)-1]
+		// Without tool calls there is no next step to stop
+		if len(step.Content.ToolCalls()) == 0 {
+			return false
+		}
+		next := models.UsageTokens(g.provider, step.Usage) + toolResultTokens(step.Content)
+		if next < g.window {
+			return false
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.fullErr = fmt.Errorf("%w: stopped before the next step's request of about %d tokens would exceed the model's context window of %d; "+
+			"compact the conversation (/compact) and ask the model to continue", ErrContextFull, next, g.window)
+		return true
+	}
+}
+
+// contextFullErr returns the error describing why the run stopped, or nil.
+func (g *contextGuard) contextFullErr() error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.fullErr
+}
+
+// toolResultTokens roughly estimates the tokens the tool results in content
+// add to the next request.
+func toolResultTokens(content fantasy.ResponseContent) int64 {
+	chars := 0
+	for _, result := range content.ToolResults() {
+		text, _ := extractToolResultText(result)
+		chars += len(text)
+	}
+	return int64(chars / 4)
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+func TestContextGuard_stopCondition(t *testing.T) {
+	toolCall := fantasy.ToolCallContent{ToolCallID: "1", ToolName: "read", Input: "{}"}
+	result := func(text string) fantasy.ToolResultContent {
+		return fantasy.ToolResultContent{ToolCallID: "1", ToolName: "read", Result: fantasy.ToolResultOutputContentText{Text: text}}
+	}
+	tests := []struct {
+		name    string
+		content fantasy.ResponseContent
+		usage   fantasy.Usage
+		want    bool
+	}{
+		{
+			name:    "room left",
+			content: fantasy.ResponseContent{toolCall, result("ok")},
+			usage:   fantasy.Usage{InputTokens: 800, OutputTokens: 50},
+		},
+		{
+			name:    "usage at the window",
+			content: fantasy.ResponseContent{toolCall, result("ok")},
+			usage:   fantasy.Usage{InputTokens: 950, OutputTokens: 50},
+			want:    true,
+		},
+		{
+			name:    "tool result fills the window",
+			content: fantasy.ResponseContent{toolCall, result(strings.Repeat("x", 800))},
+			usage:   fantasy.Usage{InputTokens: 800, OutputTokens: 50},
+			want:    true,
+		},
+		{
+			name:    "cached prompt tokens count",
+			content: fantasy.ResponseContent{toolCall, result("ok")},
+			usage:   fantasy.Usage{InputTokens: 10, CacheReadTokens: 990},
+			want:    true,
+		},
+		{
+			name:    "final answer",
+			content: fantasy.ResponseContent{fantasy.TextContent{Text: "done"}},
+			usage:   fantasy.Usage{InputTokens: 2000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &contextGuard{window: 1000, provider: "anthropic"}
+			if g.stopCondition()(nil) {
+				t.Fatal("Expected no stop before the first step")
+			}
+			stop := g.stopCondition()([]fantasy.StepResult{{Response: fantasy.Response{Content: tt.content, Usage: tt.usage}}})
+			if stop != tt.want {
+				t.Fatalf("Expected stop = %v, got %v", tt.want, stop)
+			}
+			if err := g.contextFullErr(); (err != nil) != tt.want || (err != nil && !errors.Is(err, ErrContextFull)) {
+				t.Errorf("Expected an ErrContextFull error only when stopped, got %v", err)
+			}
+		})
+	}
+}
+
+// TestContextGuard_stopsAgent verifies that the agent stops before a request
+// over the context window, keeps the steps it took and starts over with the
+// next prompt.
+func TestContextGuard_stopsAgent(t *testing.T) {
+	guard := &contextGuard{window: 1000, provider: "test"}
+	model := &scriptedModel{responses: []fantasy.Response{
+		toolCallResponse(500),
+		toolCallResponse(1000),
+		{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "done"}}, FinishReason: fantasy.FinishReasonStop},
+	}}
+	a := newScriptedAgent(model, nil, guard.stopCondition())
+	a.contextGuard = guard
+
+	result, err := a.GenerateWithLoopAndStreaming(context.Background(),
+		[]fantasy.Message{fantasy.NewUserMessage("hi")}, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateWithLoopAndStreaming: %v", err)
+	}
+	if model.calls != 2 {
+		t.Errorf("Expected the agent to stop after 2 calls, got %d", model.calls)
+	}
+	if !errors.Is(result.ContextFull, ErrContextFull) || !strings.Contains(result.ContextFull.Error(), "/compact") {
+		t.Errorf("Expected an error suggesting /compact, got %v", result.ContextFull)
+	}
+	// The prompt and both steps' tool calls and results are kept
+	if len(result.ConversationMessages) != 5 {
+		t.Errorf("Expected 5 messages, got %d", len(result.ConversationMessages))
+	}
+
+	result, err = a.GenerateWithLoopAndStreaming(context.Background(),
+		[]fantasy.Message{fantasy.NewUserMessage("go on")}, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateWithLoopAndStreaming: %v", err)
+	}
+	if result.ContextFull != nil {
+		t.Errorf("Expected the next prompt to start over, got %v", result.ContextFull)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/hooks"
 	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/permissions"
 	"github.com/mark3labs/mcphost/internal/tools"
 )
 
@@ -34,6 +36,23 @@ type AgentCreationOptions struct {
 	SpinnerFunc SpinnerFunc // Function to show spinner (provided by caller)
 	// DebugLogger is an optional logger for debugging MCP communications
 	DebugLogger tools.DebugLogger // Optional debug logger
+	// HookExecutor fires PreToolUse and PostToolUse hooks around tool calls
+	HookExecutor *hooks.Executor // Optional hook executor
+	// Permissions holds the allow/ask/deny rules checked before tool calls
+	Permissions *permissions.Ruleset // Optional permission rules
+	// Budget limits the cost and tokens spent per prompt and per session
+	Budget Budget
+	// ToolSearch enables tool search mode, keeping PinnedTools always loaded
+	ToolSearch  bool
+	PinnedTools []string
+	// MaxRepeatedToolCalls is the number of identical tool calls in a row
+	// treated as a loop (0 disables loop detection)
+	MaxRepeatedToolCalls int
+	// ResourceTool adds the read_resource tool for MCP server resources
+	ResourceTool bool
+	// AuthorizationURLFunc shows the URL authorizing mcphost for a remote MCP
+	// server requiring OAuth (nil = such servers are not authorized)
+	AuthorizationURLFunc tools.AuthorizationURLFunc
 }
 
 // CreateAgent creates an agent with optional spinner for Ollama models.
@@ -47,6 +66,15 @@ func CreateAgent(ctx context.Context, opts *AgentCreationOptions) (*Agent, error
 		MaxSteps:         opts.MaxSteps,
 		StreamingEnabled: opts.StreamingEnabled,
 		DebugLogger:      opts.DebugLogger,
+		HookExecutor:     opts.HookExecutor,
+		Permissions:      opts.Permissions,
+		Budget:           opts.Budget,
+		ToolSearch:       opts.ToolSearch,
+		PinnedTools:      opts.PinnedTools,
+
+		MaxRepeatedToolCalls: opts.MaxRepeatedToolCalls,
+		ResourceTool:         opts.ResourceTool,
+		AuthorizationURLFunc: opts.AuthorizationURLFunc,
 	}
 
 	var agent *Agent
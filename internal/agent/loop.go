@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+)
+
+// ErrToolLoop is wrapped by the error reported when the model kept repeating
+// the same tool calls after being told to stop.
+var ErrToolLoop = errors.New("tool call loop")
+
+// loopCyclePeriods are the lengths of the call cycles detected besides
+// repeats of a single call, e.g. A-B-A-B for 2.
+var loopCyclePeriods = []int{2, 3}
+
+// loopGuard detects a model stuck calling the same tools with the same
+// arguments. It fingerprints each tool call by name and canonical arguments.
+// After maxRepeats identical calls in a row, or a short cycle of calls that
+// repeats (A-B-A-B), the looping call is not run; the model gets a corrective
+// error instead. If the loop goes on after that, the call is refused again and
+// the agent stops.
+type loopGuard struct {
+	maxRepeats int
+
+	mu      sync.Mutex
+	calls   []string // fingerprints of the calls made in this run
+	warned  bool
+	stopErr error
+}
+
+// newLoopGuard returns a guard allowing maxRepeats identical calls in a row,
+// or nil when maxRepeats is 0 and loop detection is off.
+func newLoopGuard(maxRepeats int) (*loopGuard, error) {
+	switch {
+	case maxRepeats == 0:
+		return nil, nil
+	case maxRepeats < 2:
+		return nil, fmt.Errorf("invalid repeated tool call limit %d (expected 0 to disable, or at least 2)", maxRepeats)
+	}
+	return &loopGuard{maxRepeats: maxRepeats}, nil
+}
+
+// fork returns a new guard with g's limit, or nil when g is nil.
+func (g *loopGuard) fork() *loopGuard {
+	if g == nil {
+		return nil
+	}
+	return &loopGuard{maxRepeats: g.maxRepeats}
+}
+
+// reset forgets the calls of the previous run.
+func (g *loopGuard) reset() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = nil
+	g.warned = false
+	g.stopErr = nil
+}
+
+// wrap returns tools whose calls go through g.
+func (g *loopGuard) wrap(tools []fantasy.AgentTool) []fantasy.AgentTool {
+	if g == nil {
+		return tools
+	}
+	guarded := make([]fantasy.AgentTool, len(tools))
+	for i, tool := range tools {
+		guarded[i] = guardedTool{AgentTool: tool, guard: g}
+	}
+	return guarded
+}
+
+// stopCondition returns a fantasy stop condition that ends the run once the
+// model kept looping after its correction.
+func (g *loopGuard) stopCondition() fantasy.StopCondition {
+	return func(_ []fantasy.StepResult) bool {
+		return g.loopErr() != nil
+	}
+}
+
+// loopErr returns the error describing the loop that stopped the run, or nil.
+func (g *loopGuard) loopErr() error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stopErr
+}
+
+// check records call and returns a message to send back instead of running
+// it when it continues a loop.
+func (g *loopGuard) check(call fantasy.ToolCall) (refusal string, refused bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.calls = append(g.calls, fingerprint(call))
+	loop := g.detect()
+	switch {
+	case loop == "":
+		return "", false
+	case !g.warned:
+		g.warned = true
+		return fmt.Sprintf("Not run: %s. Repeating it will not give a different result. "+
+			"Change your approach, or answer with what you have. If the loop goes on, the agent is stopped.", loop), true
+	default:
+		if g.stopErr == nil {
+			g.stopErr = fmt.Errorf("%w: %s after being told to stop", ErrToolLoop, loop)
+		}
+		return fmt.Sprintf("Not run: %s again. The agent is stopped.", loop), true
+	}
+}
+
+// detect describes the loop the latest calls form, or returns "" when they
+// do not form one. The caller holds g.mu.
+func (g *loopGuard) detect() string {
+	n := len(g.calls)
+	last := g.calls[n-1]
+
+	repeats := 0
+	for i := n - 1; i >= 0 && g.calls[i] == last; i-- {
+		repeats++
+	}
+	if repeats >= g.maxRepeats {
+		return fmt.Sprintf("this is the same %s call with the same arguments %d times in a row", callName(last), repeats)
+	}
+
+	for _, period := range loopCyclePeriods {
+		if n < 2*period {
+			continue
+		}
+		cycle := g.calls[n-period:]
+		if !slices.Equal(cycle, g.calls[n-2*period:n-period]) || allEqual(cycle) {
+			continue
+		}
+		names := make([]string, period)
+		for i, fp := range cycle {
+			names[i] = callName(fp)
+		}
+		return fmt.Sprintf("the same cycle of %d calls (%v) with the same arguments is repeating", period, names)
+	}
+	return ""
+}
+
+func allEqual(s []string) bool {
+	return !slices.ContainsFunc(s, func(v string) bool { return v != s[0] })
+}
+
+// fingerprint identifies a tool call by its name and arguments. Arguments
+// are canonicalized, so key order and whitespace make no difference.
+func fingerprint(call fantasy.ToolCall) string {
+	args := strings.TrimSpace(call.Input)
+	var v any
+	if err := json.Unmarshal([]byte(args), &v); err == nil {
+		// Marshaling sorts object keys
+		if canonical, err := json.Marshal(v); err == nil {
+			args = string(canonical)
+		}
+	}
+	return call.Name + "\x00" + args
+}
+
+// callName returns the tool name of a fingerprint.
+func callName(fp string) string {
+	name, _, _ := strings.Cut(fp, "\x00")
+	return name
+}
+
+// guardedTool runs a tool's calls through a loopGuard.
+type guardedTool struct {
+	fantasy.AgentTool
+	guard *loopGuard
+}
+
+// Run runs the call unless it continues a loop, in which case the model gets
+// an error result instead.
+func (t guardedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	if refusal, refused := t.guard.check(call); refused {
+		return fantasy.NewTextErrorResponse(refusal), nil
+	}
+	return t.AgentTool.Run(ctx, call)
+}
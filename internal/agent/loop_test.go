@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// call returns a tool call of name with JSON arguments input.
+func call(name, input string) fantasy.ToolCall {
+	return fantasy.ToolCall{Name: name, Input: input}
+}
+
+func TestLoopGuard_detect(t *testing.T) {
+	var (
+		readA = call("read", `{"path":"a"}`)
+		readB = call("read", `{"path":"b"}`)
+		ls    = call("ls", `{}`)
+	)
+	tests := []struct {
+		name       string
+		maxRepeats int
+		calls      []fantasy.ToolCall
+		want       string // substring of the loop found after the last call; "" for none
+	}{
+		{
+			name:       "different calls",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, readB, ls},
+		},
+		{
+			name:       "repeats below the limit",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, readA},
+		},
+		{
+			name:       "repeats at the limit",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, readA, readA},
+			want:       "the same read call with the same arguments 3 times in a row",
+		},
+		{
+			name:       "repeats interrupted",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, readA, ls, readA},
+		},
+		{
+			name:       "same tool with other arguments",
+			maxRepeats: 2,
+			calls:      []fantasy.ToolCall{readA, readB},
+		},
+		{
+			name:       "cycle of 2",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, ls, readA, ls},
+			want:       "the same cycle of 2 calls ([read ls])",
+		},
+		{
+			name:       "cycle of 2 not repeated yet",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, ls, readA},
+		},
+		{
+			name:       "cycle of 3",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, readB, ls, readA, readB, ls},
+			want:       "the same cycle of 3 calls ([read read ls])",
+		},
+		{
+			name:       "cycle of 3 broken",
+			maxRepeats: 3,
+			calls:      []fantasy.ToolCall{readA, readB, ls, readA, ls, ls},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newLoopGuard(tt.maxRepeats)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range tt.calls {
+				g.calls = append(g.calls, fingerprint(c))
+			}
+			got := g.detect()
+			if tt.want == "" && got != "" {
+				t.Errorf("Expected no loop, got %q", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("Expected a loop %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b fantasy.ToolCall
+		same bool
+	}{
+		{"key order", call("bash", `{"command":"ls","timeout":5}`), call("bash", `{"timeout":5,"command":"ls"}`), true},
+		{"whitespace", call("bash", `{"command": "ls"}`), call("bash", " {\n\"command\":\"ls\"}\n"), true},
+		{"nested key order", call("edit", `{"opts":{"a":1,"b":2}}`), call("edit", `{"opts":{"b":2,"a":1}}`), true},
+		{"other arguments", call("bash", `{"command":"ls"}`), call("bash", `{"command":"pwd"}`), false},
+		{"other tool", call("bash", `{}`), call("shell", `{}`), false},
+		{"invalid JSON", call("bash", `{"command"`), call("bash", `{"command"`), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := fingerprint(tt.a) == fingerprint(tt.b); same != tt.same {
+				t.Errorf("Expected same fingerprint = %v for %q and %q", tt.same, tt.a.Input, tt.b.Input)
+			}
+		})
+	}
+	if got := callName(fingerprint(call("bash", `{}`))); got != "bash" {
+		t.Errorf("Expected the tool name bash, got %q", got)
+	}
+}
+
+// TestLoopGuard_check verifies that the first looping call is refused with a
+// correction, and that the second offence stops the run.
+func TestLoopGuard_check(t *testing.T) {
+	g, err := newLoopGuard(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := g.stopCondition()
+	ls := call("ls", `{}`)
+
+	if _, refused := g.check(ls); refused {
+		t.Fatal("Expected the first call to run")
+	}
+	refusal, refused := g.check(ls)
+	if !refused || !strings.Contains(refusal, "If the loop goes on, the agent is stopped") {
+		t.Fatalf("Expected the repeat to be refused with a correction, got %v %q", refused, refusal)
+	}
+	if g.loopErr() != nil || stop(nil) {
+		t.Fatal("Expected the run to go on after the correction")
+	}
+
+	refusal, refused = g.check(ls)
+	if !refused || !strings.Contains(refusal, "The agent is stopped") {
+		t.Fatalf("Expected the second offence to be refused, got %v %q", refused, refusal)
+	}
+	if err := g.loopErr(); !errors.Is(err, ErrToolLoop) || !strings.Contains(err.Error(), "after being told to stop") {
+		t.Errorf("Expected a tool loop error, got %v", err)
+	}
+	if !stop(nil) {
+		t.Error("Expected the stop condition to end the run")
+	}
+
+	g.reset()
+	if g.loopErr() != nil || stop(nil) {
+		t.Error("Expected reset to forget the loop")
+	}
+	if _, refused := g.check(ls); refused {
+		t.Error("Expected the next run to start over")
+	}
+}
+
+func TestNewLoopGuard(t *testing.T) {
+	if g, err := newLoopGuard(0); g != nil || err != nil {
+		t.Errorf("Expected no guard for 0, got %v, %v", g, err)
+	}
+	if _, err := newLoopGuard(1); err == nil {
+		t.Error("Expected an error for a limit of 1")
+	}
+}
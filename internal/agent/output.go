@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/schema"
+	"github.com/kaptinlin/jsonschema"
+)
+
+// DefaultOutputRetries is the number of times the model may retry a final
+// answer that does not match the output schema.
+const DefaultOutputRetries = 2
+
+// ErrOutputValidation is returned when the final answer still does not match
+// the output schema after all retries.
+var ErrOutputValidation = errors.New("final answer does not match the output schema")
+
+// outputToolName names the structured output. Providers without native
+// structured output receive it as a tool the model is forced to call.
+const outputToolName = "respond"
+
+const outputInstruction = `Give your final answer now by responding with JSON that matches the required schema. Include everything the answer needs; do not add any other text.`
+
+const outputRetryInstruction = `Your answer does not match the required schema: %v
+
+Respond again with corrected JSON that matches the schema.`
+
+// OutputSchema is a JSON Schema the agent's final answer must match. The
+// root of the schema must describe a JSON object.
+type OutputSchema struct {
+	// model is the subset of the schema sent to the provider.
+	model fantasy.Schema
+	// validator checks answers against the complete schema.
+	validator *jsonschema.Schema
+}
+
+// LoadOutputSchema reads a JSON Schema file for use as an output schema.
+func LoadOutputSchema(path string) (*OutputSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output schema: %w", err)
+	}
+	s, err := ParseOutputSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ParseOutputSchema parses a JSON Schema document for use as an output schema.
+func ParseOutputSchema(data []byte) (*OutputSchema, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("schema is not a JSON object: %w", err)
+	}
+	if schemaType(raw["type"]) != "object" {
+		return nil, errors.New(`schema must describe a JSON object ("type": "object")`)
+	}
+
+	validator, err := jsonschema.NewCompiler().Compile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutputSchema{
+		model:     toFantasySchema(raw),
+		validator: validator,
+	}, nil
+}
+
+// Validate checks that data is JSON matching the schema and returns it in
+// compact form.
+func (s *OutputSchema) Validate(data []byte) (json.RawMessage, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	result := s.validator.Validate(value)
+	if !result.IsValid() {
+		// Report each failed check at the location of the value it failed on
+		list := result.ToList(false)
+		var problems []string
+		for _, detail := range append([]jsonschema.List{*list}, list.Details...) {
+			location := detail.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			for _, message := range detail.Errors {
+				problems = append(problems, fmt.Sprintf("%s: %s", location, message))
+			}
+		}
+		sort.Strings(problems)
+		return nil, errors.New(strings.Join(slices.Compact(problems), "; "))
+	}
+
+	compact, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return compact, nil
+}
+
+// toFantasySchema converts the parts of a JSON Schema that providers
+// understand. Keywords outside that subset are still enforced by Validate.
+func toFantasySchema(raw map[string]any) fantasy.Schema {
+	var s fantasy.Schema
+	s.Type = schemaType(raw["type"])
+	s.Description, _ = raw["description"].(string)
+	s.Format, _ = raw["format"].(string)
+	s.Enum, _ = raw["enum"].([]any)
+
+	if props, ok := raw["properties"].(map[string]any); ok {
+		s.Properties = make(map[string]*fantasy.Schema, len(props))
+		for name, prop := range props {
+			if propMap, ok := prop.(map[string]any); ok {
+				propSchema := toFantasySchema(propMap)
+				s.Properties[name] = &propSchema
+			}
+		}
+	}
+	if required, ok := raw["required"].([]any); ok {
+		for _, name := range required {
+			if str, ok := name.(string); ok {
+				s.Required = append(s.Required, str)
+			}
+		}
+	}
+	if items, ok := raw["items"].(map[string]any); ok {
+		itemSchema := toFantasySchema(items)
+		s.Items = &itemSchema
+	}
+
+	if v, ok := raw["minimum"].(float64); ok {
+		s.Minimum = &v
+	}
+	if v, ok := raw["maximum"].(float64); ok {
+		s.Maximum = &v
+	}
+	if v, ok := raw["minLength"].(float64); ok {
+		n := int(v)
+		s.MinLength = &n
+	}
+	if v, ok := raw["maxLength"].(float64); ok {
+		n := int(v)
+		s.MaxLength = &n
+	}
+	return s
+}
+
+// schemaType returns the type of a schema's "type" keyword. For a list of
+// types the first one other than "null" is used.
+func schemaType(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if str, ok := item.(string); ok && str != "null" {
+				return str
+			}
+		}
+	}
+	return ""
+}
+
+// GenerateStructuredOutput asks the model for the final answer to the
+// conversation in result as JSON matching outputSchema. It uses the
+// provider's structured output where there is one and a forced "respond" tool
+// call otherwise. An answer that does not match the schema is sent back to
+// the model with the validation errors, up to maxRetries times, after which
+// an error wrapping ErrOutputValidation is returned.
+//
+// On success result.StructuredOutput holds the validated JSON, and the
+// request and answer are added to result's messages and usage.
+func (a *Agent) GenerateStructuredOutput(ctx context.Context, result *GenerateWithLoopResult, outputSchema *OutputSchema, maxRetries int) error {
+	var system fantasy.Prompt
+	if a.systemPrompt != "" {
+		system = append(system, fantasy.NewSystemMessage(a.systemPrompt))
+	}
+	messages := append(slices.Clone(result.ConversationMessages), fantasy.NewUserMessage(outputInstruction))
+
+	for attempt := 0; ; attempt++ {
+		resp, err := a.model.GenerateObject(ctx, fantasy.ObjectCall{
+			Prompt:            append(slices.Clone(system), messages...),
+			Schema:            outputSchema.model,
+			SchemaName:        outputToolName,
+			SchemaDescription: "Respond with the final answer in the required format.",
+		})
+
+		var answer string
+		var validationErr, budgetErr error
+		var parseErr *schema.ParseError
+		var noObjectErr *fantasy.NoObjectGeneratedError
+		switch {
+		case err == nil:
+			addUsage(&result.TotalUsage, resp.Usage)
+			budgetErr = a.budget.record(resp.Usage)
+			data, marshalErr := json.Marshal(resp.Object)
+			if marshalErr != nil {
+				return marshalErr
+			}
+			output, err := outputSchema.Validate(data)
+			if err == nil {
+				result.StructuredOutput = output
+				result.ConversationMessages = append(messages, fantasy.Message{
+					Role:    fantasy.MessageRoleAssistant,
+					Content: []fantasy.MessagePart{fantasy.TextPart{Text: string(output)}},
+				})
+				return nil
+			}
+			answer, validationErr = string(data), err
+		case errors.As(err, &noObjectErr):
+			addUsage(&result.TotalUsage, noObjectErr.Usage)
+			budgetErr = a.budget.record(noObjectErr.Usage)
+			answer, validationErr = noObjectErr.RawText, err
+		case errors.As(err, &parseErr):
+			answer, validationErr = parseErr.RawText, err
+		default:
+			return err
+		}
+
+		if attempt >= maxRetries {
+			return fmt.Errorf("%w: %v", ErrOutputValidation, validationErr)
+		}
+		// A retry that would exceed a budget is not made
+		if budgetErr != nil {
+			return budgetErr
+		}
+
+		if answer != "" {
+			messages = append(messages, fantasy.Message{
+				Role:    fantasy.MessageRoleAssistant,
+				Content: []fantasy.MessagePart{fantasy.TextPart{Text: answer}},
+			})
+		}
+		messages = append(messages, fantasy.NewUserMessage(fmt.Sprintf(outputRetryInstruction, validationErr)))
+	}
+}
+
+// addUsage adds u to total.
+func addUsage(total *fantasy.Usage, u fantasy.Usage) {
+	total.InputTokens += u.InputTokens
+	total.OutputTokens += u.OutputTokens
+	total.TotalTokens += u.TotalTokens
+	total.ReasoningTokens += u.ReasoningTokens
+	total.CacheCreationTokens += u.CacheCreationTokens
+	total.CacheReadTokens += u.CacheReadTokens
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+const testOutputSchema = `{
+	"type": "object",
+	"properties": {
+		"answer": {"type": "integer", "minimum": 0, "description": "The answer"},
+		"notes": {"type": ["string", "null"], "maxLength": 20}
+	},
+	"required": ["answer"]
+}`
+
+func mustParseOutputSchema(t *testing.T) *OutputSchema {
+	t.Helper()
+	s, err := ParseOutputSchema([]byte(testOutputSchema))
+	if err != nil {
+		t.Fatalf("ParseOutputSchema: %v", err)
+	}
+	return s
+}
+
+func TestParseOutputSchema(t *testing.T) {
+	s := mustParseOutputSchema(t)
+	if s.model.Type != "object" || len(s.model.Required) != 1 || s.model.Required[0] != "answer" {
+		t.Errorf("Expected an object requiring answer, got %+v", s.model)
+	}
+	answer := s.model.Properties["answer"]
+	if answer == nil || answer.Type != "integer" || answer.Minimum == nil || *answer.Minimum != 0 || answer.Description != "The answer" {
+		t.Errorf("Expected the answer property to be converted, got %+v", answer)
+	}
+	if notes := s.model.Properties["notes"]; notes == nil || notes.Type != "string" || notes.MaxLength == nil || *notes.MaxLength != 20 {
+		t.Errorf("Expected a nullable string to be sent as a string, got %+v", notes)
+	}
+
+	for name, data := range map[string]string{
+		"not JSON":      `{"type":`,
+		"not an object": `{"type": "array", "items": {"type": "string"}}`,
+		"no type":       `{"properties": {}}`,
+	} {
+		if _, err := ParseOutputSchema([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadOutputSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(`{"type": "string"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOutputSchema(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+	if _, err := LoadOutputSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestOutputSchema_Validate(t *testing.T) {
+	s := mustParseOutputSchema(t)
+	tests := []struct {
+		name    string
+		data    string
+		want    string // compact JSON on success
+		wantErr string
+	}{
+		{name: "valid", data: "{\n  \"answer\": 42\n}", want: `{"answer":42}`},
+		{name: "null allowed", data: `{"answer": 1, "notes": null}`, want: `{"answer":1,"notes":null}`},
+		{name: "invalid JSON", data: `{"answer": `, wantErr: "invalid JSON"},
+		{name: "missing property", data: `{"notes": "hi"}`, wantErr: "/: Required property 'answer' is missing"},
+		{name: "wrong type", data: `{"answer": "42"}`, wantErr: "/answer: Value is string but should be integer"},
+		{name: "below minimum", data: `{"answer": -1}`, wantErr: "/answer: -1 should be at least 0"},
+		{name: "too long", data: `{"answer": 1, "notes": "` + strings.Repeat("x", 21) + `"}`, wantErr: "/notes: Value should be at most 20 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Validate([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected an error about %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// noObject is the error of a GenerateObject call answered with text that is
+// not a JSON object.
+func noObject(text string) error {
+	return &fantasy.NoObjectGeneratedError{
+		RawText:    text,
+		ParseError: errors.New("invalid character"),
+		Usage:      fantasy.Usage{InputTokens: 10, OutputTokens: 5},
+	}
+}
+
+func TestGenerateStructuredOutput(t *testing.T) {
+	s := mustParseOutputSchema(t)
+	conversation := []fantasy.Message{fantasy.NewUserMessage("What is the answer?")}
+
+	t.Run("invalid JSON then valid", func(t *testing.T) {
+		model := &scriptedModel{
+			objectErr: []error{noObject("The answer is 42.")},
+			objects:   []*fantasy.ObjectResponse{nil, {Object: map[string]any{"answer": 42}, Usage: fantasy.Usage{InputTokens: 20, OutputTokens: 5}}},
+		}
+		a := &Agent{model: model, systemPrompt: "Be brief."}
+		result := &GenerateWithLoopResult{ConversationMessages: conversation}
+
+		if err := a.GenerateStructuredOutput(context.Background(), result, s, DefaultOutputRetries); err != nil {
+			t.Fatalf("GenerateStructuredOutput: %v", err)
+		}
+		if string(result.StructuredOutput) != `{"answer":42}` {
+			t.Errorf("Expected the valid answer, got %s", result.StructuredOutput)
+		}
+		if result.TotalUsage.InputTokens != 30 || result.TotalUsage.OutputTokens != 10 {
+			t.Errorf("Expected the usage of both calls, got %+v", result.TotalUsage)
+		}
+		if len(model.objCalls) != 2 {
+			t.Fatalf("Expected 2 calls, got %d", len(model.objCalls))
+		}
+
+		// The retry shows the model its answer and what was wrong with it
+		retry := model.objCalls[1].Prompt
+		if retry[0].Role != fantasy.MessageRoleSystem || model.objCalls[1].SchemaName != outputToolName {
+			t.Errorf("Expected the system prompt and the schema name, got %+v", model.objCalls[1])
+		}
+		if got := messageText(retry[len(retry)-2]); got != "The answer is 42." {
+			t.Errorf("Expected the invalid answer to be sent back, got %q", got)
+		}
+		if got := messageText(retry[len(retry)-1]); !strings.Contains(got, "does not match the required schema") {
+			t.Errorf("Expected the validation error to be sent back, got %q", got)
+		}
+
+		// The result holds the request, the retry and the valid answer
+		msgs := result.ConversationMessages
+		if len(msgs) != 5 || messageText(msgs[1]) != outputInstruction || messageText(msgs[4]) != `{"answer":42}` {
+			t.Errorf("Expected the request, the retry and the answer to be added, got %+v", msgs)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		model := &scriptedModel{objects: []*fantasy.ObjectResponse{
+			{Object: map[string]any{"answer": "many"}},
+			{Object: map[string]any{"answer": -1}},
+			{Object: map[string]any{"notes": "none"}},
+		}}
+		a := &Agent{model: model}
+		result := &GenerateWithLoopResult{ConversationMessages: conversation}
+
+		err := a.GenerateStructuredOutput(context.Background(), result, s, 2)
+		if !errors.Is(err, ErrOutputValidation) {
+			t.Fatalf("Expected an error wrapping ErrOutputValidation, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "Required property 'answer' is missing") {
+			t.Errorf("Expected the last validation error, got %v", err)
+		}
+		if len(model.objCalls) != 3 {
+			t.Errorf("Expected the first call and 2 retries, got %d calls", len(model.objCalls))
+		}
+		if result.StructuredOutput != nil || len(result.ConversationMessages) != 1 {
+			t.Errorf("Expected the result to be left alone, got %s and %d messages",
+				result.StructuredOutput, len(result.ConversationMessages))
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		providerErr := errors.New("overloaded")
+		model := &scriptedModel{objectErr: []error{providerErr}}
+		a := &Agent{model: model}
+		result := &GenerateWithLoopResult{ConversationMessages: conversation}
+
+		if err := a.GenerateStructuredOutput(context.Background(), result, s, 2); !errors.Is(err, providerErr) {
+			t.Fatalf("Expected the provider error without retries, got %v", err)
+		}
+		if len(model.objCalls) != 1 {
+			t.Errorf("Expected a single call, got %d", len(model.objCalls))
+		}
+	})
+}
+
+// messageText returns the text of msg's first text part.
+func messageText(msg fantasy.Message) string {
+	for _, part := range msg.Content {
+		if text, ok := part.(fantasy.TextPart); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/builtin"
+)
+
+// defaultSubAgentMaxSteps limits a sub-agent's tool-calling steps when the
+// task server does not configure max_steps.
+const defaultSubAgentMaxSteps = 20
+
+const defaultSubAgentSystemPrompt = `You are a sub-agent working on a single task delegated to you by another AI assistant.
+Use the available tools to complete the task. You cannot ask questions; make reasonable assumptions and note them.
+When you are done, reply with a concise, self-contained answer containing everything the assistant asked for.
+Your final reply is the only thing the assistant will see.`
+
+// NestedToolHandlers receives the tool calls made by sub-agents started from
+// the task tool, so the UI can show them nested under the task call.
+type NestedToolHandlers struct {
+	OnToolCall   ToolCallHandler
+	OnToolResult ToolResultHandler
+}
+
+type nestedToolHandlersKey struct{}
+
+// WithNestedToolHandlers returns a context carrying h. Sub-agents started by
+// tool calls made with this context report their own tool calls to h.
+func WithNestedToolHandlers(ctx context.Context, h NestedToolHandlers) context.Context {
+	return context.WithValue(ctx, nestedToolHandlersKey{}, h)
+}
+
+// nestedToolHandlersFrom returns the handlers set with WithNestedToolHandlers.
+func nestedToolHandlersFrom(ctx context.Context) NestedToolHandlers {
+	h, _ := ctx.Value(nestedToolHandlersKey{}).(NestedToolHandlers)
+	return h
+}
+
+// runTask runs a sub-agent for req and returns its final answer. The
+// sub-agent shares the model and tool manager (and so the hooks, permission
+// rules and approval prompt) with a, but has its own system prompt, tool
+// subset and step limit, and starts from an empty conversation.
+func (a *Agent) runTask(ctx context.Context, req builtin.TaskRequest) (string, error) {
+	systemPrompt := req.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultSubAgentSystemPrompt
+	}
+	maxSteps := req.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultSubAgentMaxSteps
+	}
+
+	agentOpts := append([]fantasy.AgentOption{fantasy.WithMaxRetries(0)}, a.callOpts...)
+	agentOpts = append(agentOpts,
+		fantasy.WithSystemPrompt(systemPrompt),
+		fantasy.WithStopConditions(
+			fantasy.StepCountIs(maxSteps),
+			func(_ []fantasy.StepResult) bool {
+				_, stopped := a.toolManager.HookStop()
+				return stopped
+			},
+		),
+	)
+	if a.budget != nil {
+		agentOpts = append(agentOpts, fantasy.WithStopConditions(a.budget.stopCondition()))
+	}
+	// The sub-agent's calls are checked for loops on their own, so that the
+	// parent's calls before the task do not count
+	guard := a.loopGuard.fork()
+	if guard != nil {
+		agentOpts = append(agentOpts, fantasy.WithStopConditions(guard.stopCondition()))
+	}
+	contextGuard := a.contextGuard.fork()
+	if contextGuard != nil {
+		agentOpts = append(agentOpts, fantasy.WithStopConditions(contextGuard.stopCondition()))
+	}
+	if subTools := a.toolManager.SubAgentTools(req.AllowedTools); len(subTools) > 0 {
+		agentOpts = append(agentOpts, fantasy.WithTools(cacheTools(guard.wrap(subTools), a.promptCache)...))
+	}
+
+	sub := *a
+	sub.loopGuard = guard
+	sub.prepareStep = promptCacheStep(a.promptCache)
+	sub.contextGuard = contextGuard
+	sub.fantasyAgent = fantasy.NewAgent(a.model, agentOpts...)
+	sub.systemPrompt = systemPrompt
+	sub.maxSteps = maxSteps
+	sub.nested = true
+
+	handlers := nestedToolHandlersFrom(ctx)
+	result, err := sub.GenerateWithLoopAndStreaming(ctx,
+		[]fantasy.Message{fantasy.NewUserMessage(req.Prompt)},
+		handlers.OnToolCall, nil, handlers.OnToolResult, nil, nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if result.BudgetExceeded != nil {
+		return "", result.BudgetExceeded
+	}
+	if result.ToolLoop != nil {
+		return "", result.ToolLoop
+	}
+	if result.ContextFull != nil {
+		return "", result.ContextFull
+	}
+	if result.FinalResponse == nil {
+		return "", errors.New("sub-agent returned no response")
+	}
+	return result.FinalResponse.Content.Text(), nil
+}
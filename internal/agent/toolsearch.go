@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"context"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// activeToolsStep returns a prepare step function that sends the tool
+// manager's active tools with every step, so tools loaded with load_tool, or
+// reloaded after a server changed them, can be called from the next step on.
+// Tools called earlier in the conversation are loaded first. Tool calls go
+// through guard, which may be nil. next, which may be nil, prepares the rest
+// of the step.
+func activeToolsStep(toolManager *tools.MCPToolManager, guard *loopGuard, promptCache string, next fantasy.PrepareStepFunction) fantasy.PrepareStepFunction {
+	return func(ctx context.Context, opts fantasy.PrepareStepFunctionOptions) (context.Context, fantasy.PrepareStepResult, error) {
+		var result fantasy.PrepareStepResult
+		if next != nil {
+			var err error
+			if ctx, result, err = next(ctx, opts); err != nil {
+				return ctx, result, err
+			}
+		}
+		toolManager.LoadCalledTools(opts.Messages)
+		// A nil list would keep the tools the agent was created with.
+		result.Tools = cacheTools(guard.wrap(toolManager.ActiveTools()), promptCache)
+		if result.Tools == nil {
+			result.Tools = []fantasy.AgentTool{}
+		}
+		return ctx, result, nil
+	}
+}
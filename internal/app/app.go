@@ -2,15 +2,34 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/fantasy"
 
 	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/attachments"
+	"github.com/mark3labs/mcphost/internal/hooks"
+	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/permissions"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
+// ErrPromptBlocked is returned (wrapped with the hook's reason) when a
+// UserPromptSubmit hook blocks a prompt before it reaches the agent.
+var ErrPromptBlocked = errors.New("prompt blocked by UserPromptSubmit hook")
+
+// ErrAttachmentsUnsupported is returned (wrapped with the file name) when an
+// image or PDF is attached for a model that does not accept attachments.
+var ErrAttachmentsUnsupported = errors.New("the model does not accept attachments")
+
 // App is the application-layer orchestrator. It owns the agentic loop,
 // conversation history (via MessageStore), and queue management. It is
 // designed to be created once per session and reused across multiple prompts.
@@ -54,6 +73,10 @@ type App struct {
 	// silently dropped.
 	closed bool
 
+	// contextTokens is the approximate context window fill level after the
+	// last step or compaction. Protected by mu.
+	contextTokens int
+
 	// rootCtx/rootCancel are used to signal shutdown to all goroutines.
 	rootCtx    context.Context
 	rootCancel context.CancelFunc
@@ -81,6 +104,94 @@ func (a *App) SetProgram(p *tea.Program) {
 	a.program = p
 }
 
+// --------------------------------------------------------------------------
+// Tool approval
+// --------------------------------------------------------------------------
+
+// SetToolApprovalFunc replaces the approval policy set in Options. Interactive
+// mode uses it to install NewInteractiveApprovalFunc once the TUI is running,
+// since the policy needs the App itself. A nil fn approves every call.
+func (a *App) SetToolApprovalFunc(fn ToolApprovalFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.opts.ToolApprovalFunc = fn
+}
+
+// ApproveTool asks the configured ToolApprovalFunc whether a tool call may
+// run. It is handed to the agent so that every tool call passes through it;
+// with no approval func configured every call is approved.
+func (a *App) ApproveTool(ctx context.Context, toolName, toolArgs string) (bool, error) {
+	a.mu.Lock()
+	approve := a.opts.ToolApprovalFunc
+	a.mu.Unlock()
+
+	if approve == nil {
+		return true, nil
+	}
+	return approve(ctx, toolName, toolArgs)
+}
+
+// NewInteractiveApprovalFunc returns a ToolApprovalFunc that asks the user via
+// the TUI: it sends a ToolApprovalNeededEvent to the program registered with a
+// and blocks until the user answers or ctx is cancelled. Calls made while no
+// program is registered fail with ErrApprovalRequired.
+//
+// When the user answers "always allow", an allow rule for the tool is added to
+// Options.Permissions and saved to the project permissions file.
+func NewInteractiveApprovalFunc(a *App) ToolApprovalFunc {
+	return func(ctx context.Context, toolName, toolArgs string) (bool, error) {
+		a.mu.Lock()
+		prog := a.program
+		a.mu.Unlock()
+		if prog == nil {
+			return false, fmt.Errorf("%w: %s (no interactive session)", ErrApprovalRequired, toolName)
+		}
+
+		send := func(msg tea.Msg) { prog.Send(msg) }
+		decision, err := requestApproval(ctx, send, toolName, toolArgs, a.opts.Permissions.CanPersist())
+		if err != nil {
+			return false, err
+		}
+		if decision.Approved && decision.Always {
+			a.allowAlways(send, toolName)
+		}
+		return decision.Approved, nil
+	}
+}
+
+// requestApproval performs the approval handshake: it sends a
+// ToolApprovalNeededEvent through send and waits for the decision on the
+// event's response channel, giving up when ctx is cancelled.
+func requestApproval(ctx context.Context, send func(tea.Msg), toolName, toolArgs string, canAlwaysAllow bool) (ToolApprovalDecision, error) {
+	// Buffered so the TUI never blocks if we stopped waiting in the meantime.
+	ch := make(chan ToolApprovalDecision, 1)
+	send(ToolApprovalNeededEvent{
+		ToolName:       toolName,
+		ToolArgs:       toolArgs,
+		CanAlwaysAllow: canAlwaysAllow,
+		ResponseChan:   ch,
+	})
+
+	select {
+	case decision := <-ch:
+		return decision, nil
+	case <-ctx.Done():
+		return ToolApprovalDecision{}, ctx.Err()
+	}
+}
+
+// allowAlways adds and saves an allow rule for toolName after the user chose
+// "always allow", then reports the outcome through send.
+func (a *App) allowAlways(send func(tea.Msg), toolName string) {
+	rule := permissions.Rule{Tool: toolName, Action: permissions.Allow}
+	err := a.opts.Permissions.AddProjectRule(rule)
+	send(PermissionRuleAddedEvent{
+		Rule: rule.String(),
+		Path: a.opts.Permissions.ProjectPath(),
+		Err:  err,
+	})
+}
+
 // --------------------------------------------------------------------------
 // AppController interface
 // --------------------------------------------------------------------------
@@ -115,7 +226,7 @@ func (a *App) Run(prompt string) int {
 	a.busy = true
 	a.wg.Add(1)
 	a.mu.Unlock()
-	go a.drainQueue(prompt)
+	go a.drainQueue(func() { a.runPrompt(prompt) })
 	return 0
 }
 
@@ -163,9 +274,10 @@ func (a *App) ClearMessages() {
 // --------------------------------------------------------------------------
 
 // RunOnce executes a single agent step synchronously and prints the final
-// response text to stdout. No intermediate events are emitted. Blocks until
-// the step completes or ctx is cancelled.
-func (a *App) RunOnce(ctx context.Context, prompt string) error {
+// response text to Options.Output, or only the validated JSON when an output
+// schema is set. No intermediate events are emitted. Blocks until the step completes or
+// ctx is cancelled. atts are attached to the prompt (see --attach).
+func (a *App) RunOnce(ctx context.Context, prompt string, atts ...*attachments.Attachment) error {
 	stepCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -173,7 +285,7 @@ func (a *App) RunOnce(ctx context.Context, prompt string) error {
 	a.cancelStep = cancel
 	a.mu.Unlock()
 
-	result, err := a.executeStep(stepCtx, prompt, nil)
+	result, err := a.executeStep(stepCtx, prompt, atts, nil)
 	if err != nil {
 		return err
 	}
@@ -181,12 +293,32 @@ func (a *App) RunOnce(ctx context.Context, prompt string) error {
 	// Record token usage for the completed step.
 	a.updateUsage(result, prompt)
 
+	if err := stoppedEarly(result); err != nil {
+		return err
+	}
+
+	if a.opts.OutputSchema != nil && result.StructuredOutput == nil {
+		// Only a hook stopping the loop skips the structured answer.
+		if result.HookStopReason != "" {
+			return fmt.Errorf("stopped by hook before the final answer: %s", result.HookStopReason)
+		}
+		return errors.New("stopped by hook before the final answer")
+	}
+
 	responseText := ""
-	if result.FinalResponse != nil {
+	if result.StructuredOutput != nil {
+		responseText = string(result.StructuredOutput)
+	} else if result.FinalResponse != nil {
 		responseText = result.FinalResponse.Content.Text()
 	}
 	if responseText != "" {
-		fmt.Println(responseText)
+		out := a.opts.Output
+		if out == nil {
+			out = os.Stdout
+		}
+		if _, err := fmt.Fprintln(out, responseText); err != nil {
+			return fmt.Errorf("failed to print the response: %w", err)
+		}
 	}
 	return nil
 }
@@ -200,8 +332,9 @@ func (a *App) RunOnce(ctx context.Context, prompt string) error {
 // (SpinnerEvent, ToolCallStartedEvent, StreamChunkEvent, StepCompleteEvent,
 // etc.) and is responsible for rendering them.
 //
-// Blocks until the step completes or ctx is cancelled.
-func (a *App) RunOnceWithDisplay(ctx context.Context, prompt string, eventFn func(tea.Msg)) error {
+// Blocks until the step completes or ctx is cancelled. atts are attached to
+// the prompt (see --attach).
+func (a *App) RunOnceWithDisplay(ctx context.Context, prompt string, eventFn func(tea.Msg), atts ...*attachments.Attachment) error {
 	stepCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -209,7 +342,7 @@ func (a *App) RunOnceWithDisplay(ctx context.Context, prompt string, eventFn fun
 	a.cancelStep = cancel
 	a.mu.Unlock()
 
-	result, err := a.executeStep(stepCtx, prompt, eventFn)
+	result, err := a.executeStep(stepCtx, prompt, atts, eventFn)
 	if err != nil {
 		return err
 	}
@@ -217,6 +350,10 @@ func (a *App) RunOnceWithDisplay(ctx context.Context, prompt string, eventFn fun
 	// Record token usage for the completed step.
 	a.updateUsage(result, prompt)
 
+	if err := stoppedEarly(result); err != nil {
+		return err
+	}
+
 	// Send step complete so the display handler can render the final response.
 	if eventFn != nil && result.FinalResponse != nil {
 		eventFn(StepCompleteEvent{
@@ -256,16 +393,14 @@ func (a *App) Close() {
 // Internal: queue drain loop
 // --------------------------------------------------------------------------
 
-// drainQueue runs in a goroutine. It executes the given prompt and then
-// continues draining the queue until it is empty.
+// drainQueue runs in a goroutine. It runs first (a prompt or a manual
+// compaction) and then continues draining the queue until it is empty.
 // Must be called with a.busy == true and a.wg incremented.
-func (a *App) drainQueue(firstPrompt string) {
+func (a *App) drainQueue(first func()) {
 	defer a.wg.Done()
 
-	prompt := firstPrompt
+	first()
 	for {
-		a.runPrompt(prompt)
-
 		a.mu.Lock()
 		// Stop draining if the app is shutting down.
 		if a.closed || a.rootCtx.Err() != nil {
@@ -279,17 +414,21 @@ func (a *App) drainQueue(firstPrompt string) {
 			a.mu.Unlock()
 			return
 		}
-		prompt = a.queue[0]
+		prompt := a.queue[0]
 		a.queue = a.queue[1:]
 		qLen := len(a.queue)
 		a.mu.Unlock()
 		// sendEvent must be called without a.mu held (see sendEvent comment).
 		a.sendEvent(QueueUpdatedEvent{Length: qLen})
+
+		a.runPrompt(prompt)
 	}
 }
 
 // runPrompt executes a single prompt: adds the user message to the store,
-// runs the agent step, and sends the appropriate event to the program.
+// runs the agent step, and sends the appropriate event to the program. Files
+// mentioned as "@path" and MCP resources mentioned as "@server:uri" in the
+// prompt are attached to it.
 func (a *App) runPrompt(prompt string) {
 	// Create a per-step cancellable context.
 	stepCtx, cancel := context.WithCancel(a.rootCtx)
@@ -309,7 +448,19 @@ func (a *App) runPrompt(prompt string) {
 		}
 	}
 
-	result, err := a.executeStep(stepCtx, prompt, eventFn)
+	atts, err := a.loadMentions(stepCtx, prompt)
+	if err != nil {
+		a.sendEvent(StepErrorEvent{Err: err})
+		return
+	}
+
+	result, err := a.executeStep(stepCtx, prompt, atts, eventFn)
+	a.reportStep(stepCtx, prompt, result, err)
+}
+
+// reportStep records the usage of a step run in the background and sends the
+// event reporting its outcome to the program.
+func (a *App) reportStep(stepCtx context.Context, prompt string, result *agent.GenerateWithLoopResult, err error) {
 	if err != nil {
 		if stepCtx.Err() != nil {
 			// Step was cancelled by the user (e.g. double-ESC). Send a
@@ -325,6 +476,14 @@ func (a *App) runPrompt(prompt string) {
 	// Record token usage for the completed step.
 	a.updateUsage(result, prompt)
 
+	// A budget, a tool call loop or a full context window stopped the step
+	// before the final answer; the steps it did take are kept in the
+	// conversation.
+	if err := stoppedEarly(result); err != nil {
+		a.sendEvent(StepErrorEvent{Err: err})
+		return
+	}
+
 	a.sendEvent(StepCompleteEvent{
 		Response: result.FinalResponse,
 		Usage:    result.TotalUsage,
@@ -340,24 +499,96 @@ func (a *App) runPrompt(prompt string) {
 // replaces the store with the full updated conversation on success.
 // eventFn receives intermediate display events (tool calls, streaming chunks,
 // etc.); it may be nil when no display is needed (e.g. quiet RunOnce).
-func (a *App) executeStep(ctx context.Context, prompt string, eventFn func(tea.Msg)) (*agent.GenerateWithLoopResult, error) {
+//
+// When a hook executor is configured, UserPromptSubmit hooks run before the
+// prompt is added to the store (and may block it), and Stop hooks run once the
+// agent has finished, whether it completed, failed or was cancelled.
+func (a *App) executeStep(ctx context.Context, prompt string, atts []*attachments.Attachment, eventFn func(tea.Msg)) (*agent.GenerateWithLoopResult, error) {
+	if err := a.checkAttachments(atts); err != nil {
+		return nil, err
+	}
+	return a.executeMessages(ctx, prompt, []fantasy.Message{attachments.NewUserMessage(prompt, atts)}, eventFn)
+}
+
+// executeMessages runs a single agentic step after adding msgs to the
+// conversation. prompt is their text, which UserPromptSubmit hooks receive.
+// See executeStep.
+func (a *App) executeMessages(ctx context.Context, prompt string, msgs []fantasy.Message, eventFn func(tea.Msg)) (*agent.GenerateWithLoopResult, error) {
 	sendFn := func(msg tea.Msg) {
 		if eventFn != nil {
 			eventFn(msg)
 		}
 	}
 
-	// Add user message to the store immediately so history is consistent
+	// A blocked prompt never reaches the history or the agent.
+	if err := a.fireUserPromptSubmit(ctx, prompt); err != nil {
+		return nil, err
+	}
+
+	// Make room before the prompt is added when the context is nearly full.
+	// A failed compaction is reported but the step still runs; it may fit.
+	if a.shouldCompact() {
+		sendFn(SpinnerEvent{Show: true})
+		summarized, kept, err := a.compact(ctx, "")
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+		if !errors.Is(err, ErrNothingToCompact) {
+			sendFn(CompactionEvent{Auto: true, Summarized: summarized, Kept: kept, Err: err})
+		}
+	}
+
+	// Add the messages to the store immediately so history is consistent
 	// even if the step is later cancelled.
-	userMsg := fantasy.NewUserMessage(prompt)
-	a.store.Add(userMsg)
+	for _, msg := range msgs {
+		a.store.Add(msg)
+	}
 
 	// Build the full message slice for the agent call.
-	msgs := a.store.GetAll()
+	msgs = a.store.GetAll()
 
 	// Signal spinner start.
 	sendFn(SpinnerEvent{Show: true})
 
+	// Retries and fallbacks of the model are reported as they happen.
+	ctx = models.WithFallbackObserver(ctx, models.FallbackObserver{
+		OnRetry: func(model string, attempt int, delay time.Duration, err error) {
+			sendFn(ModelRetryEvent{Model: model, Attempt: attempt, Delay: delay, Err: err})
+		},
+		OnFallback: func(from, to string, err error) {
+			sendFn(ModelFallbackEvent{From: from, To: to, Err: err})
+		},
+		OnAnswered: func(model, primary string) {
+			sendFn(ModelAnsweredEvent{Model: model, Primary: primary})
+		},
+	})
+
+	// Budgets close to being used up are reported before they stop a step.
+	ctx = agent.WithBudgetWarningHandler(ctx, func(warning string) {
+		sendFn(BudgetWarningEvent{Warning: warning})
+	})
+
+	// Tool calls made by sub-agents of the task tool are shown nested.
+	ctx = agent.WithNestedToolHandlers(ctx, agent.NestedToolHandlers{
+		OnToolCall: func(toolName, toolArgs string) {
+			sendFn(ToolCallStartedEvent{ToolName: toolName, ToolArgs: toolArgs, Nested: true})
+		},
+		OnToolResult: func(toolName, toolArgs, result string, isError bool) {
+			sendFn(ToolResultEvent{
+				ToolName: toolName,
+				ToolArgs: toolArgs,
+				Result:   result,
+				IsError:  isError,
+				Nested:   true,
+			})
+		},
+	})
+
+	// MCP servers report the progress of long-running tool calls.
+	ctx = tools.WithProgressHandler(ctx, func(toolName string, p tools.ToolProgress) {
+		sendFn(ToolProgressEvent{ToolName: toolName, Progress: p.Progress, Total: p.Total, Message: p.Message})
+	})
+
 	result, err := a.opts.Agent.GenerateWithLoopAndStreaming(ctx, msgs,
 		// onToolCall
 		func(toolName, toolArgs string) {
@@ -388,19 +619,178 @@ func (a *App) executeStep(ctx context.Context, prompt string, eventFn func(tea.M
 		func(chunk string) {
 			sendFn(StreamChunkEvent{Content: chunk})
 		},
+		// onReasoning — the model's thinking, streamed ahead of its answer
+		func(delta string) {
+			sendFn(ReasoningChunkEvent{Content: delta})
+		},
 	)
 
 	if err != nil {
+		a.fireStop(ctx, nil, err)
 		return nil, err
 	}
 
 	// Replace the store with the full updated conversation returned by the agent
-	// (includes tool call/result messages added during the step).
+	// (includes tool call/result messages added during the step). It is kept
+	// even when the structured answer fails below.
 	a.store.Replace(result.ConversationMessages)
+	a.recordContextTokens(result)
+
+	if a.opts.OutputSchema != nil && !result.StoppedByHook && stoppedEarly(result) == nil {
+		if err := a.generateStructuredOutput(ctx, result); err != nil {
+			a.fireStop(ctx, nil, err)
+			return nil, err
+		}
+	}
+
+	if result.StoppedByHook {
+		sendFn(HookBlockedEvent{Reason: result.HookStopReason})
+	}
+
+	a.fireStop(ctx, result, nil)
 
 	return result, nil
 }
 
+// loadMentions loads the files and MCP resources mentioned in prompt.
+// Resources are only looked for when the agent can read them.
+func (a *App) loadMentions(ctx context.Context, prompt string) ([]*attachments.Attachment, error) {
+	atts, err := attachments.LoadAll(attachments.Mentions(prompt))
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := a.opts.Agent.(ResourceReader)
+	if !ok {
+		return atts, nil
+	}
+
+	var servers []string
+	for _, r := range reader.GetResources() {
+		if !slices.Contains(servers, r.Server) {
+			servers = append(servers, r.Server)
+		}
+	}
+	for _, mention := range attachments.ResourceMentions(prompt, servers) {
+		resourceAtts, err := reader.ReadResource(ctx, mention.Server, mention.URI)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, resourceAtts...)
+	}
+	return atts, nil
+}
+
+// stoppedEarly returns the error describing why the agent stopped before its
+// final answer: a budget that would be exceeded, a tool call loop or a full
+// context window. It returns nil when the agent finished or a hook stopped it.
+func stoppedEarly(result *agent.GenerateWithLoopResult) error {
+	if result.BudgetExceeded != nil {
+		return result.BudgetExceeded
+	}
+	if result.ToolLoop != nil {
+		return result.ToolLoop
+	}
+	return result.ContextFull
+}
+
+// checkAttachments rejects media attachments when the model does not accept
+// them. Text files are inlined into the prompt, so every model takes them.
+func (a *App) checkAttachments(atts []*attachments.Attachment) error {
+	if !a.opts.AttachmentsUnsupported {
+		return nil
+	}
+	for _, att := range atts {
+		if att.IsMedia() {
+			return fmt.Errorf("%w: %s cannot be attached (%s)", ErrAttachmentsUnsupported, att.Name, att.MediaType)
+		}
+	}
+	return nil
+}
+
+// generateStructuredOutput asks the agent for the step's final answer as JSON
+// matching the configured output schema, updating result.
+func (a *App) generateStructuredOutput(ctx context.Context, result *agent.GenerateWithLoopResult) error {
+	generator, ok := a.opts.Agent.(StructuredOutputGenerator)
+	if !ok {
+		return fmt.Errorf("agent does not support structured output")
+	}
+	return generator.GenerateStructuredOutput(ctx, result, a.opts.OutputSchema, a.opts.OutputRetries)
+}
+
+// --------------------------------------------------------------------------
+// Internal: hooks
+// --------------------------------------------------------------------------
+
+// fireUserPromptSubmit runs UserPromptSubmit hooks for prompt. It returns an
+// error wrapping ErrPromptBlocked when a hook blocks the prompt or asks to stop
+// with "continue": false. Hook execution failures never block the prompt.
+func (a *App) fireUserPromptSubmit(ctx context.Context, prompt string) error {
+	if a.opts.HookExecutor == nil {
+		return nil
+	}
+
+	input := &hooks.UserPromptSubmitInput{
+		CommonInput: a.opts.HookExecutor.PopulateCommonFields(hooks.UserPromptSubmit),
+		Prompt:      prompt,
+	}
+	output, err := a.opts.HookExecutor.ExecuteHooks(ctx, hooks.UserPromptSubmit, input)
+	if err != nil || output == nil {
+		return nil
+	}
+
+	stopped := output.Continue != nil && !*output.Continue
+	if output.Decision != "block" && !stopped {
+		return nil
+	}
+
+	reason := strings.TrimSpace(output.Reason)
+	if reason == "" {
+		reason = output.StopReason
+	}
+	if reason == "" {
+		return ErrPromptBlocked
+	}
+	return fmt.Errorf("%w: %s", ErrPromptBlocked, reason)
+}
+
+// fireStop runs Stop hooks after the agent has finished responding. result is
+// nil when the step failed; stepErr is the error it failed with.
+func (a *App) fireStop(ctx context.Context, result *agent.GenerateWithLoopResult, stepErr error) {
+	if a.opts.HookExecutor == nil {
+		return
+	}
+
+	input := &hooks.StopInput{
+		CommonInput: a.opts.HookExecutor.PopulateCommonFields(hooks.Stop),
+		StopReason:  "completed",
+	}
+	switch {
+	case stepErr != nil && ctx.Err() != nil:
+		input.StopReason = "cancelled"
+	case stepErr != nil:
+		input.StopReason = "error"
+	case result.ToolLoop != nil:
+		input.StopReason = "tool_loop"
+	case result.BudgetExceeded != nil:
+		input.StopReason = "budget_exceeded"
+	case result.ContextFull != nil:
+		input.StopReason = "context_full"
+	}
+
+	if result != nil {
+		if result.FinalResponse != nil {
+			input.Response = result.FinalResponse.Content.Text()
+		}
+		if meta, err := json.Marshal(map[string]any{"usage": result.TotalUsage}); err == nil {
+			input.Meta = meta
+		}
+	}
+
+	// The step context is already cancelled when the user aborted the step,
+	// but Stop hooks should still get to run.
+	_, _ = a.opts.HookExecutor.ExecuteHooks(context.WithoutCancel(ctx), hooks.Stop, input)
+}
+
 // --------------------------------------------------------------------------
 // Internal: event helpers
 // --------------------------------------------------------------------------
@@ -448,10 +838,9 @@ func (a *App) updateUsage(result *agent.GenerateWithLoopResult, userPrompt strin
 
 	// Set context window utilization from the final API call's per-step usage.
 	// FinalResponse.Usage represents the last step only (not the aggregate),
-	// so input+output there reflects the actual context fill level.
+	// so its tokens, cached ones included, reflect the actual context fill level.
 	if result.FinalResponse != nil {
-		fu := result.FinalResponse.Usage
-		if ct := int(fu.InputTokens) + int(fu.OutputTokens); ct > 0 {
+		if ct := a.contextFill(result.FinalResponse.Usage); ct > 0 {
 			a.opts.UsageTracker.SetContextTokens(ct)
 		}
 	}
@@ -1,15 +1,26 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	tea "charm.land/bubbletea/v2"
 	"charm.land/fantasy"
 
 	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/attachments"
+	"github.com/mark3labs/mcphost/internal/hooks"
+	"github.com/mark3labs/mcphost/internal/permissions"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // --------------------------------------------------------------------------
@@ -56,6 +67,7 @@ func (s *stubAgent) GenerateWithLoopAndStreaming(
 	_ agent.ResponseHandler,
 	_ agent.ToolCallContentHandler,
 	_ agent.StreamingResponseHandler,
+	_ agent.ReasoningHandler,
 ) (*agent.GenerateWithLoopResult, error) {
 	// Optional blocking: wait for a signal or ctx cancellation.
 	if s.blockCh != nil {
@@ -581,3 +593,583 @@ func TestQueueLength_reflects(t *testing.T) {
 		t.Fatalf("expected 3, got %d", got)
 	}
 }
+
+// --------------------------------------------------------------------------
+// Hooks
+// --------------------------------------------------------------------------
+
+// newHookExecutor builds a hook executor that runs command for event.
+func newHookExecutor(event hooks.HookEvent, command string) *hooks.Executor {
+	return hooks.NewExecutor(&hooks.HookConfig{
+		Hooks: map[hooks.HookEvent][]hooks.HookMatcher{
+			event: {{Hooks: []hooks.HookEntry{{Type: "command", Command: command}}}},
+		},
+	}, "test-session", "")
+}
+
+// TestRunOnce_userPromptSubmitHookBlocks verifies that a blocking
+// UserPromptSubmit hook stops the prompt before it reaches the agent or the
+// message store, and that the hook's reason is surfaced in the error.
+func TestRunOnce_userPromptSubmitHookBlocks(t *testing.T) {
+	stub := newStubAgent(makeResult("should not run"))
+	app := New(Options{
+		Agent:        stub,
+		HookExecutor: newHookExecutor(hooks.UserPromptSubmit, `echo "no secrets please" >&2; exit 2`),
+	}, nil)
+	defer app.Close()
+
+	err := app.RunOnce(context.Background(), "my password is hunter2")
+	if !errors.Is(err, ErrPromptBlocked) {
+		t.Fatalf("expected ErrPromptBlocked, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "no secrets please") {
+		t.Fatalf("expected hook reason in error, got %q", err.Error())
+	}
+	if got := stub.CallCount(); got != 0 {
+		t.Fatalf("expected agent not to be called, got %d calls", got)
+	}
+	if got := app.store.Len(); got != 0 {
+		t.Fatalf("expected blocked prompt to stay out of the store, got %d messages", got)
+	}
+}
+
+// TestRunOnce_userPromptSubmitHookApproves verifies that a non-blocking
+// UserPromptSubmit hook lets the prompt through.
+func TestRunOnce_userPromptSubmitHookApproves(t *testing.T) {
+	stub := newStubAgent(makeResult("ok"))
+	app := New(Options{
+		Agent:        stub,
+		HookExecutor: newHookExecutor(hooks.UserPromptSubmit, `echo '{"decision": "approve"}'`),
+	}, nil)
+	defer app.Close()
+
+	if err := app.RunOnce(context.Background(), "hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stub.CallCount(); got != 1 {
+		t.Fatalf("expected agent called once, got %d", got)
+	}
+}
+
+// TestRunOnce_stopHookFires verifies that Stop hooks receive the final response
+// and the stop reason once the step completes or fails.
+func TestRunOnce_stopHookFires(t *testing.T) {
+	tests := []struct {
+		name       string
+		stub       *stubAgent
+		wantReason string
+		wantResp   string
+	}{
+		{
+			name: "completed",
+			// The response text is read from its TextContent, which
+			// makeResult does not set.
+			stub: newStubAgent(&agent.GenerateWithLoopResult{
+				FinalResponse: &fantasy.Response{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "all done"}}},
+			}),
+			wantReason: "completed",
+			wantResp:   "all done",
+		},
+		{
+			name: "error",
+			stub: newStubAgentWithFuncs(func(_ context.Context) (*agent.GenerateWithLoopResult, error) {
+				return nil, errors.New("agent exploded")
+			}),
+			wantReason: "error",
+		},
+		{
+			name: "tool loop",
+			stub: newStubAgentWithFuncs(func(_ context.Context) (*agent.GenerateWithLoopResult, error) {
+				result := makeResult("")
+				result.ToolLoop = fmt.Errorf("%w: the same bash call 3 times in a row", agent.ErrToolLoop)
+				return result, nil
+			}),
+			wantReason: "tool_loop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "stop.json")
+			app := New(Options{
+				Agent:        tt.stub,
+				HookExecutor: newHookExecutor(hooks.Stop, "cat > "+out),
+			}, nil)
+			defer app.Close()
+
+			_ = app.RunOnce(context.Background(), "hello")
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("stop hook did not run: %v", err)
+			}
+			var input hooks.StopInput
+			if err := json.Unmarshal(data, &input); err != nil {
+				t.Fatalf("invalid stop hook input %q: %v", data, err)
+			}
+			if input.HookEventName != hooks.Stop {
+				t.Errorf("expected hook_event_name %q, got %q", hooks.Stop, input.HookEventName)
+			}
+			if input.StopReason != tt.wantReason {
+				t.Errorf("expected stop_reason %q, got %q", tt.wantReason, input.StopReason)
+			}
+			if input.Response != tt.wantResp {
+				t.Errorf("expected response %q, got %q", tt.wantResp, input.Response)
+			}
+		})
+	}
+}
+
+// TestRunOnceWithDisplay_hookStopEvent verifies that a step ended early by a
+// tool hook emits a HookBlockedEvent before the StepCompleteEvent.
+func TestRunOnceWithDisplay_hookStopEvent(t *testing.T) {
+	result := makeResult("")
+	result.StoppedByHook = true
+	result.HookStopReason = "policy says stop"
+
+	app := newTestApp(newStubAgent(result))
+	defer app.Close()
+
+	var events []tea.Msg
+	if err := app.RunOnceWithDisplay(context.Background(), "hello", func(msg tea.Msg) {
+		events = append(events, msg)
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blockedAt, completeAt := -1, -1
+	for i, evt := range events {
+		switch e := evt.(type) {
+		case HookBlockedEvent:
+			blockedAt = i
+			if e.Reason != "policy says stop" {
+				t.Errorf("expected reason %q, got %q", "policy says stop", e.Reason)
+			}
+		case StepCompleteEvent:
+			completeAt = i
+		}
+	}
+	if blockedAt == -1 {
+		t.Fatal("expected a HookBlockedEvent")
+	}
+	if completeAt < blockedAt {
+		t.Fatalf("expected StepCompleteEvent after HookBlockedEvent, got events %v", events)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Tool approval
+// --------------------------------------------------------------------------
+
+// TestApproveTool_defaultsToApprove verifies that without a ToolApprovalFunc
+// every tool call is approved.
+func TestApproveTool_defaultsToApprove(t *testing.T) {
+	app := newTestApp(newStubAgent())
+	defer app.Close()
+
+	approved, err := app.ApproveTool(context.Background(), "bash", "{}")
+	if err != nil || !approved {
+		t.Fatalf("expected approval, got approved=%v err=%v", approved, err)
+	}
+}
+
+// TestApproveTool_usesConfiguredFunc verifies that ApproveTool delegates to the
+// func from Options and that SetToolApprovalFunc replaces it.
+func TestApproveTool_usesConfiguredFunc(t *testing.T) {
+	var gotName, gotArgs string
+	app := New(Options{
+		Agent: newStubAgent(),
+		ToolApprovalFunc: func(_ context.Context, toolName, toolArgs string) (bool, error) {
+			gotName, gotArgs = toolName, toolArgs
+			return false, nil
+		},
+	}, nil)
+	defer app.Close()
+
+	approved, err := app.ApproveTool(context.Background(), "fs__write", `{"path":"x"}`)
+	if err != nil || approved {
+		t.Fatalf("expected denial, got approved=%v err=%v", approved, err)
+	}
+	if gotName != "fs__write" || gotArgs != `{"path":"x"}` {
+		t.Fatalf("approval func got name=%q args=%q", gotName, gotArgs)
+	}
+
+	app.SetToolApprovalFunc(AutoApproveFunc)
+	if approved, _ := app.ApproveTool(context.Background(), "fs__write", "{}"); !approved {
+		t.Fatal("expected AutoApproveFunc to approve after SetToolApprovalFunc")
+	}
+}
+
+// TestRequireApprovalFunc verifies that RequireApprovalFunc fails with
+// ErrApprovalRequired.
+func TestRequireApprovalFunc(t *testing.T) {
+	approved, err := RequireApprovalFunc(context.Background(), "bash", "{}")
+	if approved {
+		t.Fatal("expected RequireApprovalFunc to deny")
+	}
+	if !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("expected ErrApprovalRequired, got %v", err)
+	}
+}
+
+// TestRequestApproval_handshake verifies that the approval handshake emits a
+// ToolApprovalNeededEvent and returns the decision sent on its channel.
+func TestRequestApproval_handshake(t *testing.T) {
+	decisions := []ToolApprovalDecision{
+		{Approved: true},
+		{Approved: false},
+		{Approved: true, Always: true},
+	}
+	for _, decision := range decisions {
+		var evt ToolApprovalNeededEvent
+		send := func(msg tea.Msg) {
+			evt = msg.(ToolApprovalNeededEvent)
+			// Answer from another goroutine, as the TUI would.
+			go func() { evt.ResponseChan <- decision }()
+		}
+
+		got, err := requestApproval(context.Background(), send, "bash", `{"command":"ls"}`, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != decision {
+			t.Fatalf("expected %+v, got %+v", decision, got)
+		}
+		if evt.ToolName != "bash" || evt.ToolArgs != `{"command":"ls"}` || !evt.CanAlwaysAllow {
+			t.Fatalf("unexpected event %+v", evt)
+		}
+	}
+}
+
+// TestRequestApproval_ctxCancel verifies that a pending approval unblocks with
+// the context error when the step is cancelled, and that a late answer does
+// not block the sender.
+func TestRequestApproval_ctxCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events := make(chan ToolApprovalNeededEvent, 1)
+	send := func(msg tea.Msg) { events <- msg.(ToolApprovalNeededEvent) }
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := requestApproval(ctx, send, "bash", "{}", false)
+		done <- err
+	}()
+
+	evt := <-events
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("requestApproval did not unblock after cancel")
+	}
+
+	// The TUI may still answer after the step was cancelled.
+	select {
+	case evt.ResponseChan <- ToolApprovalDecision{Approved: true}:
+	default:
+		t.Fatal("sending a late decision should not block")
+	}
+}
+
+// TestAllowAlways_savesRule verifies that an "always allow" answer adds a rule
+// to the ruleset, saves it to the project file, and reports it.
+func TestAllowAlways_savesRule(t *testing.T) {
+	projectPath := filepath.Join(t.TempDir(), ".mcphost", "permissions.yml")
+	rules, err := permissions.Load(nil, projectPath, filepath.Join(t.TempDir(), permissions.ApprovalsFile))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	app := New(Options{Agent: newStubAgent(), Permissions: rules}, nil)
+	defer app.Close()
+
+	var events []tea.Msg
+	app.allowAlways(func(msg tea.Msg) { events = append(events, msg) }, "fs__read_file")
+
+	if action, _ := rules.Evaluate("fs__read_file", "{}"); action != permissions.Allow {
+		t.Fatalf("expected fs__read_file to be allowed, got %q", action)
+	}
+	if _, err := os.Stat(projectPath); err != nil {
+		t.Fatalf("expected project permissions file to be written: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	added, ok := events[0].(PermissionRuleAddedEvent)
+	if !ok || added.Err != nil || added.Path != projectPath {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+}
+
+// TestInteractiveApprovalFunc_noProgram verifies that the interactive policy
+// fails fast instead of hanging when no TUI is registered.
+func TestInteractiveApprovalFunc_noProgram(t *testing.T) {
+	app := newTestApp(newStubAgent())
+	defer app.Close()
+
+	approve := NewInteractiveApprovalFunc(app)
+	if _, err := approve(context.Background(), "bash", "{}"); !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("expected ErrApprovalRequired, got %v", err)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Structured output
+// --------------------------------------------------------------------------
+
+// structuredStubAgent adds StructuredOutputGenerator to stubAgent. Each call
+// returns the next error in errs (nil once they run out) and otherwise sets
+// the structured output to output.
+type structuredStubAgent struct {
+	*stubAgent
+	output   json.RawMessage
+	errs     []error
+	requests int
+}
+
+func (s *structuredStubAgent) GenerateStructuredOutput(_ context.Context, result *agent.GenerateWithLoopResult, _ *agent.OutputSchema, _ int) error {
+	s.requests++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	result.StructuredOutput = s.output
+	return nil
+}
+
+// TestRunOnce_structuredOutput verifies that an output schema adds a
+// structured-output request after the agent loop and that its errors fail
+// the step.
+func TestRunOnce_structuredOutput(t *testing.T) {
+	validationErr := fmt.Errorf("%w: answer: missing property", agent.ErrOutputValidation)
+
+	tests := []struct {
+		name       string
+		agent      AgentRunner
+		wantErr    error
+		wantOutput string
+	}{
+		{
+			name:       "valid",
+			agent:      &structuredStubAgent{stubAgent: newStubAgent(makeResult("done")), output: json.RawMessage(`{"answer":42}`)},
+			wantOutput: "{\"answer\":42}\n",
+		},
+		{
+			name:    "never validates",
+			agent:   &structuredStubAgent{stubAgent: newStubAgent(makeResult("done")), errs: []error{validationErr}},
+			wantErr: agent.ErrOutputValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			app := New(Options{
+				Agent:         tt.agent,
+				OutputSchema:  &agent.OutputSchema{},
+				OutputRetries: agent.DefaultOutputRetries,
+				Output:        &out,
+			}, nil)
+			defer app.Close()
+
+			err := app.RunOnce(context.Background(), "hello")
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got := tt.agent.(*structuredStubAgent).requests; got != 1 {
+				t.Errorf("expected one structured output request, got %d", got)
+			}
+			// Only the JSON is printed, and nothing when it never validates
+			if out.String() != tt.wantOutput {
+				t.Errorf("expected output %q, got %q", tt.wantOutput, out.String())
+			}
+		})
+	}
+}
+
+// TestRunOnce_structuredOutputKeepsConversation verifies that the agent's
+// conversation, with its tool calls, is kept when the structured answer
+// fails.
+func TestRunOnce_structuredOutputKeepsConversation(t *testing.T) {
+	result := makeResult("done")
+	result.ConversationMessages = []fantasy.Message{
+		fantasy.NewUserMessage("hello"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.ToolCallPart{ToolCallID: "1", ToolName: "read", Input: "{}"}}},
+		{Role: fantasy.MessageRoleTool, Content: []fantasy.MessagePart{fantasy.ToolResultPart{ToolCallID: "1", Output: fantasy.ToolResultOutputContentText{Text: "ok"}}}},
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "done"}}},
+	}
+	stub := &structuredStubAgent{stubAgent: newStubAgent(result), errs: []error{agent.ErrOutputValidation}}
+
+	app := New(Options{Agent: stub, OutputSchema: &agent.OutputSchema{}}, nil)
+	defer app.Close()
+
+	if err := app.RunOnce(context.Background(), "hello"); !errors.Is(err, agent.ErrOutputValidation) {
+		t.Fatalf("expected ErrOutputValidation, got %v", err)
+	}
+	if got := app.store.Len(); got != len(result.ConversationMessages) {
+		t.Errorf("expected the %d messages of the step kept, got %d", len(result.ConversationMessages), got)
+	}
+}
+
+// TestRunOnce_structuredOutputUnsupported verifies that an output schema is
+// rejected when the agent cannot generate structured output.
+func TestRunOnce_structuredOutputUnsupported(t *testing.T) {
+	app := New(Options{Agent: newStubAgent(makeResult("done")), OutputSchema: &agent.OutputSchema{}}, nil)
+	defer app.Close()
+
+	if err := app.RunOnce(context.Background(), "hello"); err == nil {
+		t.Fatal("expected an error for an agent without structured output support")
+	}
+}
+
+// TestRunOnce_structuredOutputSkippedAfterHookStop verifies that no structured
+// answer is requested when a hook stopped the loop, and that RunOnce fails
+// instead of printing an unvalidated answer.
+func TestRunOnce_structuredOutputSkippedAfterHookStop(t *testing.T) {
+	result := makeResult("")
+	result.StoppedByHook = true
+	result.HookStopReason = "policy says stop"
+	stub := &structuredStubAgent{stubAgent: newStubAgent(result), output: json.RawMessage(`{}`)}
+
+	app := New(Options{Agent: stub, OutputSchema: &agent.OutputSchema{}}, nil)
+	defer app.Close()
+
+	err := app.RunOnce(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "policy says stop") {
+		t.Fatalf("expected the hook's reason in the error, got %v", err)
+	}
+	if stub.requests != 0 {
+		t.Errorf("expected no structured output request, got %d", stub.requests)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Attachments
+// --------------------------------------------------------------------------
+
+// TestRunOnce_attachmentsUnsupported verifies that media attachments are
+// rejected before the agent runs when the model does not accept them, while
+// text files, which are inlined into the prompt, are still allowed.
+func TestRunOnce_attachmentsUnsupported(t *testing.T) {
+	image := &attachments.Attachment{Path: "/tmp/chart.png", Name: "chart.png", MediaType: "image/png", Data: []byte("png")}
+	text := &attachments.Attachment{Path: "/tmp/notes.txt", Name: "notes.txt", MediaType: "text/plain", Data: []byte("notes")}
+
+	stub := newStubAgent(makeResult("ok"))
+	app := New(Options{Agent: stub, AttachmentsUnsupported: true}, nil)
+	defer app.Close()
+
+	if err := app.RunOnce(context.Background(), "describe", image); !errors.Is(err, ErrAttachmentsUnsupported) {
+		t.Fatalf("expected ErrAttachmentsUnsupported, got %v", err)
+	}
+	if got := stub.CallCount(); got != 0 {
+		t.Fatalf("expected agent not to be called, got %d calls", got)
+	}
+
+	if err := app.RunOnce(context.Background(), "summarize", text); err != nil {
+		t.Fatalf("unexpected error for a text attachment: %v", err)
+	}
+	if got := stub.CallCount(); got != 1 {
+		t.Fatalf("expected agent called once, got %d", got)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Budgets
+// --------------------------------------------------------------------------
+
+// TestRunOnceWithDisplay_budgetExceeded verifies that a step stopped by a
+// budget returns its error instead of completing, and keeps the steps it took
+// in the conversation.
+func TestRunOnceWithDisplay_budgetExceeded(t *testing.T) {
+	result := makeResult("")
+	result.ConversationMessages = []fantasy.Message{
+		fantasy.NewUserMessage("refactor everything"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+			fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "bash", Input: `{"command":"ls"}`},
+		}},
+	}
+	result.BudgetExceeded = fmt.Errorf("%w: stopped before the next step would exceed the prompt cost budget of $0.50 ($0.46 spent)", agent.ErrBudgetExceeded)
+
+	app := newTestApp(newStubAgent(result))
+	defer app.Close()
+
+	var completed bool
+	err := app.RunOnceWithDisplay(context.Background(), "refactor everything", func(msg tea.Msg) {
+		if _, ok := msg.(StepCompleteEvent); ok {
+			completed = true
+		}
+	})
+	if !errors.Is(err, agent.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if completed {
+		t.Error("expected no StepCompleteEvent after a budget stop")
+	}
+	if got := len(app.store.GetAll()); got != 2 {
+		t.Errorf("expected the 2 messages of the stopped step in the store, got %d", got)
+	}
+}
+
+// TestRunOnce_toolLoop verifies that a step stopped by a tool call loop
+// returns the loop error instead of printing a response.
+func TestRunOnce_toolLoop(t *testing.T) {
+	result := makeResult("")
+	result.ToolLoop = fmt.Errorf("%w: the same bash call with the same arguments 3 times in a row after being told to stop", agent.ErrToolLoop)
+
+	app := newTestApp(newStubAgent(result))
+	defer app.Close()
+
+	if err := app.RunOnce(context.Background(), "fix the tests"); !errors.Is(err, agent.ErrToolLoop) {
+		t.Fatalf("expected ErrToolLoop, got %v", err)
+	}
+}
+
+// resourceStubAgent is a stubAgent that offers MCP resources.
+type resourceStubAgent struct {
+	*stubAgent
+	resources []tools.Resource
+	read      []string
+}
+
+func (s *resourceStubAgent) GetResources() []tools.Resource {
+	return s.resources
+}
+
+func (s *resourceStubAgent) ReadResource(_ context.Context, serverName, uri string) ([]*attachments.Attachment, error) {
+	s.read = append(s.read, serverName+":"+uri)
+	return []*attachments.Attachment{{Name: serverName + ":" + uri, MediaType: "text/plain", Data: []byte("contents")}}, nil
+}
+
+// TestLoadMentions_resources verifies that resources mentioned as
+// "@server:uri" are read from servers offering resources, and other
+// mentions are left alone.
+func TestLoadMentions_resources(t *testing.T) {
+	stub := &resourceStubAgent{
+		stubAgent: newStubAgent(makeResult("ok")),
+		resources: []tools.Resource{{Server: "docs", URI: "file:///{path}", Template: true}},
+	}
+	app := newTestApp(stub)
+	defer app.Close()
+
+	atts, err := app.loadMentions(context.Background(), "Summarize @docs:file:///guide.md for @alice and @notes:todo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(atts) != 1 || atts[0].Name != "docs:file:///guide.md" {
+		t.Fatalf("expected the docs resource attached, got %v", atts)
+	}
+	if len(stub.read) != 1 {
+		t.Errorf("expected one resource read, got %v", stub.read)
+	}
+}
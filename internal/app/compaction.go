@@ -0,0 +1,322 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// ErrBusy is returned by Compact, RunServerPrompt and ChangeDirectory when an
+// agent step is already running.
+var ErrBusy = errors.New("the agent is busy")
+
+// ErrNothingToCompact is returned when the conversation has no messages older
+// than the turns that are kept verbatim.
+var ErrNothingToCompact = errors.New("not enough conversation history to compact")
+
+// ErrCompactionUnsupported is returned when the configured agent cannot
+// generate the summary (it does not implement TextGenerator).
+var ErrCompactionUnsupported = errors.New("the agent does not support compaction")
+
+// maxTranscriptResultLen caps each tool result in the transcript sent to the
+// summarizer. Large tool outputs are usually why the context filled up, and
+// the summary only needs their gist.
+const maxTranscriptResultLen = 2000
+
+const compactionSystemPrompt = `You summarize conversations between a user and an AI assistant that uses tools.
+Your summary replaces the conversation in the assistant's context, so it must keep everything needed to continue the work:
+the user's goals and requests, decisions that were made, important facts and tool results (file paths, commands,
+identifiers, errors), and any tasks that are still unfinished. Be concise. Output only the summary.`
+
+// compactionSummaryPrefix introduces the summary in the compacted history.
+const compactionSummaryPrefix = "The earlier part of this conversation was compacted to save context. Summary:\n\n"
+
+// compactionAck is the assistant reply inserted after the summary so that
+// user and assistant messages keep alternating.
+const compactionAck = "Understood. I'll continue from this summary."
+
+// --------------------------------------------------------------------------
+// Public API
+// --------------------------------------------------------------------------
+
+// Compact summarizes the older part of the conversation in the background and
+// replaces it with the summary, keeping the last Options.CompactKeepTurns
+// turns verbatim. instructions is optional extra guidance for the summary
+// (e.g. "focus on the database migration").
+//
+// It returns ErrBusy instead of queueing when a step is running. The outcome
+// is reported with a CompactionEvent (or StepCancelledEvent if the user
+// cancels); like Run, Compact itself never sends events so it is safe to call
+// from within Bubble Tea's Update loop.
+//
+// Satisfies ui.AppController.
+func (a *App) Compact(instructions string) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	if a.busy {
+		a.mu.Unlock()
+		return ErrBusy
+	}
+	a.busy = true
+	a.wg.Add(1)
+	a.mu.Unlock()
+
+	go a.drainQueue(func() { a.runCompaction(instructions) })
+	return nil
+}
+
+// --------------------------------------------------------------------------
+// Internal
+// --------------------------------------------------------------------------
+
+// runCompaction performs a manual compaction as its own step and reports the
+// result to the program.
+func (a *App) runCompaction(instructions string) {
+	stepCtx, cancel := context.WithCancel(a.rootCtx)
+	a.mu.Lock()
+	a.cancelStep = cancel
+	a.mu.Unlock()
+	defer cancel()
+
+	a.sendEvent(SpinnerEvent{Show: true})
+
+	summarized, kept, err := a.compact(stepCtx, instructions)
+	if err != nil && stepCtx.Err() != nil {
+		a.sendEvent(StepCancelledEvent{})
+		return
+	}
+	a.sendEvent(CompactionEvent{Summarized: summarized, Kept: kept, Err: err})
+}
+
+// shouldCompact reports whether the context window is full enough for
+// automatic compaction before the next prompt.
+func (a *App) shouldCompact() bool {
+	if a.opts.CompactThreshold <= 0 || a.opts.ContextWindow <= 0 {
+		return false
+	}
+	a.mu.Lock()
+	tokens := a.contextTokens
+	a.mu.Unlock()
+	return float64(tokens) >= a.opts.CompactThreshold*float64(a.opts.ContextWindow)
+}
+
+// compact summarizes all but the most recent Options.CompactKeepTurns turns
+// and swaps them for the summary in the MessageStore. It returns how many
+// messages were summarized and how many were kept verbatim.
+//
+// Must only run while the app is busy so that no step modifies the store
+// concurrently.
+func (a *App) compact(ctx context.Context, instructions string) (summarized, kept int, err error) {
+	gen, ok := a.opts.Agent.(TextGenerator)
+	if !ok {
+		return 0, 0, ErrCompactionUnsupported
+	}
+
+	msgs := a.store.GetAll()
+	cut := compactionSplit(msgs, a.opts.CompactKeepTurns)
+	if cut == 0 {
+		return 0, 0, ErrNothingToCompact
+	}
+
+	resp, err := gen.GenerateText(ctx, compactionSystemPrompt, compactionPrompt(msgs[:cut], instructions))
+	if err != nil {
+		return 0, 0, fmt.Errorf("summarizing conversation: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Content.Text())
+	if summary == "" {
+		return 0, 0, errors.New("summarizing conversation: the model returned an empty summary")
+	}
+
+	compacted := compactedHistory(summary, msgs[cut:])
+	a.store.Replace(compacted)
+
+	// The summary call costs tokens like any other request; the context now
+	// holds only the compacted history.
+	if a.opts.UsageTracker != nil && resp.Usage.InputTokens > 0 {
+		a.opts.UsageTracker.UpdateUsage(int(resp.Usage.InputTokens), int(resp.Usage.OutputTokens),
+			int(resp.Usage.CacheReadTokens), int(resp.Usage.CacheCreationTokens))
+	}
+	a.setContextTokens(estimateMessageTokens(compacted))
+
+	return cut, len(msgs) - cut, nil
+}
+
+// setContextTokens records the current context window fill level used by
+// shouldCompact and mirrors it to the UsageTracker.
+func (a *App) setContextTokens(tokens int) {
+	a.mu.Lock()
+	a.contextTokens = tokens
+	a.mu.Unlock()
+	if a.opts.UsageTracker != nil {
+		a.opts.UsageTracker.SetContextTokens(tokens)
+	}
+}
+
+// contextFill returns how full the context window was at the API call that
+// reported usage, counting the prompt tokens read from or written to the
+// prompt cache too.
+func (a *App) contextFill(usage fantasy.Usage) int {
+	return int(models.UsageTokens(a.opts.Provider, usage))
+}
+
+// recordContextTokens remembers how full the context window is after a step.
+// Like updateUsage it uses the final API call's usage, and falls back to an
+// estimate from the history when the provider omits token counts.
+func (a *App) recordContextTokens(result *agent.GenerateWithLoopResult) {
+	tokens := 0
+	if result.FinalResponse != nil {
+		tokens = a.contextFill(result.FinalResponse.Usage)
+	}
+	if tokens == 0 {
+		tokens = estimateMessageTokens(result.ConversationMessages)
+	}
+	a.mu.Lock()
+	a.contextTokens = tokens
+	a.mu.Unlock()
+}
+
+// compactionSplit returns the index of the first message kept verbatim when
+// compacting msgs; everything before it is summarized. The last keepTurns
+// turns (a user message and everything after it) are kept. The split is moved
+// earlier so that it never separates a tool call from its result and never
+// summarizes a tool call that has no result yet. It returns 0 when there is
+// nothing to summarize.
+func compactionSplit(msgs []fantasy.Message, keepTurns int) int {
+	cut := len(msgs)
+	if keepTurns > 0 {
+		turns := 0
+		for i := len(msgs) - 1; i >= 0 && turns < keepTurns; i-- {
+			if msgs[i].Role == fantasy.MessageRoleUser {
+				turns++
+				cut = i
+			}
+		}
+		if turns < keepTurns {
+			return 0
+		}
+	}
+
+	// Locate every tool call and the calls that have a result.
+	callAt := make(map[string]int)
+	answered := make(map[string]bool)
+	for i, msg := range msgs {
+		for _, part := range msg.Content {
+			if tc, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+				callAt[tc.ToolCallID] = i
+			}
+			if tr, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part); ok {
+				answered[tr.ToolCallID] = true
+			}
+		}
+	}
+	for id, i := range callAt {
+		if !answered[id] && i < cut {
+			cut = i
+		}
+	}
+
+	// Pull in calls whose results are kept. Moving the split can only add
+	// more kept results, so repeat until it settles.
+	for moved := true; moved; {
+		moved = false
+		for _, msg := range msgs[cut:] {
+			for _, part := range msg.Content {
+				tr, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+				if !ok {
+					continue
+				}
+				if i, ok := callAt[tr.ToolCallID]; ok && i < cut {
+					cut = i
+					moved = true
+				}
+			}
+		}
+	}
+	return cut
+}
+
+// compactionPrompt builds the summarization request for msgs.
+func compactionPrompt(msgs []fantasy.Message, instructions string) string {
+	var b strings.Builder
+	b.WriteString("Summarize the following conversation.\n")
+	if instructions = strings.TrimSpace(instructions); instructions != "" {
+		fmt.Fprintf(&b, "\nAdditional instructions: %s\n", instructions)
+	}
+	b.WriteString("\n<conversation>\n")
+	b.WriteString(renderTranscript(msgs, maxTranscriptResultLen))
+	b.WriteString("</conversation>\n")
+	return b.String()
+}
+
+// compactedHistory returns the new history: the summary followed by the kept
+// messages. An acknowledgement is inserted when the next message (or the
+// upcoming prompt) is from the user, so that roles keep alternating.
+func compactedHistory(summary string, kept []fantasy.Message) []fantasy.Message {
+	history := []fantasy.Message{fantasy.NewUserMessage(compactionSummaryPrefix + summary)}
+	if len(kept) == 0 || kept[0].Role == fantasy.MessageRoleUser {
+		history = append(history, fantasy.Message{
+			Role:    fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{fantasy.TextPart{Text: compactionAck}},
+		})
+	}
+	return append(history, kept...)
+}
+
+// renderTranscript renders msgs as plain text. Tool results longer than
+// maxResultLen are truncated; 0 keeps them whole. Reasoning is omitted.
+func renderTranscript(msgs []fantasy.Message, maxResultLen int) string {
+	var b strings.Builder
+	for _, msg := range msgs {
+		fmt.Fprintf(&b, "[%s]\n", msg.Role)
+		for _, part := range msg.Content {
+			if p, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+				b.WriteString(p.Text + "\n")
+			} else if p, ok := fantasy.AsMessagePart[fantasy.FilePart](part); ok {
+				fmt.Fprintf(&b, "(attached file %q, %s)\n", p.Filename, p.MediaType)
+			} else if p, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+				fmt.Fprintf(&b, "(tool call %s: %s)\n", p.ToolName, p.Input)
+			} else if p, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part); ok {
+				b.WriteString(truncate(toolResultText(p), maxResultLen) + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// toolResultText returns a short text form of a tool result.
+func toolResultText(p fantasy.ToolResultPart) string {
+	if out, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](p.Output); ok {
+		return "(tool result) " + out.Text
+	}
+	if out, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](p.Output); ok && out.Error != nil {
+		return "(tool error) " + out.Error.Error()
+	}
+	if out, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](p.Output); ok {
+		return fmt.Sprintf("(tool result: %s) %s", out.MediaType, out.Text)
+	}
+	return "(tool result)"
+}
+
+// truncate shortens s to at most n bytes (plus an ellipsis); n <= 0 keeps s.
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// estimateMessageTokens roughly estimates the tokens msgs occupy in the
+// context, at ~4 characters per token.
+func estimateMessageTokens(msgs []fantasy.Message) int {
+	return len(renderTranscript(msgs, 0)) / 4
+}
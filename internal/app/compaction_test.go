@@ -0,0 +1,395 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/agent"
+)
+
+// --------------------------------------------------------------------------
+// Stub summarizing agent
+// --------------------------------------------------------------------------
+
+// compactingAgent implements AgentRunner and TextGenerator. Steps echo the
+// conversation back with a fixed assistant reply; GenerateText returns summary
+// (or summaryErr) and records the prompts it was given.
+type compactingAgent struct {
+	mu           sync.Mutex
+	summary      string
+	summaryErr   error
+	prompts      []string
+	lastMessages []fantasy.Message
+}
+
+func (s *compactingAgent) GenerateWithLoopAndStreaming(
+	_ context.Context,
+	msgs []fantasy.Message,
+	_ agent.ToolCallHandler,
+	_ agent.ToolExecutionHandler,
+	_ agent.ToolResultHandler,
+	_ agent.ResponseHandler,
+	_ agent.ToolCallContentHandler,
+	_ agent.StreamingResponseHandler,
+	_ agent.ReasoningHandler,
+) (*agent.GenerateWithLoopResult, error) {
+	s.mu.Lock()
+	s.lastMessages = msgs
+	s.mu.Unlock()
+
+	result := makeResult("reply")
+	result.ConversationMessages = append(append([]fantasy.Message{}, msgs...), assistantMessage("reply"))
+	return result, nil
+}
+
+func (s *compactingAgent) GenerateText(_ context.Context, _, prompt string) (*fantasy.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompts = append(s.prompts, prompt)
+	if s.summaryErr != nil {
+		return nil, s.summaryErr
+	}
+	return &fantasy.Response{Content: fantasy.ResponseContent{fantasy.TextContent{Text: s.summary}}}, nil
+}
+
+// --------------------------------------------------------------------------
+// Helpers
+// --------------------------------------------------------------------------
+
+func assistantMessage(text string) fantasy.Message {
+	return fantasy.Message{
+		Role:    fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{fantasy.TextPart{Text: text}},
+	}
+}
+
+func toolCallMessage(id string) fantasy.Message {
+	return fantasy.Message{
+		Role:    fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{fantasy.ToolCallPart{ToolCallID: id, ToolName: "fs__read_file", Input: `{"path":"a.go"}`}},
+	}
+}
+
+func toolResultMessage(id string) fantasy.Message {
+	return fantasy.Message{
+		Role: fantasy.MessageRoleTool,
+		Content: []fantasy.MessagePart{fantasy.ToolResultPart{
+			ToolCallID: id,
+			Output:     fantasy.ToolResultOutputContentText{Text: "package main"},
+		}},
+	}
+}
+
+// threeTurns is a conversation with three user turns, the second of which
+// contains a completed tool call.
+func threeTurns() []fantasy.Message {
+	return []fantasy.Message{
+		fantasy.NewUserMessage("first"),
+		assistantMessage("one"),
+		fantasy.NewUserMessage("second"),
+		toolCallMessage("call-1"),
+		toolResultMessage("call-1"),
+		assistantMessage("two"),
+		fantasy.NewUserMessage("third"),
+		assistantMessage("three"),
+	}
+}
+
+func waitIdle(t *testing.T, a *App) {
+	t.Helper()
+	ok := waitForCondition(2*time.Second, func() bool {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return !a.busy
+	})
+	if !ok {
+		t.Fatal("app did not become idle within 2s")
+	}
+}
+
+// --------------------------------------------------------------------------
+// Split and history
+// --------------------------------------------------------------------------
+
+func TestCompactionSplit(t *testing.T) {
+	openCall := append(threeTurns(), toolCallMessage("call-2"))
+
+	// A result kept in the last turn whose call comes before the turn.
+	straddling := []fantasy.Message{
+		fantasy.NewUserMessage("first"),
+		toolCallMessage("call-1"),
+		fantasy.NewUserMessage("interjection"),
+		toolResultMessage("call-1"),
+		assistantMessage("done"),
+	}
+
+	// A call that never got a result, early in the history.
+	earlyOpen := []fantasy.Message{
+		fantasy.NewUserMessage("first"),
+		toolCallMessage("lost"),
+		fantasy.NewUserMessage("second"),
+		assistantMessage("two"),
+		fantasy.NewUserMessage("third"),
+		assistantMessage("three"),
+	}
+
+	tests := []struct {
+		name      string
+		msgs      []fantasy.Message
+		keepTurns int
+		want      int
+	}{
+		{"keep one turn", threeTurns(), 1, 6},
+		{"keep two turns", threeTurns(), 2, 2},
+		{"keep all turns", threeTurns(), 3, 0},
+		{"more turns than history", threeTurns(), 5, 0},
+		{"keep nothing", threeTurns(), 0, 8},
+		{"open call at the end is kept", openCall, 0, 8},
+		{"result pulls in its call", straddling, 1, 1},
+		{"early open call is kept", earlyOpen, 1, 1},
+		{"empty history", nil, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compactionSplit(tt.msgs, tt.keepTurns); got != tt.want {
+				t.Fatalf("compactionSplit(keepTurns=%d) = %d, want %d", tt.keepTurns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompactedHistory(t *testing.T) {
+	// Kept part starts with a user message: an acknowledgement keeps roles alternating.
+	history := compactedHistory("the gist", threeTurns()[6:])
+	if len(history) != 4 {
+		t.Fatalf("expected summary, ack and 2 kept messages, got %d messages", len(history))
+	}
+	if history[0].Role != fantasy.MessageRoleUser || history[1].Role != fantasy.MessageRoleAssistant {
+		t.Fatalf("expected user summary followed by assistant ack, got %s, %s", history[0].Role, history[1].Role)
+	}
+	if text := renderTranscript(history[:1], 0); !strings.Contains(text, "the gist") {
+		t.Fatalf("expected summary text in first message, got %q", text)
+	}
+
+	// Kept part starts with an assistant tool call: no acknowledgement.
+	history = compactedHistory("the gist", threeTurns()[3:])
+	if len(history) != 6 || history[1].Role != fantasy.MessageRoleAssistant {
+		t.Fatalf("expected summary followed directly by kept messages, got %d messages", len(history))
+	}
+}
+
+func TestRenderTranscript_truncatesToolResults(t *testing.T) {
+	msgs := []fantasy.Message{{
+		Role: fantasy.MessageRoleTool,
+		Content: []fantasy.MessagePart{fantasy.ToolResultPart{
+			ToolCallID: "1",
+			Output:     fantasy.ToolResultOutputContentText{Text: strings.Repeat("x", 100)},
+		}},
+	}}
+
+	if got := renderTranscript(msgs, 10); strings.Count(got, "x") > 10 {
+		t.Fatalf("expected tool result truncated to 10 bytes, got %q", got)
+	}
+	if got := renderTranscript(msgs, 0); strings.Count(got, "x") != 100 {
+		t.Fatalf("expected full tool result, got %q", got)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Manual compaction
+// --------------------------------------------------------------------------
+
+func TestCompact_replacesOlderMessages(t *testing.T) {
+	stub := &compactingAgent{summary: "summary of the first turns"}
+	app := New(Options{Agent: stub, CompactKeepTurns: 1}, threeTurns())
+	defer app.Close()
+
+	if err := app.Compact("focus on file names"); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	waitIdle(t, app)
+
+	msgs := app.store.GetAll()
+	// summary + ack + the kept third turn (user + assistant)
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 messages after compaction, got %d", len(msgs))
+	}
+	if text := renderTranscript(msgs[:1], 0); !strings.Contains(text, "summary of the first turns") {
+		t.Errorf("expected summary as first message, got %q", text)
+	}
+	if text := renderTranscript(msgs[2:], 0); !strings.Contains(text, "third") || !strings.Contains(text, "three") {
+		t.Errorf("expected last turn kept verbatim, got %q", text)
+	}
+
+	if len(stub.prompts) != 1 {
+		t.Fatalf("expected one summary request, got %d", len(stub.prompts))
+	}
+	prompt := stub.prompts[0]
+	if !strings.Contains(prompt, "focus on file names") {
+		t.Errorf("expected instructions in summary prompt, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "second") || strings.Contains(prompt, "third") {
+		t.Errorf("expected only the older turns in summary prompt, got %q", prompt)
+	}
+}
+
+func TestCompact_failureKeepsHistory(t *testing.T) {
+	tests := map[string]*compactingAgent{
+		"summary error": {summaryErr: errors.New("provider down")},
+		"empty summary": {summary: "  "},
+	}
+	for name, stub := range tests {
+		t.Run(name, func(t *testing.T) {
+			app := New(Options{Agent: stub, CompactKeepTurns: 1}, threeTurns())
+			defer app.Close()
+
+			if _, _, err := app.compact(context.Background(), ""); err == nil {
+				t.Fatal("expected compaction to fail")
+			}
+			if got := app.store.Len(); got != len(threeTurns()) {
+				t.Fatalf("expected history unchanged, got %d messages", got)
+			}
+		})
+	}
+}
+
+func TestCompact_errors(t *testing.T) {
+	t.Run("nothing to compact", func(t *testing.T) {
+		app := New(Options{Agent: &compactingAgent{summary: "s"}, CompactKeepTurns: 5}, threeTurns())
+		defer app.Close()
+		if _, _, err := app.compact(context.Background(), ""); !errors.Is(err, ErrNothingToCompact) {
+			t.Fatalf("expected ErrNothingToCompact, got %v", err)
+		}
+	})
+
+	t.Run("unsupported agent", func(t *testing.T) {
+		app := New(Options{Agent: newStubAgent()}, threeTurns())
+		defer app.Close()
+		if _, _, err := app.compact(context.Background(), ""); !errors.Is(err, ErrCompactionUnsupported) {
+			t.Fatalf("expected ErrCompactionUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		stub := newStubAgent()
+		stub.blockCh = make(chan struct{})
+		app := newTestApp(stub)
+		defer app.Close()
+
+		app.Run("long running")
+		if err := app.Compact(""); !errors.Is(err, ErrBusy) {
+			t.Fatalf("expected ErrBusy while a step runs, got %v", err)
+		}
+		close(stub.blockCh)
+	})
+}
+
+// --------------------------------------------------------------------------
+// Automatic compaction
+// --------------------------------------------------------------------------
+
+func TestRunOnceWithDisplay_autoCompaction(t *testing.T) {
+	tests := []struct {
+		name          string
+		contextTokens int
+		wantCompacted bool
+	}{
+		{"below threshold", 500, false},
+		{"above threshold", 900, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &compactingAgent{summary: "earlier work"}
+			app := New(Options{
+				Agent:            stub,
+				CompactThreshold: 0.8,
+				CompactKeepTurns: 1,
+				ContextWindow:    1000,
+			}, threeTurns())
+			defer app.Close()
+			app.contextTokens = tt.contextTokens
+
+			var events []tea.Msg
+			if err := app.RunOnceWithDisplay(context.Background(), "fourth", func(msg tea.Msg) {
+				events = append(events, msg)
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var compaction *CompactionEvent
+			for _, evt := range events {
+				if e, ok := evt.(CompactionEvent); ok {
+					compaction = &e
+				}
+			}
+			if (compaction != nil) != tt.wantCompacted {
+				t.Fatalf("expected compaction=%v, got event %+v", tt.wantCompacted, compaction)
+			}
+
+			sent := renderTranscript(stub.lastMessages, 0)
+			if !strings.Contains(sent, "fourth") {
+				t.Fatalf("expected the new prompt to reach the agent, got %q", sent)
+			}
+			if !tt.wantCompacted {
+				if strings.Contains(sent, "earlier work") {
+					t.Fatalf("did not expect a summary, got %q", sent)
+				}
+				return
+			}
+
+			if !compaction.Auto || compaction.Err != nil || compaction.Summarized != 6 || compaction.Kept != 2 {
+				t.Errorf("unexpected compaction event %+v", *compaction)
+			}
+			if !strings.Contains(sent, "earlier work") || strings.Contains(sent, "second") {
+				t.Fatalf("expected the agent to get the summary instead of older turns, got %q", sent)
+			}
+		})
+	}
+}
+
+func TestRecordContextTokens(t *testing.T) {
+	app := newTestApp(newStubAgent())
+	defer app.Close()
+
+	result := makeResult("hi")
+	result.FinalResponse.Usage = fantasy.Usage{InputTokens: 120, OutputTokens: 30}
+	app.recordContextTokens(result)
+	if app.contextTokens != 150 {
+		t.Fatalf("expected 150 context tokens from usage, got %d", app.contextTokens)
+	}
+
+	// Anthropic reports the cached prompt tokens apart from the input tokens
+	app.opts.Provider = "anthropic"
+	result = makeResult("hi")
+	result.FinalResponse.Usage = fantasy.Usage{InputTokens: 20, OutputTokens: 30, CacheReadTokens: 900, CacheCreationTokens: 100}
+	app.recordContextTokens(result)
+	if app.contextTokens != 1050 {
+		t.Fatalf("expected 1050 context tokens including cached ones, got %d", app.contextTokens)
+	}
+
+	// The usage tracker shows the same fill level
+	usage := &usageRecorder{}
+	app.opts.UsageTracker = usage
+	result.TotalUsage = result.FinalResponse.Usage
+	app.updateUsage(result, "hi")
+	if usage.context != 1050 {
+		t.Fatalf("expected the tracker to get 1050 context tokens, got %d", usage.context)
+	}
+
+	// Without usage data the history is estimated instead.
+	result = makeResult("hi")
+	result.ConversationMessages = []fantasy.Message{fantasy.NewUserMessage(strings.Repeat("a", 400))}
+	app.recordContextTokens(result)
+	if app.contextTokens < 100 {
+		t.Fatalf("expected an estimate of at least 100 tokens, got %d", app.contextTokens)
+	}
+}
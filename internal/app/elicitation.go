@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	tea "charm.land/bubbletea/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// SetElicitationFunc replaces the elicitation policy set in Options. A nil fn
+// declines every request.
+func (a *App) SetElicitationFunc(fn tools.ElicitationFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.opts.ElicitationFunc = fn
+}
+
+// Elicit answers an MCP server's request for input from the user with the
+// configured ElicitationFunc. It is handed to the agent like ApproveTool;
+// with no elicitation func configured every request is declined.
+func (a *App) Elicit(ctx context.Context, serverName string, request tools.ElicitationRequest) (tools.ElicitationResponse, error) {
+	a.mu.Lock()
+	elicit := a.opts.ElicitationFunc
+	a.mu.Unlock()
+
+	if elicit == nil {
+		return tools.ElicitationResponse{Action: tools.ElicitationDecline}, nil
+	}
+	return elicit(ctx, serverName, request)
+}
+
+// NewInteractiveElicitationFunc returns an elicitation func that asks the user
+// via the TUI: it sends an ElicitationNeededEvent to the program registered
+// with a and blocks until the user answers or ctx is cancelled. Requests made
+// while no program is registered are declined.
+func NewInteractiveElicitationFunc(a *App) tools.ElicitationFunc {
+	return func(ctx context.Context, serverName string, request tools.ElicitationRequest) (tools.ElicitationResponse, error) {
+		a.mu.Lock()
+		prog := a.program
+		a.mu.Unlock()
+		if prog == nil {
+			return tools.ElicitationResponse{Action: tools.ElicitationDecline}, nil
+		}
+
+		return requestElicitation(ctx, func(msg tea.Msg) { prog.Send(msg) }, serverName, request)
+	}
+}
+
+// requestElicitation sends an ElicitationNeededEvent through send and waits
+// for the answer. When ctx is cancelled first, the request is cancelled.
+func requestElicitation(ctx context.Context, send func(tea.Msg), serverName string, request tools.ElicitationRequest) (tools.ElicitationResponse, error) {
+	// Buffered so the TUI never blocks if we stopped waiting in the meantime.
+	ch := make(chan tools.ElicitationResponse, 1)
+	send(ElicitationNeededEvent{
+		ServerName:   serverName,
+		Request:      request,
+		ResponseChan: ch,
+	})
+
+	select {
+	case response := <-ch:
+		return response, nil
+	case <-ctx.Done():
+		return tools.ElicitationResponse{Action: tools.ElicitationCancel}, ctx.Err()
+	}
+}
+
+// LoadElicitationAnswers reads an answers file for non-interactive mode and
+// returns an elicitation func answering from it. The file, YAML or JSON, maps
+// server names to the values of the fields their requests ask for:
+//
+//	deployer:
+//	  target: staging
+//	  confirm: true
+//
+// Requests of servers not in the file are declined. A request whose required
+// fields are not all answered, or answered with invalid values, fails.
+func LoadElicitationAnswers(path string) (tools.ElicitationFunc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read elicitation answers: %w", err)
+	}
+	var answers map[string]map[string]any
+	if err := yaml.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("invalid elicitation answers %s: %w", path, err)
+	}
+	return answerElicitations(answers), nil
+}
+
+// answerElicitations returns an elicitation func answering from answers, the
+// field values by server name.
+func answerElicitations(answers map[string]map[string]any) tools.ElicitationFunc {
+	return func(_ context.Context, serverName string, request tools.ElicitationRequest) (tools.ElicitationResponse, error) {
+		values, ok := answers[serverName]
+		if !ok {
+			return tools.ElicitationResponse{Action: tools.ElicitationDecline}, nil
+		}
+		content, err := request.Content(values)
+		if err != nil {
+			return tools.ElicitationResponse{}, fmt.Errorf("elicitation answers of %s: %w", serverName, err)
+		}
+		return tools.ElicitationResponse{Action: tools.ElicitationAccept, Content: content}, nil
+	}
+}
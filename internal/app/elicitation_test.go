@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// deployRequest asks for a deployment target and a confirmation.
+var deployRequest = tools.ElicitationRequest{
+	Message: "Where should we deploy?",
+	Fields: []tools.ElicitationField{
+		{Name: "confirm", Type: "boolean", Required: true},
+		{Name: "target", Type: "string", Required: true, Enum: []string{"staging", "production"}},
+	},
+}
+
+func TestElicit(t *testing.T) {
+	app := newTestApp(newStubAgent())
+	defer app.Close()
+
+	response, err := app.Elicit(context.Background(), "deployer", deployRequest)
+	if err != nil || response.Action != tools.ElicitationDecline {
+		t.Fatalf("expected a decline without an elicitation func, got %+v, %v", response, err)
+	}
+}
+
+func TestRequestElicitation_handshake(t *testing.T) {
+	var evt ElicitationNeededEvent
+	send := func(msg tea.Msg) {
+		evt = msg.(ElicitationNeededEvent)
+		go func() {
+			evt.ResponseChan <- tools.ElicitationResponse{Action: tools.ElicitationAccept, Content: map[string]any{"target": "staging"}}
+		}()
+	}
+
+	response, err := requestElicitation(context.Background(), send, "deployer", deployRequest)
+	if err != nil || response.Action != tools.ElicitationAccept || response.Content["target"] != "staging" {
+		t.Fatalf("expected the answer, got %+v, %v", response, err)
+	}
+	if evt.ServerName != "deployer" || evt.Request.Message != deployRequest.Message {
+		t.Fatalf("unexpected event %+v", evt)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, err = requestElicitation(ctx, func(tea.Msg) {}, "deployer", deployRequest)
+	if err == nil || response.Action != tools.ElicitationCancel {
+		t.Fatalf("expected a cancelled request, got %+v, %v", response, err)
+	}
+}
+
+func TestLoadElicitationAnswers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.yml")
+	answers := "deployer:\n  target: staging\n  confirm: true\nbroken:\n  target: staging\n"
+	if err := os.WriteFile(path, []byte(answers), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	elicit, err := LoadElicitationAnswers(path)
+	if err != nil {
+		t.Fatalf("LoadElicitationAnswers: %v", err)
+	}
+
+	response, err := elicit(context.Background(), "deployer", deployRequest)
+	if err != nil || response.Action != tools.ElicitationAccept {
+		t.Fatalf("expected the request answered, got %+v, %v", response, err)
+	}
+	if response.Content["target"] != "staging" || response.Content["confirm"] != true {
+		t.Errorf("unexpected content: %v", response.Content)
+	}
+
+	if response, err := elicit(context.Background(), "other", deployRequest); err != nil || response.Action != tools.ElicitationDecline {
+		t.Errorf("expected servers without answers declined, got %+v, %v", response, err)
+	}
+	if _, err := elicit(context.Background(), "broken", deployRequest); err == nil {
+		t.Error("expected an error for a missing required answer")
+	}
+
+	if _, err := LoadElicitationAnswers(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -1,6 +1,12 @@
 package app
 
-import "charm.land/fantasy"
+import (
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
 
 // StreamChunkEvent is sent by the app layer when a streaming text delta arrives
 // from the LLM. Each chunk contains an incremental portion of the response.
@@ -9,6 +15,13 @@ type StreamChunkEvent struct {
 	Content string
 }
 
+// ReasoningChunkEvent is sent by the app layer when a reasoning ("thinking")
+// delta arrives from the LLM. Reasoning precedes the text of the same step.
+type ReasoningChunkEvent struct {
+	// Content is the incremental reasoning delta.
+	Content string
+}
+
 // ToolCallStartedEvent is sent when a tool call has been parsed and is about to execute.
 // It carries the tool name and its arguments for display purposes.
 type ToolCallStartedEvent struct {
@@ -16,6 +29,8 @@ type ToolCallStartedEvent struct {
 	ToolName string
 	// ToolArgs is the JSON-encoded arguments for the tool call.
 	ToolArgs string
+	// Nested is true when a sub-agent started by the task tool made the call.
+	Nested bool
 }
 
 // ToolExecutionEvent is sent when a tool starts or finishes executing.
@@ -27,6 +42,31 @@ type ToolExecutionEvent struct {
 	IsStarting bool
 }
 
+// ToolProgressEvent is sent when an MCP server reports the progress of a
+// running tool call.
+type ToolProgressEvent struct {
+	// ToolName is the name of the running tool.
+	ToolName string
+	// Progress is the progress so far.
+	Progress float64
+	// Total is the progress at which the call is done, or 0 when unknown.
+	Total float64
+	// Message describes the current progress; it may be empty.
+	Message string
+}
+
+// ServerLogEvent is sent when an MCP server logs a message.
+type ServerLogEvent struct {
+	// ServerName is the name of the logging server.
+	ServerName string
+	// Level is the severity of the message, such as "info" or "error".
+	Level string
+	// Logger names the part of the server logging; it may be empty.
+	Logger string
+	// Message is the logged text.
+	Message string
+}
+
 // ToolResultEvent is sent after a tool execution completes with its result.
 type ToolResultEvent struct {
 	// ToolName is the name of the tool that was executed.
@@ -37,6 +77,8 @@ type ToolResultEvent struct {
 	Result string
 	// IsError indicates whether the tool returned an error result.
 	IsError bool
+	// Nested is true when a sub-agent started by the task tool made the call.
+	Nested bool
 }
 
 // ToolCallContentEvent is sent when a step includes text content alongside tool calls.
@@ -75,6 +117,165 @@ type StepErrorEvent struct {
 // displaying an error.
 type StepCancelledEvent struct{}
 
+// HookBlockedEvent is sent when a hook ends the current step early by returning
+// "continue": false from a PreToolUse or PostToolUse hook. The step still
+// completes normally afterwards; this event lets the UI explain why it stopped.
+type HookBlockedEvent struct {
+	// Reason is the stop reason given by the hook (may be empty).
+	Reason string
+}
+
+// CompactionEvent is sent after the older part of the conversation has been
+// replaced by a summary, or when compaction failed. Automatic compactions
+// happen at the start of a step, which then continues as usual; a manual
+// compaction (App.Compact) ends with this event instead of StepCompleteEvent.
+type CompactionEvent struct {
+	// Auto is true when compaction was triggered by the context window
+	// filling up rather than by the user.
+	Auto bool
+	// Summarized is the number of messages replaced by the summary.
+	Summarized int
+	// Kept is the number of recent messages kept verbatim.
+	Kept int
+	// Err is non-nil when compaction failed; the history is then unchanged.
+	Err error
+}
+
+// ModelRetryEvent is sent when a model request failed with a transient error
+// (rate limit, overload, server error) and is about to be retried.
+type ModelRetryEvent struct {
+	// Model is the model being retried, e.g. "anthropic/claude-sonnet-4-5".
+	Model string
+	// Attempt counts the retries of Model for this request, starting at 1.
+	Attempt int
+	// Delay is how long the app waits before retrying.
+	Delay time.Duration
+	// Err is the error that caused the retry.
+	Err error
+}
+
+// ModelFallbackEvent is sent when a model gave up on a request and the next
+// model of the fallback chain is tried instead.
+type ModelFallbackEvent struct {
+	// From is the model that failed.
+	From string
+	// To is the model that is tried next.
+	To string
+	// Err is the last error returned by From.
+	Err error
+}
+
+// ModelAnsweredEvent is sent when a model other than the primary one answered
+// a request: after a fallback, or because the primary model is cooling down
+// since an earlier failure.
+type ModelAnsweredEvent struct {
+	// Model is the model that answered.
+	Model string
+	// Primary is the first model of the fallback chain.
+	Primary string
+}
+
+// BudgetWarningEvent is sent when the spending of the prompt or the session
+// reaches the warning threshold of a cost or token budget. The step goes on;
+// a StepErrorEvent follows if the budget stops it.
+type BudgetWarningEvent struct {
+	// Warning describes the budget and how much of it is used, e.g.
+	// "prompt cost budget: $0.41 of $0.50 used (82%)".
+	Warning string
+}
+
+// ToolApprovalNeededEvent is sent when a tool call is waiting for the user's
+// approval. The agent is blocked until a decision is sent on ResponseChan or
+// the step is cancelled. ResponseChan is buffered, so sending on it never blocks.
+type ToolApprovalNeededEvent struct {
+	// ToolName is the name of the tool awaiting approval.
+	ToolName string
+	// ToolArgs is the JSON-encoded arguments of the pending call.
+	ToolArgs string
+	// CanAlwaysAllow is true when an "always allow" answer can be saved as a
+	// permission rule, so the UI should offer it.
+	CanAlwaysAllow bool
+	// ResponseChan receives the user's decision.
+	ResponseChan chan<- ToolApprovalDecision
+}
+
+// ToolApprovalDecision is the user's answer to a ToolApprovalNeededEvent.
+type ToolApprovalDecision struct {
+	// Approved is true when the tool call may run.
+	Approved bool
+	// Always asks for an allow rule for this tool to be saved, so future calls
+	// run without asking. Only meaningful when Approved is true.
+	Always bool
+}
+
+// SamplingApprovalNeededEvent is sent when an MCP server asks the model to
+// answer a sampling request and the user must approve it. The server waits
+// until a decision is sent on ResponseChan, which is buffered. Always is
+// ignored: sampling is trusted per server in the configuration instead.
+type SamplingApprovalNeededEvent struct {
+	// ServerName is the name of the server making the request.
+	ServerName string
+	// Request is the sampling request, as it will be sent to the model.
+	Request tools.SamplingRequest
+	// ResponseChan receives the user's decision.
+	ResponseChan chan<- ToolApprovalDecision
+}
+
+// ElicitationNeededEvent is sent when an MCP server asks the user for input.
+// The server waits until the answer is sent on ResponseChan, which is
+// buffered.
+type ElicitationNeededEvent struct {
+	// ServerName is the name of the server making the request.
+	ServerName string
+	// Request holds the server's message and the fields to fill in.
+	Request tools.ElicitationRequest
+	// ResponseChan receives the user's answer.
+	ResponseChan chan<- tools.ElicitationResponse
+}
+
+// ServerListChangedEvent is sent after an MCP server changed its tools,
+// resources or prompts and they were listed again. It carries the updated
+// lists of all servers; they are nil when the agent cannot list them.
+type ServerListChangedEvent struct {
+	// ServerName is the name of the server whose list changed.
+	ServerName string
+	// Kind is the list that changed.
+	Kind tools.ListKind
+	// ToolNames are the names of all tools.
+	ToolNames []string
+	// Resources are the resources and resource templates of all servers.
+	Resources []tools.Resource
+	// Prompts are the prompts of all servers.
+	Prompts []tools.Prompt
+}
+
+// ServerStatusEvent is sent when an MCP server that was still connecting
+// when startup finished, or a lazy server started by a tool call, is ready or
+// failed. It carries the updated lists of
+// all servers, like ServerListChangedEvent.
+type ServerStatusEvent struct {
+	// Status is the server's new status.
+	Status tools.ServerStatus
+	// ToolNames are the names of all tools.
+	ToolNames []string
+	// Resources are the resources and resource templates of all servers.
+	Resources []tools.Resource
+	// Prompts are the prompts of all servers.
+	Prompts []tools.Prompt
+}
+
+// PermissionRuleAddedEvent is sent after an "always allow" answer added a
+// permission rule. The rule applies for the rest of the session even when
+// saving it failed.
+type PermissionRuleAddedEvent struct {
+	// Rule describes the added rule (e.g. "allow fs__read_file").
+	Rule string
+	// Path is the file the rule was saved to.
+	Path string
+	// Err is non-nil when the rule could not be saved.
+	Err error
+}
+
 // QueueUpdatedEvent is sent whenever the message queue length changes.
 // The TUI uses this to update the queue badge display.
 type QueueUpdatedEvent struct {
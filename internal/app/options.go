@@ -2,12 +2,19 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 
 	"charm.land/fantasy"
 
 	"github.com/mark3labs/mcphost/internal/agent"
+	"github.com/mark3labs/mcphost/internal/attachments"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/hooks"
+	"github.com/mark3labs/mcphost/internal/permissions"
 	"github.com/mark3labs/mcphost/internal/session"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // AgentRunner is the minimal interface the app layer requires from the agent
@@ -23,6 +30,7 @@ type AgentRunner interface {
 		onResponse agent.ResponseHandler,
 		onToolCallContent agent.ToolCallContentHandler,
 		onStreamingResponse agent.StreamingResponseHandler,
+		onReasoning agent.ReasoningHandler,
 	) (*agent.GenerateWithLoopResult, error)
 }
 
@@ -44,6 +52,81 @@ type UsageUpdater interface {
 	SetContextTokens(tokens int)
 }
 
+// TextGenerator is implemented by agents that can answer a single prompt
+// without tools or conversation history. The app layer uses it to summarize
+// the conversation when compacting. *agent.Agent satisfies this interface;
+// agents that do not implement it cannot be compacted.
+type TextGenerator interface {
+	GenerateText(ctx context.Context, systemPrompt, prompt string) (*fantasy.Response, error)
+}
+
+// StructuredOutputGenerator is implemented by agents that can give their final
+// answer as JSON matching a schema. The app layer uses it when an output
+// schema is configured. *agent.Agent satisfies this interface.
+type StructuredOutputGenerator interface {
+	GenerateStructuredOutput(ctx context.Context, result *agent.GenerateWithLoopResult,
+		outputSchema *agent.OutputSchema, maxRetries int) error
+}
+
+// ResourceReader is implemented by agents that can read the resources of
+// their MCP servers. The app layer uses it to attach resources mentioned as
+// "@server:uri" to a prompt. *agent.Agent satisfies this interface.
+type ResourceReader interface {
+	GetResources() []tools.Resource
+	ReadResource(ctx context.Context, serverName, uri string) ([]*attachments.Attachment, error)
+}
+
+// PromptGetter is implemented by agents that can fill in the prompts of
+// their MCP servers. The app layer uses it to run a server prompt as a step
+// (see App.RunServerPrompt). *agent.Agent satisfies this interface.
+type PromptGetter interface {
+	GetPrompt(ctx context.Context, serverName, promptName string, args map[string]string) ([]fantasy.Message, error)
+}
+
+// RootsManager is implemented by agents whose MCP servers learn the
+// directories they operate on from their roots. The app layer uses it to
+// list and add roots and to move the default root along with the working
+// directory. *agent.Agent satisfies this interface.
+type RootsManager interface {
+	Roots() map[string][]string
+	AddRoot(dir string) (string, error)
+	SetWorkingDirectory(dir string)
+}
+
+// ServerLister is implemented by agents that list the tools, resources and
+// prompts of their MCP servers, which servers may change during the session.
+// The app layer passes the updated lists to the TUI (see
+// App.ServerListChanged). *agent.Agent satisfies this interface.
+type ServerLister interface {
+	GetTools() []fantasy.AgentTool
+	GetResources() []tools.Resource
+	GetPrompts() []tools.Prompt
+}
+
+// ToolApprovalFunc decides whether a tool call may run. It is called before
+// every tool call with the tool name and its JSON-encoded arguments, and may
+// block until a decision is made. Returning false denies the call, which is
+// reported back to the model as a tool error; returning an error aborts the
+// step. Implementations must return promptly once ctx is cancelled.
+type ToolApprovalFunc func(ctx context.Context, toolName, toolArgs string) (bool, error)
+
+// ErrApprovalRequired is returned (wrapped with the tool name) when a tool call
+// needs approval but nobody is available to give it.
+var ErrApprovalRequired = errors.New("tool call requires approval")
+
+// AutoApproveFunc approves every tool call. It is the default policy for
+// non-interactive mode.
+var AutoApproveFunc ToolApprovalFunc = func(context.Context, string, string) (bool, error) {
+	return true, nil
+}
+
+// RequireApprovalFunc fails every tool call with ErrApprovalRequired. It is used
+// in non-interactive mode when auto-approval is disabled, so that a run which
+// needs a tool fails instead of silently executing it.
+var RequireApprovalFunc ToolApprovalFunc = func(_ context.Context, toolName, _ string) (bool, error) {
+	return false, fmt.Errorf("%w: %s (auto-approval is disabled)", ErrApprovalRequired, toolName)
+}
+
 // Options configures an App instance. It mirrors the fields from AgenticLoopConfig
 // in cmd/root.go but is owned by the app layer rather than the CLI.
 type Options struct {
@@ -51,6 +134,34 @@ type Options struct {
 	// *agent.Agent satisfies this interface; tests may supply stubs.
 	Agent AgentRunner
 
+	// ToolApprovalFunc is consulted before every tool call via App.ApproveTool.
+	// Nil approves every call. Interactive mode replaces it with
+	// NewInteractiveApprovalFunc once the TUI is running (see SetToolApprovalFunc).
+	ToolApprovalFunc ToolApprovalFunc
+
+	// SamplingApprovalFunc is consulted via App.ApproveSampling before an MCP
+	// server has the model answer a sampling request. Nil approves every
+	// request. Interactive mode replaces it with
+	// NewInteractiveSamplingApprovalFunc (see SetSamplingApprovalFunc).
+	SamplingApprovalFunc tools.SamplingApprovalFunc
+
+	// ElicitationFunc answers, via App.Elicit, the requests of MCP servers for
+	// input from the user. Nil declines every request. Interactive mode
+	// replaces it with NewInteractiveElicitationFunc (see SetElicitationFunc);
+	// non-interactive mode may answer from a file (LoadElicitationAnswers).
+	ElicitationFunc tools.ElicitationFunc
+
+	// Permissions holds the allow/ask/deny rules. The agent checks them before
+	// asking for approval; the app layer adds to them when the user answers
+	// "always allow" in the interactive approval prompt. Nil disables both.
+	Permissions *permissions.Ruleset
+
+	// HookExecutor is the optional hook executor. When non-nil, the app layer
+	// fires UserPromptSubmit before each prompt and Stop after each step. The
+	// same executor should be handed to the agent so that PreToolUse and
+	// PostToolUse hooks fire around tool calls.
+	HookExecutor *hooks.Executor
+
 	// SessionManager is the optional session manager for persisting conversation
 	// history to disk. When non-nil, the MessageStore calls it on every mutation.
 	SessionManager *session.Manager
@@ -62,6 +173,11 @@ type Options struct {
 	// ModelName is the display name of the model (e.g. "claude-sonnet-4-5").
 	ModelName string
 
+	// Provider is the provider of the model (e.g. "anthropic"). It tells
+	// whether the prompt tokens the model reports include cached ones when
+	// judging the context window fill level (see models.UsageTokens).
+	Provider string
+
 	// ServerNames holds the names of loaded MCP servers, used for slash command
 	// autocomplete.
 	ServerNames []string
@@ -76,6 +192,10 @@ type Options struct {
 	// Quiet suppresses all output except the final response (non-interactive mode).
 	Quiet bool
 
+	// Output is where RunOnce prints the final response. Nil prints to
+	// os.Stdout.
+	Output io.Writer
+
 	// Debug enables verbose debug logging.
 	Debug bool
 
@@ -83,6 +203,36 @@ type Options struct {
 	// message formatting.
 	CompactMode bool
 
+	// CompactThreshold is the share of the context window (0-1) at which the
+	// conversation is compacted automatically before the next prompt. Zero
+	// disables automatic compaction; App.Compact still works.
+	CompactThreshold float64
+
+	// CompactKeepTurns is the number of most recent turns (a user prompt and
+	// everything after it) kept verbatim when compacting. Zero summarizes the
+	// whole conversation.
+	CompactKeepTurns int
+
+	// ContextWindow is the model's context window in tokens (from
+	// models.ModelInfo.Limit.Context). Zero disables automatic compaction,
+	// since the fill level cannot be judged.
+	ContextWindow int
+
+	// AttachmentsUnsupported is set when the models registry says the model
+	// does not accept attachments. Images and PDFs are then rejected; text
+	// files are still inlined into the prompt. Unknown models accept them.
+	AttachmentsUnsupported bool
+
+	// OutputSchema, when non-nil, makes every step end with a final answer as
+	// JSON matching the schema (see agent.GenerateStructuredOutput). The
+	// validated JSON is what RunOnce prints. The agent must implement
+	// StructuredOutputGenerator.
+	OutputSchema *agent.OutputSchema
+
+	// OutputRetries is the number of times the model may retry an answer that
+	// does not match OutputSchema before the step fails.
+	OutputRetries int
+
 	// UsageTracker is an optional callback for recording token usage after each
 	// agent step. When non-nil, the app layer calls UpdateUsage (or
 	// EstimateAndUpdateUsage as a fallback) using the usage data returned by the
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
+)
+
+// ErrPromptsUnsupported is returned when the configured agent cannot fill in
+// server prompts (it does not implement PromptGetter).
+var ErrPromptsUnsupported = errors.New("the agent does not support server prompts")
+
+// RunServerPrompt fills in the prompt promptName of the MCP server serverName
+// with args in the background, adds its messages to the conversation and runs
+// the agent on them, like Run does for a typed prompt.
+//
+// Like Compact, it returns ErrBusy instead of queueing when a step is
+// running, and never sends events itself, so it is safe to call from within
+// Bubble Tea's Update loop. The outcome is reported with the same events as
+// a prompt passed to Run.
+//
+// Satisfies ui.AppController.
+func (a *App) RunServerPrompt(serverName, promptName string, args map[string]string) error {
+	getter, ok := a.opts.Agent.(PromptGetter)
+	if !ok {
+		return ErrPromptsUnsupported
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	if a.busy {
+		a.mu.Unlock()
+		return ErrBusy
+	}
+	a.busy = true
+	a.wg.Add(1)
+	a.mu.Unlock()
+
+	go a.drainQueue(func() { a.runServerPrompt(getter, serverName, promptName, args) })
+	return nil
+}
+
+// runServerPrompt gets a server prompt and runs it as a step, reporting the
+// outcome to the program.
+func (a *App) runServerPrompt(getter PromptGetter, serverName, promptName string, args map[string]string) {
+	stepCtx, cancel := context.WithCancel(a.rootCtx)
+	a.mu.Lock()
+	a.cancelStep = cancel
+	prog := a.program
+	a.mu.Unlock()
+	defer cancel()
+
+	eventFn := func(msg tea.Msg) {
+		if prog != nil {
+			prog.Send(msg)
+		}
+	}
+
+	a.sendEvent(SpinnerEvent{Show: true})
+	msgs, err := getter.GetPrompt(stepCtx, serverName, promptName, args)
+	if err != nil {
+		a.reportStep(stepCtx, "", nil, err)
+		return
+	}
+
+	prompt := messagesText(msgs)
+	result, err := a.executeMessages(stepCtx, prompt, msgs, eventFn)
+	a.reportStep(stepCtx, prompt, result, err)
+}
+
+// messagesText returns the text of msgs, one message per paragraph.
+func messagesText(msgs []fantasy.Message) string {
+	var texts []string
+	for _, msg := range msgs {
+		var parts []string
+		for _, part := range msg.Content {
+			if text, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+				parts = append(parts, text.Text)
+			}
+		}
+		if len(parts) > 0 {
+			texts = append(texts, strings.Join(parts, "\n"))
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
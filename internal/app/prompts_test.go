@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// promptStubAgent is a stubAgent that fills in server prompts.
+type promptStubAgent struct {
+	*stubAgent
+	messages []fantasy.Message
+	err      error
+	args     map[string]string
+}
+
+func (s *promptStubAgent) GetPrompt(_ context.Context, _, _ string, args map[string]string) ([]fantasy.Message, error) {
+	s.args = args
+	return s.messages, s.err
+}
+
+func TestRunServerPrompt_addsMessagesAndRuns(t *testing.T) {
+	stub := &promptStubAgent{
+		stubAgent: newStubAgent(makeResult("done")),
+		messages: []fantasy.Message{
+			fantasy.NewUserMessage("Review pull request 42"),
+			{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "Which files?"}}},
+			fantasy.NewUserMessage("All of them"),
+		},
+	}
+	stub.blockCh = make(chan struct{})
+	app := newTestApp(stub)
+	defer app.Close()
+
+	if err := app.RunServerPrompt("github", "review-pr", map[string]string{"pr": "42"}); err != nil {
+		t.Fatalf("RunServerPrompt: %v", err)
+	}
+	if !waitForCondition(2*time.Second, func() bool { return len(app.store.GetAll()) == 3 }) {
+		t.Fatalf("expected the prompt messages in the conversation, got %d messages", len(app.store.GetAll()))
+	}
+	if err := app.RunServerPrompt("github", "review-pr", nil); !errors.Is(err, ErrBusy) {
+		t.Fatalf("expected ErrBusy while a step runs, got %v", err)
+	}
+	close(stub.blockCh)
+	waitIdle(t, app)
+
+	if stub.args["pr"] != "42" {
+		t.Errorf("expected the arguments passed on, got %v", stub.args)
+	}
+	if stub.CallCount() != 1 {
+		t.Errorf("expected one agent call, got %d", stub.CallCount())
+	}
+}
+
+func TestRunServerPrompt_errors(t *testing.T) {
+	t.Run("unsupported agent", func(t *testing.T) {
+		app := newTestApp(newStubAgent())
+		defer app.Close()
+		if err := app.RunServerPrompt("github", "review-pr", nil); !errors.Is(err, ErrPromptsUnsupported) {
+			t.Fatalf("expected ErrPromptsUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("prompt fails", func(t *testing.T) {
+		stub := &promptStubAgent{stubAgent: newStubAgent(makeResult("unused")), err: errors.New("no such prompt")}
+		app := newTestApp(stub)
+		defer app.Close()
+
+		if err := app.RunServerPrompt("github", "missing", nil); err != nil {
+			t.Fatalf("RunServerPrompt: %v", err)
+		}
+		waitIdle(t, app)
+		if stub.CallCount() != 0 {
+			t.Errorf("expected the agent not called, got %d calls", stub.CallCount())
+		}
+		if n := len(app.store.GetAll()); n != 0 {
+			t.Errorf("expected no messages added, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrRootsUnsupported is returned when the configured agent cannot manage the
+// roots of its MCP servers (it does not implement RootsManager).
+var ErrRootsUnsupported = errors.New("the agent does not support roots")
+
+// Roots returns the root directories advertised to each MCP server that
+// supports roots, by server name. It is empty when the agent does not
+// implement RootsManager.
+//
+// Satisfies ui.AppController.
+func (a *App) Roots() map[string][]string {
+	roots, ok := a.opts.Agent.(RootsManager)
+	if !ok {
+		return nil
+	}
+	return roots.Roots()
+}
+
+// AddRoot adds dir, which may start with "~", to the roots of every MCP
+// server and sends them notifications/roots/list_changed. It returns the
+// absolute path of the directory.
+//
+// Satisfies ui.AppController.
+func (a *App) AddRoot(dir string) (string, error) {
+	roots, ok := a.opts.Agent.(RootsManager)
+	if !ok {
+		return "", ErrRootsUnsupported
+	}
+	return roots.AddRoot(expandHome(dir))
+}
+
+// ChangeDirectory changes the working directory of mcphost, and so of the
+// tools it runs, to dir, which may be relative or start with "~". MCP servers
+// without configured roots are told that their root moved. It returns the
+// new working directory, or ErrBusy while a step runs, whose tools would
+// otherwise see the directory change under them.
+//
+// Satisfies ui.AppController.
+func (a *App) ChangeDirectory(dir string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.busy {
+		return "", ErrBusy
+	}
+
+	abs, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chdir(abs); err != nil {
+		return "", fmt.Errorf("changing directory: %w", err)
+	}
+	if roots, ok := a.opts.Agent.(RootsManager); ok {
+		roots.SetWorkingDirectory(abs)
+	}
+	return abs, nil
+}
+
+// expandHome replaces a leading "~" in path with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rootsStubAgent is a stubAgent that records the roots it is told about.
+type rootsStubAgent struct {
+	*stubAgent
+	workDir string
+	added   []string
+}
+
+func (s *rootsStubAgent) Roots() map[string][]string {
+	return map[string][]string{"fs": append([]string{s.workDir}, s.added...)}
+}
+
+func (s *rootsStubAgent) AddRoot(dir string) (string, error) {
+	s.added = append(s.added, dir)
+	return dir, nil
+}
+
+func (s *rootsStubAgent) SetWorkingDirectory(dir string) {
+	s.workDir = dir
+}
+
+func TestChangeDirectory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	stub := &rootsStubAgent{stubAgent: newStubAgent()}
+	app := newTestApp(stub)
+	defer app.Close()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "project"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := app.ChangeDirectory(filepath.Join(dir, "project"))
+	if err != nil {
+		t.Fatalf("ChangeDirectory: %v", err)
+	}
+	cwd, _ := os.Getwd()
+	if got != cwd || stub.workDir != cwd {
+		t.Errorf("expected %s as working directory and root, got %s and %s", cwd, got, stub.workDir)
+	}
+
+	if _, err := app.ChangeDirectory("missing"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	app.mu.Lock()
+	app.busy = true
+	app.mu.Unlock()
+	if _, err := app.ChangeDirectory(".."); !errors.Is(err, ErrBusy) {
+		t.Errorf("expected ErrBusy while a step runs, got %v", err)
+	}
+	app.mu.Lock()
+	app.busy = false
+	app.mu.Unlock()
+}
+
+func TestAddRoot(t *testing.T) {
+	plain := newTestApp(newStubAgent())
+	defer plain.Close()
+	if _, err := plain.AddRoot("/srv"); !errors.Is(err, ErrRootsUnsupported) {
+		t.Fatalf("expected ErrRootsUnsupported, got %v", err)
+	}
+	if roots := plain.Roots(); roots != nil {
+		t.Errorf("expected no roots, got %v", roots)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	stub := &rootsStubAgent{stubAgent: newStubAgent()}
+	app := newTestApp(stub)
+	defer app.Close()
+	if _, err := app.AddRoot("~/src"); err != nil {
+		t.Fatalf("AddRoot: %v", err)
+	}
+	if want := filepath.Join(home, "src"); len(stub.added) != 1 || stub.added[0] != want {
+		t.Errorf("expected %s added, got %v", want, stub.added)
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// RequireSamplingApprovalFunc fails every sampling request. It is used in
+// non-interactive mode when auto-approval is disabled, like
+// RequireApprovalFunc for tool calls.
+var RequireSamplingApprovalFunc tools.SamplingApprovalFunc = func(_ context.Context, serverName string, _ tools.SamplingRequest) (bool, error) {
+	return false, fmt.Errorf("sampling request of %s requires approval (auto-approval is disabled)", serverName)
+}
+
+// SetSamplingApprovalFunc replaces the sampling approval policy set in
+// Options. A nil fn approves every request.
+func (a *App) SetSamplingApprovalFunc(fn tools.SamplingApprovalFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.opts.SamplingApprovalFunc = fn
+}
+
+// ApproveSampling asks the configured SamplingApprovalFunc whether an MCP
+// server may have the model answer request. It is handed to the agent like
+// ApproveTool; with no approval func configured every request is approved.
+func (a *App) ApproveSampling(ctx context.Context, serverName string, request tools.SamplingRequest) (bool, error) {
+	a.mu.Lock()
+	approve := a.opts.SamplingApprovalFunc
+	a.mu.Unlock()
+
+	if approve == nil {
+		return true, nil
+	}
+	return approve(ctx, serverName, request)
+}
+
+// NewInteractiveSamplingApprovalFunc returns a sampling approval func that
+// asks the user via the TUI: it sends a SamplingApprovalNeededEvent to the
+// program registered with a and blocks until the user answers or ctx is
+// cancelled. Requests made while no program is registered are denied.
+func NewInteractiveSamplingApprovalFunc(a *App) tools.SamplingApprovalFunc {
+	return func(ctx context.Context, serverName string, request tools.SamplingRequest) (bool, error) {
+		a.mu.Lock()
+		prog := a.program
+		a.mu.Unlock()
+		if prog == nil {
+			return false, fmt.Errorf("sampling request of %s requires approval (no interactive session)", serverName)
+		}
+
+		return requestSamplingApproval(ctx, func(msg tea.Msg) { prog.Send(msg) }, serverName, request)
+	}
+}
+
+// requestSamplingApproval sends a SamplingApprovalNeededEvent through send and
+// waits for the decision, giving up when ctx is cancelled.
+func requestSamplingApproval(ctx context.Context, send func(tea.Msg), serverName string, request tools.SamplingRequest) (bool, error) {
+	// Buffered so the TUI never blocks if we stopped waiting in the meantime.
+	ch := make(chan ToolApprovalDecision, 1)
+	send(SamplingApprovalNeededEvent{
+		ServerName:   serverName,
+		Request:      request,
+		ResponseChan: ch,
+	})
+
+	select {
+	case decision := <-ch:
+		return decision.Approved, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// RecordSamplingUsage adds the token usage of a sampling request answered for
+// an MCP server to the UsageTracker, so it counts toward the session's usage.
+func (a *App) RecordSamplingUsage(usage fantasy.Usage) {
+	if a.opts.UsageTracker == nil || usage.InputTokens == 0 {
+		return
+	}
+	a.opts.UsageTracker.UpdateUsage(int(usage.InputTokens), int(usage.OutputTokens),
+		int(usage.CacheReadTokens), int(usage.CacheCreationTokens))
+}
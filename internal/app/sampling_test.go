@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// usageRecorder is a UsageUpdater remembering the last recorded usage.
+type usageRecorder struct {
+	input, output int
+	context       int
+}
+
+func (u *usageRecorder) UpdateUsage(inputTokens, outputTokens, _, _ int) {
+	u.input, u.output = inputTokens, outputTokens
+}
+func (u *usageRecorder) EstimateAndUpdateUsage(string, string) {}
+func (u *usageRecorder) SetContextTokens(tokens int)           { u.context = tokens }
+
+func TestApproveSampling(t *testing.T) {
+	app := newTestApp(newStubAgent())
+	defer app.Close()
+
+	request := tools.SamplingRequest{SystemPrompt: "You are a poet.", MaxTokens: 100}
+	if approved, err := app.ApproveSampling(context.Background(), "poems", request); err != nil || !approved {
+		t.Fatalf("expected approval without an approval func, got approved=%v err=%v", approved, err)
+	}
+
+	app.SetSamplingApprovalFunc(RequireSamplingApprovalFunc)
+	if approved, err := app.ApproveSampling(context.Background(), "poems", request); err == nil || approved {
+		t.Fatalf("expected RequireSamplingApprovalFunc to fail, got approved=%v err=%v", approved, err)
+	}
+}
+
+func TestRequestSamplingApproval_handshake(t *testing.T) {
+	var evt SamplingApprovalNeededEvent
+	send := func(msg tea.Msg) {
+		evt = msg.(SamplingApprovalNeededEvent)
+		go func() { evt.ResponseChan <- ToolApprovalDecision{Approved: true} }()
+	}
+
+	approved, err := requestSamplingApproval(context.Background(), send, "poems", tools.SamplingRequest{MaxTokens: 100})
+	if err != nil || !approved {
+		t.Fatalf("expected approval, got approved=%v err=%v", approved, err)
+	}
+	if evt.ServerName != "poems" || evt.Request.MaxTokens != 100 {
+		t.Fatalf("unexpected event %+v", evt)
+	}
+}
+
+func TestRecordSamplingUsage(t *testing.T) {
+	usage := &usageRecorder{}
+	app := New(Options{Agent: newStubAgent(), UsageTracker: usage}, nil)
+	defer app.Close()
+
+	app.RecordSamplingUsage(fantasy.Usage{InputTokens: 120, OutputTokens: 30})
+	if usage.input != 120 || usage.output != 30 {
+		t.Errorf("expected the sampling usage recorded, got %+v", usage)
+	}
+}
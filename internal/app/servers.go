@@ -0,0 +1,83 @@
+package app
+
+import (
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// ServerListChanged tells the TUI that the tools, resources or prompts of
+// serverName changed. It is handed to the agent as its ListChangedFunc. The
+// agent itself sends the new tools to the model from its next step on; without
+// a registered program there is nothing else to update.
+func (a *App) ServerListChanged(serverName string, kind tools.ListKind) {
+	a.mu.Lock()
+	prog := a.program
+	a.mu.Unlock()
+	if prog == nil {
+		return
+	}
+	a.sendServerListChanged(func(msg tea.Msg) { prog.Send(msg) }, serverName, kind)
+}
+
+// ServerLog shows a log message of an MCP server under the running tool. It
+// is handed to the agent as its LogFunc; without a registered program the
+// message only goes to the debug log.
+func (a *App) ServerLog(serverName string, message tools.LogMessage) {
+	a.mu.Lock()
+	prog := a.program
+	a.mu.Unlock()
+	if prog == nil {
+		return
+	}
+	prog.Send(ServerLogEvent{
+		ServerName: serverName,
+		Level:      message.Level,
+		Logger:     message.Logger,
+		Message:    message.Message,
+	})
+}
+
+// ServerStatusChanged tells the TUI that an MCP server still connecting at
+// startup, or a lazy one started by a tool call, is ready or failed. It is
+// handed to the agent as its
+// ServerStatusFunc; without a registered program the startup screen already
+// showed what there was to show.
+func (a *App) ServerStatusChanged(status tools.ServerStatus) {
+	a.mu.Lock()
+	prog := a.program
+	a.mu.Unlock()
+	if prog == nil || status.State == tools.ServerConnecting {
+		return
+	}
+	a.sendServerStatus(func(msg tea.Msg) { prog.Send(msg) }, status)
+}
+
+// sendServerListChanged sends a ServerListChangedEvent with the agent's
+// current lists through send.
+func (a *App) sendServerListChanged(send func(tea.Msg), serverName string, kind tools.ListKind) {
+	evt := ServerListChangedEvent{ServerName: serverName, Kind: kind}
+	evt.ToolNames, evt.Resources, evt.Prompts = a.serverLists()
+	send(evt)
+}
+
+// sendServerStatus sends a ServerStatusEvent with the agent's current lists
+// through send.
+func (a *App) sendServerStatus(send func(tea.Msg), status tools.ServerStatus) {
+	evt := ServerStatusEvent{Status: status}
+	evt.ToolNames, evt.Resources, evt.Prompts = a.serverLists()
+	send(evt)
+}
+
+// serverLists returns the tool names, resources and prompts of the agent's
+// servers, or nils when the agent cannot list them.
+func (a *App) serverLists() (toolNames []string, resources []tools.Resource, prompts []tools.Prompt) {
+	lister, ok := a.opts.Agent.(ServerLister)
+	if !ok {
+		return nil, nil, nil
+	}
+	for _, tool := range lister.GetTools() {
+		toolNames = append(toolNames, tool.Info().Name)
+	}
+	return toolNames, lister.GetResources(), lister.GetPrompts()
+}
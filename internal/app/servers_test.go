@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// listerStubAgent is a stubAgent that lists tools, resources and prompts.
+type listerStubAgent struct {
+	*stubAgent
+}
+
+func (s *listerStubAgent) GetTools() []fantasy.AgentTool {
+	noop := func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.NewTextResponse(""), nil
+	}
+	return []fantasy.AgentTool{fantasy.NewAgentTool("deploy__status", "Deployment status", noop)}
+}
+
+func (s *listerStubAgent) GetResources() []tools.Resource {
+	return []tools.Resource{{Server: "deploy", URI: "deploy://log"}}
+}
+
+func (s *listerStubAgent) GetPrompts() []tools.Prompt {
+	return []tools.Prompt{{Server: "deploy", Name: "rollback"}}
+}
+
+func TestSendServerListChanged(t *testing.T) {
+	app := newTestApp(&listerStubAgent{stubAgent: newStubAgent()})
+	defer app.Close()
+
+	var evt ServerListChangedEvent
+	app.sendServerListChanged(func(msg tea.Msg) { evt = msg.(ServerListChangedEvent) }, "deploy", tools.ListTools)
+	if evt.ServerName != "deploy" || evt.Kind != tools.ListTools {
+		t.Fatalf("unexpected event %+v", evt)
+	}
+	if len(evt.ToolNames) != 1 || evt.ToolNames[0] != "deploy__status" || len(evt.Resources) != 1 || len(evt.Prompts) != 1 {
+		t.Errorf("expected the agent's lists, got %+v", evt)
+	}
+
+	plain := newTestApp(newStubAgent())
+	defer plain.Close()
+	plain.sendServerListChanged(func(msg tea.Msg) { evt = msg.(ServerListChangedEvent) }, "deploy", tools.ListPrompts)
+	if evt.Kind != tools.ListPrompts || evt.ToolNames != nil || evt.Prompts != nil {
+		t.Errorf("expected no lists from an agent that cannot list them, got %+v", evt)
+	}
+}
+
+func TestSendServerStatus(t *testing.T) {
+	app := newTestApp(&listerStubAgent{stubAgent: newStubAgent()})
+	defer app.Close()
+
+	var evt ServerStatusEvent
+	status := tools.ServerStatus{Name: "deploy", State: tools.ServerReady, Tools: 1}
+	app.sendServerStatus(func(msg tea.Msg) { evt = msg.(ServerStatusEvent) }, status)
+	if evt.Status != status {
+		t.Fatalf("expected the status %+v, got %+v", status, evt.Status)
+	}
+	if len(evt.ToolNames) != 1 || evt.ToolNames[0] != "deploy__status" || len(evt.Resources) != 1 || len(evt.Prompts) != 1 {
+		t.Errorf("expected the agent's lists, got %+v", evt)
+	}
+}
@@ -0,0 +1,304 @@
+// Package attachments turns files named on the command line (--attach) or
+// mentioned in a prompt (@path), and MCP resources mentioned as @server:uri,
+// into parts of a user message. Images and PDFs are sent to the model as
+// media; text is inlined into the prompt.
+package attachments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"charm.land/fantasy"
+)
+
+const (
+	// MaxMediaSize is the largest image or PDF that can be attached.
+	MaxMediaSize = 20 << 20
+	// MaxTextSize is the largest text file that can be inlined into a prompt.
+	MaxTextSize = 256 << 10
+)
+
+// ErrUnsupportedType is returned for files that are neither text nor a
+// supported media type.
+var ErrUnsupportedType = errors.New("unsupported attachment type")
+
+// mediaTypes are the media types sent to the model as files. Providers accept
+// images; OpenAI-style providers also accept PDFs.
+var mediaTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// Attachment is a file or MCP resource attached to a user message.
+type Attachment struct {
+	// Path is the absolute path of the file, or empty for a resource.
+	Path string
+	// Name is the path as the user gave it, or "server:uri" for a resource.
+	// It is used in headers and file names.
+	Name string
+	// MediaType is the detected media type, e.g. "image/png" or "text/plain".
+	MediaType string
+	// Data is the file content.
+	Data []byte
+}
+
+// IsMedia reports whether the attachment is sent to the model as a file
+// rather than inlined as text. Only models that accept attachments can take
+// media.
+func (a *Attachment) IsMedia() bool {
+	return mediaTypes[a.MediaType]
+}
+
+// Load reads the file at path and detects its type. Images and PDFs are
+// attached as media and valid UTF-8 files as text; anything else is rejected
+// with ErrUnsupportedType.
+func Load(path string) (*Attachment, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve attachment %s: %w", path, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("attachment %s is a directory", path)
+	}
+	if info.Size() > MaxMediaSize {
+		return nil, fmt.Errorf("attachment %s is too large (%d bytes, limit %d)", path, info.Size(), MaxMediaSize)
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	a, err := New(path, detectMediaType(absPath, data), data)
+	if err != nil {
+		return nil, err
+	}
+	a.Path = absPath
+	return a, nil
+}
+
+// New returns an attachment named name holding data that was not read from
+// a file, such as an MCP resource. Images and PDFs are attached as media and
+// valid UTF-8 data as text; anything else is rejected with
+// ErrUnsupportedType. An empty mediaType is detected from the data.
+func New(name, mediaType string, data []byte) (*Attachment, error) {
+	if mediaType == "" {
+		mediaType = detectMediaType(name, data)
+	}
+	a := &Attachment{Name: name, MediaType: mediaType, Data: data}
+	switch {
+	case a.IsMedia():
+		if len(data) > MaxMediaSize {
+			return nil, fmt.Errorf("attachment %s is too large (%d bytes, limit %d)", name, len(data), MaxMediaSize)
+		}
+	case utf8.Valid(data):
+		if len(data) > MaxTextSize {
+			return nil, fmt.Errorf("text attachment %s is too large (%d bytes, limit %d)", name, len(data), MaxTextSize)
+		}
+		a.MediaType = "text/plain"
+	default:
+		return nil, fmt.Errorf("%w: %s (%s)", ErrUnsupportedType, name, a.MediaType)
+	}
+	return a, nil
+}
+
+// LoadAll loads each of paths in order.
+func LoadAll(paths []string) ([]*Attachment, error) {
+	var atts []*Attachment
+	for _, path := range paths {
+		a, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, a)
+	}
+	return atts, nil
+}
+
+// detectMediaType sniffs the content of a file, falling back to its extension
+// when the content is not conclusive.
+func detectMediaType(path string, data []byte) string {
+	mediaType := http.DetectContentType(data)
+	if mediaType == "application/octet-stream" || strings.HasPrefix(mediaType, "text/plain") {
+		if byExt := mime.TypeByExtension(filepath.Ext(path)); byExt != "" {
+			mediaType = byExt
+		}
+	}
+	if base, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = base
+	}
+	return mediaType
+}
+
+// mentionPattern matches "@path" at the start of the prompt or after
+// whitespace, so e-mail addresses are left alone.
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// mentions returns what is mentioned as "@..." in prompt, in order and
+// without duplicates. Trailing punctuation is ignored ("see @notes.txt.").
+func mentions(prompt string) []string {
+	var mentioned []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(prompt, -1) {
+		m := strings.TrimRight(match[1], ".,;:!?)\"'")
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+		mentioned = append(mentioned, m)
+	}
+	return mentioned
+}
+
+// Mentions returns the paths mentioned as "@path" in prompt that name
+// existing files, in order and without duplicates. Trailing punctuation is
+// ignored ("see @notes.txt."). Mentions of anything else, such as "@alice",
+// are left alone.
+func Mentions(prompt string) []string {
+	var paths []string
+	for _, path := range mentions(prompt) {
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// ResourceMention is an MCP resource mentioned in a prompt as "@server:uri".
+type ResourceMention struct {
+	Server string
+	URI    string
+}
+
+// ResourceMentions returns the resources mentioned as "@server:uri" in
+// prompt, in order and without duplicates. Only mentions of one of servers
+// count, so that "@notes:todo" stays a file name when no server is called
+// "notes".
+func ResourceMentions(prompt string, servers []string) []ResourceMention {
+	var resources []ResourceMention
+	for _, m := range mentions(prompt) {
+		server, uri, ok := strings.Cut(m, ":")
+		if !ok || uri == "" || !slices.Contains(servers, server) {
+			continue
+		}
+		resources = append(resources, ResourceMention{Server: server, URI: uri})
+	}
+	return resources
+}
+
+// NewUserMessage builds a user message from prompt and atts. Text files and
+// resources are appended to the prompt in fenced blocks headed by their name;
+// media follows the text as file parts, files marked with their Source.
+func NewUserMessage(prompt string, atts []*Attachment) fantasy.Message {
+	var text strings.Builder
+	text.WriteString(prompt)
+	var files []fantasy.FilePart
+	for _, a := range atts {
+		if a.IsMedia() {
+			part := fantasy.FilePart{
+				Filename:  filepath.Base(a.Name),
+				Data:      a.Data,
+				MediaType: a.MediaType,
+			}
+			if a.Path != "" {
+				part.Filename = filepath.Base(a.Path)
+				part.ProviderOptions = fantasy.ProviderOptions{SourceKey: &Source{Path: a.Path}}
+			}
+			files = append(files, part)
+			continue
+		}
+		header := "File"
+		if a.Path == "" {
+			header = "Resource"
+		}
+		fence := fenceFor(string(a.Data))
+		fmt.Fprintf(&text, "\n\n%s: %s\n%s%s\n%s", header, a.Name, fence, strings.TrimPrefix(filepath.Ext(a.Name), "."), a.Data)
+		if !strings.HasSuffix(string(a.Data), "\n") {
+			text.WriteString("\n")
+		}
+		text.WriteString(fence)
+	}
+	return fantasy.NewUserMessage(text.String(), files...)
+}
+
+// fenceFor returns a code fence longer than any run of backticks in content.
+func fenceFor(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// SourceKey is the provider options key of Source. Providers only read their
+// own keys, so the option is never sent to a model.
+const SourceKey = "mcphost"
+
+// TypeSource identifies Source in fantasy's provider type registry.
+const TypeSource = SourceKey + ".attachment_source"
+
+func init() {
+	fantasy.RegisterProviderType(TypeSource, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v Source
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// Source records the file a media part was read from, so that sessions can
+// store large attachments by reference instead of inline.
+type Source struct {
+	Path string `json:"path"`
+}
+
+// Options implements fantasy.ProviderOptionsData.
+func (*Source) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for Source.
+func (s Source) MarshalJSON() ([]byte, error) {
+	type plain Source
+	return fantasy.MarshalProviderType(TypeSource, plain(s))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for Source.
+func (s *Source) UnmarshalJSON(data []byte) error {
+	type plain Source
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*s = Source(p)
+	return nil
+}
+
+// SourcePath returns the path a file part was read from, or "" when unknown.
+func SourcePath(part fantasy.FilePart) string {
+	if s, ok := part.ProviderOptions[SourceKey].(*Source); ok {
+		return s.Path
+	}
+	return ""
+}
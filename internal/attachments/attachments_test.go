@@ -0,0 +1,139 @@
+package attachments
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		data      []byte
+		mediaType string
+		media     bool
+	}{
+		{"chart.png", pngHeader, "image/png", true},
+		// Content wins over a misleading extension
+		{"photo.txt", pngHeader, "image/png", true},
+		{"main.go", []byte("package main\n"), "text/plain", false},
+		{"notes", []byte("no extension, still text"), "text/plain", false},
+	}
+	for _, tt := range tests {
+		a, err := Load(writeFile(t, dir, tt.name, tt.data))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if a.MediaType != tt.mediaType || a.IsMedia() != tt.media {
+			t.Errorf("%s: expected %s (media %v), got %s (media %v)", tt.name, tt.mediaType, tt.media, a.MediaType, a.IsMedia())
+		}
+		if !filepath.IsAbs(a.Path) {
+			t.Errorf("%s: expected an absolute path, got %q", tt.name, a.Path)
+		}
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(writeFile(t, dir, "data.bin", []byte{0x00, 0xff, 0xfe, 0x01})); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType for binary data, got %v", err)
+	}
+	if _, err := Load(writeFile(t, dir, "big.txt", []byte(strings.Repeat("a", MaxTextSize+1)))); err == nil {
+		t.Error("Expected an error for a text file over the limit")
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for a directory")
+	}
+	if _, err := Load(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestMentions(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFile(t, dir, "notes.txt", []byte("notes"))
+	writeFile(t, dir, "chart.png", pngHeader)
+
+	got := Mentions("@chart.png compare with @notes.txt, then @notes.txt. Ask @alice or mail me@example.com")
+	want := []string{"chart.png", "notes.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected mentions %v, got %v", want, got)
+	}
+}
+
+func TestNewUserMessage(t *testing.T) {
+	atts := []*Attachment{
+		{Path: "/work/chart.png", Name: "chart.png", MediaType: "image/png", Data: pngHeader},
+		{Path: "/work/README.md", Name: "README.md", MediaType: "text/plain", Data: []byte("Use ```go``` blocks")},
+	}
+	msg := NewUserMessage("Summarize these", atts)
+
+	if len(msg.Content) != 2 {
+		t.Fatalf("Expected a text part and a file part, got %d parts", len(msg.Content))
+	}
+	text, ok := msg.Content[0].(fantasy.TextPart)
+	if !ok {
+		t.Fatalf("Expected the text part first, got %T", msg.Content[0])
+	}
+	want := "Summarize these\n\nFile: README.md\n````md\nUse ```go``` blocks\n````"
+	if text.Text != want {
+		t.Errorf("Expected text %q, got %q", want, text.Text)
+	}
+
+	file, ok := msg.Content[1].(fantasy.FilePart)
+	if !ok {
+		t.Fatalf("Expected a file part, got %T", msg.Content[1])
+	}
+	if file.Filename != "chart.png" || file.MediaType != "image/png" {
+		t.Errorf("Unexpected file part %s (%s)", file.Filename, file.MediaType)
+	}
+	if got := SourcePath(file); got != "/work/chart.png" {
+		t.Errorf("Expected source path /work/chart.png, got %q", got)
+	}
+}
+
+func TestResourceMentions(t *testing.T) {
+	got := ResourceMentions("Compare @docs:file:///guide.md with @docs:file:///guide.md. and @notes:todo, ask @alice", []string{"docs", "db"})
+	want := []ResourceMention{{Server: "docs", URI: "file:///guide.md"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected resource mentions %v, got %v", want, got)
+	}
+}
+
+func TestNewResourceAttachment(t *testing.T) {
+	a, err := New("docs:file:///guide.md", "text/plain", []byte("# Guide"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg := NewUserMessage("Read this", []*Attachment{a})
+	want := "Read this\n\nResource: docs:file:///guide.md\n```md\n# Guide\n```"
+	if text, ok := msg.Content[0].(fantasy.TextPart); !ok || text.Text != want {
+		t.Errorf("Expected text %q, got %v", want, msg.Content[0])
+	}
+
+	if _, err := New("db:blob://1", "", []byte{0x00, 0x01, 0xff}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType for binary data, got %v", err)
+	}
+}
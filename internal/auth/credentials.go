@@ -10,9 +10,11 @@ import (
 )
 
 // CredentialStore holds all stored credentials for various providers.
-// Currently supports Anthropic credentials with both OAuth and API key authentication methods.
+// Currently supports Anthropic credentials with both OAuth and API key authentication methods,
+// and the OAuth tokens of remote MCP servers, by server name.
 type CredentialStore struct {
-	Anthropic *AnthropicCredentials `json:"anthropic,omitempty"`
+	Anthropic  *AnthropicCredentials            `json:"anthropic,omitempty"`
+	MCPServers map[string]*MCPServerCredentials `json:"mcp_servers,omitempty"`
 }
 
 // AnthropicCredentials holds Anthropic API credentials supporting both OAuth
@@ -177,9 +179,13 @@ func (cm *CredentialManager) RemoveAnthropicCredentials() error {
 	}
 
 	store.Anthropic = nil
+	return cm.saveOrRemove(store)
+}
 
-	// If store is empty, remove the file entirely
-	if store.Anthropic == nil {
+// saveOrRemove saves store, or removes the credentials file when store holds
+// no credentials.
+func (cm *CredentialManager) saveOrRemove(store *CredentialStore) error {
+	if store.Anthropic == nil && len(store.MCPServers) == 0 {
 		if err := os.Remove(cm.credentialsPath); err != nil && !os.IsNotExist(err) {
 			return fmt.Errorf("failed to remove credentials file: %w", err)
 		}
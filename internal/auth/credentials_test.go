@@ -250,3 +250,58 @@ func TestCredentialStorePersistence(t *testing.T) {
 		t.Errorf("Expected file permissions 0600, got %v", info.Mode().Perm())
 	}
 }
+
+func TestMCPServerCredentials(t *testing.T) {
+	cm := &CredentialManager{credentialsPath: filepath.Join(t.TempDir(), "credentials.json")}
+
+	creds, err := cm.GetMCPServerCredentials("calendar")
+	if err != nil {
+		t.Fatalf("GetMCPServerCredentials failed: %v", err)
+	}
+	if creds != nil {
+		t.Fatalf("Expected no credentials initially, got %+v", creds)
+	}
+
+	err = cm.UpdateMCPServerCredentials("calendar", func(creds *MCPServerCredentials) {
+		creds.ServerURL = "https://calendar.example.com/mcp"
+		creds.ClientID = "client"
+	})
+	if err != nil {
+		t.Fatalf("UpdateMCPServerCredentials failed: %v", err)
+	}
+	err = cm.UpdateMCPServerCredentials("calendar", func(creds *MCPServerCredentials) {
+		creds.AccessToken = "token"
+	})
+	if err != nil {
+		t.Fatalf("UpdateMCPServerCredentials failed: %v", err)
+	}
+
+	creds, err = cm.GetMCPServerCredentials("calendar")
+	if err != nil {
+		t.Fatalf("GetMCPServerCredentials failed: %v", err)
+	}
+	if creds == nil || creds.ClientID != "client" || creds.AccessToken != "token" {
+		t.Fatalf("Expected the registration and the token, got %+v", creds)
+	}
+	if creds.CreatedAt.IsZero() {
+		t.Error("Expected CreatedAt to be set")
+	}
+
+	// Removing the Anthropic credentials keeps those of the servers.
+	if err := cm.SetAnthropicCredentials("sk-ant-REDACTED"); err != nil {
+		t.Fatalf("SetAnthropicCredentials failed: %v", err)
+	}
+	if err := cm.RemoveAnthropicCredentials(); err != nil {
+		t.Fatalf("RemoveAnthropicCredentials failed: %v", err)
+	}
+	if creds, _ := cm.GetMCPServerCredentials("calendar"); creds == nil {
+		t.Fatal("Expected the server credentials to survive the Anthropic logout")
+	}
+
+	if err := cm.RemoveMCPServerCredentials("calendar"); err != nil {
+		t.Fatalf("RemoveMCPServerCredentials failed: %v", err)
+	}
+	if _, err := os.Stat(cm.credentialsPath); !os.IsNotExist(err) {
+		t.Error("Expected credentials file to be removed when empty")
+	}
+}
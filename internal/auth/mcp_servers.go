@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// mcpServersMu serializes the updates of MCP server credentials, which the
+// connections of several servers may refresh at the same time.
+var mcpServersMu sync.Mutex
+
+// MCPServerCredentials holds the OAuth client registration and tokens of a
+// remote MCP server. They belong to the server at ServerURL; when the
+// server's URL changes they no longer apply.
+type MCPServerCredentials struct {
+	ServerURL    string    `json:"server_url"`
+	ClientID     string    `json:"client_id,omitempty"`     // registered dynamically, unless configured
+	RedirectURI  string    `json:"redirect_uri,omitempty"`  // registered with ClientID
+	AccessToken  string    `json:"access_token,omitempty"`  // empty until authorized
+	TokenType    string    `json:"token_type,omitempty"`    // usually "Bearer"
+	RefreshToken string    `json:"refresh_token,omitempty"` // may be empty
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    int64     `json:"expires_at,omitempty"` // Unix time; 0 if the token does not expire
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// IsExpired checks if the access token is expired based on the ExpiresAt
+// timestamp. Returns false if no expiration is set.
+func (c *MCPServerCredentials) IsExpired() bool {
+	return c.ExpiresAt != 0 && time.Now().Unix() >= c.ExpiresAt
+}
+
+// GetMCPServerCredentials retrieves the stored credentials of the MCP server
+// named serverName. Returns nil if none are stored.
+func (cm *CredentialManager) GetMCPServerCredentials(serverName string) (*MCPServerCredentials, error) {
+	store, err := cm.LoadCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.MCPServers[serverName], nil
+}
+
+// UpdateMCPServerCredentials calls update with the stored credentials of the
+// MCP server named serverName, or new ones if none are stored, and saves
+// them. Concurrent updates do not overwrite each other.
+func (cm *CredentialManager) UpdateMCPServerCredentials(serverName string, update func(creds *MCPServerCredentials)) error {
+	mcpServersMu.Lock()
+	defer mcpServersMu.Unlock()
+
+	store, err := cm.LoadCredentials()
+	if err != nil {
+		return err
+	}
+
+	creds := store.MCPServers[serverName]
+	if creds == nil {
+		creds = &MCPServerCredentials{CreatedAt: time.Now()}
+	}
+	update(creds)
+
+	if store.MCPServers == nil {
+		store.MCPServers = make(map[string]*MCPServerCredentials)
+	}
+	store.MCPServers[serverName] = creds
+	return cm.SaveCredentials(store)
+}
+
+// RemoveMCPServerCredentials removes the stored credentials of the MCP server
+// named serverName. If these were the only credentials stored, the entire
+// credentials file is removed.
+func (cm *CredentialManager) RemoveMCPServerCredentials(serverName string) error {
+	mcpServersMu.Lock()
+	defer mcpServersMu.Unlock()
+
+	store, err := cm.LoadCredentials()
+	if err != nil {
+		return err
+	}
+
+	delete(store.MCPServers, serverName)
+	return cm.saveOrRemove(store)
+}
@@ -98,8 +98,10 @@ func executeBash(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToo
 	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Execute the command
+	// Execute the command; a timeout or cancellation kills it along with
+	// the processes it started
 	cmd := exec.CommandContext(cmdCtx, "bash", "-c", command)
+	setProcessGroup(cmd)
 
 	// Capture both stdout and stderr
 	output, err := cmd.CombinedOutput()
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package builtin
+
+import "os/exec"
+
+// setProcessGroup leaves cmd as it is: without process groups, cancelling
+// cmd kills the shell only.
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package builtin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// TestExecuteBash_cancelKillsProcessGroup verifies that cancelling a command
+// also kills the processes it started, which would otherwise keep its output
+// open until they exit.
+func TestExecuteBash_cancelKillsProcessGroup(t *testing.T) {
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "run_shell_cmd",
+			Arguments: map[string]any{
+				"command":     "sleep 30 & wait",
+				"description": "Wait for a background sleep",
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := executeBash(ctx, request); err != nil {
+		t.Fatalf("executeBash: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the background sleep killed on cancellation, the call took %v", elapsed)
+	}
+}
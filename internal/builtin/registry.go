@@ -32,12 +32,13 @@ func (w *BuiltinServerWrapper) GetServer() *server.MCPServer {
 // It provides a centralized registry for creating instances of builtin MCP servers
 // with their respective configurations.
 type Registry struct {
-	servers map[string]func(options map[string]any, model fantasy.LanguageModel) (*BuiltinServerWrapper, error)
+	servers    map[string]func(options map[string]any, model fantasy.LanguageModel) (*BuiltinServerWrapper, error)
+	taskRunner TaskRunner // runs sub-agents for the task server; set via SetTaskRunner
 }
 
 // NewRegistry creates a new builtin server registry with all available builtin
 // servers registered. The registry includes filesystem (fs), bash, todo, fetch,
-// and HTTP servers.
+// HTTP, and task servers.
 func NewRegistry() *Registry {
 	r := &Registry{
 		servers: make(map[string]func(options map[string]any, model fantasy.LanguageModel) (*BuiltinServerWrapper, error)),
@@ -48,10 +49,17 @@ func NewRegistry() *Registry {
 	r.registerTodoServer()
 	r.registerFetchServer()
 	r.registerHTTPServer()
+	r.registerTaskServer()
 
 	return r
 }
 
+// SetTaskRunner sets the function the task server uses to run sub-agents.
+// The task server cannot be created without one.
+func (r *Registry) SetTaskRunner(runner TaskRunner) {
+	r.taskRunner = runner
+}
+
 // CreateServer creates a new instance of a builtin server by name. The options
 // parameter provides server-specific configuration, and the model parameter provides
 // an optional LLM for AI-powered features. Returns an error if the server name
@@ -160,3 +168,20 @@ func (r *Registry) registerHTTPServer() {
 		return &BuiltinServerWrapper{server: server}, nil
 	}
 }
+
+// registerTaskServer registers the task (sub-agent) server
+func (r *Registry) registerTaskServer() {
+	r.servers["task"] = func(options map[string]any, model fantasy.LanguageModel) (*BuiltinServerWrapper, error) {
+		taskOptions, err := parseTaskOptions(options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task server options: %v", err)
+		}
+
+		server, err := NewTaskServer(r.taskRunner, taskOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create task server: %v", err)
+		}
+
+		return &BuiltinServerWrapper{server: server}, nil
+	}
+}
@@ -0,0 +1,161 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// TaskRequest describes one task delegated to a sub-agent through the task
+// tool. Description and Prompt come from the model's tool call; the remaining
+// fields come from the server's options in the config file.
+type TaskRequest struct {
+	// Description is a short label for the task, shown in the UI.
+	Description string
+	// Prompt is the full instructions for the sub-agent.
+	Prompt string
+	// SystemPrompt replaces the default sub-agent system prompt when set.
+	SystemPrompt string
+	// AllowedTools limits the sub-agent to tools whose prefixed names match
+	// one of these globs. Empty allows every tool except the task tool itself.
+	AllowedTools []string
+	// MaxSteps limits the sub-agent's tool-calling steps. Zero uses the
+	// runner's default.
+	MaxSteps int
+}
+
+// TaskRunner runs a sub-agent for a TaskRequest and returns its final answer.
+// The agent package provides the implementation; the builtin package only
+// exposes it as an MCP tool.
+type TaskRunner func(ctx context.Context, req TaskRequest) (string, error)
+
+// TaskOptions holds the task server's configuration.
+type TaskOptions struct {
+	SystemPrompt string
+	AllowedTools []string
+	MaxSteps     int
+}
+
+// TaskServer implements the task MCP server, which lets the model delegate
+// self-contained work to a sub-agent so that the intermediate tool calls stay
+// out of the main conversation.
+type TaskServer struct {
+	runner  TaskRunner
+	options TaskOptions
+}
+
+// NewTaskServer creates a new MCP server providing the "task" tool. Each call
+// runs a sub-agent through runner and returns only its final answer. Returns
+// an error if runner is nil.
+func NewTaskServer(runner TaskRunner, options TaskOptions) (*server.MCPServer, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("task server requires a sub-agent runner")
+	}
+
+	taskServer := &TaskServer{runner: runner, options: options}
+
+	s := server.NewMCPServer("task-server", "1.0.0", server.WithToolCapabilities(true))
+
+	taskTool := mcp.NewTool("task",
+		mcp.WithDescription(taskDescription),
+		mcp.WithString("description",
+			mcp.Required(),
+			mcp.Description("A short (3-5 word) description of the task"),
+		),
+		mcp.WithString("prompt",
+			mcp.Required(),
+			mcp.Description("Detailed, self-contained instructions for the sub-agent, including what to return"),
+		),
+	)
+
+	s.AddTool(taskTool, taskServer.executeTask)
+
+	return s, nil
+}
+
+// executeTask handles the task tool execution
+func (ts *TaskServer) executeTask(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	prompt, err := request.RequireString("prompt")
+	if err != nil || strings.TrimSpace(prompt) == "" {
+		return mcp.NewToolResultError("prompt is required"), nil
+	}
+
+	answer, err := ts.runner(ctx, TaskRequest{
+		Description:  request.GetString("description", ""),
+		Prompt:       prompt,
+		SystemPrompt: ts.options.SystemPrompt,
+		AllowedTools: ts.options.AllowedTools,
+		MaxSteps:     ts.options.MaxSteps,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("sub-agent failed: %v", err)), nil
+	}
+
+	if strings.TrimSpace(answer) == "" {
+		return mcp.NewToolResultText("The sub-agent finished without a final answer."), nil
+	}
+	return mcp.NewToolResultText(answer), nil
+}
+
+// parseTaskOptions reads the task server options from the config file.
+func parseTaskOptions(options map[string]any) (TaskOptions, error) {
+	var opts TaskOptions
+
+	if v, ok := options["system_prompt"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return opts, fmt.Errorf("system_prompt must be a string")
+		}
+		opts.SystemPrompt = s
+	}
+
+	if v, ok := options["max_steps"]; ok {
+		switch n := v.(type) {
+		case int:
+			opts.MaxSteps = n
+		case float64:
+			opts.MaxSteps = int(n)
+		default:
+			return opts, fmt.Errorf("max_steps must be a number")
+		}
+		if opts.MaxSteps < 0 {
+			return opts, fmt.Errorf("max_steps must not be negative")
+		}
+	}
+
+	if v, ok := options["allowed_tools"]; ok {
+		switch tools := v.(type) {
+		case []string:
+			opts.AllowedTools = tools
+		case []any:
+			for _, tool := range tools {
+				s, ok := tool.(string)
+				if !ok {
+					return opts, fmt.Errorf("allowed_tools must be an array of strings")
+				}
+				opts.AllowedTools = append(opts.AllowedTools, s)
+			}
+		case string:
+			opts.AllowedTools = []string{tools}
+		default:
+			return opts, fmt.Errorf("allowed_tools must be a string or array of strings")
+		}
+	}
+
+	return opts, nil
+}
+
+const taskDescription = `Delegate a self-contained task to a sub-agent that works on it independently and reports back.
+
+The sub-agent has its own context and can use tools (it cannot start further sub-agents). Only its final answer is returned to you; its intermediate tool calls and results are not added to your context. Use this tool for research-style work that takes many steps, such as searching a codebase, reading many files, or gathering information from several sources, when you only need the conclusion.
+
+Usage notes:
+- The sub-agent does not see this conversation. Put everything it needs into the prompt: the goal, relevant context, constraints, and exactly what information to return.
+- Ask for a concise, self-contained answer, since that answer is all you get back.
+- Do not use this tool for simple tasks you can do with one or two direct tool calls.`
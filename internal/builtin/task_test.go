@@ -0,0 +1,158 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestNewTaskServerRequiresRunner(t *testing.T) {
+	if _, err := NewTaskServer(nil, TaskOptions{}); err == nil {
+		t.Fatal("Expected an error without a runner")
+	}
+}
+
+func TestTaskServerRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	if !slices.Contains(registry.ListServers(), "task") {
+		t.Fatal("task server not found in registry")
+	}
+
+	// Without a runner the server cannot be created
+	if _, err := registry.CreateServer("task", map[string]any{}, nil); err == nil {
+		t.Error("Expected an error creating the task server without a runner")
+	}
+
+	registry.SetTaskRunner(func(ctx context.Context, req TaskRequest) (string, error) {
+		return "done", nil
+	})
+	wrapper, err := registry.CreateServer("task", map[string]any{"max_steps": 5}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create task server through registry: %v", err)
+	}
+	if wrapper.GetServer() == nil {
+		t.Fatal("Expected wrapped server to be non-nil")
+	}
+
+	if _, err := registry.CreateServer("task", map[string]any{"max_steps": "many"}, nil); err == nil {
+		t.Error("Expected an error for invalid max_steps")
+	}
+}
+
+func TestExecuteTask(t *testing.T) {
+	var got TaskRequest
+	server := &TaskServer{
+		runner: func(ctx context.Context, req TaskRequest) (string, error) {
+			got = req
+			return "The answer is 42.", nil
+		},
+		options: TaskOptions{
+			SystemPrompt: "You are a researcher.",
+			AllowedTools: []string{"fs__*"},
+			MaxSteps:     7,
+		},
+	}
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "task",
+			Arguments: map[string]any{
+				"description": "Find the answer",
+				"prompt":      "Find the answer to everything.",
+			},
+		},
+	}
+
+	result, err := server.executeTask(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Failed to execute task: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected success, got error result: %v", result.Content)
+	}
+	if text, ok := mcp.AsTextContent(result.Content[0]); !ok || text.Text != "The answer is 42." {
+		t.Errorf("Expected the sub-agent's answer, got %v", result.Content[0])
+	}
+
+	if got.Description != "Find the answer" || got.Prompt != "Find the answer to everything." {
+		t.Errorf("Unexpected request: %+v", got)
+	}
+	if got.SystemPrompt != "You are a researcher." || got.MaxSteps != 7 ||
+		!slices.Equal(got.AllowedTools, []string{"fs__*"}) {
+		t.Errorf("Expected server options in request, got %+v", got)
+	}
+}
+
+func TestExecuteTaskErrors(t *testing.T) {
+	server := &TaskServer{
+		runner: func(ctx context.Context, req TaskRequest) (string, error) {
+			return "", errors.New("model unavailable")
+		},
+	}
+
+	// Missing prompt
+	result, err := server.executeTask(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "task", Arguments: map[string]any{"description": "x"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("Expected an error result for a missing prompt")
+	}
+
+	// Runner failure is reported to the model
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "task", Arguments: map[string]any{"prompt": "do it"}},
+	}
+	result, err = server.executeTask(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("Expected an error result when the sub-agent fails")
+	}
+
+	// Cancellation is returned as an error rather than a tool result
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := server.executeTask(ctx, request); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseTaskOptions(t *testing.T) {
+	opts, err := parseTaskOptions(map[string]any{
+		"system_prompt": "Be brief.",
+		"max_steps":     float64(10),
+		"allowed_tools": []any{"fs__read_file", "fetch__*"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.SystemPrompt != "Be brief." || opts.MaxSteps != 10 ||
+		!slices.Equal(opts.AllowedTools, []string{"fs__read_file", "fetch__*"}) {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	opts, err = parseTaskOptions(map[string]any{"allowed_tools": "bash__*"})
+	if err != nil || !slices.Equal(opts.AllowedTools, []string{"bash__*"}) {
+		t.Errorf("Expected a single glob, got %+v (err %v)", opts, err)
+	}
+
+	invalid := []map[string]any{
+		{"system_prompt": 1},
+		{"max_steps": -1},
+		{"allowed_tools": []any{1}},
+		{"allowed_tools": 3},
+	}
+	for _, options := range invalid {
+		if _, err := parseTaskOptions(options); err == nil {
+			t.Errorf("Expected an error for %v", options)
+		}
+	}
+}
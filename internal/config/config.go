@@ -3,10 +3,13 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/mark3labs/mcphost/internal/permissions"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -23,6 +26,17 @@ type MCPServerConfig struct {
 	Options       map[string]any    `json:"options,omitempty"` // For builtin servers
 	AllowedTools  []string          `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
 	ExcludedTools []string          `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+	Sampling      *SamplingConfig   `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+	Roots         []string          `json:"roots,omitempty" yaml:"roots,omitempty"`       // directories or file:// URIs; default: the working directory
+	LogLevel      string            `json:"logLevel,omitempty" yaml:"logLevel,omitempty"` // minimum level of the server's log messages; see LogLevels
+	OAuth         *OAuthConfig      `json:"oauth,omitempty" yaml:"oauth,omitempty"`       // for remote servers; optional, see OAuthConfig
+	// StartupTimeout is how many seconds startup waits for the server
+	// before going on without it; 0 means the default. The server keeps
+	// connecting, and its tools are added once it is ready.
+	StartupTimeout int `json:"startupTimeout,omitempty" yaml:"startupTimeout,omitempty"`
+	// Lazy starts a local server only when one of its tools is first called.
+	// Until then, the tools it listed when last started are advertised.
+	Lazy bool `json:"lazy,omitempty" yaml:"lazy,omitempty"`
 
 	// Legacy fields for backward compatibility
 	Transport string         `json:"transport,omitempty"`
@@ -31,33 +45,103 @@ type MCPServerConfig struct {
 	Headers   []string       `json:"headers,omitempty"`
 }
 
+// Sampling approval modes of SamplingConfig.Approval.
+const (
+	SamplingApprovalAsk  = "ask"  // every request is approved by the user (default)
+	SamplingApprovalAuto = "auto" // requests run without asking
+	SamplingApprovalDeny = "deny" // sampling is not offered to the server
+)
+
+// LogLevels are the levels of MCPServerConfig.LogLevel, from the most
+// verbose to the most severe (the syslog severities used by MCP).
+var LogLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// DefaultSamplingRequestsPerMinute is the sampling rate limit of servers that
+// do not set MaxRequestsPerMinute.
+const DefaultSamplingRequestsPerMinute = 10
+
+// SamplingConfig controls how an MCP server may have the host's model generate
+// messages for it (sampling/createMessage requests).
+type SamplingConfig struct {
+	// Approval is one of "ask", "auto" or "deny". Empty means "ask".
+	Approval string `json:"approval,omitempty" yaml:"approval,omitempty"`
+	// MaxRequestsPerMinute limits the requests of the server; 0 means
+	// DefaultSamplingRequestsPerMinute.
+	MaxRequestsPerMinute int `json:"maxRequestsPerMinute,omitempty" yaml:"maxRequestsPerMinute,omitempty"`
+	// MaxTokens caps the maxTokens of the server's requests; 0 means no cap.
+	MaxTokens int `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+}
+
+// OAuthConfig sets up OAuth for a remote MCP server. Remote servers answering
+// 401 are authorized without it, by registering mcphost with their
+// authorization server; it is needed for servers that do not support dynamic
+// client registration or that require particular scopes.
+type OAuthConfig struct {
+	// ClientID and ClientSecret identify mcphost to the authorization server
+	// when it was registered beforehand; the secret is optional.
+	ClientID     string `json:"clientId,omitempty" yaml:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty" yaml:"clientSecret,omitempty"`
+	// Scopes are the scopes requested; empty requests the default scopes.
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// RedirectURI is the loopback URI the authorization server redirects the
+	// browser to, such as "http://127.0.0.1:8085/callback". Empty picks a
+	// free port.
+	RedirectURI string `json:"redirectUri,omitempty" yaml:"redirectUri,omitempty"`
+}
+
+// IsLoopbackURI reports whether uri is an http URI of this machine, which a
+// browser can redirect to and mcphost can listen on.
+func IsLoopbackURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	switch u.Hostname() {
+	case "127.0.0.1", "::1", "localhost":
+		return true
+	}
+	return false
+}
+
 // UnmarshalJSON handles both new and legacy config formats for backward compatibility.
 // New format uses "type" field with "local", "remote", or "builtin" values.
 // Legacy format uses "transport", "command", "args", and "env" fields.
 func (s *MCPServerConfig) UnmarshalJSON(data []byte) error {
 	// First try to unmarshal as the new format
 	type newFormat struct {
-		Type          string            `json:"type"`
-		Command       []string          `json:"command,omitempty"`
-		Environment   map[string]string `json:"environment,omitempty"`
-		URL           string            `json:"url,omitempty"`
-		Headers       []string          `json:"headers,omitempty"`
-		Name          string            `json:"name,omitempty"`
-		Options       map[string]any    `json:"options,omitempty"`
-		AllowedTools  []string          `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
-		ExcludedTools []string          `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+		Type           string            `json:"type"`
+		Command        []string          `json:"command,omitempty"`
+		Environment    map[string]string `json:"environment,omitempty"`
+		URL            string            `json:"url,omitempty"`
+		Headers        []string          `json:"headers,omitempty"`
+		Name           string            `json:"name,omitempty"`
+		Options        map[string]any    `json:"options,omitempty"`
+		AllowedTools   []string          `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
+		ExcludedTools  []string          `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+		Sampling       *SamplingConfig   `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+		Roots          []string          `json:"roots,omitempty" yaml:"roots,omitempty"`
+		LogLevel       string            `json:"logLevel,omitempty" yaml:"logLevel,omitempty"`
+		OAuth          *OAuthConfig      `json:"oauth,omitempty" yaml:"oauth,omitempty"`
+		StartupTimeout int               `json:"startupTimeout,omitempty" yaml:"startupTimeout,omitempty"`
+		Lazy           bool              `json:"lazy,omitempty" yaml:"lazy,omitempty"`
 	}
 
 	// Also try legacy format
 	type legacyFormat struct {
-		Transport     string         `json:"transport,omitempty"`
-		Command       string         `json:"command,omitempty"`
-		Args          []string       `json:"args,omitempty"`
-		Env           map[string]any `json:"env,omitempty"`
-		URL           string         `json:"url,omitempty"`
-		Headers       []string       `json:"headers,omitempty"`
-		AllowedTools  []string       `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
-		ExcludedTools []string       `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+		Transport      string          `json:"transport,omitempty"`
+		Command        string          `json:"command,omitempty"`
+		Args           []string        `json:"args,omitempty"`
+		Env            map[string]any  `json:"env,omitempty"`
+		URL            string          `json:"url,omitempty"`
+		Headers        []string        `json:"headers,omitempty"`
+		AllowedTools   []string        `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
+		ExcludedTools  []string        `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+		Sampling       *SamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+		Roots          []string        `json:"roots,omitempty" yaml:"roots,omitempty"`
+		LogLevel       string          `json:"logLevel,omitempty" yaml:"logLevel,omitempty"`
+		OAuth          *OAuthConfig    `json:"oauth,omitempty" yaml:"oauth,omitempty"`
+		StartupTimeout int             `json:"startupTimeout,omitempty" yaml:"startupTimeout,omitempty"`
+		Lazy           bool            `json:"lazy,omitempty" yaml:"lazy,omitempty"`
 	}
 
 	// Try new format first
@@ -72,6 +156,12 @@ func (s *MCPServerConfig) UnmarshalJSON(data []byte) error {
 		s.Options = newConfig.Options
 		s.AllowedTools = newConfig.AllowedTools
 		s.ExcludedTools = newConfig.ExcludedTools
+		s.Sampling = newConfig.Sampling
+		s.Roots = newConfig.Roots
+		s.LogLevel = newConfig.LogLevel
+		s.OAuth = newConfig.OAuth
+		s.StartupTimeout = newConfig.StartupTimeout
+		s.Lazy = newConfig.Lazy
 		return nil
 	}
 
@@ -92,6 +182,12 @@ func (s *MCPServerConfig) UnmarshalJSON(data []byte) error {
 	s.Headers = legacyConfig.Headers
 	s.AllowedTools = legacyConfig.AllowedTools
 	s.ExcludedTools = legacyConfig.ExcludedTools
+	s.Sampling = legacyConfig.Sampling
+	s.Roots = legacyConfig.Roots
+	s.LogLevel = legacyConfig.LogLevel
+	s.OAuth = legacyConfig.OAuth
+	s.StartupTimeout = legacyConfig.StartupTimeout
+	s.Lazy = legacyConfig.Lazy
 
 	// Infer type from legacy format for better compatibility
 	// Only set Type when it doesn't change existing transport behavior
@@ -175,6 +271,15 @@ type Config struct {
 
 	// TLS configuration
 	TLSSkipVerify bool `json:"tls-skip-verify,omitempty" yaml:"tls-skip-verify,omitempty"`
+
+	// JSON Schema file the final answer must match (non-interactive mode)
+	OutputSchema string `json:"output-schema,omitempty" yaml:"output-schema,omitempty"`
+
+	// File answering the elicitation requests of MCP servers (non-interactive mode)
+	ElicitationAnswers string `json:"elicitation-answers,omitempty" yaml:"elicitation-answers,omitempty"`
+
+	// Permission rules (allow / ask / deny) checked before every tool call
+	Permissions []permissions.Rule `json:"permissions,omitempty" yaml:"permissions,omitempty"`
 }
 
 // GetTransportType returns the transport type for the server config, mapping
@@ -218,8 +323,39 @@ func (c *Config) Validate() error {
 		if len(serverConfig.AllowedTools) > 0 && len(serverConfig.ExcludedTools) > 0 {
 			return fmt.Errorf("server %s: allowedTools and excludedTools are mutually exclusive", serverName)
 		}
+		if s := serverConfig.Sampling; s != nil {
+			switch s.Approval {
+			case "", SamplingApprovalAsk, SamplingApprovalAuto, SamplingApprovalDeny:
+			default:
+				return fmt.Errorf("server %s: invalid sampling approval '%s'. Supported values: ask, auto, deny", serverName, s.Approval)
+			}
+			if s.MaxRequestsPerMinute < 0 || s.MaxTokens < 0 {
+				return fmt.Errorf("server %s: sampling limits must not be negative", serverName)
+			}
+		}
+		if slices.Contains(serverConfig.Roots, "") {
+			return fmt.Errorf("server %s: roots must not be empty", serverName)
+		}
+		if level := serverConfig.LogLevel; level != "" && !slices.Contains(LogLevels, level) {
+			return fmt.Errorf("server %s: invalid log level '%s'. Supported values: %s", serverName, level, strings.Join(LogLevels, ", "))
+		}
+
+		if serverConfig.StartupTimeout < 0 {
+			return fmt.Errorf("server %s: startupTimeout must not be negative", serverName)
+		}
 
 		transport := serverConfig.GetTransportType()
+		if serverConfig.Lazy && transport != "stdio" {
+			return fmt.Errorf("server %s: lazy is only supported for local servers", serverName)
+		}
+		if o := serverConfig.OAuth; o != nil {
+			if transport != "sse" && transport != "streamable" {
+				return fmt.Errorf("server %s: oauth is only supported for remote servers", serverName)
+			}
+			if o.RedirectURI != "" && !IsLoopbackURI(o.RedirectURI) {
+				return fmt.Errorf("server %s: oauth redirectUri must be an http URI of 127.0.0.1, [::1] or localhost", serverName)
+			}
+		}
 		switch transport {
 		case "stdio":
 			// Check both new and legacy command formats
@@ -323,6 +459,10 @@ func createDefaultConfig(homeDir string) error {
 #     command: ["uvx", "mcp-server-sqlite", "--db-path", "/tmp/example.db"]
 #     environment:
 #       SQLITE_DEBUG: "1"
+#     # Directories the server may work in (MCP roots); default the working directory
+#     roots: ["/tmp"]
+#     # Only start the server when one of its tools is first called
+#     lazy: true
 #   
 #   # Builtin MCP servers - run in-process for optimal performance
 #   filesystem-builtin:
@@ -357,10 +497,30 @@ func createDefaultConfig(homeDir string) error {
 #   websearch:
 #     type: "remote"
 #     url: "https://api.example.com/mcp"
+#     # Only show the server's log messages from warnings up
+#     logLevel: "warning"
+#     # Start without the server after 5 seconds; its tools follow once ready
+#     startupTimeout: 5
 #   
 #   weather:
 #     type: "remote"
 #     url: "https://weather-mcp.example.com"
+#     # Let the server use the model (MCP sampling) without asking, at most
+#     # 5 times a minute and 1000 tokens per request
+#     sampling:
+#       approval: "auto"   # ask (default), auto or deny
+#       maxRequestsPerMinute: 5
+#       maxTokens: 1000
+#   
+#   # Servers answering 401 are authorized with OAuth in the browser; the
+#   # 'oauth' field is only needed for clients registered beforehand
+#   calendar:
+#     type: "remote"
+#     url: "https://calendar.example.com/mcp"
+#     oauth:
+#       clientId: "mcphost"
+#       scopes: ["calendar.read"]
+#       redirectUri: "http://127.0.0.1:8085/callback"
 #   
 #   # Legacy format still supported for backward compatibility:
 #   # legacy-server:
@@ -373,9 +533,27 @@ mcpServers:
 
 # Application settings (all optional)
 # model: "anthropic/claude-sonnet-4-5-20250929"  # Default model to use
+# fallback-models: ["openai/gpt-4o"]          # Models tried in order when the model keeps failing (rate limits, overload, server errors)
+# max-retries: 3                               # Retries of those errors per model before falling back (0 to disable)
+# thinking-budget: 8192                        # Tokens for extended thinking (Anthropic, Gemini, OpenRouter; 0 to disable)
+# reasoning-effort: "medium"                   # Reasoning effort: minimal, low, medium or high (OpenAI, OpenRouter)
+# prompt-cache: "auto"                         # Prompt caching for Anthropic models: off, auto or explicit
 # max-steps: 10                                # Maximum agent steps (0 for unlimited)
+# max-cost: 0.50                               # Most a prompt may cost in US dollars (0 for unlimited)
+# max-total-tokens: 200000                     # Most tokens a prompt may use (0 for unlimited)
+# max-session-cost: 5.00                       # Most the whole session may cost in US dollars (0 for unlimited)
+# max-session-tokens: 2000000                  # Most tokens the whole session may use (0 for unlimited)
+# budget-warning: 0.8                          # Share of a budget at which a warning is shown
+# tool-search: false                           # Send only search_tools, load_tool and pinned tools; the model loads the rest
+# pinned-tools: ["todo__*"]                    # Tools always sent in tool search mode (globs over prefixed names)
+# max-repeated-tool-calls: 3                   # Identical tool calls in a row treated as a loop (0 to disable)
+# resource-tool: false                         # Add a read_resource tool for the resources of MCP servers
+# output-schema: "/path/to/schema.json"       # JSON Schema the final answer must match (non-interactive mode)
+# elicitation-answers: "/path/to/answers.yml"  # Answers to the input requests of MCP servers (non-interactive mode)
 # debug: false                                 # Enable debug logging
 # system-prompt: "/path/to/system-prompt.txt" # System prompt text file
+# compaction-threshold: 0.8                    # Summarize older messages at this share of the context window (0 to disable)
+# compaction-keep-turns: 2                     # Recent turns kept verbatim when compacting
 
 # Model generation parameters (all optional)
 # max-tokens: 4096                             # Maximum tokens in response
@@ -593,3 +593,108 @@ func TestEnsureConfigExistsWhenFileExists(t *testing.T) {
 		t.Error("Existing config file was modified when it shouldn't have been")
 	}
 }
+
+func TestConfig_ValidateSampling(t *testing.T) {
+	var server MCPServerConfig
+	if err := json.Unmarshal([]byte(`{"type": "local", "command": ["echo"], "sampling": {"approval": "auto", "maxRequestsPerMinute": 5, "maxTokens": 2000}}`), &server); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if server.Sampling == nil || server.Sampling.Approval != SamplingApprovalAuto || server.Sampling.MaxRequestsPerMinute != 5 || server.Sampling.MaxTokens != 2000 {
+		t.Fatalf("Unexpected sampling config: %+v", server.Sampling)
+	}
+	config := &Config{MCPServers: map[string]MCPServerConfig{"server": server}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validation failed: %v", err)
+	}
+
+	server.Sampling = &SamplingConfig{Approval: "always"}
+	config.MCPServers["server"] = server
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for an unknown approval mode")
+	}
+}
+
+func TestConfig_ValidateLogLevel(t *testing.T) {
+	var server MCPServerConfig
+	if err := json.Unmarshal([]byte(`{"type": "local", "command": ["echo"], "logLevel": "warning"}`), &server); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if server.LogLevel != "warning" {
+		t.Fatalf("Expected log level warning, got %q", server.LogLevel)
+	}
+	config := &Config{MCPServers: map[string]MCPServerConfig{"server": server}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validation failed: %v", err)
+	}
+
+	server.LogLevel = "verbose"
+	config.MCPServers["server"] = server
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for an unknown log level")
+	}
+}
+
+func TestConfig_ValidateStartupTimeout(t *testing.T) {
+	var server MCPServerConfig
+	if err := json.Unmarshal([]byte(`{"type": "local", "command": ["echo"], "startupTimeout": 30}`), &server); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if server.StartupTimeout != 30 {
+		t.Fatalf("Expected startup timeout 30, got %d", server.StartupTimeout)
+	}
+	config := &Config{MCPServers: map[string]MCPServerConfig{"server": server}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validation failed: %v", err)
+	}
+
+	server.StartupTimeout = -1
+	config.MCPServers["server"] = server
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for a negative startup timeout")
+	}
+}
+
+func TestConfig_ValidateLazy(t *testing.T) {
+	var server MCPServerConfig
+	if err := json.Unmarshal([]byte(`{"type": "local", "command": ["docker", "run", "browser"], "lazy": true}`), &server); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if !server.Lazy {
+		t.Fatal("Expected a lazy server")
+	}
+	config := &Config{MCPServers: map[string]MCPServerConfig{"browser": server}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validation failed: %v", err)
+	}
+
+	config.MCPServers["browser"] = MCPServerConfig{Type: "remote", URL: "https://example.com/mcp", Lazy: true}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for a lazy remote server")
+	}
+}
+
+func TestConfig_ValidateOAuth(t *testing.T) {
+	var server MCPServerConfig
+	data := `{"type": "remote", "url": "https://example.com/mcp", "oauth": {"clientId": "mcphost", "scopes": ["read"], "redirectUri": "http://127.0.0.1:8085/callback"}}`
+	if err := json.Unmarshal([]byte(data), &server); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if server.OAuth == nil || server.OAuth.ClientID != "mcphost" || len(server.OAuth.Scopes) != 1 {
+		t.Fatalf("Expected the oauth settings, got %+v", server.OAuth)
+	}
+	config := &Config{MCPServers: map[string]MCPServerConfig{"server": server}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validation failed: %v", err)
+	}
+
+	server.OAuth.RedirectURI = "https://example.com/callback"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for a redirect URI that is not a loopback URI")
+	}
+
+	local := MCPServerConfig{Type: "local", Command: []string{"echo"}, OAuth: &OAuthConfig{}}
+	config.MCPServers["server"] = local
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for oauth on a local server")
+	}
+}
@@ -51,7 +51,7 @@ type StopInput struct {
 	CommonInput
 	StopHookActive bool            `json:"stop_hook_active"`
 	Response       string          `json:"response"`       // The agent's final response
-	StopReason     string          `json:"stop_reason"`    // "completed", "cancelled", "error"
+	StopReason     string          `json:"stop_reason"`    // "completed", "cancelled", "error", "budget_exceeded", "tool_loop", "context_full"
 	Meta           json.RawMessage `json:"meta,omitempty"` // Additional metadata (e.g., token usage, model info)
 }
 
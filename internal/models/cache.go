@@ -17,11 +17,12 @@ type cacheEnvelope struct {
 	Providers map[string]modelsDBProvider `json:"providers"`
 }
 
-// dataDir returns the mcphost data directory following XDG Base Directory spec.
+// DataDir returns the mcphost data directory following XDG Base Directory spec.
+// It holds caches, such as the provider data and the tools of lazy MCP servers.
 //
 //	Linux/macOS: $XDG_DATA_HOME/mcphost  (default ~/.local/share/mcphost)
 //	Windows:     %LOCALAPPDATA%/mcphost
-func dataDir() (string, error) {
+func DataDir() (string, error) {
 	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
 		return filepath.Join(xdg, "mcphost"), nil
 	}
@@ -41,7 +42,7 @@ func dataDir() (string, error) {
 
 // cachePath returns the full path to the cache file.
 func cachePath() (string, error) {
-	dir, err := dataDir()
+	dir, err := DataDir()
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+)
+
+// Prompt cache modes, the accepted values of ProviderConfig.PromptCache.
+const (
+	// PromptCacheOff sets no cache breakpoints.
+	PromptCacheOff = "off"
+	// PromptCacheAuto caches the tool definitions and the system prompt, and
+	// keeps a rolling breakpoint on the latest message so each step of the
+	// agent loop reads the conversation so far from the cache.
+	PromptCacheAuto = "auto"
+	// PromptCacheExplicit only caches the tool definitions and the system
+	// prompt, which stay the same for the whole session.
+	PromptCacheExplicit = "explicit"
+)
+
+// PromptCacheModes lists the accepted values of ProviderConfig.PromptCache.
+var PromptCacheModes = []string{PromptCacheOff, PromptCacheAuto, PromptCacheExplicit}
+
+// anthropicProviders serve Claude models through Anthropic's Messages API.
+var anthropicProviders = map[string]bool{
+	"anthropic":               true,
+	"bedrock":                 true,
+	"google-vertex-anthropic": true,
+}
+
+// IsAnthropicProvider reports whether provider serves Claude models through
+// Anthropic's Messages API. These providers take cache breakpoints, and the
+// input tokens they report leave out tokens read from or written to the cache.
+func IsAnthropicProvider(provider string) bool {
+	return anthropicProviders[provider]
+}
+
+// PromptCacheMode returns the prompt cache mode configured in config, which
+// defaults to PromptCacheAuto. It is PromptCacheOff when neither the model nor
+// any of its fallback models is served by an Anthropic provider, as no other
+// provider takes cache breakpoints.
+func PromptCacheMode(config *ProviderConfig) (string, error) {
+	mode := strings.ToLower(config.PromptCache)
+	switch mode {
+	case "":
+		mode = PromptCacheAuto
+	case PromptCacheOff, PromptCacheAuto, PromptCacheExplicit:
+	default:
+		return "", fmt.Errorf("invalid prompt cache mode %q (expected one of: %s)",
+			config.PromptCache, strings.Join(PromptCacheModes, ", "))
+	}
+	if mode == PromptCacheOff {
+		return mode, nil
+	}
+
+	for _, modelString := range append([]string{config.ModelString}, config.FallbackModels...) {
+		if provider, _, err := ParseModelString(modelString); err == nil && IsAnthropicProvider(provider) {
+			return mode, nil
+		}
+	}
+	return PromptCacheOff, nil
+}
+
+// CacheBreakpoint returns the provider options that end a cached prefix at
+// the message, message part or tool they are set on. Anthropic allows four
+// breakpoints per request. Other providers ignore them.
+func CacheBreakpoint() fantasy.ProviderOptions {
+	return anthropic.NewProviderCacheControlOptions(&anthropic.ProviderCacheControlOptions{
+		CacheControl: anthropic.CacheControl{Type: "ephemeral"},
+	})
+}
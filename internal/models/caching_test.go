@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"charm.land/fantasy/providers/anthropic"
+)
+
+func TestPromptCacheMode(t *testing.T) {
+	tests := []struct {
+		config *ProviderConfig
+		want   string
+	}{
+		{&ProviderConfig{ModelString: "anthropic/claude-sonnet-4-5"}, PromptCacheAuto},
+		{&ProviderConfig{ModelString: "bedrock/anthropic.claude-sonnet-4-5", PromptCache: "Explicit"}, PromptCacheExplicit},
+		{&ProviderConfig{ModelString: "anthropic/claude-sonnet-4-5", PromptCache: "off"}, PromptCacheOff},
+		// Other providers take no cache breakpoints
+		{&ProviderConfig{ModelString: "openai/gpt-4o", PromptCache: "auto"}, PromptCacheOff},
+		// unless a fallback model is served by Anthropic
+		{&ProviderConfig{ModelString: "openai/gpt-4o", FallbackModels: []string{"google-vertex-anthropic/claude-sonnet-4-5"}}, PromptCacheAuto},
+	}
+	for _, tt := range tests {
+		got, err := PromptCacheMode(tt.config)
+		if err != nil {
+			t.Errorf("%+v: unexpected error: %v", *tt.config, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%+v: expected %q, got %q", *tt.config, tt.want, got)
+		}
+	}
+
+	if _, err := PromptCacheMode(&ProviderConfig{ModelString: "anthropic/claude-sonnet-4-5", PromptCache: "always"}); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestCacheBreakpoint(t *testing.T) {
+	cacheControl := anthropic.GetCacheControl(CacheBreakpoint())
+	if cacheControl == nil || cacheControl.Type != "ephemeral" {
+		t.Errorf("Expected an ephemeral cache control, got %#v", cacheControl)
+	}
+}
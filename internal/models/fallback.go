@@ -0,0 +1,413 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// RetryPolicy controls how FallbackModel retries transient provider errors
+// (rate limits, overload and server errors) before moving to the next model.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries per model. 0 disables retrying;
+	// the next model is tried right away.
+	MaxRetries int
+	// InitialDelay is the wait before the first retry.
+	InitialDelay time.Duration
+	// BackoffFactor multiplies the delay after every retry.
+	BackoffFactor float64
+	// MaxDelay caps the backoff delay. A model whose Retry-After header asks
+	// for a longer wait is given up on instead.
+	MaxDelay time.Duration
+	// Cooldown is how long a model that exhausted its retries is skipped by
+	// later calls, which start with the next model in the chain instead.
+	Cooldown time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by CreateProvider.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:    3,
+		InitialDelay:  time.Second,
+		BackoffFactor: 2,
+		MaxDelay:      30 * time.Second,
+		Cooldown:      time.Minute,
+	}
+}
+
+// NamedModel is one entry of a fallback chain.
+type NamedModel struct {
+	// Name identifies the model in notifications, e.g. "openai/gpt-4o".
+	Name  string
+	Model fantasy.LanguageModel
+}
+
+// FallbackObserver receives notifications about retries and fallbacks of a
+// FallbackModel call. Attach it to the call's context with
+// WithFallbackObserver. Any func may be nil.
+type FallbackObserver struct {
+	// OnRetry is called before waiting delay to retry model after err.
+	// attempt counts the retries of model, starting at 1.
+	OnRetry func(model string, attempt int, delay time.Duration, err error)
+	// OnFallback is called when from gave up after err and to is tried next.
+	OnFallback func(from, to string, err error)
+	// OnAnswered is called when model, which is not primary, answered the
+	// call: after a fallback, or because primary is cooling down since an
+	// earlier call. A stream counts as answered once its output starts.
+	OnAnswered func(model, primary string)
+}
+
+type fallbackObserverKey struct{}
+
+// WithFallbackObserver returns a context that reports the retries and
+// fallbacks of model calls made with it to o.
+func WithFallbackObserver(ctx context.Context, o FallbackObserver) context.Context {
+	return context.WithValue(ctx, fallbackObserverKey{}, o)
+}
+
+// fallbackObserverFrom returns the observer set with WithFallbackObserver.
+func fallbackObserverFrom(ctx context.Context) FallbackObserver {
+	o, _ := ctx.Value(fallbackObserverKey{}).(FallbackObserver)
+	return o
+}
+
+// FallbackModel is a fantasy.LanguageModel over a chain of models. Each call
+// goes to the first model in the chain that is not cooling down. Transient
+// errors are retried with exponential backoff, honouring the provider's
+// Retry-After header, and once a model's retries are exhausted the call moves
+// to the next model. Other errors are returned as they are.
+//
+// A stream is only retried while it has not produced any output; an error
+// after that is passed through to the caller.
+//
+// Each model is sent the media tool results it accepts, and the text of the
+// others, so media can be sent when any model of the chain accepts it.
+type FallbackModel struct {
+	chain  []NamedModel
+	policy RetryPolicy
+
+	// cooldowns is shared with the chains Prefer returns; slots holds the
+	// index of each chain entry in it.
+	cooldowns *cooldowns
+	slots     []int
+
+	// sleep waits for d or until ctx is done; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewFallbackModel creates a FallbackModel trying chain in order. chain must
+// not be empty; its first entry is the primary model.
+func NewFallbackModel(policy RetryPolicy, chain ...NamedModel) *FallbackModel {
+	slots := make([]int, len(chain))
+	for i := range slots {
+		slots[i] = i
+	}
+	return &FallbackModel{
+		chain:     chain,
+		policy:    policy,
+		cooldowns: &cooldowns{until: make([]time.Time, len(chain))},
+		slots:     slots,
+		sleep:     sleepContext,
+	}
+}
+
+// cooldowns records until when the models of a chain are skipped.
+type cooldowns struct {
+	mu    sync.Mutex
+	until []time.Time // zero when available
+}
+
+// Provider returns the primary model's provider.
+func (m *FallbackModel) Provider() string {
+	return m.chain[0].Model.Provider()
+}
+
+// Model returns the primary model's ID.
+func (m *FallbackModel) Model() string {
+	return m.chain[0].Model.Model()
+}
+
+// Prefer returns a FallbackModel over the same chain that starts with the
+// first model matching one of hints, tried in order. A hint matches a model
+// when it is a substring of its name or ID, like the model hints of MCP
+// sampling requests. m itself is returned when no hint matches. The returned
+// model shares m's cooldowns, so a model cooling down is skipped by both.
+func (m *FallbackModel) Prefer(hints ...string) *FallbackModel {
+	for _, hint := range hints {
+		if hint == "" {
+			continue
+		}
+		for i, entry := range m.chain {
+			if !strings.Contains(entry.Name, hint) && !strings.Contains(entry.Model.Model(), hint) {
+				continue
+			}
+			if i == 0 {
+				return m
+			}
+			return &FallbackModel{
+				chain:     moveToFront(m.chain, i),
+				policy:    m.policy,
+				cooldowns: m.cooldowns,
+				slots:     moveToFront(m.slots, i),
+				sleep:     m.sleep,
+			}
+		}
+	}
+	return m
+}
+
+// moveToFront returns a copy of s with its element at index i moved first.
+func moveToFront[T any](s []T, i int) []T {
+	moved := append([]T{s[i]}, s[:i]...)
+	return append(moved, s[i+1:]...)
+}
+
+// Generate implements fantasy.LanguageModel.
+func (m *FallbackModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return withFallback(ctx, m, func(model fantasy.LanguageModel, _ func()) (*fantasy.Response, error) {
+		call := call
+		call.Prompt = textToolResults(model, call.Prompt)
+		return model.Generate(ctx, call)
+	})
+}
+
+// GenerateObject implements fantasy.LanguageModel.
+func (m *FallbackModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return withFallback(ctx, m, func(model fantasy.LanguageModel, _ func()) (*fantasy.ObjectResponse, error) {
+		call := call
+		call.Prompt = textToolResults(model, call.Prompt)
+		return model.GenerateObject(ctx, call)
+	})
+}
+
+// StreamObject implements fantasy.LanguageModel. Only errors creating the
+// stream are retried.
+func (m *FallbackModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return withFallback(ctx, m, func(model fantasy.LanguageModel, _ func()) (fantasy.ObjectStreamResponse, error) {
+		call := call
+		call.Prompt = textToolResults(model, call.Prompt)
+		return model.StreamObject(ctx, call)
+	})
+}
+
+// Stream implements fantasy.LanguageModel. Errors are retried until the
+// first part other than warnings has been passed on; warnings are held back
+// until then so that a retried stream does not repeat them.
+func (m *FallbackModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	return func(yield func(fantasy.StreamPart) bool) {
+		_, err := withFallback(ctx, m, func(model fantasy.LanguageModel, answering func()) (struct{}, error) {
+			call := call
+			call.Prompt = textToolResults(model, call.Prompt)
+			stream, err := model.Stream(ctx, call)
+			if err != nil {
+				return struct{}{}, err
+			}
+
+			var held []fantasy.StreamPart
+			started := false
+			for part := range stream {
+				if !started {
+					if part.Type == fantasy.StreamPartTypeError {
+						return struct{}{}, part.Error
+					}
+					if part.Type == fantasy.StreamPartTypeWarnings {
+						held = append(held, part)
+						continue
+					}
+					started = true
+					answering()
+					for _, w := range held {
+						if !yield(w) {
+							return struct{}{}, nil
+						}
+					}
+				}
+				if !yield(part) {
+					return struct{}{}, nil
+				}
+			}
+			// A stream of nothing but warnings still passes them on
+			if !started {
+				for _, w := range held {
+					if !yield(w) {
+						break
+					}
+				}
+			}
+			return struct{}{}, nil
+		})
+		if err != nil {
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: err})
+		}
+	}, nil
+}
+
+// withFallback runs call against the models of m's chain as described on
+// FallbackModel and returns the first successful result. call may report
+// that its model is answering before it returns, as streams do once their
+// output starts; the observer's OnAnswered is told once either way.
+func withFallback[T any](ctx context.Context, m *FallbackModel, call func(model fantasy.LanguageModel, answering func()) (T, error)) (T, error) {
+	observer := fallbackObserverFrom(ctx)
+
+	var zero T
+	for i := m.firstAvailable(); ; {
+		entry := m.chain[i]
+		answering := sync.OnceFunc(func() {
+			if i > 0 && observer.OnAnswered != nil {
+				observer.OnAnswered(entry.Name, m.chain[0].Name)
+			}
+		})
+		var err error
+		for attempt := 0; ; attempt++ {
+			var result T
+			result, err = call(entry.Model, answering)
+			if err == nil {
+				answering()
+				return result, nil
+			}
+			if ctx.Err() != nil || !IsTransientError(err) {
+				return zero, err
+			}
+			if attempt >= m.policy.MaxRetries {
+				break
+			}
+			delay, ok := m.retryDelay(err, attempt)
+			if !ok {
+				break
+			}
+			if observer.OnRetry != nil {
+				observer.OnRetry(entry.Name, attempt+1, delay, err)
+			}
+			if err := m.sleep(ctx, delay); err != nil {
+				return zero, err
+			}
+		}
+
+		m.coolDown(i)
+		if i+1 >= len(m.chain) {
+			return zero, err
+		}
+		if observer.OnFallback != nil {
+			observer.OnFallback(entry.Name, m.chain[i+1].Name, err)
+		}
+		i++
+	}
+}
+
+// firstAvailable returns the index of the first model that is not cooling
+// down, or 0 when all of them are.
+func (m *FallbackModel) firstAvailable() int {
+	m.cooldowns.mu.Lock()
+	defer m.cooldowns.mu.Unlock()
+	now := time.Now()
+	for i, slot := range m.slots {
+		if now.After(m.cooldowns.until[slot]) {
+			return i
+		}
+	}
+	return 0
+}
+
+// coolDown makes later calls skip the model at index i for the cooldown.
+func (m *FallbackModel) coolDown(i int) {
+	if m.policy.Cooldown <= 0 {
+		return
+	}
+	m.cooldowns.mu.Lock()
+	m.cooldowns.until[m.slots[i]] = time.Now().Add(m.policy.Cooldown)
+	m.cooldowns.mu.Unlock()
+}
+
+// retryDelay returns how long to wait before retry number attempt+1 after
+// err. A delay requested by the provider takes precedence over the backoff;
+// ok is false when it is longer than the policy's MaxDelay.
+func (m *FallbackModel) retryDelay(err error, attempt int) (delay time.Duration, ok bool) {
+	if after, found := retryAfter(err); found {
+		if m.policy.MaxDelay > 0 && after > m.policy.MaxDelay {
+			return 0, false
+		}
+		return after, true
+	}
+
+	delay = time.Duration(float64(m.policy.InitialDelay) * math.Pow(m.policy.BackoffFactor, float64(attempt)))
+	if m.policy.MaxDelay > 0 && delay > m.policy.MaxDelay {
+		delay = m.policy.MaxDelay
+	}
+	return delay, true
+}
+
+// IsTransientError reports whether err is worth retrying: a request timeout,
+// conflict, rate limit (429), server error (5xx, including Anthropic's 529
+// "overloaded"), or an overload error reported in the middle of a stream.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var providerErr *fantasy.ProviderError
+	if errors.As(err, &providerErr) && providerErr.StatusCode != 0 {
+		switch code := providerErr.StatusCode; {
+		case code == http.StatusRequestTimeout, code == http.StatusConflict, code == http.StatusTooManyRequests:
+			return true
+		case code >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "overloaded") || strings.Contains(msg, "rate limit")
+}
+
+// retryAfter returns the delay requested by the Retry-After (or the more
+// precise retry-after-ms) header of a provider error.
+func retryAfter(err error) (time.Duration, bool) {
+	var providerErr *fantasy.ProviderError
+	if !errors.As(err, &providerErr) {
+		return 0, false
+	}
+
+	var afterMs, after string
+	for name, value := range providerErr.ResponseHeaders {
+		switch strings.ToLower(name) {
+		case "retry-after-ms":
+			afterMs = value
+		case "retry-after":
+			after = value
+		}
+	}
+
+	if ms, err := strconv.ParseFloat(afterMs, 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	if after == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(after, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(after); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
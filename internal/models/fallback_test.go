@@ -0,0 +1,464 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// stubModel is a fantasy.LanguageModel whose Generate and Stream results are
+// scripted per call.
+type stubModel struct {
+	name     string
+	provider string  // "stub" when empty
+	errs     []error // error returned by the n-th call; nil or missing = success
+	calls    int
+	prompts  []fantasy.Prompt // prompt of each Generate call
+	warnings bool             // Stream yields a warnings part before the error or text
+}
+
+func (s *stubModel) nextErr() error {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return s.errs[s.calls-1]
+	}
+	return nil
+}
+
+func (s *stubModel) Generate(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	s.prompts = append(s.prompts, call.Prompt)
+	if err := s.nextErr(); err != nil {
+		return nil, err
+	}
+	return &fantasy.Response{Content: fantasy.ResponseContent{fantasy.TextContent{Text: s.name}}}, nil
+}
+
+func (s *stubModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	err := s.nextErr()
+	return func(yield func(fantasy.StreamPart) bool) {
+		if s.warnings && !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeWarnings}) {
+			return
+		}
+		if err != nil {
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: err})
+			return
+		}
+		if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, Delta: s.name}) {
+			return
+		}
+		yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish})
+	}, nil
+}
+
+func (s *stubModel) GenerateObject(context.Context, fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubModel) Provider() string {
+	if s.provider == "" {
+		return "stub"
+	}
+	return s.provider
+}
+
+func (s *stubModel) Model() string { return s.name }
+
+func statusErr(code int, headers map[string]string) error {
+	return &fantasy.ProviderError{Message: http.StatusText(code), StatusCode: code, ResponseHeaders: headers}
+}
+
+// newTestFallbackModel returns a FallbackModel over models that records its
+// sleeps instead of waiting.
+func newTestFallbackModel(policy RetryPolicy, models ...*stubModel) (*FallbackModel, *[]time.Duration) {
+	var chain []NamedModel
+	for _, m := range models {
+		chain = append(chain, NamedModel{Name: "stub/" + m.name, Model: m})
+	}
+	fm := NewFallbackModel(policy, chain...)
+	var slept []time.Duration
+	fm.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return fm, &slept
+}
+
+func TestFallbackModel_RetriesThenFallsBack(t *testing.T) {
+	primary := &stubModel{name: "primary", errs: []error{
+		statusErr(http.StatusTooManyRequests, nil),
+		statusErr(529, nil),
+		statusErr(http.StatusServiceUnavailable, nil),
+	}}
+	fallback := &stubModel{name: "fallback"}
+	fm, slept := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+	fm.policy.MaxRetries = 2
+
+	var retries []int
+	var from, to string
+	var fallbacks int
+	var answered []string
+	ctx := WithFallbackObserver(context.Background(), FallbackObserver{
+		OnRetry: func(model string, attempt int, delay time.Duration, err error) {
+			retries = append(retries, attempt)
+		},
+		OnFallback: func(f, t string, err error) {
+			from, to = f, t
+			fallbacks++
+		},
+		OnAnswered: func(model, primary string) {
+			answered = append(answered, model+" for "+primary)
+		},
+	})
+
+	resp, err := fm.Generate(ctx, fantasy.Call{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got := resp.Content.Text(); got != "fallback" {
+		t.Errorf("Expected the fallback to answer, got %q", got)
+	}
+	if primary.calls != 3 || fallback.calls != 1 {
+		t.Errorf("Expected 3 primary and 1 fallback calls, got %d and %d", primary.calls, fallback.calls)
+	}
+	if len(retries) != 2 || retries[0] != 1 || retries[1] != 2 {
+		t.Errorf("Expected retries 1 and 2, got %v", retries)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; len(*slept) != 2 || (*slept)[0] != want[0] || (*slept)[1] != want[1] {
+		t.Errorf("Expected backoff %v, got %v", want, *slept)
+	}
+	if from != "stub/primary" || to != "stub/fallback" {
+		t.Errorf("Expected fallback from primary to fallback, got %q -> %q", from, to)
+	}
+	if len(answered) != 1 || answered[0] != "stub/fallback for stub/primary" {
+		t.Errorf("Expected the fallback to be announced as answering, got %v", answered)
+	}
+
+	// The primary is cooling down, so the next call goes to the fallback
+	// directly: there is no fallback to report, but who answered still is.
+	if _, err := fm.Generate(ctx, fantasy.Call{}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if primary.calls != 3 || fallback.calls != 2 {
+		t.Errorf("Expected the primary to be skipped, got %d and %d calls", primary.calls, fallback.calls)
+	}
+	if fallbacks != 1 {
+		t.Errorf("Expected no second fallback, got %d", fallbacks)
+	}
+	if len(answered) != 2 || answered[1] != "stub/fallback for stub/primary" {
+		t.Errorf("Expected the fallback to be announced after the cooldown, got %v", answered)
+	}
+}
+
+func TestFallbackModel_PrimaryAnswersSilently(t *testing.T) {
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), &stubModel{name: "primary"}, &stubModel{name: "fallback"})
+	ctx := WithFallbackObserver(context.Background(), FallbackObserver{
+		OnAnswered: func(model, _ string) { t.Errorf("Expected no notice when the primary answers, got %s", model) },
+	})
+	if _, err := fm.Generate(ctx, fantasy.Call{}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+}
+
+func TestFallbackModel_NonTransientError(t *testing.T) {
+	primary := &stubModel{name: "primary", errs: []error{statusErr(http.StatusUnauthorized, nil)}}
+	fallback := &stubModel{name: "fallback"}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+
+	if _, err := fm.Generate(context.Background(), fantasy.Call{}); err == nil {
+		t.Fatal("Expected the error to be returned")
+	}
+	if primary.calls != 1 || fallback.calls != 0 {
+		t.Errorf("Expected no retry or fallback, got %d and %d calls", primary.calls, fallback.calls)
+	}
+}
+
+func TestFallbackModel_RetryAfter(t *testing.T) {
+	primary := &stubModel{name: "primary", errs: []error{
+		statusErr(http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}),
+		statusErr(http.StatusTooManyRequests, map[string]string{"Retry-After": "120"}),
+	}}
+	fallback := &stubModel{name: "fallback"}
+	fm, slept := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+
+	resp, err := fm.Generate(context.Background(), fantasy.Call{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	// The first Retry-After is honoured; the second is longer than MaxDelay,
+	// so the primary is given up on without waiting.
+	if len(*slept) != 1 || (*slept)[0] != 3*time.Second {
+		t.Errorf("Expected a single 3s wait, got %v", *slept)
+	}
+	if resp.Content.Text() != "fallback" {
+		t.Errorf("Expected the fallback to answer, got %q", resp.Content.Text())
+	}
+}
+
+func TestFallbackModel_LastModelFails(t *testing.T) {
+	overloaded := statusErr(529, nil)
+	primary := &stubModel{name: "primary", errs: []error{overloaded, overloaded}}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), primary)
+	fm.policy.MaxRetries = 1
+
+	_, err := fm.Generate(context.Background(), fantasy.Call{})
+	if !errors.Is(err, overloaded) {
+		t.Fatalf("Expected the last error, got %v", err)
+	}
+	if primary.calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", primary.calls)
+	}
+}
+
+func TestFallbackModel_Prefer(t *testing.T) {
+	primary := &stubModel{name: "claude-sonnet-4-5"}
+	fallback := &stubModel{name: "gpt-4o-mini"}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+
+	if got := fm.Prefer("gemini", "claude"); got != fm {
+		t.Error("Expected the model itself when the primary matches")
+	}
+	if got := fm.Prefer("gemini"); got != fm {
+		t.Error("Expected the model itself when no hint matches")
+	}
+
+	preferred := fm.Prefer("gemini", "gpt-4o")
+	if preferred.Model() != "gpt-4o-mini" {
+		t.Fatalf("Expected gpt-4o-mini first, got %s", preferred.Model())
+	}
+	if len(preferred.chain) != 2 || preferred.chain[1].Model != primary {
+		t.Errorf("Expected the primary kept as fallback, got %v", preferred.chain)
+	}
+}
+
+// TestFallbackModel_PreferCoolingDown verifies that a preferred model
+// cooling down since an earlier call is skipped by later preferred chains.
+func TestFallbackModel_PreferCoolingDown(t *testing.T) {
+	primary := &stubModel{name: "claude-sonnet-4-5"}
+	fallback := &stubModel{name: "gpt-4o-mini", errs: []error{statusErr(529, nil)}}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+	fm.policy.MaxRetries = 0
+
+	// The preferred model is overloaded and cools down
+	if _, err := fm.Prefer("gpt-4o").Generate(context.Background(), fantasy.Call{}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if fallback.calls != 1 || primary.calls != 1 {
+		t.Fatalf("Expected a fallback to the primary, got %d and %d calls", fallback.calls, primary.calls)
+	}
+
+	// A later preferred chain starts with the primary while it cools down
+	if _, err := fm.Prefer("gpt-4o").Generate(context.Background(), fantasy.Call{}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if fallback.calls != 1 || primary.calls != 2 {
+		t.Errorf("Expected the cooling down model skipped, got %d and %d calls", fallback.calls, primary.calls)
+	}
+}
+
+func TestFallbackModel_Stream(t *testing.T) {
+	primary := &stubModel{name: "primary", warnings: true, errs: []error{errors.New("overloaded_error: Overloaded")}}
+	fallback := &stubModel{name: "fallback", warnings: true}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+	fm.policy.MaxRetries = 0
+
+	var types []fantasy.StreamPartType
+	var answered string
+	ctx := WithFallbackObserver(context.Background(), FallbackObserver{
+		OnAnswered: func(model, _ string) {
+			answered = model
+			// Announced before the answer is streamed
+			if len(types) != 0 {
+				t.Errorf("Expected the notice before any part, got it after %v", types)
+			}
+		},
+	})
+	stream, err := fm.Stream(ctx, fantasy.Call{})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var text string
+	for part := range stream {
+		types = append(types, part.Type)
+		text += part.Delta
+	}
+	if answered != "stub/fallback" {
+		t.Errorf("Expected the fallback to be announced, got %q", answered)
+	}
+
+	want := []fantasy.StreamPartType{fantasy.StreamPartTypeWarnings, fantasy.StreamPartTypeTextDelta, fantasy.StreamPartTypeFinish}
+	if len(types) != len(want) {
+		t.Fatalf("Expected parts %v, got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("Expected parts %v, got %v", want, types)
+		}
+	}
+	if text != "fallback" {
+		t.Errorf("Expected text from the fallback, got %q", text)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limit", statusErr(http.StatusTooManyRequests, nil), true},
+		{"overloaded", statusErr(529, nil), true},
+		{"bad gateway", statusErr(http.StatusBadGateway, nil), true},
+		{"bad request", statusErr(http.StatusBadRequest, nil), false},
+		{"unauthorized", statusErr(http.StatusUnauthorized, nil), false},
+		{"stream overload", errors.New("received error while streaming: overloaded_error"), true},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.want {
+				t.Errorf("IsTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// newAnthropicTestServer serves the Anthropic messages API. respond decides
+// the status code for each request from the requested model and the number
+// of requests so far; successful responses answer with the model name.
+func newAnthropicTestServer(t *testing.T, respond func(model string, n int32) int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		n := requests.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		status := respond(body.Model, n)
+		if status != http.StatusOK {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"type":  "error",
+				"error": map[string]any{"type": "overloaded_error", "message": http.StatusText(status)},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":            "msg_test",
+			"type":          "message",
+			"role":          "assistant",
+			"model":         body.Model,
+			"content":       []map[string]any{{"type": "text", "text": "answered by " + body.Model}},
+			"stop_reason":   "end_turn",
+			"stop_sequence": nil,
+			"usage":         map[string]any{"input_tokens": 5, "output_tokens": 3},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestCreateProvider_RetriesRateLimit(t *testing.T) {
+	server, requests := newAnthropicTestServer(t, func(model string, n int32) int {
+		if n == 1 {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusOK
+	})
+
+	result, err := CreateProvider(context.Background(), &ProviderConfig{
+		ModelString:    "anthropic/test-model",
+		ProviderAPIKey: "test-key",
+		ProviderURL:    server.URL,
+		MaxTokens:      100,
+		MaxRetries:     2,
+	})
+	if err != nil {
+		t.Fatalf("CreateProvider: %v", err)
+	}
+
+	resp, err := result.Model.Generate(context.Background(), fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got := resp.Content.Text(); got != "answered by test-model" {
+		t.Errorf("Unexpected response %q", got)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
+
+func TestCreateProvider_FallsBackWhenOverloaded(t *testing.T) {
+	server, requests := newAnthropicTestServer(t, func(model string, n int32) int {
+		if model == "primary-model" {
+			return 529
+		}
+		return http.StatusOK
+	})
+
+	result, err := CreateProvider(context.Background(), &ProviderConfig{
+		ModelString:    "anthropic/primary-model",
+		ProviderAPIKey: "test-key",
+		ProviderURL:    server.URL,
+		MaxTokens:      100,
+		MaxRetries:     1,
+		FallbackModels: []string{"anthropic/fallback-model"},
+	})
+	if err != nil {
+		t.Fatalf("CreateProvider: %v", err)
+	}
+
+	var to string
+	ctx := WithFallbackObserver(context.Background(), FallbackObserver{
+		OnFallback: func(_, next string, _ error) { to = next },
+	})
+	resp, err := result.Model.Generate(ctx, fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got := resp.Content.Text(); got != "answered by fallback-model" {
+		t.Errorf("Unexpected response %q", got)
+	}
+	if to != "anthropic/fallback-model" {
+		t.Errorf("Expected a fallback to anthropic/fallback-model, got %q", to)
+	}
+	// One retry of the primary, then the fallback
+	if got := requests.Load(); got != 3 {
+		t.Errorf("Expected 3 requests, got %d", got)
+	}
+}
+
+func TestFallbackConfig(t *testing.T) {
+	config := &ProviderConfig{
+		ModelString:    "anthropic/claude-sonnet-4-5",
+		ProviderAPIKey: "key",
+		ProviderURL:    "https://proxy.example.com",
+		FallbackModels: []string{"anthropic/claude-haiku-4-5", "openai/gpt-4o"},
+	}
+
+	same := fallbackConfig(config, "anthropic/claude-haiku-4-5")
+	if same.ProviderAPIKey != "key" || same.ProviderURL != "https://proxy.example.com" || len(same.FallbackModels) != 0 {
+		t.Errorf("Unexpected same-provider config: %+v", same)
+	}
+
+	other := fallbackConfig(config, "openai/gpt-4o")
+	if other.ProviderAPIKey != "" || other.ProviderURL != "" || other.ModelString != "openai/gpt-4o" {
+		t.Errorf("Unexpected other-provider config: %+v", other)
+	}
+}
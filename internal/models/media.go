@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+)
+
+// toolResultMediaTypes lists the media types each provider accepts in tool
+// results. The other providers drop media tool results.
+var toolResultMediaTypes = map[string][]string{
+	anthropic.Name: {"image/png", "image/jpeg", "image/gif", "image/webp"},
+}
+
+// AcceptsToolResultMedia reports whether model can receive media of the
+// given type in a tool result. A FallbackModel accepts it when any model of
+// its chain does; the models that do not get the result's text instead (see
+// textToolResults).
+func AcceptsToolResultMedia(model fantasy.LanguageModel, mediaType string) bool {
+	if m, ok := model.(*FallbackModel); ok {
+		return slices.ContainsFunc(m.chain, func(n NamedModel) bool {
+			return AcceptsToolResultMedia(n.Model, mediaType)
+		})
+	}
+	return slices.Contains(toolResultMediaTypes[model.Provider()], mediaType)
+}
+
+// textToolResults returns prompt with the media tool results that model
+// cannot receive replaced by their text, noting that the media was left out.
+// prompt is returned as it is when there are none; it is never modified.
+func textToolResults(model fantasy.LanguageModel, prompt fantasy.Prompt) fantasy.Prompt {
+	var replaced fantasy.Prompt
+	for i, msg := range prompt {
+		var content []fantasy.MessagePart
+		for j, part := range msg.Content {
+			result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+			if !ok {
+				continue
+			}
+			media, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](result.Output)
+			if !ok || AcceptsToolResultMedia(model, media.MediaType) {
+				continue
+			}
+			if content == nil {
+				content = slices.Clone(msg.Content)
+			}
+			text := fmt.Sprintf("(The %s was not shown to the model.)", media.MediaType)
+			if media.Text != "" {
+				text = media.Text + "\n" + text
+			}
+			result.Output = fantasy.ToolResultOutputContentText{Text: text}
+			content[j] = result
+		}
+		if content == nil {
+			continue
+		}
+		if replaced == nil {
+			replaced = slices.Clone(prompt)
+		}
+		replaced[i].Content = content
+	}
+	if replaced == nil {
+		return prompt
+	}
+	return replaced
+}
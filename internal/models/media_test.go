@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// screenshotPrompt is a conversation whose tool result holds an image.
+func screenshotPrompt() fantasy.Prompt {
+	return fantasy.Prompt{
+		fantasy.NewUserMessage("Take a screenshot"),
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{fantasy.ToolResultPart{
+				ToolCallID: "1",
+				Output: fantasy.ToolResultOutputContentMedia{
+					Data:      "aW1hZ2U=",
+					MediaType: "image/png",
+					Text:      "[image 64x32 png]",
+				},
+			}},
+		},
+	}
+}
+
+func TestAcceptsToolResultMedia(t *testing.T) {
+	anthropic := &stubModel{name: "claude", provider: "anthropic"}
+	openai := &stubModel{name: "gpt", provider: "openai"}
+
+	if !AcceptsToolResultMedia(anthropic, "image/png") || AcceptsToolResultMedia(anthropic, "audio/wav") {
+		t.Error("Expected Anthropic models to accept images only")
+	}
+	if AcceptsToolResultMedia(openai, "image/png") {
+		t.Error("Expected OpenAI models not to accept images")
+	}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), openai, anthropic)
+	if !AcceptsToolResultMedia(fm, "image/png") {
+		t.Error("Expected a chain to accept images when one of its models does")
+	}
+}
+
+// TestFallbackModel_toolResultMedia verifies that each model of the chain is
+// sent the media it accepts and the text of the rest, whichever model is
+// the primary.
+func TestFallbackModel_toolResultMedia(t *testing.T) {
+	primary := &stubModel{name: "claude", provider: "anthropic", errs: []error{statusErr(529, nil)}}
+	fallback := &stubModel{name: "gpt", provider: "openai"}
+	fm, _ := newTestFallbackModel(DefaultRetryPolicy(), primary, fallback)
+	fm.policy.MaxRetries = 0
+
+	prompt := screenshotPrompt()
+	if _, err := fm.Generate(context.Background(), fantasy.Call{Prompt: prompt}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	toolResult := func(p fantasy.Prompt) fantasy.ToolResultOutputContent {
+		return p[1].Content[0].(fantasy.ToolResultPart).Output
+	}
+	if _, ok := toolResult(primary.prompts[0]).(fantasy.ToolResultOutputContentMedia); !ok {
+		t.Errorf("Expected the primary to get the image, got %#v", toolResult(primary.prompts[0]))
+	}
+	text, ok := toolResult(fallback.prompts[0]).(fantasy.ToolResultOutputContentText)
+	if !ok || text.Text != "[image 64x32 png]\n(The image/png was not shown to the model.)" {
+		t.Errorf("Expected the fallback to get the placeholder, got %#v", toolResult(fallback.prompts[0]))
+	}
+	if _, ok := toolResult(prompt).(fantasy.ToolResultOutputContentMedia); !ok {
+		t.Error("Expected the caller's prompt to be left alone")
+	}
+}
+
+func TestTextToolResults_unchanged(t *testing.T) {
+	prompt := screenshotPrompt()
+	got := textToolResults(&stubModel{name: "claude", provider: "anthropic"}, prompt)
+	if &got[0] != &prompt[0] {
+		t.Error("Expected the prompt itself when the model accepts its media")
+	}
+}
@@ -71,6 +71,24 @@ type ProviderConfig struct {
 	NumGPU         *int32
 	MainGPU        *int32
 	TLSSkipVerify  bool
+	// FallbackModels are tried in order, as "provider/model" strings, when
+	// the model keeps failing with transient errors. ProviderAPIKey and
+	// ProviderURL only apply to fallbacks of the same provider.
+	FallbackModels []string
+	// MaxRetries is the number of retries of a transient error (rate limit,
+	// overload, server error) per model before falling back. 0 disables
+	// retrying.
+	MaxRetries int
+	// ThinkingBudget is the number of tokens the model may spend on
+	// extended thinking (Anthropic, Gemini, OpenRouter). 0 disables it.
+	ThinkingBudget int
+	// ReasoningEffort is "minimal", "low", "medium" or "high" for reasoning
+	// models (OpenAI o-series and GPT-5, OpenRouter). Providers that only
+	// take a budget derive one from it when ThinkingBudget is 0.
+	ReasoningEffort string
+	// PromptCache is the prompt cache mode for Anthropic models: "off",
+	// "auto" (the default) or "explicit". See PromptCacheMode.
+	PromptCache string
 }
 
 // ProviderResult contains the result of provider creation.
@@ -112,6 +130,79 @@ func ParseModelString(modelString string) (provider, model string, err error) {
 }
 
 // CreateProvider creates a fantasy LanguageModel based on the provider configuration.
+// Unless retries and fallbacks are both disabled, the model is a FallbackModel
+// over config.ModelString followed by config.FallbackModels; see
+// createProvider for how each model is created.
+func CreateProvider(ctx context.Context, config *ProviderConfig) (*ProviderResult, error) {
+	primary, err := createProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.FallbackModels) == 0 && config.MaxRetries <= 0 {
+		return primary, nil
+	}
+
+	chain := []NamedModel{{Name: config.ModelString, Model: primary.Model}}
+	closers := multiCloser{}
+	if primary.Closer != nil {
+		closers = append(closers, primary.Closer)
+	}
+
+	for _, modelString := range config.FallbackModels {
+		fallback, err := createProvider(ctx, fallbackConfig(config, modelString))
+		if err != nil {
+			_ = closers.Close()
+			return nil, fmt.Errorf("failed to create fallback model %s: %w", modelString, err)
+		}
+		chain = append(chain, NamedModel{Name: modelString, Model: fallback.Model})
+		if fallback.Closer != nil {
+			closers = append(closers, fallback.Closer)
+		}
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = max(config.MaxRetries, 0)
+
+	result := &ProviderResult{
+		Model:   NewFallbackModel(policy, chain...),
+		Message: primary.Message,
+	}
+	if len(closers) > 0 {
+		result.Closer = closers
+	}
+	return result, nil
+}
+
+// fallbackConfig returns the configuration for the fallback model
+// modelString. The explicit API key and base URL belong to the primary
+// provider, so they are dropped when the fallback uses another provider.
+func fallbackConfig(config *ProviderConfig, modelString string) *ProviderConfig {
+	fallback := *config
+	fallback.ModelString = modelString
+	fallback.FallbackModels = nil
+
+	primaryProvider, _, _ := ParseModelString(config.ModelString)
+	if provider, _, err := ParseModelString(modelString); err != nil || provider != primaryProvider {
+		fallback.ProviderAPIKey = ""
+		fallback.ProviderURL = ""
+	}
+	return &fallback
+}
+
+// multiCloser closes several providers, returning the first error.
+type multiCloser []io.Closer
+
+func (c multiCloser) Close() error {
+	var first error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// createProvider creates the fantasy LanguageModel for config.ModelString.
 // Model metadata is looked up from the models.dev database for cost tracking and
 // capability detection, but unknown models are passed through to the provider
 // API — the database is advisory, not a gatekeeper.
@@ -120,7 +211,7 @@ func ParseModelString(modelString string) (provider, model string, err error) {
 // openrouter, bedrock, vercel.
 // Any provider in models.dev with an api URL or openai-compatible npm package
 // is auto-routed through fantasy's openaicompat provider.
-func CreateProvider(ctx context.Context, config *ProviderConfig) (*ProviderResult, error) {
+func createProvider(ctx context.Context, config *ProviderConfig) (*ProviderResult, error) {
 	provider, modelName, err := ParseModelString(config.ModelString)
 	if err != nil {
 		return nil, err
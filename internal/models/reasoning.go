@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"charm.land/fantasy/providers/openrouter"
+	"charm.land/fantasy/providers/vercel"
+)
+
+// ReasoningEfforts lists the accepted values of ProviderConfig.ReasoningEffort.
+var ReasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// effortBudgets translates a reasoning effort into a thinking budget for
+// providers that only accept a token budget (Anthropic, Gemini).
+var effortBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     4096,
+	"medium":  8192,
+	"high":    16384,
+}
+
+// minThinkingBudget is the smallest budget Anthropic accepts.
+const minThinkingBudget = 1024
+
+// ThinkingBudget returns the number of tokens config reserves for reasoning:
+// ThinkingBudget when set, otherwise the budget matching ReasoningEffort, or 0
+// when reasoning is not configured.
+func ThinkingBudget(config *ProviderConfig) int {
+	if config.ThinkingBudget > 0 {
+		return config.ThinkingBudget
+	}
+	return effortBudgets[strings.ToLower(config.ReasoningEffort)]
+}
+
+// ReasoningProviderOptions returns the per-call provider options that turn on
+// extended thinking as configured by config's ThinkingBudget and
+// ReasoningEffort. Options are keyed by provider, so the result holds an entry
+// for every provider that supports reasoning and also applies to fallback
+// models of other providers. Returns nil when reasoning is not configured.
+//
+// Anthropic and Gemini take a token budget, OpenAI takes an effort level, and
+// OpenRouter and Vercel take either. A missing budget is derived from the
+// effort; OpenAI models only reason differently when an effort is given.
+func ReasoningProviderOptions(config *ProviderConfig) (fantasy.ProviderOptions, error) {
+	if config.ThinkingBudget < 0 {
+		return nil, fmt.Errorf("thinking budget must not be negative")
+	}
+	effort := strings.ToLower(config.ReasoningEffort)
+	if effort != "" {
+		if _, ok := effortBudgets[effort]; !ok {
+			return nil, fmt.Errorf("invalid reasoning effort %q (expected one of: %s)",
+				config.ReasoningEffort, strings.Join(ReasoningEfforts, ", "))
+		}
+	}
+	if config.ThinkingBudget > 0 && config.ThinkingBudget < minThinkingBudget {
+		return nil, fmt.Errorf("thinking budget must be at least %d tokens", minThinkingBudget)
+	}
+
+	budget := int64(config.ThinkingBudget)
+	if budget == 0 {
+		budget = int64(effortBudgets[effort])
+	}
+	if budget == 0 {
+		return nil, nil
+	}
+
+	opts := fantasy.ProviderOptions{
+		anthropic.Name: &anthropic.ProviderOptions{
+			Thinking:      &anthropic.ThinkingProviderOption{BudgetTokens: budget},
+			SendReasoning: fantasy.Opt(true),
+		},
+		google.Name: &google.ProviderOptions{
+			ThinkingConfig: &google.ThinkingConfig{
+				ThinkingBudget:  fantasy.Opt(budget),
+				IncludeThoughts: fantasy.Opt(true),
+			},
+		},
+	}
+
+	// OpenRouter and Vercel accept a budget or an effort, but not both.
+	openRouterReasoning := &openrouter.ReasoningOptions{Enabled: fantasy.Opt(true)}
+	vercelReasoning := &vercel.ReasoningOptions{Enabled: fantasy.Opt(true)}
+	if config.ThinkingBudget > 0 {
+		openRouterReasoning.MaxTokens = fantasy.Opt(budget)
+		vercelReasoning.MaxTokens = fantasy.Opt(budget)
+	} else {
+		// OpenRouter has no "minimal" level
+		openRouterEffort := effort
+		if openRouterEffort == "minimal" {
+			openRouterEffort = "low"
+		}
+		openRouterReasoning.Effort = openrouter.ReasoningEffortOption(openrouter.ReasoningEffort(openRouterEffort))
+		vercelReasoning.Effort = fantasy.Opt(vercel.ReasoningEffort(effort))
+	}
+	opts[openrouter.Name] = &openrouter.ProviderOptions{Reasoning: openRouterReasoning}
+	opts[vercel.Name] = &vercel.ProviderOptions{Reasoning: vercelReasoning}
+
+	if effort != "" {
+		reasoningEffort := openai.ReasoningEffortOption(openai.ReasoningEffort(effort))
+		opts[openai.Name] = &openai.ProviderOptions{ReasoningEffort: reasoningEffort}
+		opts[openaicompat.Name] = &openaicompat.ProviderOptions{ReasoningEffort: reasoningEffort}
+	}
+
+	return opts, nil
+}
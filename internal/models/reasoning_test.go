@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openrouter"
+)
+
+func TestReasoningProviderOptionsDisabled(t *testing.T) {
+	opts, err := ReasoningProviderOptions(&ProviderConfig{ModelString: "anthropic/claude-sonnet-4-5"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts != nil {
+		t.Errorf("Expected no options without a budget or effort, got %v", opts)
+	}
+}
+
+func TestReasoningProviderOptionsBudget(t *testing.T) {
+	opts, err := ReasoningProviderOptions(&ProviderConfig{ThinkingBudget: 2048})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	anthropicOpts, ok := opts[anthropic.Name].(*anthropic.ProviderOptions)
+	if !ok || anthropicOpts.Thinking == nil || anthropicOpts.Thinking.BudgetTokens != 2048 {
+		t.Errorf("Expected an anthropic thinking budget of 2048, got %#v", opts[anthropic.Name])
+	}
+
+	googleOpts, ok := opts[google.Name].(*google.ProviderOptions)
+	if !ok || googleOpts.ThinkingConfig == nil || *googleOpts.ThinkingConfig.ThinkingBudget != 2048 ||
+		!*googleOpts.ThinkingConfig.IncludeThoughts {
+		t.Errorf("Expected a google thinking budget of 2048 with thoughts, got %#v", opts[google.Name])
+	}
+
+	openRouterOpts, ok := opts[openrouter.Name].(*openrouter.ProviderOptions)
+	if !ok || openRouterOpts.Reasoning == nil || *openRouterOpts.Reasoning.MaxTokens != 2048 ||
+		openRouterOpts.Reasoning.Effort != nil {
+		t.Errorf("Expected an openrouter reasoning budget of 2048, got %#v", opts[openrouter.Name])
+	}
+
+	// OpenAI only takes an effort
+	if _, ok := opts[openai.Name]; ok {
+		t.Error("Expected no openai options without an effort")
+	}
+}
+
+func TestReasoningProviderOptionsEffort(t *testing.T) {
+	config := &ProviderConfig{ReasoningEffort: "High"}
+	opts, err := ReasoningProviderOptions(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	openaiOpts, ok := opts[openai.Name].(*openai.ProviderOptions)
+	if !ok || openaiOpts.ReasoningEffort == nil || *openaiOpts.ReasoningEffort != openai.ReasoningEffortHigh {
+		t.Errorf("Expected openai reasoning effort high, got %#v", opts[openai.Name])
+	}
+
+	// Providers that need a budget derive it from the effort
+	anthropicOpts, ok := opts[anthropic.Name].(*anthropic.ProviderOptions)
+	if !ok || anthropicOpts.Thinking.BudgetTokens != int64(effortBudgets["high"]) {
+		t.Errorf("Expected the high effort budget for anthropic, got %#v", opts[anthropic.Name])
+	}
+	if got := ThinkingBudget(config); got != effortBudgets["high"] {
+		t.Errorf("Expected ThinkingBudget %d, got %d", effortBudgets["high"], got)
+	}
+
+	openRouterOpts := opts[openrouter.Name].(*openrouter.ProviderOptions)
+	if openRouterOpts.Reasoning.Effort == nil || *openRouterOpts.Reasoning.Effort != openrouter.ReasoningEffortHigh {
+		t.Errorf("Expected openrouter reasoning effort high, got %#v", openRouterOpts.Reasoning)
+	}
+}
+
+func TestReasoningProviderOptionsInvalid(t *testing.T) {
+	invalid := []*ProviderConfig{
+		{ReasoningEffort: "extreme"},
+		{ThinkingBudget: -1},
+		{ThinkingBudget: 100},
+	}
+	for _, config := range invalid {
+		if _, err := ReasoningProviderOptions(config); err == nil {
+			t.Errorf("Expected an error for %+v", *config)
+		}
+	}
+}
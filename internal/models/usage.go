@@ -0,0 +1,39 @@
+package models
+
+import "charm.land/fantasy"
+
+// UsageTokens returns the number of tokens in usage: prompt tokens, including
+// those read from or written to the prompt cache, plus output tokens.
+// provider tells how the prompt tokens were reported (see IsAnthropicProvider).
+func UsageTokens(provider string, usage fantasy.Usage) int64 {
+	tokens := usage.InputTokens + usage.OutputTokens
+	if IsAnthropicProvider(provider) {
+		tokens += usage.CacheReadTokens + usage.CacheCreationTokens
+	}
+	return tokens
+}
+
+// UsageCost returns the cost of usage in US dollars at the prices in c, which
+// are per million tokens. Cached prompt tokens are charged at the cache prices
+// when the model has them and at the input price otherwise. provider tells
+// how the prompt tokens were reported (see IsAnthropicProvider).
+func (c Cost) UsageCost(provider string, usage fantasy.Usage) float64 {
+	cacheRead, cacheWrite := c.Input, c.Input
+	if c.CacheRead != nil {
+		cacheRead = *c.CacheRead
+	}
+	if c.CacheWrite != nil {
+		cacheWrite = *c.CacheWrite
+	}
+
+	input := usage.InputTokens
+	if !IsAnthropicProvider(provider) {
+		// Other providers count cached tokens as part of the input tokens
+		input = max(0, input-usage.CacheReadTokens-usage.CacheCreationTokens)
+	}
+	cost := float64(input)*c.Input +
+		float64(usage.OutputTokens)*c.Output +
+		float64(usage.CacheReadTokens)*cacheRead +
+		float64(usage.CacheCreationTokens)*cacheWrite
+	return cost / 1000000
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+func TestUsageTokens(t *testing.T) {
+	usage := fantasy.Usage{InputTokens: 100, OutputTokens: 50, CacheReadTokens: 800, CacheCreationTokens: 200}
+
+	if got := UsageTokens("anthropic", usage); got != 1150 {
+		t.Errorf("Expected 1150 tokens for Anthropic, got %d", got)
+	}
+	// Other providers include cached tokens in the input tokens
+	if got := UsageTokens("openai", usage); got != 150 {
+		t.Errorf("Expected 150 tokens for OpenAI, got %d", got)
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	cacheRead, cacheWrite := 0.3, 3.75
+	cost := Cost{Input: 3, Output: 15, CacheRead: &cacheRead, CacheWrite: &cacheWrite}
+
+	usage := fantasy.Usage{InputTokens: 1000, OutputTokens: 100, CacheReadTokens: 10000, CacheCreationTokens: 2000}
+	want := (1000*3 + 100*15 + 10000*0.3 + 2000*3.75) / 1e6
+	if got := cost.UsageCost("anthropic", usage); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Expected $%f, got $%f", want, got)
+	}
+
+	// Cached tokens are part of OpenAI's input tokens and only charged once
+	usage = fantasy.Usage{InputTokens: 1000, OutputTokens: 100, CacheReadTokens: 600}
+	want = (400*3 + 100*15 + 600*0.3) / 1e6
+	if got := cost.UsageCost("openai", usage); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Expected $%f, got $%f", want, got)
+	}
+
+	// Without cache prices cached tokens cost as much as input tokens
+	if got := (Cost{Input: 1}).UsageCost("anthropic", fantasy.Usage{CacheReadTokens: 1000000}); got != 1 {
+		t.Errorf("Expected $1, got $%f", got)
+	}
+}
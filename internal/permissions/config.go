@@ -0,0 +1,229 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the project-level permissions file, relative to the working
+// directory. "Always allow" answers from the approval prompt are saved here.
+const ProjectFile = ".mcphost/permissions.yml"
+
+// ApprovalsFile is the user's record of the allow rules saved to project files
+// from the approval prompt, relative to the data directory. A project file
+// comes with the project, so its allow rules only apply when recorded here;
+// its deny and ask rules, which only restrict, always apply.
+const ApprovalsFile = "permission-approvals.json"
+
+// fileConfig is the on-disk layout of the project permissions file. It uses the
+// same "permissions" key as the main config file.
+type fileConfig struct {
+	Permissions []Rule `yaml:"permissions"`
+}
+
+// Load builds a Ruleset from the rules in the main config file followed by
+// those in the project file at projectPath (if it exists). Allow rules of the
+// project file only apply when approvalsPath records them as approved in the
+// prompt (see ApprovalsFile); Ignored returns the others. Relative paths in
+// "within" conditions resolve against the current working directory. An empty
+// projectPath disables the project file entirely, and an empty approvalsPath
+// ignores all of its allow rules.
+func Load(configRules []Rule, projectPath, approvalsPath string) (*Ruleset, error) {
+	baseDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+
+	rules := slices.Clone(configRules)
+	var ignored []Rule
+	if projectPath != "" {
+		projectRules, err := readFile(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		approved, err := readApprovals(approvalsPath, projectPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range projectRules {
+			if rule.Action == Allow && !slices.ContainsFunc(approved, rule.equal) {
+				ignored = append(ignored, rule)
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid permission %w", err)
+		}
+	}
+
+	return &Ruleset{
+		rules:         rules,
+		ignored:       ignored,
+		baseDir:       baseDir,
+		projectPath:   projectPath,
+		approvalsPath: approvalsPath,
+	}, nil
+}
+
+// Ignored returns the allow rules of the project file that were not approved
+// in the prompt and so do not apply.
+func (r *Ruleset) Ignored() []Rule {
+	if r == nil {
+		return nil
+	}
+	return slices.Clone(r.ignored)
+}
+
+// CanPersist reports whether AddProjectRule will save rules to disk. Saving
+// needs an approvals file, without which saved allow rules would not apply.
+func (r *Ruleset) CanPersist() bool {
+	return r != nil && r.projectPath != "" && r.approvalsPath != ""
+}
+
+// AddProjectRule activates rule for the rest of the session and appends it to
+// the project permissions file, recording it as approved so that it applies
+// in later sessions too. The rule stays active even when saving fails; the
+// returned error only reports the failed write.
+func (r *Ruleset) AddProjectRule(rule Rule) error {
+	if r == nil {
+		return fmt.Errorf("permissions are not enabled")
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules = append(r.rules, rule)
+	if !r.CanPersist() {
+		return nil
+	}
+
+	if err := r.approve(rule); err != nil {
+		return err
+	}
+
+	existing, err := readFile(r.projectPath)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(existing, rule.equal) {
+		return nil // already saved
+	}
+
+	data, err := yaml.Marshal(fileConfig{Permissions: append(existing, rule)})
+	if err != nil {
+		return fmt.Errorf("encoding permissions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.projectPath), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(r.projectPath), err)
+	}
+	if err := os.WriteFile(r.projectPath, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", r.projectPath, err)
+	}
+	return nil
+}
+
+// ProjectPath returns the file AddProjectRule writes to, or "" when
+// persistence is disabled.
+func (r *Ruleset) ProjectPath() string {
+	if !r.CanPersist() {
+		return ""
+	}
+	return r.projectPath
+}
+
+// approve records rule as approved for the project file. Only allow rules
+// need approval. The caller holds r.mu.
+func (r *Ruleset) approve(rule Rule) error {
+	if rule.Action != Allow {
+		return nil
+	}
+	key, err := filepath.Abs(r.projectPath)
+	if err != nil {
+		return err
+	}
+
+	approvals, err := readApprovalsFile(r.approvalsPath)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(approvals[key], rule.equal) {
+		return nil
+	}
+	approvals[key] = append(approvals[key], rule)
+
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding approvals: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.approvalsPath), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(r.approvalsPath), err)
+	}
+	if err := os.WriteFile(r.approvalsPath, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", r.approvalsPath, err)
+	}
+	return nil
+}
+
+// readApprovals returns the rules of the project file at projectPath that
+// approvalsPath records as approved.
+func readApprovals(approvalsPath, projectPath string) ([]Rule, error) {
+	if approvalsPath == "" {
+		return nil, nil
+	}
+	key, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := readApprovalsFile(approvalsPath)
+	if err != nil {
+		return nil, err
+	}
+	return approvals[key], nil
+}
+
+// readApprovalsFile reads the approved rules per absolute project file path.
+// A missing file approves nothing.
+func readApprovalsFile(path string) (map[string][]Rule, error) {
+	approvals := make(map[string][]Rule)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return approvals, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &approvals); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return approvals, nil
+}
+
+// readFile reads the rules from a project permissions file. A missing file
+// holds no rules.
+func readFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var cfg fileConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg.Permissions, nil
+}
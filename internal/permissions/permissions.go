@@ -0,0 +1,351 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Action is what a matching rule decides for a tool call.
+type Action string
+
+const (
+	// Allow lets the tool call run without asking for approval.
+	Allow Action = "allow"
+	// Ask sends the tool call through the normal approval flow, even when
+	// another rule would allow it.
+	Ask Action = "ask"
+	// Deny rejects the tool call; the model is told it was denied.
+	Deny Action = "deny"
+)
+
+// Rule matches tool calls by tool name and, optionally, by their arguments.
+// Tool is a glob (path.Match syntax) over the prefixed tool name, e.g.
+// "bash__run_shell_cmd" or "fs__*". All conditions in When must hold for the
+// rule to match.
+type Rule struct {
+	Tool   string      `json:"tool" yaml:"tool"`
+	Action Action      `json:"action" yaml:"action"`
+	When   []Condition `json:"when,omitempty" yaml:"when,omitempty"`
+}
+
+// Condition tests one value in the tool arguments. Path selects the value
+// with a simple JSON path ("command", "$.options.path", "files[0]"), and
+// exactly one operator compares it. Not inverts the result. A condition whose
+// path is missing from the arguments never holds, even with Not set.
+type Condition struct {
+	Path     string `json:"path" yaml:"path"`
+	Equals   string `json:"equals,omitempty" yaml:"equals,omitempty"`
+	Prefix   string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Contains string `json:"contains,omitempty" yaml:"contains,omitempty"`
+	Matches  string `json:"matches,omitempty" yaml:"matches,omitempty"` // regular expression
+	Within   string `json:"within,omitempty" yaml:"within,omitempty"`   // directory; relative paths resolve against the working directory
+	Not      bool   `json:"not,omitempty" yaml:"not,omitempty"`
+
+	re *regexp.Regexp // compiled Matches
+}
+
+// equal reports whether r and o are the same rule.
+func (r Rule) equal(o Rule) bool {
+	return r.Tool == o.Tool && r.Action == o.Action && slices.EqualFunc(r.When, o.When, func(a, b Condition) bool {
+		return a.Path == b.Path && a.Equals == b.Equals && a.Prefix == b.Prefix && a.Contains == b.Contains &&
+			a.Matches == b.Matches && a.Within == b.Within && a.Not == b.Not
+	})
+}
+
+// String returns a short human-readable description of the rule.
+func (r Rule) String() string {
+	if len(r.When) == 0 {
+		return fmt.Sprintf("%s %s", r.Action, r.Tool)
+	}
+	return fmt.Sprintf("%s %s (%d conditions)", r.Action, r.Tool, len(r.When))
+}
+
+// Validate checks that the rule has a valid action, tool glob, and conditions,
+// and compiles any regular expressions it uses.
+func (r *Rule) Validate() error {
+	switch r.Action {
+	case Allow, Ask, Deny:
+	default:
+		return fmt.Errorf("rule for %q: action must be allow, ask or deny, got %q", r.Tool, r.Action)
+	}
+	if r.Tool == "" {
+		return fmt.Errorf("rule: tool is required")
+	}
+	if _, err := path.Match(r.Tool, ""); err != nil {
+		return fmt.Errorf("rule for %q: invalid tool pattern: %w", r.Tool, err)
+	}
+
+	for i := range r.When {
+		if err := r.When[i].compile(); err != nil {
+			return fmt.Errorf("rule for %q: condition %d: %w", r.Tool, i+1, err)
+		}
+	}
+	return nil
+}
+
+// compile validates the condition and compiles its regular expression.
+func (c *Condition) compile() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if _, err := parsePath(c.Path); err != nil {
+		return err
+	}
+
+	operators := 0
+	for _, op := range []string{c.Equals, c.Prefix, c.Contains, c.Matches, c.Within} {
+		if op != "" {
+			operators++
+		}
+	}
+	if operators != 1 {
+		return fmt.Errorf("exactly one of equals, prefix, contains, matches or within is required")
+	}
+
+	if c.Matches != "" {
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return fmt.Errorf("invalid matches pattern: %w", err)
+		}
+		c.re = re
+	}
+	return nil
+}
+
+// Ruleset holds the active permission rules. It is safe for concurrent use,
+// and a nil *Ruleset matches nothing.
+type Ruleset struct {
+	mu    sync.RWMutex
+	rules []Rule
+
+	// ignored holds the project file's allow rules that were not approved.
+	ignored []Rule
+
+	// baseDir resolves relative paths in "within" conditions.
+	baseDir string
+
+	// projectPath is where AddProjectRule persists new rules. Empty disables
+	// persistence.
+	projectPath string
+
+	// approvalsPath records the allow rules approved for project files.
+	approvalsPath string
+}
+
+// Evaluate returns the action for a tool call and the rule that decided it.
+// When several rules match, deny wins over ask and ask wins over allow, so a
+// broad allow can never override a more specific deny. It returns an empty
+// action and nil rule when no rule matches.
+func (r *Ruleset) Evaluate(toolName, toolArgs string) (Action, *Rule) {
+	if r == nil {
+		return "", nil
+	}
+
+	var args any
+	if strings.TrimSpace(toolArgs) != "" {
+		// Unparseable arguments simply fail every condition.
+		_ = json.Unmarshal([]byte(toolArgs), &args)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var decided *Rule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !r.matches(rule, toolName, args) {
+			continue
+		}
+		if decided == nil || precedence(rule.Action) > precedence(decided.Action) {
+			decided = rule
+		}
+	}
+	if decided == nil {
+		return "", nil
+	}
+	matched := *decided
+	return matched.Action, &matched
+}
+
+// Rules returns a copy of the active rules.
+func (r *Ruleset) Rules() []Rule {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Rule(nil), r.rules...)
+}
+
+// precedence orders actions so that the most restrictive one wins.
+func precedence(a Action) int {
+	switch a {
+	case Deny:
+		return 3
+	case Ask:
+		return 2
+	case Allow:
+		return 1
+	}
+	return 0
+}
+
+// matches reports whether rule applies to a call of toolName with args.
+func (r *Ruleset) matches(rule *Rule, toolName string, args any) bool {
+	if ok, _ := path.Match(rule.Tool, toolName); !ok {
+		return false
+	}
+	for i := range rule.When {
+		if !r.holds(&rule.When[i], args) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds evaluates a single condition against the parsed arguments.
+func (r *Ruleset) holds(c *Condition, args any) bool {
+	raw, ok := lookup(args, c.Path)
+	if !ok {
+		return false
+	}
+	value := stringify(raw)
+
+	var result bool
+	switch {
+	case c.Equals != "":
+		result = value == c.Equals
+	case c.Prefix != "":
+		result = strings.HasPrefix(value, c.Prefix)
+	case c.Contains != "":
+		result = strings.Contains(value, c.Contains)
+	case c.Matches != "":
+		re := c.re
+		if re == nil {
+			re = regexp.MustCompile(c.Matches)
+		}
+		result = re.MatchString(value)
+	case c.Within != "":
+		result = isWithin(value, c.Within, r.baseDir)
+	}
+
+	if c.Not {
+		return !result
+	}
+	return result
+}
+
+// isWithin reports whether p is dir or lies below it. Relative paths resolve
+// against base. Symlinks are not followed.
+func isWithin(p, dir, base string) bool {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(base, p)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(p))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// stringify converts a decoded JSON value to the string conditions compare.
+func stringify(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// pathSegment is one step of a parsed JSON path: an object key, or an array
+// index when index >= 0.
+type pathSegment struct {
+	key   string
+	index int
+}
+
+// parsePath parses a simple JSON path such as "$.a.b", "a.b" or "a[0].b".
+func parsePath(p string) ([]pathSegment, error) {
+	p = strings.TrimPrefix(p, "$")
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return nil, fmt.Errorf("invalid path %q", p)
+	}
+
+	var segments []pathSegment
+	for part := range strings.SplitSeq(p, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", p)
+		}
+		if key != "" {
+			segments = append(segments, pathSegment{key: key, index: -1})
+		}
+		for rest != "" {
+			idx, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid path %q: missing ]", p)
+			}
+			n, err := strconv.Atoi(idx)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", p, idx)
+			}
+			segments = append(segments, pathSegment{index: n})
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("invalid path %q", p)
+			}
+			rest = after[1:]
+		}
+	}
+	return segments, nil
+}
+
+// lookup resolves a JSON path against decoded JSON arguments.
+func lookup(args any, p string) (any, bool) {
+	segments, err := parsePath(p)
+	if err != nil {
+		return nil, false
+	}
+
+	current := args
+	for _, seg := range segments {
+		if seg.index >= 0 {
+			list, ok := current.([]any)
+			if !ok || seg.index >= len(list) {
+				return nil, false
+			}
+			current = list[seg.index]
+			continue
+		}
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[seg.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,241 @@
+package permissions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustLoad(t *testing.T, rules []Rule) *Ruleset {
+	t.Helper()
+	rs, err := Load(rules, "", "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return rs
+}
+
+func TestEvaluate(t *testing.T) {
+	rs := mustLoad(t, []Rule{
+		{Tool: "bash__run_shell_cmd", Action: Allow, When: []Condition{{Path: "command", Prefix: "git status"}}},
+		{Tool: "fs__write_file", Action: Deny, When: []Condition{{Path: "path", Within: "./src", Not: true}}},
+		{Tool: "fs__*", Action: Allow},
+		{Tool: "fs__delete_*", Action: Ask},
+	})
+
+	tests := []struct {
+		name string
+		tool string
+		args string
+		want Action
+	}{
+		{"prefix matches", "bash__run_shell_cmd", `{"command": "git status --short"}`, Allow},
+		{"prefix does not match", "bash__run_shell_cmd", `{"command": "rm -rf /"}`, ""},
+		{"missing argument", "bash__run_shell_cmd", `{}`, ""},
+		{"write inside src", "fs__write_file", `{"path": "src/main.go"}`, Allow},
+		{"write outside src", "fs__write_file", `{"path": "../etc/passwd"}`, Deny},
+		{"write via traversal", "fs__write_file", `{"path": "src/../../x"}`, Deny},
+		{"glob allows", "fs__read_file", `{"path": "/etc/hosts"}`, Allow},
+		{"ask beats allow", "fs__delete_file", `{}`, Ask},
+		{"unmatched tool", "todo__todoread", `{}`, ""},
+		{"invalid arguments", "bash__run_shell_cmd", `not json`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rule := rs.Evaluate(tt.tool, tt.args)
+			if got != tt.want {
+				t.Fatalf("Evaluate(%s, %s) = %q, want %q", tt.tool, tt.args, got, tt.want)
+			}
+			if (rule != nil) != (tt.want != "") {
+				t.Fatalf("expected rule only when an action is returned, got %+v", rule)
+			}
+		})
+	}
+}
+
+func TestEvaluate_Operators(t *testing.T) {
+	tests := []struct {
+		name string
+		cond Condition
+		args string
+		want bool
+	}{
+		{"equals", Condition{Path: "mode", Equals: "read"}, `{"mode": "read"}`, true},
+		{"equals number", Condition{Path: "count", Equals: "3"}, `{"count": 3}`, true},
+		{"contains", Condition{Path: "url", Contains: "example.com"}, `{"url": "https://example.com/x"}`, true},
+		{"matches", Condition{Path: "url", Matches: `^https://`}, `{"url": "http://example.com"}`, false},
+		{"nested path", Condition{Path: "$.options.path", Equals: "a"}, `{"options": {"path": "a"}}`, true},
+		{"array index", Condition{Path: "files[1]", Equals: "b"}, `{"files": ["a", "b"]}`, true},
+		{"index out of range", Condition{Path: "files[5]", Equals: "b"}, `{"files": ["a", "b"]}`, false},
+		{"not", Condition{Path: "mode", Equals: "read", Not: true}, `{"mode": "write"}`, true},
+		{"not with missing path", Condition{Path: "mode", Equals: "read", Not: true}, `{}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := mustLoad(t, []Rule{{Tool: "t", Action: Allow, When: []Condition{tt.cond}}})
+			got, _ := rs.Evaluate("t", tt.args)
+			if (got == Allow) != tt.want {
+				t.Fatalf("condition %+v on %s: got %q, want match=%v", tt.cond, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate_NilRuleset(t *testing.T) {
+	var rs *Ruleset
+	if action, rule := rs.Evaluate("any", "{}"); action != "" || rule != nil {
+		t.Fatalf("expected nil ruleset to match nothing, got %q %+v", action, rule)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]Rule{
+		"bad action":     {Tool: "x", Action: "maybe"},
+		"missing tool":   {Action: Allow},
+		"bad glob":       {Tool: "[", Action: Allow},
+		"no operator":    {Tool: "x", Action: Allow, When: []Condition{{Path: "a"}}},
+		"two operators":  {Tool: "x", Action: Allow, When: []Condition{{Path: "a", Equals: "1", Prefix: "1"}}},
+		"bad regexp":     {Tool: "x", Action: Allow, When: []Condition{{Path: "a", Matches: "("}}},
+		"bad path index": {Tool: "x", Action: Allow, When: []Condition{{Path: "a[x]", Equals: "1"}}},
+		"missing path":   {Tool: "x", Action: Allow, When: []Condition{{Equals: "1"}}},
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load([]Rule{rule}, "", ""); err == nil {
+				t.Fatalf("expected error for %+v", rule)
+			}
+		})
+	}
+}
+
+func TestAddProjectRule(t *testing.T) {
+	projectPath := filepath.Join(t.TempDir(), ".mcphost", "permissions.yml")
+	approvalsPath := filepath.Join(t.TempDir(), ApprovalsFile)
+
+	rs, err := Load([]Rule{{Tool: "fs__*", Action: Deny}}, projectPath, approvalsPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !rs.CanPersist() {
+		t.Fatal("expected ruleset with a project path to persist")
+	}
+
+	rule := Rule{Tool: "todo__todoread", Action: Allow}
+	if err := rs.AddProjectRule(rule); err != nil {
+		t.Fatalf("AddProjectRule: %v", err)
+	}
+	// Adding the same rule twice must not duplicate it on disk.
+	if err := rs.AddProjectRule(rule); err != nil {
+		t.Fatalf("AddProjectRule: %v", err)
+	}
+
+	if action, _ := rs.Evaluate("todo__todoread", "{}"); action != Allow {
+		t.Fatalf("expected new rule to apply immediately, got %q", action)
+	}
+
+	data, err := os.ReadFile(projectPath)
+	if err != nil {
+		t.Fatalf("reading project file: %v", err)
+	}
+	if strings.Count(string(data), "todo__todoread") != 1 {
+		t.Fatalf("expected the rule to be saved once, got:\n%s", data)
+	}
+	if strings.Contains(string(data), "fs__*") {
+		t.Fatalf("config file rules must not be copied to the project file, got:\n%s", data)
+	}
+
+	// A fresh load picks the saved rule up again.
+	reloaded, err := Load(nil, projectPath, approvalsPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if action, _ := reloaded.Evaluate("todo__todoread", "{}"); action != Allow {
+		t.Fatalf("expected saved rule after reload, got %q", action)
+	}
+}
+
+// TestLoad_untrustedProjectFile verifies that a project file's allow rules
+// only apply once approved in the prompt, while its deny rules always apply.
+func TestLoad_untrustedProjectFile(t *testing.T) {
+	projectPath := filepath.Join(t.TempDir(), ".mcphost", "permissions.yml")
+	approvalsPath := filepath.Join(t.TempDir(), ApprovalsFile)
+	if err := os.MkdirAll(filepath.Dir(projectPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	project := "permissions:\n  - tool: bash__*\n    action: allow\n  - tool: fs__delete\n    action: deny\n"
+	if err := os.WriteFile(projectPath, []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := Load(nil, projectPath, approvalsPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if action, _ := rs.Evaluate("bash__run", "{}"); action != "" {
+		t.Fatalf("expected the unapproved allow rule to be ignored, got %q", action)
+	}
+	if action, _ := rs.Evaluate("fs__delete", "{}"); action != Deny {
+		t.Fatalf("expected the deny rule to apply, got %q", action)
+	}
+	if ignored := rs.Ignored(); len(ignored) != 1 || ignored[0].Tool != "bash__*" {
+		t.Fatalf("expected the allow rule to be reported as ignored, got %+v", ignored)
+	}
+
+	// Without an approvals file no allow rule of the project file applies,
+	// and none can be saved
+	rs, err = Load(nil, projectPath, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if action, _ := rs.Evaluate("bash__run", "{}"); action != "" || rs.CanPersist() {
+		t.Fatalf("expected no allow rule and no persistence, got %q and %v", action, rs.CanPersist())
+	}
+
+	// Approving the rule in the prompt records it, so it applies from then on
+	if err := rs.AddProjectRule(Rule{Tool: "todo__*", Action: Allow}); err != nil {
+		t.Fatalf("AddProjectRule: %v", err)
+	}
+	rs, err = Load(nil, projectPath, approvalsPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := rs.AddProjectRule(Rule{Tool: "bash__*", Action: Allow}); err != nil {
+		t.Fatalf("AddProjectRule: %v", err)
+	}
+	reloaded, err := Load(nil, projectPath, approvalsPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if action, _ := reloaded.Evaluate("bash__run", "{}"); action != Allow {
+		t.Fatalf("expected the approved rule to apply, got %q", action)
+	}
+	if ignored := reloaded.Ignored(); len(ignored) != 0 {
+		t.Fatalf("expected no ignored rules, got %+v", ignored)
+	}
+
+	// Approvals are per project file
+	other := filepath.Join(t.TempDir(), "permissions.yml")
+	if err := os.WriteFile(other, []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err = Load(nil, other, approvalsPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if action, _ := rs.Evaluate("bash__run", "{}"); action != "" {
+		t.Fatalf("expected approvals of another project file not to apply, got %q", action)
+	}
+}
+
+func TestAddProjectRule_Invalid(t *testing.T) {
+	rs := mustLoad(t, nil)
+	if err := rs.AddProjectRule(Rule{Tool: "x", Action: "sometimes"}); err == nil {
+		t.Fatal("expected invalid rule to be rejected")
+	}
+	if len(rs.Rules()) != 0 {
+		t.Fatal("invalid rule must not be added")
+	}
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -9,8 +10,15 @@ import (
 	"time"
 
 	"charm.land/fantasy"
+
+	"github.com/mark3labs/mcphost/internal/attachments"
 )
 
+// maxInlineAttachment is the largest attachment stored inline in a session
+// file. Larger attachments are stored by reference to the file they were
+// read from, when it is known.
+const maxInlineAttachment = 512 << 10
+
 // Session represents a complete conversation session with metadata.
 // It stores all messages exchanged during a conversation along with
 // contextual information about the session such as the provider, model,
@@ -56,6 +64,36 @@ type Message struct {
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 	// ToolCallID links a tool result message to its corresponding tool call
 	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Reasoning contains the model's reasoning ("thinking") blocks that
+	// preceded an assistant message
+	Reasoning []Reasoning `json:"reasoning,omitempty"`
+	// Attachments contains the images and other files attached to a user
+	// message (text files are part of Content), or the media returned by a
+	// tool, whose text rendering is Content
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment represents a file attached to a user message. It is stored
+// either inline (Data) or by reference to the file it was read from (Path).
+type Attachment struct {
+	// Filename is the file's name as sent to the model
+	Filename string `json:"filename"`
+	// MediaType is the media type of the file, e.g. "image/png"
+	MediaType string `json:"media_type"`
+	// Path is the file the attachment was read from
+	Path string `json:"path,omitempty"`
+	// Data is the file content (base64 in JSON); empty when stored by reference
+	Data []byte `json:"data,omitempty"`
+}
+
+// Reasoning represents a reasoning block of an assistant message.
+type Reasoning struct {
+	// Text is the reasoning text; it may be empty for redacted reasoning
+	Text string `json:"text"`
+	// ProviderOptions holds provider data that must be sent back with the
+	// block, such as Anthropic's thinking signature, keyed by provider and
+	// encoded with fantasy's provider type registry
+	ProviderOptions map[string]json.RawMessage `json:"provider_options,omitempty"`
 }
 
 // ToolCall represents a tool invocation within an assistant message.
@@ -140,6 +178,13 @@ func ConvertFromFantasyMessage(msg fantasy.Message) Message {
 		switch p := part.(type) {
 		case fantasy.TextPart:
 			textParts = append(textParts, p.Text)
+		case fantasy.FilePart:
+			sessionMsg.Attachments = append(sessionMsg.Attachments, convertFilePart(p))
+		case fantasy.ReasoningPart:
+			sessionMsg.Reasoning = append(sessionMsg.Reasoning, Reasoning{
+				Text:            p.Text,
+				ProviderOptions: marshalProviderOptions(p.ProviderOptions),
+			})
 		case fantasy.ToolCallPart:
 			sessionMsg.ToolCalls = append(sessionMsg.ToolCalls, ToolCall{
 				ID:        p.ToolCallID,
@@ -149,6 +194,14 @@ func ConvertFromFantasyMessage(msg fantasy.Message) Message {
 		case fantasy.ToolResultPart:
 			// Tool result messages — store the tool call ID
 			sessionMsg.ToolCallID = p.ToolCallID
+			// Media is stored as an attachment next to its text rendering
+			if media, ok := p.Output.(fantasy.ToolResultOutputContentMedia); ok {
+				textParts = append(textParts, media.Text)
+				if data, err := base64.StdEncoding.DecodeString(media.Data); err == nil {
+					sessionMsg.Attachments = append(sessionMsg.Attachments, Attachment{MediaType: media.MediaType, Data: data})
+				}
+				continue
+			}
 			// Marshal result for storage
 			if p.Output != nil {
 				if resultBytes, err := json.Marshal(p.Output); err == nil {
@@ -180,6 +233,19 @@ func (m *Message) ConvertToFantasyMessage() fantasy.Message {
 	// Build content parts based on role
 	switch m.Role {
 	case "assistant":
+		// Reasoning comes first, as the model produced it
+		for _, r := range m.Reasoning {
+			providerOptions, err := fantasy.UnmarshalProviderOptions(r.ProviderOptions)
+			if err != nil {
+				// Without its signature the block would be rejected by the
+				// provider, so leave it out
+				continue
+			}
+			msg.Content = append(msg.Content, fantasy.ReasoningPart{
+				Text:            r.Text,
+				ProviderOptions: providerOptions,
+			})
+		}
 		// Add text content if present
 		if m.Content != "" {
 			msg.Content = append(msg.Content, fantasy.TextPart{Text: m.Content})
@@ -204,6 +270,13 @@ func (m *Message) ConvertToFantasyMessage() fantasy.Message {
 		msg.Role = fantasy.MessageRoleTool
 		var resultContent fantasy.ToolResultOutputContent
 		resultContent = fantasy.ToolResultOutputContentText{Text: m.Content}
+		if len(m.Attachments) > 0 && m.Attachments[0].Data != nil {
+			resultContent = fantasy.ToolResultOutputContentMedia{
+				Data:      base64.StdEncoding.EncodeToString(m.Attachments[0].Data),
+				MediaType: m.Attachments[0].MediaType,
+				Text:      m.Content,
+			}
+		}
 
 		msg.Content = append(msg.Content, fantasy.ToolResultPart{
 			ToolCallID: m.ToolCallID,
@@ -211,6 +284,9 @@ func (m *Message) ConvertToFantasyMessage() fantasy.Message {
 		})
 	case "user":
 		msg.Content = append(msg.Content, fantasy.TextPart{Text: m.Content})
+		for _, a := range m.Attachments {
+			msg.Content = append(msg.Content, a.convertToFantasyPart())
+		}
 	case "system":
 		msg.Content = append(msg.Content, fantasy.TextPart{Text: m.Content})
 	default:
@@ -220,6 +296,55 @@ func (m *Message) ConvertToFantasyMessage() fantasy.Message {
 	return msg
 }
 
+// convertFilePart converts an attached file for storage. Large files read
+// from disk are stored by reference; everything else is stored inline.
+func convertFilePart(p fantasy.FilePart) Attachment {
+	a := Attachment{Filename: p.Filename, MediaType: p.MediaType}
+	if path := attachments.SourcePath(p); path != "" {
+		a.Path = path
+		if len(p.Data) > maxInlineAttachment {
+			return a
+		}
+	}
+	a.Data = p.Data
+	return a
+}
+
+// convertToFantasyPart converts a stored attachment back into a message part.
+// An attachment stored by reference is read from its file again; when that
+// fails the model is told the file is no longer available.
+func (a Attachment) convertToFantasyPart() fantasy.MessagePart {
+	data := a.Data
+	if data == nil && a.Path != "" {
+		var err error
+		if data, err = os.ReadFile(a.Path); err != nil {
+			return fantasy.TextPart{Text: fmt.Sprintf("[attachment %s is no longer available at %s]", a.Filename, a.Path)}
+		}
+	}
+	part := fantasy.FilePart{Filename: a.Filename, Data: data, MediaType: a.MediaType}
+	if a.Path != "" {
+		part.ProviderOptions = fantasy.ProviderOptions{attachments.SourceKey: &attachments.Source{Path: a.Path}}
+	}
+	return part
+}
+
+// marshalProviderOptions encodes provider options for storage. Options that
+// cannot be encoded are dropped.
+func marshalProviderOptions(options fantasy.ProviderOptions) map[string]json.RawMessage {
+	if len(options) == 0 {
+		return nil
+	}
+	encoded := make(map[string]json.RawMessage, len(options))
+	for provider, data := range options {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			continue
+		}
+		encoded[provider] = raw
+	}
+	return encoded
+}
+
 // generateMessageID generates a unique message ID.
 func generateMessageID() string {
 	bytes := make([]byte, 8)
@@ -0,0 +1,186 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+
+	"github.com/mark3labs/mcphost/internal/attachments"
+)
+
+func TestReasoningRoundTrip(t *testing.T) {
+	assistant := fantasy.Message{
+		Role: fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{
+			fantasy.ReasoningPart{
+				Text: "The user wants the weather, so I should call the tool.",
+				ProviderOptions: fantasy.ProviderOptions{
+					anthropic.Name: &anthropic.ReasoningOptionMetadata{Signature: "sig-123"},
+				},
+			},
+			fantasy.TextPart{Text: "Let me check."},
+			fantasy.ToolCallPart{ToolCallID: "call_1", ToolName: "weather", Input: `{"city":"Paris"}`},
+		},
+	}
+
+	// Save and load through a session file
+	s := NewSession()
+	s.AddMessage(ConvertFromFantasyMessage(assistant))
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+
+	msg := loaded.Messages[0].ConvertToFantasyMessage()
+	if len(msg.Content) != 3 {
+		t.Fatalf("Expected reasoning, text and tool call parts, got %d parts", len(msg.Content))
+	}
+
+	reasoning, ok := msg.Content[0].(fantasy.ReasoningPart)
+	if !ok {
+		t.Fatalf("Expected the reasoning part first, got %T", msg.Content[0])
+	}
+	if reasoning.Text != "The user wants the weather, so I should call the tool." {
+		t.Errorf("Unexpected reasoning text %q", reasoning.Text)
+	}
+	metadata, ok := reasoning.ProviderOptions[anthropic.Name].(*anthropic.ReasoningOptionMetadata)
+	if !ok {
+		t.Fatalf("Expected anthropic reasoning metadata, got %T", reasoning.ProviderOptions[anthropic.Name])
+	}
+	if metadata.Signature != "sig-123" {
+		t.Errorf("Expected the signature to survive, got %q", metadata.Signature)
+	}
+
+	if text, ok := msg.Content[1].(fantasy.TextPart); !ok || text.Text != "Let me check." {
+		t.Errorf("Expected the text part second, got %#v", msg.Content[1])
+	}
+}
+
+func TestReasoningWithUnknownProviderDataIsDropped(t *testing.T) {
+	m := Message{
+		Role:    "assistant",
+		Content: "Done.",
+		Reasoning: []Reasoning{{
+			Text:            "thinking",
+			ProviderOptions: map[string]json.RawMessage{"other": json.RawMessage(`{"type":"other.metadata","data":{}}`)},
+		}},
+	}
+
+	msg := m.ConvertToFantasyMessage()
+	if len(msg.Content) != 1 {
+		t.Fatalf("Expected only the text part, got %d parts", len(msg.Content))
+	}
+	if _, ok := msg.Content[0].(fantasy.TextPart); !ok {
+		t.Errorf("Expected a text part, got %T", msg.Content[0])
+	}
+}
+
+func TestAttachmentRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	small := []byte("small image")
+	large := make([]byte, maxInlineAttachment+1)
+	largePath := filepath.Join(dir, "large.png")
+	if err := os.WriteFile(largePath, large, 0644); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+
+	user := fantasy.NewUserMessage("What is in these?",
+		fantasy.FilePart{Filename: "small.png", MediaType: "image/png", Data: small},
+		fantasy.FilePart{
+			Filename:        "large.png",
+			MediaType:       "image/png",
+			Data:            large,
+			ProviderOptions: fantasy.ProviderOptions{attachments.SourceKey: &attachments.Source{Path: largePath}},
+		},
+	)
+
+	stored := ConvertFromFantasyMessage(user)
+	if len(stored.Attachments) != 2 {
+		t.Fatalf("Expected 2 attachments, got %d", len(stored.Attachments))
+	}
+	if stored.Attachments[0].Data == nil || stored.Attachments[0].Path != "" {
+		t.Errorf("Expected the small attachment inline, got %+v", stored.Attachments[0])
+	}
+	if stored.Attachments[1].Data != nil || stored.Attachments[1].Path != largePath {
+		t.Errorf("Expected the large attachment by reference, got path %q and %d bytes",
+			stored.Attachments[1].Path, len(stored.Attachments[1].Data))
+	}
+
+	// Save and load through a session file
+	s := NewSession()
+	s.AddMessage(stored)
+	path := filepath.Join(dir, "session.json")
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+
+	msg := loaded.Messages[0].ConvertToFantasyMessage()
+	if len(msg.Content) != 3 {
+		t.Fatalf("Expected text and 2 file parts, got %d parts", len(msg.Content))
+	}
+	for i, want := range [][]byte{small, large} {
+		file, ok := msg.Content[i+1].(fantasy.FilePart)
+		if !ok {
+			t.Fatalf("Expected a file part at %d, got %T", i+1, msg.Content[i+1])
+		}
+		if !bytes.Equal(file.Data, want) {
+			t.Errorf("Attachment %s did not survive the round trip", file.Filename)
+		}
+	}
+	if got := attachments.SourcePath(msg.Content[2].(fantasy.FilePart)); got != largePath {
+		t.Errorf("Expected the source path to be kept, got %q", got)
+	}
+
+	// A referenced file that is gone is replaced by a note
+	if err := os.Remove(largePath); err != nil {
+		t.Fatalf("Failed to remove attachment: %v", err)
+	}
+	msg = loaded.Messages[0].ConvertToFantasyMessage()
+	note, ok := msg.Content[2].(fantasy.TextPart)
+	if !ok || !strings.Contains(note.Text, "no longer available") {
+		t.Errorf("Expected a note for the missing attachment, got %#v", msg.Content[2])
+	}
+}
+
+func TestToolMediaResultRoundTrip(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte("png bytes"))
+	tool := fantasy.Message{
+		Role: fantasy.MessageRoleTool,
+		Content: []fantasy.MessagePart{fantasy.ToolResultPart{
+			ToolCallID: "call_1",
+			Output:     fantasy.ToolResultOutputContentMedia{Data: data, MediaType: "image/png", Text: "[image 1x1 png]"},
+		}},
+	}
+
+	stored := ConvertFromFantasyMessage(tool)
+	if stored.Content != "[image 1x1 png]" || len(stored.Attachments) != 1 {
+		t.Fatalf("Expected the text rendering and one attachment, got %q and %d attachments", stored.Content, len(stored.Attachments))
+	}
+
+	result, ok := stored.ConvertToFantasyMessage().Content[0].(fantasy.ToolResultPart)
+	if !ok {
+		t.Fatal("Expected a tool result part")
+	}
+	media, ok := result.Output.(fantasy.ToolResultOutputContentMedia)
+	if !ok {
+		t.Fatalf("Expected a media result, got %T", result.Output)
+	}
+	if media.Data != data || media.MediaType != "image/png" || media.Text != "[image 1x1 png]" {
+		t.Errorf("Media result did not survive the round trip: %+v", media)
+	}
+}
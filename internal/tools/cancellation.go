@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// methodNotificationCancelled is the method of the notification cancelling a
+// request, which mcp-go does not declare.
+const methodNotificationCancelled = "notifications/cancelled"
+
+// cancelNotificationTimeout bounds sending notifications/cancelled, which
+// happens after the request's own context is done.
+const cancelNotificationTimeout = 5 * time.Second
+
+// cancellingTransport wraps the transport of an MCP client so that requests
+// abandoned because their context was cancelled or timed out, such as the
+// tool calls of a cancelled step, are cancelled on the server too: it sends
+// notifications/cancelled with the request's ID, and the server can stop
+// working on it. The initialize request is never cancelled, as MCP requires.
+//
+// mcp-go's client looks for optional transport interfaces, such as
+// transport.BidirectionalInterface for the requests of the server; they are
+// passed through to the wrapped transport when it implements them.
+type cancellingTransport struct {
+	transport.Interface
+}
+
+// newCancellingTransport wraps t in a cancellingTransport.
+func newCancellingTransport(t transport.Interface) *cancellingTransport {
+	return &cancellingTransport{Interface: t}
+}
+
+// SendRequest sends request and, when ctx ends before the response arrives,
+// tells the server the request was cancelled.
+func (t *cancellingTransport) SendRequest(ctx context.Context, request transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	response, err := t.Interface.SendRequest(ctx, request)
+	if err != nil && ctx.Err() != nil && request.Method != string(mcp.MethodInitialize) {
+		t.cancel(request.ID, ctx.Err())
+	}
+	return response, err
+}
+
+// cancel sends notifications/cancelled for the request with the given ID.
+// Failures are ignored: the server may be gone, and the request was already
+// abandoned.
+func (t *cancellingTransport) cancel(id mcp.RequestId, cause error) {
+	reason := "request cancelled by the client"
+	if errors.Is(cause, context.DeadlineExceeded) {
+		reason = "request timed out"
+	}
+
+	var notification mcp.JSONRPCNotification
+	notification.JSONRPC = mcp.JSONRPC_VERSION
+	notification.Method = methodNotificationCancelled
+	notification.Params.AdditionalFields = map[string]any{
+		"requestId": id,
+		"reason":    reason,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelNotificationTimeout)
+	defer cancel()
+	_ = t.SendNotification(ctx, notification)
+}
+
+// SetRequestHandler implements transport.BidirectionalInterface.
+func (t *cancellingTransport) SetRequestHandler(handler transport.RequestHandler) {
+	if bidirectional, ok := t.Interface.(transport.BidirectionalInterface); ok {
+		bidirectional.SetRequestHandler(handler)
+	}
+}
+
+// SetProtocolVersion implements transport.HTTPConnection.
+func (t *cancellingTransport) SetProtocolVersion(version string) {
+	if httpConn, ok := t.Interface.(transport.HTTPConnection); ok {
+		httpConn.SetProtocolVersion(version)
+	}
+}
+
+// SetConnectionLostHandler passes handler to the wrapped transport when it
+// reports lost connections.
+func (t *cancellingTransport) SetConnectionLostHandler(handler func(error)) {
+	if setter, ok := t.Interface.(interface{ SetConnectionLostHandler(func(error)) }); ok {
+		setter.SetConnectionLostHandler(handler)
+	}
+}
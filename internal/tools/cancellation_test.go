@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// TestCancelledToolCall_notifiesServer runs a tool of a streamable HTTP
+// server that never finishes and verifies that the server is sent
+// notifications/cancelled once the call's context times out.
+func TestCancelledToolCall_notifiesServer(t *testing.T) {
+	release := make(chan struct{})
+	cancelled := make(chan mcp.JSONRPCNotification, 1)
+
+	srv := server.NewMCPServer("slow", "1.0.0", server.WithToolCapabilities(true))
+	srv.AddTool(mcp.NewTool("wait", mcp.WithDescription("Never finishes")),
+		func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return mcp.NewToolResultText("done"), nil
+		})
+	srv.AddNotificationHandler(methodNotificationCancelled, func(_ context.Context, n mcp.JSONRPCNotification) {
+		cancelled <- n
+	})
+	ts := httptest.NewServer(server.NewStreamableHTTPServer(srv))
+	defer ts.Close()
+	defer close(release)
+
+	manager := NewMCPToolManager()
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServerConfig{
+			"slow": {Type: "remote", URL: ts.URL + "/mcp"},
+		},
+	}
+	loadCtx, cancelLoad := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelLoad()
+	if err := manager.LoadTools(loadCtx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := findTool(t, manager, "slow__wait").Run(ctx, fantasy.ToolCall{ID: "1", Name: "slow__wait", Input: "{}"}); err == nil {
+		t.Fatal("expected the timed out call to fail")
+	}
+
+	select {
+	case n := <-cancelled:
+		if n.Params.AdditionalFields["requestId"] == nil {
+			t.Errorf("expected the ID of the cancelled request, got %v", n.Params.AdditionalFields)
+		}
+		if reason := n.Params.AdditionalFields["reason"]; reason != "request timed out" {
+			t.Errorf("expected the timeout as reason, got %v", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server never saw the cancellation")
+	}
+}
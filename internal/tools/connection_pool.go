@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -52,6 +53,7 @@ type MCPConnection struct {
 	isHealthy    bool
 	errorCount   int
 	lastError    error
+	capabilities mcp.ServerCapabilities // announced by the server when initialized
 	mu           sync.RWMutex
 }
 
@@ -62,6 +64,7 @@ type MCPConnection struct {
 // to proactively identify and remove unhealthy connections.
 type MCPConnectionPool struct {
 	connections map[string]*MCPConnection
+	pending     map[string]*pendingConnection // connections being created
 	config      *ConnectionPoolConfig
 	mu          sync.RWMutex
 	model       fantasy.LanguageModel
@@ -69,6 +72,31 @@ type MCPConnectionPool struct {
 	cancel      context.CancelFunc
 	debug       bool
 	debugLogger DebugLogger
+	taskRunner  builtin.TaskRunner // handed to the builtin task server
+
+	// onNotification receives the notifications of every server; set before
+	// the first connection.
+	onNotification func(serverName string, notification mcp.JSONRPCNotification)
+
+	// samplingMu protects the sampling state; the funcs are set after the
+	// first connections are made and read when a server samples.
+	samplingMu       sync.Mutex
+	samplers         map[string]*sampler // per server, kept across reconnections
+	samplingApprove  SamplingApprovalFunc
+	samplingRecorder SamplingUsageFunc
+	samplingBudgetFn SamplingBudgetFunc
+
+	elicitationMu sync.Mutex
+	elicitationFn ElicitationFunc
+
+	authorizationMu sync.Mutex
+	authorizationFn AuthorizationURLFunc
+
+	// rootsMu protects the roots state, read when a server lists its roots.
+	rootsMu       sync.Mutex
+	rootsHandlers map[string]*rootsHandler // per server, kept across reconnections
+	workDir       string                   // root of servers configuring none
+	addedRoots    []string                 // added during the session, for every server
 }
 
 // NewMCPConnectionPool creates a new MCP connection pool with the specified configuration.
@@ -82,13 +110,16 @@ func NewMCPConnectionPool(config *ConnectionPoolConfig, model fantasy.LanguageMo
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	workDir, _ := os.Getwd()
 	pool := &MCPConnectionPool{
 		connections: make(map[string]*MCPConnection),
+		pending:     make(map[string]*pendingConnection),
 		config:      config,
 		model:       model,
 		ctx:         ctx,
 		cancel:      cancel,
 		debug:       debug,
+		workDir:     workDir,
 	}
 
 	go pool.startHealthCheck()
@@ -104,21 +135,129 @@ func (p *MCPConnectionPool) SetDebugLogger(logger DebugLogger) {
 	p.debugLogger = logger
 }
 
+// SetTaskRunner sets the sub-agent runner used by builtin task servers. It
+// must be called before the pool connects to a task server.
+func (p *MCPConnectionPool) SetTaskRunner(runner builtin.TaskRunner) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.taskRunner = runner
+}
+
+// SetNotificationHandler sets the function receiving the notifications of
+// the servers, such as notifications/tools/list_changed. It must be called
+// before the pool connects to any server.
+func (p *MCPConnectionPool) SetNotificationHandler(handler func(serverName string, notification mcp.JSONRPCNotification)) {
+	p.onNotification = handler
+}
+
+// SetSamplingApprovalFunc sets the callback consulted before the sampling
+// requests of servers whose approval is "ask". A nil func lets them run.
+func (p *MCPConnectionPool) SetSamplingApprovalFunc(fn SamplingApprovalFunc) {
+	p.samplingMu.Lock()
+	defer p.samplingMu.Unlock()
+	p.samplingApprove = fn
+}
+
+// SetSamplingUsageFunc sets the callback receiving the token usage of
+// sampling requests.
+func (p *MCPConnectionPool) SetSamplingUsageFunc(fn SamplingUsageFunc) {
+	p.samplingMu.Lock()
+	defer p.samplingMu.Unlock()
+	p.samplingRecorder = fn
+}
+
+// SetSamplingBudgetFunc sets the callback consulted before every sampling
+// request. A nil func lets them run.
+func (p *MCPConnectionPool) SetSamplingBudgetFunc(fn SamplingBudgetFunc) {
+	p.samplingMu.Lock()
+	defer p.samplingMu.Unlock()
+	p.samplingBudgetFn = fn
+}
+
+func (p *MCPConnectionPool) samplingApproval() SamplingApprovalFunc {
+	p.samplingMu.Lock()
+	defer p.samplingMu.Unlock()
+	return p.samplingApprove
+}
+
+func (p *MCPConnectionPool) samplingUsage() SamplingUsageFunc {
+	p.samplingMu.Lock()
+	defer p.samplingMu.Unlock()
+	return p.samplingRecorder
+}
+
+func (p *MCPConnectionPool) samplingBudget() SamplingBudgetFunc {
+	p.samplingMu.Lock()
+	defer p.samplingMu.Unlock()
+	return p.samplingBudgetFn
+}
+
+// SetElicitationFunc sets the callback answering the elicitation requests of
+// servers. A nil func declines them.
+func (p *MCPConnectionPool) SetElicitationFunc(fn ElicitationFunc) {
+	p.elicitationMu.Lock()
+	defer p.elicitationMu.Unlock()
+	p.elicitationFn = fn
+}
+
+func (p *MCPConnectionPool) elicitation() ElicitationFunc {
+	p.elicitationMu.Lock()
+	defer p.elicitationMu.Unlock()
+	return p.elicitationFn
+}
+
+// SetAuthorizationURLFunc sets the callback showing the authorization URL of
+// remote servers that require OAuth. A nil func leaves them unauthorized.
+func (p *MCPConnectionPool) SetAuthorizationURLFunc(fn AuthorizationURLFunc) {
+	p.authorizationMu.Lock()
+	defer p.authorizationMu.Unlock()
+	p.authorizationFn = fn
+}
+
+func (p *MCPConnectionPool) authorizationURL() AuthorizationURLFunc {
+	p.authorizationMu.Lock()
+	defer p.authorizationMu.Unlock()
+	return p.authorizationFn
+}
+
+// clientOptions returns the options of the client of serverName, which
+// install the handlers of the requests the server may send to mcphost.
+// Roots and elicitation are always offered. Sampling is offered when there is
+// a model and the server's sampling approval is not "deny".
+func (p *MCPConnectionPool) clientOptions(serverName string, serverConfig config.MCPServerConfig) []client.ClientOption {
+	options := []client.ClientOption{
+		client.WithRootsHandler(p.rootsHandlerFor(serverName, serverConfig)),
+		client.WithElicitationHandler(&elicitor{pool: p, serverName: serverName}),
+	}
+	if p.model != nil && (serverConfig.Sampling == nil || serverConfig.Sampling.Approval != config.SamplingApprovalDeny) {
+		p.samplingMu.Lock()
+		s, ok := p.samplers[serverName]
+		if !ok {
+			s = newSampler(p, serverName, serverConfig.Sampling)
+			if p.samplers == nil {
+				p.samplers = make(map[string]*sampler)
+			}
+			p.samplers[serverName] = s
+		}
+		p.samplingMu.Unlock()
+		options = append(options, client.WithSamplingHandler(s))
+	}
+	return options
+}
+
 // GetConnection retrieves or creates a connection for the specified MCP server.
 // If a healthy, non-idle connection exists in the pool, it will be reused.
 // Otherwise, a new connection is created and added to the pool.
 // Returns an error if connection creation or initialization fails.
-// Thread-safe for concurrent calls.
+// Thread-safe for concurrent calls; connections to different servers are
+// created concurrently.
 func (p *MCPConnectionPool) GetConnection(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (*MCPConnection, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if conn, exists := p.connections[serverName]; exists {
-		conn.mu.RLock()
-		isHealthy := conn.isHealthy && time.Since(conn.lastUsed) < p.config.MaxIdleTime
-		conn.mu.RUnlock()
+	p.mu.RLock()
+	conn, exists := p.connections[serverName]
+	p.mu.RUnlock()
 
-		if isHealthy {
+	if exists {
+		if p.isUsable(conn) {
 			conn.mu.Lock()
 			conn.lastUsed = time.Now()
 			conn.mu.Unlock()
@@ -126,27 +265,14 @@ func (p *MCPConnectionPool) GetConnection(ctx context.Context, serverName string
 				p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Reusing connection for %s", serverName))
 			}
 			return conn, nil
-		} else {
-			if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
-				if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
-					p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Connection %s unhealthy, removing", serverName))
-				}
-			}
-			_ = conn.client.Close()
-			delete(p.connections, serverName)
 		}
+		if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
+			p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Connection %s unhealthy, removing", serverName))
+		}
+		p.removeConnection(serverName, conn)
 	}
 
-	if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
-		p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Creating new connection for %s", serverName))
-	}
-	conn, err := p.createConnection(ctx, serverName, serverConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create connection for %s: %w", serverName, err)
-	}
-
-	p.connections[serverName] = conn
-	return conn, nil
+	return p.connect(ctx, serverName, serverConfig)
 }
 
 // GetConnectionWithHealthCheck retrieves a connection with an additional proactive health check.
@@ -156,15 +282,12 @@ func (p *MCPConnectionPool) GetConnection(ctx context.Context, serverName string
 // existing one fails the health check or doesn't exist.
 // Thread-safe for concurrent calls.
 func (p *MCPConnectionPool) GetConnectionWithHealthCheck(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (*MCPConnection, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if conn, exists := p.connections[serverName]; exists {
-		conn.mu.RLock()
-		isHealthy := conn.isHealthy && time.Since(conn.lastUsed) < p.config.MaxIdleTime
-		conn.mu.RUnlock()
+	p.mu.RLock()
+	conn, exists := p.connections[serverName]
+	p.mu.RUnlock()
 
-		if isHealthy {
+	if exists {
+		if p.isUsable(conn) {
 			// Perform proactive health check before reusing connection
 			if p.performHealthCheck(ctx, conn) {
 				conn.mu.Lock()
@@ -174,19 +297,64 @@ func (p *MCPConnectionPool) GetConnectionWithHealthCheck(ctx context.Context, se
 					p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Reusing healthy connection for %s", serverName))
 				}
 				return conn, nil
-			} else {
-				if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
-					p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Connection %s failed health check, removing", serverName))
-				}
-				_ = conn.client.Close()
-				delete(p.connections, serverName)
 			}
-		} else {
 			if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
-				p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Connection %s unhealthy, removing", serverName))
+				p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Connection %s failed health check, removing", serverName))
 			}
-			_ = conn.client.Close()
-			delete(p.connections, serverName)
+		} else if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
+			p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Connection %s unhealthy, removing", serverName))
+		}
+		p.removeConnection(serverName, conn)
+	}
+
+	return p.connect(ctx, serverName, serverConfig)
+}
+
+// isUsable reports whether conn is healthy and was used recently enough to
+// be reused.
+func (p *MCPConnectionPool) isUsable(conn *MCPConnection) bool {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	return conn.isHealthy && time.Since(conn.lastUsed) < p.config.MaxIdleTime
+}
+
+// removeConnection closes conn and removes it from the pool, unless another
+// caller replaced it with a new connection already.
+func (p *MCPConnectionPool) removeConnection(serverName string, conn *MCPConnection) {
+	p.mu.Lock()
+	if p.connections[serverName] == conn {
+		delete(p.connections, serverName)
+	}
+	p.mu.Unlock()
+	_ = conn.client.Close()
+}
+
+// pendingConnection is a connection being created. Callers asking for the
+// same server meanwhile wait for it instead of starting the server again.
+type pendingConnection struct {
+	done chan struct{} // closed once conn or err is set
+	conn *MCPConnection
+	err  error
+}
+
+// connect creates a connection for serverName and adds it to the pool. The
+// pool is not locked while the server starts, which may take long, so other
+// servers are connected to meanwhile.
+func (p *MCPConnectionPool) connect(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (*MCPConnection, error) {
+	p.mu.Lock()
+	pending, waiting := p.pending[serverName]
+	if !waiting {
+		pending = &pendingConnection{done: make(chan struct{})}
+		p.pending[serverName] = pending
+	}
+	p.mu.Unlock()
+
+	if waiting {
+		select {
+		case <-pending.done:
+			return pending.conn, pending.err
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to create connection for %s: %w", serverName, ctx.Err())
 		}
 	}
 
@@ -195,11 +363,24 @@ func (p *MCPConnectionPool) GetConnectionWithHealthCheck(ctx context.Context, se
 	}
 	conn, err := p.createConnection(ctx, serverName, serverConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create connection for %s: %w", serverName, err)
+		err = fmt.Errorf("failed to create connection for %s: %w", serverName, err)
 	}
 
-	p.connections[serverName] = conn
-	return conn, nil
+	p.mu.Lock()
+	delete(p.pending, serverName)
+	if err == nil && p.ctx.Err() != nil {
+		// The pool was closed while the server started.
+		_ = conn.client.Close()
+		conn, err = nil, fmt.Errorf("failed to create connection for %s: connection pool closed", serverName)
+	}
+	if err == nil {
+		p.connections[serverName] = conn
+	}
+	p.mu.Unlock()
+
+	pending.conn, pending.err = conn, err
+	close(pending.done)
+	return conn, err
 }
 
 // performHealthCheck performs a quick health check on the connection
@@ -231,16 +412,35 @@ func (p *MCPConnectionPool) performHealthCheck(ctx context.Context, conn *MCPCon
 	return true
 }
 
-// createConnection creates a new connection
+// createConnection creates a new connection. A remote server answering 401
+// is authorized with OAuth when there is an authorization URL func, and
+// connected to again.
 func (p *MCPConnectionPool) createConnection(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (*MCPConnection, error) {
-	client, err := p.createMCPClient(ctx, serverName, serverConfig)
+	client, initResult, err := p.startClient(ctx, serverName, serverConfig)
+	if err != nil && needsAuthorization(err) {
+		showURL := p.authorizationURL()
+		if showURL == nil {
+			return nil, fmt.Errorf("%w; run 'mcphost auth login --server %s' to authorize", err, serverName)
+		}
+		if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
+			p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Authorizing %s: %v", serverName, err))
+		}
+		if err := AuthorizeServer(ctx, serverName, serverConfig, showURL); err != nil {
+			return nil, err
+		}
+		client, initResult, err = p.startClient(ctx, serverName, serverConfig)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := p.initializeClient(ctx, client); err != nil {
-		_ = client.Close()
-		return nil, err
+	// Servers that log send messages from the configured level up; a server
+	// refusing the level still works, with its own default.
+	if level := serverConfig.LogLevel; level != "" && initResult.Capabilities.Logging != nil {
+		request := mcp.SetLevelRequest{Params: mcp.SetLevelParams{Level: mcp.LoggingLevel(level)}}
+		if err := client.SetLevel(ctx, request); err != nil && p.debugLogger != nil {
+			p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Failed to set the log level of %s: %v", serverName, err))
+		}
 	}
 
 	conn := &MCPConnection{
@@ -251,6 +451,7 @@ func (p *MCPConnectionPool) createConnection(ctx context.Context, serverName str
 		isHealthy:    true,
 		errorCount:   0,
 		lastError:    nil,
+		capabilities: initResult.Capabilities,
 	}
 
 	if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
@@ -259,17 +460,37 @@ func (p *MCPConnectionPool) createConnection(ctx context.Context, serverName str
 	return conn, nil
 }
 
+// startClient creates the client of serverName and initializes it.
+func (p *MCPConnectionPool) startClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, *mcp.InitializeResult, error) {
+	client, err := p.createMCPClient(ctx, serverName, serverConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.onNotification != nil {
+		client.OnNotification(func(notification mcp.JSONRPCNotification) {
+			p.onNotification(serverName, notification)
+		})
+	}
+
+	initResult, err := p.initializeClient(ctx, client)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+	return client, initResult, nil
+}
+
 // createMCPClient creates an MCP client
 func (p *MCPConnectionPool) createMCPClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
 	transportType := serverConfig.GetTransportType()
 
 	switch transportType {
 	case "stdio":
-		return p.createStdioClient(ctx, serverConfig)
+		return p.createStdioClient(ctx, serverName, serverConfig)
 	case "sse":
-		return p.createSSEClient(ctx, serverConfig)
+		return p.createSSEClient(ctx, serverName, serverConfig)
 	case "streamable":
-		return p.createStreamableClient(ctx, serverConfig)
+		return p.createStreamableClient(ctx, serverName, serverConfig)
 	case "inprocess":
 		return p.createBuiltinClient(ctx, serverName, serverConfig)
 	default:
@@ -278,7 +499,7 @@ func (p *MCPConnectionPool) createMCPClient(ctx context.Context, serverName stri
 }
 
 // createStdioClient creates a STDIO client
-func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
 	var env []string
 	var command string
 	var args []string
@@ -299,9 +520,11 @@ func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverConfig
 	}
 
 	stdioTransport := transport.NewStdio(command, env, args...)
-	stdioClient := client.NewClient(stdioTransport)
+	stdioClient := client.NewClient(newCancellingTransport(stdioTransport), p.clientOptions(serverName, serverConfig)...)
 
-	if err := stdioTransport.Start(ctx); err != nil {
+	// Starting the client rather than the transport also installs the
+	// handler of the server's requests.
+	if err := stdioClient.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start stdio transport: %v", err)
 	}
 
@@ -309,8 +532,10 @@ func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverConfig
 	return stdioClient, nil
 }
 
-// createSSEClient creates an SSE client
-func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+// createSSEClient creates an SSE client. The SSE transport cannot receive
+// requests from the server, so neither roots, sampling nor elicitation are
+// offered.
+func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
 	var options []transport.ClientOption
 
 	if len(serverConfig.Headers) > 0 {
@@ -327,21 +552,29 @@ func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverConfig co
 			options = append(options, transport.WithHeaders(headers))
 		}
 	}
-
-	sseClient, err := client.NewSSEMCPClient(serverConfig.URL, options...)
+	oauth, err := remoteOAuthConfig(serverName, serverConfig)
 	if err != nil {
 		return nil, err
 	}
+	if oauth != nil {
+		options = append(options, transport.WithOAuth(*oauth))
+	}
+
+	sseTransport, err := transport.NewSSE(serverConfig.URL, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSE transport: %w", err)
+	}
+	sseClient := client.NewClient(newCancellingTransport(sseTransport))
 
 	if err := sseClient.Start(ctx); err != nil {
-		return nil, fmt.Errorf("failed to start SSE client: %v", err)
+		return nil, fmt.Errorf("failed to start SSE client: %w", err)
 	}
 
 	return sseClient, nil
 }
 
 // createStreamableClient creates a Streamable client
-func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
 	var options []transport.StreamableHTTPCOption
 
 	if len(serverConfig.Headers) > 0 {
@@ -358,22 +591,32 @@ func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverCo
 			options = append(options, transport.WithHTTPHeaders(headers))
 		}
 	}
+	oauth, err := remoteOAuthConfig(serverName, serverConfig)
+	if err != nil {
+		return nil, err
+	}
+	if oauth != nil {
+		options = append(options, transport.WithHTTPOAuth(*oauth))
+	}
 
-	streamableClient, err := client.NewStreamableHttpClient(serverConfig.URL, options...)
+	streamableTransport, err := transport.NewStreamableHTTP(serverConfig.URL, options...)
 	if err != nil {
 		return nil, err
 	}
+	streamableClient := client.NewClient(newCancellingTransport(streamableTransport), p.clientOptions(serverName, serverConfig)...)
 
 	if err := streamableClient.Start(ctx); err != nil {
-		return nil, fmt.Errorf("failed to start streamable HTTP client: %v", err)
+		return nil, fmt.Errorf("failed to start streamable HTTP client: %w", err)
 	}
 
 	return streamableClient, nil
 }
 
-// createBuiltinClient creates a builtin client
+// createBuiltinClient creates a builtin client. Builtin servers run their
+// tools with the caller's context, so cancelling it stops them directly.
 func (p *MCPConnectionPool) createBuiltinClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
 	registry := builtin.NewRegistry()
+	registry.SetTaskRunner(p.taskRunner)
 
 	builtinServer, err := registry.CreateServer(serverConfig.Name, serverConfig.Options, p.model)
 	if err != nil {
@@ -388,8 +631,8 @@ func (p *MCPConnectionPool) createBuiltinClient(ctx context.Context, serverName
 	return inProcessClient, nil
 }
 
-// initializeClient initializes the client
-func (p *MCPConnectionPool) initializeClient(ctx context.Context, client client.MCPClient) error {
+// initializeClient initializes the client and returns the server's answer
+func (p *MCPConnectionPool) initializeClient(ctx context.Context, client client.MCPClient) (*mcp.InitializeResult, error) {
 	initCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
@@ -399,17 +642,19 @@ func (p *MCPConnectionPool) initializeClient(ctx context.Context, client client.
 		Name:    "mcphost",
 		Version: "1.0.0",
 	}
+	// The client adds the capabilities of the request handlers it was
+	// created with, such as sampling.
 	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
 
-	_, err := client.Initialize(initCtx, initRequest)
+	result, err := client.Initialize(initCtx, initRequest)
 	if err != nil {
-		return fmt.Errorf("initialization timeout or failed: %v", err)
+		return nil, fmt.Errorf("initialization timeout or failed: %w", err)
 	}
 
 	if p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
 		p.debugLogger.LogDebug("[POOL] Initialized MCP client")
 	}
-	return nil
+	return result, nil
 }
 
 // startHealthCheck starts the health check routine
@@ -519,6 +764,12 @@ func (c *MCPConnection) ServerName() string {
 	return c.serverName
 }
 
+// Capabilities returns the capabilities the server announced when the
+// connection was initialized.
+func (c *MCPConnection) Capabilities() mcp.ServerCapabilities {
+	return c.capabilities
+}
+
 // GetClients returns a map of all MCP clients currently in the pool.
 // The map keys are server names and values are the corresponding MCP client instances.
 // The returned map is a copy and modifications won't affect the pool.
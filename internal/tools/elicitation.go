@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ElicitationRequest is a request of an MCP server for structured input from
+// the user, such as confirming a deployment target.
+type ElicitationRequest struct {
+	// Message explains what is asked for and why.
+	Message string
+	// Fields are the values asked for, required ones first.
+	Fields []ElicitationField
+}
+
+// ElicitationField is one value of an elicitation request, a property of its
+// requested schema.
+type ElicitationField struct {
+	Name        string
+	Title       string
+	Description string
+	// Type is "string", "number", "integer" or "boolean".
+	Type     string
+	Required bool
+	Default  any
+	// Enum lists the allowed values of a string field, if restricted, and
+	// EnumNames their display names, if given.
+	Enum      []string
+	EnumNames []string
+	Minimum   *float64
+	Maximum   *float64
+	MinLength *int
+	MaxLength *int
+}
+
+// Label returns the title of the field, or its name.
+func (f ElicitationField) Label() string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.Name
+}
+
+// Value checks v against the field and returns it as the field's type.
+// Strings are parsed, so text typed by the user and answers read from a file
+// can both be passed as they are. An empty string or nil returns nil; the
+// caller decides whether the field may be left out.
+func (f ElicitationField) Value(v any) (any, error) {
+	if s, ok := v.(string); ok && f.Type != "string" {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, nil
+		}
+		var err error
+		switch f.Type {
+		case "boolean":
+			switch strings.ToLower(s) {
+			case "true", "yes", "y":
+				v = true
+			case "false", "no", "n":
+				v = false
+			default:
+				return nil, fmt.Errorf("%s must be yes or no", f.Label())
+			}
+		case "number", "integer":
+			if v, err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, fmt.Errorf("%s must be a number", f.Label())
+			}
+		}
+	}
+	if v == nil || v == "" {
+		return nil, nil
+	}
+
+	switch f.Type {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be text", f.Label())
+		}
+		if len(f.Enum) > 0 && !slices.Contains(f.Enum, s) {
+			return nil, fmt.Errorf("%s must be one of %s", f.Label(), strings.Join(f.Enum, ", "))
+		}
+		n := len([]rune(s))
+		if f.MinLength != nil && n < *f.MinLength {
+			return nil, fmt.Errorf("%s must have at least %d characters", f.Label(), *f.MinLength)
+		}
+		if f.MaxLength != nil && n > *f.MaxLength {
+			return nil, fmt.Errorf("%s must have at most %d characters", f.Label(), *f.MaxLength)
+		}
+		return s, nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be yes or no", f.Label())
+		}
+		return b, nil
+	default: // number, integer
+		var n float64
+		switch x := v.(type) {
+		case float64:
+			n = x
+		case int:
+			n = float64(x)
+		case int64:
+			n = float64(x)
+		default:
+			return nil, fmt.Errorf("%s must be a number", f.Label())
+		}
+		if f.Minimum != nil && n < *f.Minimum {
+			return nil, fmt.Errorf("%s must be at least %v", f.Label(), *f.Minimum)
+		}
+		if f.Maximum != nil && n > *f.Maximum {
+			return nil, fmt.Errorf("%s must be at most %v", f.Label(), *f.Maximum)
+		}
+		if f.Type == "integer" {
+			if n != math.Trunc(n) {
+				return nil, fmt.Errorf("%s must be a whole number", f.Label())
+			}
+			return int64(n), nil
+		}
+		return n, nil
+	}
+}
+
+// Content checks values, by field name, against the fields of the request
+// and returns the content of an "accept" response. Values of unknown fields
+// are dropped; a missing required field is an error.
+func (r ElicitationRequest) Content(values map[string]any) (map[string]any, error) {
+	content := make(map[string]any)
+	for _, field := range r.Fields {
+		v, err := field.Value(values[field.Name])
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			if field.Required {
+				return nil, fmt.Errorf("%s is required", field.Label())
+			}
+			continue
+		}
+		content[field.Name] = v
+	}
+	return content, nil
+}
+
+// ElicitationAction is how the user answered an elicitation request.
+type ElicitationAction string
+
+const (
+	// ElicitationAccept submits the requested values.
+	ElicitationAccept ElicitationAction = "accept"
+	// ElicitationDecline explicitly refuses to provide them.
+	ElicitationDecline ElicitationAction = "decline"
+	// ElicitationCancel dismisses the request without a choice.
+	ElicitationCancel ElicitationAction = "cancel"
+)
+
+// ElicitationResponse is the answer to an elicitation request. Content holds
+// the values, by field name, of an accepted request.
+type ElicitationResponse struct {
+	Action  ElicitationAction
+	Content map[string]any
+}
+
+// ElicitationFunc answers the elicitation requests of serverName. It may
+// block until the user answers. An error is passed on to the server.
+type ElicitationFunc func(ctx context.Context, serverName string, request ElicitationRequest) (ElicitationResponse, error)
+
+// SetElicitationFunc sets the callback answering the elicitation requests of
+// servers. Without one (the default) every request is declined. Like
+// SetSamplingApprovalFunc it can be set before or after LoadTools.
+func (m *MCPToolManager) SetElicitationFunc(fn ElicitationFunc) {
+	m.elicitationFunc = fn
+	if m.connectionPool != nil {
+		m.connectionPool.SetElicitationFunc(fn)
+	}
+}
+
+// elicitor answers the elicitation requests of one MCP server with the pool's
+// elicitation func.
+type elicitor struct {
+	pool       *MCPConnectionPool
+	serverName string
+}
+
+// Elicit implements client.ElicitationHandler. Only form requests are
+// supported; requests to open a URL are declined.
+func (e *elicitor) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	response := ElicitationResponse{Action: ElicitationDecline}
+	if request.Params.Mode == "" || request.Params.Mode == mcp.ElicitationModeForm {
+		fields, err := elicitationFields(request.Params.RequestedSchema)
+		if err != nil {
+			return nil, err
+		}
+		if fn := e.pool.elicitation(); fn != nil {
+			response, err = fn(ctx, e.serverName, ElicitationRequest{Message: request.Params.Message, Fields: fields})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := &mcp.ElicitationResult{}
+	result.Action = mcp.ElicitationResponseAction(response.Action)
+	if response.Action == ElicitationAccept {
+		result.Content = response.Content
+	}
+	return result, nil
+}
+
+// elicitationSchema is the restricted JSON Schema of an elicitation request:
+// an object of primitive properties.
+type elicitationSchema struct {
+	Type       string                         `json:"type"`
+	Properties map[string]elicitationProperty `json:"properties"`
+	Required   []string                       `json:"required"`
+}
+
+type elicitationProperty struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Default     any      `json:"default"`
+	Enum        []string `json:"enum"`
+	EnumNames   []string `json:"enumNames"`
+	OneOf       []struct {
+		Const string `json:"const"`
+		Title string `json:"title"`
+	} `json:"oneOf"`
+	Minimum   *float64 `json:"minimum"`
+	Maximum   *float64 `json:"maximum"`
+	MinLength *int     `json:"minLength"`
+	MaxLength *int     `json:"maxLength"`
+}
+
+// elicitationFields returns the fields of the requested schema, required
+// fields first and otherwise by name: the order of the properties is lost
+// when the request is decoded.
+func elicitationFields(schema any) ([]ElicitationField, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+	var s elicitationSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+	if s.Type != "" && s.Type != "object" {
+		return nil, fmt.Errorf("unsupported requested schema type %q", s.Type)
+	}
+
+	fields := make([]ElicitationField, 0, len(s.Properties))
+	for name, p := range s.Properties {
+		switch p.Type {
+		case "string", "number", "integer", "boolean":
+		default:
+			return nil, fmt.Errorf("unsupported type %q of property %s", p.Type, name)
+		}
+		field := ElicitationField{
+			Name:        name,
+			Title:       p.Title,
+			Description: p.Description,
+			Type:        p.Type,
+			Required:    slices.Contains(s.Required, name),
+			Default:     p.Default,
+			Enum:        p.Enum,
+			Minimum:     p.Minimum,
+			Maximum:     p.Maximum,
+			MinLength:   p.MinLength,
+			MaxLength:   p.MaxLength,
+		}
+		if len(p.EnumNames) == len(p.Enum) {
+			field.EnumNames = p.EnumNames
+		}
+		if len(field.Enum) == 0 {
+			for _, option := range p.OneOf {
+				field.Enum = append(field.Enum, option.Const)
+				field.EnumNames = append(field.EnumNames, option.Title)
+			}
+		}
+		fields = append(fields, field)
+	}
+	slices.SortFunc(fields, func(a, b ElicitationField) int {
+		if a.Required != b.Required {
+			if a.Required {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return fields, nil
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// deploySchema is a requested schema as decoded from a request.
+func deploySchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"target": map[string]any{
+				"type":      "string",
+				"title":     "Target",
+				"enum":      []any{"staging", "production"},
+				"enumNames": []any{"Staging", "Production"},
+			},
+			"replicas": map[string]any{"type": "integer", "minimum": 1.0, "maximum": 10.0},
+			"confirm":  map[string]any{"type": "boolean", "default": false},
+			"note":     map[string]any{"type": "string", "maxLength": 5.0},
+			"region": map[string]any{
+				"type":  "string",
+				"oneOf": []any{map[string]any{"const": "eu", "title": "Europe"}},
+			},
+		},
+		"required": []any{"target", "confirm"},
+	}
+}
+
+func TestElicitationFields(t *testing.T) {
+	fields, err := elicitationFields(deploySchema())
+	if err != nil {
+		t.Fatalf("elicitationFields: %v", err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	want := []string{"confirm", "target", "note", "region", "replicas"}
+	if len(names) != len(want) {
+		t.Fatalf("expected fields %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected fields %v, got %v", want, names)
+		}
+	}
+	if target := fields[1]; !target.Required || target.Label() != "Target" || len(target.EnumNames) != 2 {
+		t.Errorf("unexpected target field: %+v", target)
+	}
+	if region := fields[3]; len(region.Enum) != 1 || region.Enum[0] != "eu" || region.EnumNames[0] != "Europe" {
+		t.Errorf("expected the oneOf options of region, got %+v", region)
+	}
+
+	nested := map[string]any{"type": "object", "properties": map[string]any{"tags": map[string]any{"type": "array"}}}
+	if _, err := elicitationFields(nested); err == nil {
+		t.Error("expected an error for an array property")
+	}
+}
+
+func TestElicitationRequest_Content(t *testing.T) {
+	fields, err := elicitationFields(deploySchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := ElicitationRequest{Fields: fields}
+
+	content, err := request.Content(map[string]any{"target": "staging", "confirm": "yes", "replicas": "3", "unknown": 1})
+	if err != nil {
+		t.Fatalf("Content: %v", err)
+	}
+	if content["target"] != "staging" || content["confirm"] != true || content["replicas"] != int64(3) {
+		t.Errorf("unexpected content: %v", content)
+	}
+	if _, ok := content["unknown"]; ok {
+		t.Error("expected unknown fields dropped")
+	}
+
+	for name, values := range map[string]map[string]any{
+		"missing required": {"target": "staging"},
+		"not in enum":      {"target": "qa", "confirm": true},
+		"not a boolean":    {"target": "staging", "confirm": "maybe"},
+		"below minimum":    {"target": "staging", "confirm": true, "replicas": 0},
+		"not an integer":   {"target": "staging", "confirm": true, "replicas": 1.5},
+		"too long":         {"target": "staging", "confirm": true, "note": "too long"},
+	} {
+		if _, err := request.Content(values); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestElicitor(t *testing.T) {
+	pool := &MCPConnectionPool{}
+	e := &elicitor{pool: pool, serverName: "deployer"}
+	var request mcp.ElicitationRequest
+	request.Params.Message = "Where to?"
+	request.Params.RequestedSchema = deploySchema()
+
+	result, err := e.Elicit(context.Background(), request)
+	if err != nil || result.Action != mcp.ElicitationResponseActionDecline {
+		t.Fatalf("expected a decline without an elicitation func, got %+v, %v", result, err)
+	}
+
+	pool.SetElicitationFunc(func(_ context.Context, serverName string, r ElicitationRequest) (ElicitationResponse, error) {
+		if serverName != "deployer" || r.Message != "Where to?" || len(r.Fields) != 5 {
+			t.Errorf("unexpected request of %s: %+v", serverName, r)
+		}
+		return ElicitationResponse{Action: ElicitationAccept, Content: map[string]any{"target": "staging"}}, nil
+	})
+	result, err = e.Elicit(context.Background(), request)
+	if err != nil || result.Action != mcp.ElicitationResponseActionAccept {
+		t.Fatalf("expected the request accepted, got %+v, %v", result, err)
+	}
+	if content, ok := result.Content.(map[string]any); !ok || content["target"] != "staging" {
+		t.Errorf("unexpected content: %#v", result.Content)
+	}
+
+	request.Params.Mode = mcp.ElicitationModeURL
+	if result, _ := e.Elicit(context.Background(), request); result.Action != mcp.ElicitationResponseActionDecline {
+		t.Errorf("expected URL requests declined, got %+v", result)
+	}
+}
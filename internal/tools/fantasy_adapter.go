@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"charm.land/fantasy"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/permissions"
 )
 
 // mcpFantasyTool adapts an MCP tool to the fantasy.AgentTool interface.
@@ -25,7 +27,14 @@ func (t *mcpFantasyTool) Info() fantasy.ToolInfo {
 
 // Run executes the MCP tool by routing through the connection pool.
 // It maps the prefixed tool name back to the original name, retrieves a healthy
-// connection, invokes the tool, and converts the MCP result to a fantasy ToolResponse.
+// connection, invokes the tool, and converts the MCP result to a fantasy ToolResponse
+// (see convertToolResult).
+// PreToolUse hooks run before the call and may block it, in which case the hook's
+// reason is returned to the model as an error result; PostToolUse hooks run after it.
+// Permission rules are checked next: a deny rule rejects the call and an allow rule
+// lets it through. Otherwise, when an approval func is set, the call waits for it,
+// and a denial is likewise returned to the model as an error result. A lazy
+// server is started by the first call that gets past these checks.
 func (t *mcpFantasyTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
 	// Parse and validate JSON arguments
 	var arguments any
@@ -40,6 +49,42 @@ func (t *mcpFantasyTool) Run(ctx context.Context, call fantasy.ToolCall) (fantas
 		arguments = json.RawMessage(input)
 	}
 
+	// Run PreToolUse hooks; a block (or "continue": false) skips the call
+	if output := t.mapping.manager.firePreToolUse(ctx, t.toolInfo.Name, input); output != nil {
+		stopped := output.Continue != nil && !*output.Continue
+		if output.Decision == "block" || stopped {
+			reason := strings.TrimSpace(output.Reason)
+			if reason == "" {
+				reason = "no reason given"
+			}
+			return fantasy.NewTextErrorResponse(fmt.Sprintf("tool call blocked by PreToolUse hook: %s", reason)), nil
+		}
+	}
+
+	// Permission rules decide before anyone is asked
+	action, rule := t.mapping.manager.permissions.Evaluate(t.toolInfo.Name, input)
+	if action == permissions.Deny {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("tool call denied by permission rule: %s", rule)), nil
+	}
+
+	// Ask for approval; a denial goes back to the model so it can adjust
+	if approve := t.mapping.manager.approvalFunc; approve != nil && action != permissions.Allow {
+		approved, err := approve(ctx, t.toolInfo.Name, input)
+		if err != nil {
+			return fantasy.ToolResponse{}, fmt.Errorf("tool approval failed: %w", err)
+		}
+		if !approved {
+			return fantasy.NewTextErrorResponse("tool call denied by the user"), nil
+		}
+	}
+
+	// Lazy servers start on the first call of one of their tools
+	if t.mapping.serverConfig.Lazy {
+		if err := t.mapping.manager.startLazyServer(ctx, t.mapping.serverName, t.mapping.serverConfig); err != nil {
+			return fantasy.ToolResponse{}, fmt.Errorf("failed to start server %s: %w", t.mapping.serverName, err)
+		}
+	}
+
 	// Get connection from pool with health check
 	conn, err := t.mapping.manager.connectionPool.GetConnectionWithHealthCheck(
 		ctx, t.mapping.serverName, t.mapping.serverConfig,
@@ -48,6 +93,16 @@ func (t *mcpFantasyTool) Run(ctx context.Context, call fantasy.ToolCall) (fantas
 		return fantasy.ToolResponse{}, fmt.Errorf("failed to get healthy connection from pool: %w", err)
 	}
 
+	// Ask for progress updates when someone is listening for them
+	var meta *mcp.Meta
+	if onProgress := progressHandlerFrom(ctx); onProgress != nil {
+		token, done := t.mapping.manager.trackProgress(func(p ToolProgress) {
+			onProgress(t.toolInfo.Name, p)
+		})
+		defer done()
+		meta = &mcp.Meta{ProgressToken: token}
+	}
+
 	// Call the MCP tool using the original (unprefixed) name
 	result, err := conn.client.CallTool(ctx, mcp.CallToolRequest{
 		Request: mcp.Request{
@@ -56,6 +111,7 @@ func (t *mcpFantasyTool) Run(ctx context.Context, call fantasy.ToolCall) (fantas
 		Params: mcp.CallToolParams{
 			Name:      t.mapping.originalName,
 			Arguments: arguments,
+			Meta:      meta,
 		},
 	})
 	if err != nil {
@@ -70,11 +126,12 @@ func (t *mcpFantasyTool) Run(ctx context.Context, call fantasy.ToolCall) (fantas
 		return fantasy.ToolResponse{}, fmt.Errorf("failed to marshal mcp tool result: %w", err)
 	}
 
-	// Return as text response, preserving error status from MCP
-	if result.IsError {
-		return fantasy.NewTextErrorResponse(string(marshaledResult)), nil
-	}
-	return fantasy.NewTextResponse(string(marshaledResult)), nil
+	// PostToolUse hooks cannot change the result, but may end the step
+	t.mapping.manager.firePostToolUse(ctx, t.toolInfo.Name, input, string(marshaledResult))
+
+	// Images and audio go to the model as media where it accepts them,
+	// preserving error status from MCP
+	return convertToolResult(result, t.mapping.manager.acceptsToolResultMedia), nil
 }
 
 // ProviderOptions returns provider-specific options for this tool.
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/hooks"
+)
+
+// SetHookExecutor sets the hook executor used to fire PreToolUse and PostToolUse
+// hooks around every MCP tool call. Passing nil disables tool hooks.
+func (m *MCPToolManager) SetHookExecutor(executor *hooks.Executor) {
+	m.hookExecutor = executor
+}
+
+// HookStop reports whether a tool hook returned "continue": false since the last
+// call to ResetHookStop, along with the reason the hook gave (which may be empty).
+// The agent uses this as a stop condition to end the current step early.
+func (m *MCPToolManager) HookStop() (string, bool) {
+	m.hookStopMu.Lock()
+	defer m.hookStopMu.Unlock()
+	return m.hookStopReason, m.hookStopped
+}
+
+// ResetHookStop clears any pending stop request recorded by a tool hook.
+// It should be called at the start of every agent step.
+func (m *MCPToolManager) ResetHookStop() {
+	m.hookStopMu.Lock()
+	defer m.hookStopMu.Unlock()
+	m.hookStopped = false
+	m.hookStopReason = ""
+}
+
+// requestHookStop records that a hook asked to end the current step.
+func (m *MCPToolManager) requestHookStop(reason string) {
+	m.hookStopMu.Lock()
+	defer m.hookStopMu.Unlock()
+	m.hookStopped = true
+	m.hookStopReason = reason
+}
+
+// firePreToolUse runs the PreToolUse hooks for a tool call. It returns nil when
+// no executor is configured or no hook matched the tool.
+func (m *MCPToolManager) firePreToolUse(ctx context.Context, toolName, toolInput string) *hooks.HookOutput {
+	if m.hookExecutor == nil {
+		return nil
+	}
+
+	input := &hooks.PreToolUseInput{
+		CommonInput: m.hookExecutor.PopulateCommonFields(hooks.PreToolUse),
+		ToolName:    toolName,
+		ToolInput:   rawJSONOrEmpty(toolInput),
+	}
+	return m.executeHooks(ctx, hooks.PreToolUse, input)
+}
+
+// firePostToolUse runs the PostToolUse hooks once a tool call has returned.
+// toolResponse is the JSON-encoded MCP result.
+func (m *MCPToolManager) firePostToolUse(ctx context.Context, toolName, toolInput, toolResponse string) *hooks.HookOutput {
+	if m.hookExecutor == nil {
+		return nil
+	}
+
+	input := &hooks.PostToolUseInput{
+		CommonInput:  m.hookExecutor.PopulateCommonFields(hooks.PostToolUse),
+		ToolName:     toolName,
+		ToolInput:    rawJSONOrEmpty(toolInput),
+		ToolResponse: rawJSONOrEmpty(toolResponse),
+	}
+	return m.executeHooks(ctx, hooks.PostToolUse, input)
+}
+
+// executeHooks runs the hooks for event and records a stop request when any of
+// them returned "continue": false.
+func (m *MCPToolManager) executeHooks(ctx context.Context, event hooks.HookEvent, input any) *hooks.HookOutput {
+	output, err := m.hookExecutor.ExecuteHooks(ctx, event, input)
+	if err != nil {
+		if m.debugLogger != nil && m.debugLogger.IsDebugEnabled() {
+			m.debugLogger.LogDebug(fmt.Sprintf("[HOOKS] %s hooks failed: %v", event, err))
+		}
+		return nil
+	}
+	if output == nil {
+		return nil
+	}
+
+	if output.Continue != nil && !*output.Continue {
+		reason := output.StopReason
+		if reason == "" {
+			reason = strings.TrimSpace(output.Reason)
+		}
+		m.requestHookStop(reason)
+	}
+	return output
+}
+
+// rawJSONOrEmpty converts s to a json.RawMessage, substituting an empty object
+// for blank or invalid input so the hook payload always marshals.
+func rawJSONOrEmpty(s string) json.RawMessage {
+	if strings.TrimSpace(s) == "" || !json.Valid([]byte(s)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(s)
+}
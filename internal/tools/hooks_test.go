@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcphost/internal/hooks"
+)
+
+// newHookedTodoManager loads the builtin todo server into a tool manager whose
+// hook executor runs command for the given event on every todo tool.
+func newHookedTodoManager(t *testing.T, event hooks.HookEvent, command string) *MCPToolManager {
+	t.Helper()
+
+	manager := newTodoManager(t)
+	manager.SetHookExecutor(hooks.NewExecutor(&hooks.HookConfig{
+		Hooks: map[hooks.HookEvent][]hooks.HookMatcher{
+			event: {{
+				Matcher: "todo",
+				Hooks:   []hooks.HookEntry{{Type: "command", Command: command}},
+			}},
+		},
+	}, "test-session", ""))
+	return manager
+}
+
+// findTool returns the loaded tool with the given prefixed name.
+func findTool(t *testing.T, manager *MCPToolManager, name string) fantasy.AgentTool {
+	t.Helper()
+	for _, tool := range manager.GetTools() {
+		if tool.Info().Name == name {
+			return tool
+		}
+	}
+	t.Fatalf("tool %s not loaded", name)
+	return nil
+}
+
+func TestMCPToolManager_PreToolUseBlock(t *testing.T) {
+	manager := newHookedTodoManager(t, hooks.PreToolUse,
+		`echo '{"decision": "block", "reason": "not today"}'`)
+	tool := findTool(t, manager, "todo-server__todoread")
+
+	resp, err := tool.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: "todo-server__todoread", Input: "{}"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !resp.IsError {
+		t.Fatal("Expected blocked tool call to return an error response")
+	}
+	if !strings.Contains(resp.Content, "not today") {
+		t.Errorf("Expected block reason in tool result, got %q", resp.Content)
+	}
+	if _, stopped := manager.HookStop(); stopped {
+		t.Error("A plain block should not end the step")
+	}
+}
+
+func TestMCPToolManager_PreToolUseExitCode2StopsStep(t *testing.T) {
+	manager := newHookedTodoManager(t, hooks.PreToolUse, `echo "policy violation" >&2; exit 2`)
+	tool := findTool(t, manager, "todo-server__todoread")
+
+	resp, err := tool.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: "todo-server__todoread", Input: "{}"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !resp.IsError || !strings.Contains(resp.Content, "policy violation") {
+		t.Errorf("Expected blocked error response with reason, got %+v", resp)
+	}
+
+	reason, stopped := manager.HookStop()
+	if !stopped {
+		t.Fatal("Expected exit code 2 to request a stop")
+	}
+	if reason != "policy violation" {
+		t.Errorf("Expected stop reason %q, got %q", "policy violation", reason)
+	}
+
+	manager.ResetHookStop()
+	if _, stopped := manager.HookStop(); stopped {
+		t.Error("ResetHookStop did not clear the stop request")
+	}
+}
+
+func TestMCPToolManager_PostToolUseContinueFalse(t *testing.T) {
+	manager := newHookedTodoManager(t, hooks.PostToolUse,
+		`echo '{"continue": false, "stopReason": "enough for now"}'`)
+	tool := findTool(t, manager, "todo-server__todoread")
+
+	resp, err := tool.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: "todo-server__todoread", Input: ""})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if resp.IsError {
+		t.Errorf("PostToolUse hooks must not change the tool result, got error %q", resp.Content)
+	}
+
+	reason, stopped := manager.HookStop()
+	if !stopped || reason != "enough for now" {
+		t.Errorf("Expected stop with reason %q, got stopped=%v reason=%q", "enough for now", stopped, reason)
+	}
+}
+
+func TestRawJSONOrEmpty(t *testing.T) {
+	tests := map[string]string{
+		"":              "{}",
+		"   ":           "{}",
+		"not json":      "{}",
+		`{"a":1}`:       `{"a":1}`,
+		`["x", "y"]`:    `["x", "y"]`,
+		`"just text"`:   `"just text"`,
+		`{"broken": `:   "{}",
+		`{"nested":{}}`: `{"nested":{}}`,
+	}
+	for input, want := range tests {
+		if got := string(rawJSONOrEmpty(input)); got != want {
+			t.Errorf("rawJSONOrEmpty(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// toolCacheDir is the directory of the data directory holding the tools of
+// lazy servers.
+const toolCacheDir = "tool-cache"
+
+// toolCache is the content of a lazy server's cache file.
+type toolCache struct {
+	Tools []mcp.Tool `json:"tools"`
+}
+
+// lazyStart is the start of a lazy server, which callers of its tools wait
+// for.
+type lazyStart struct {
+	done chan struct{} // closed once err is set
+	err  error
+}
+
+// toolCachePath returns the file caching the tools of the lazy server
+// configured by serverConfig. It is named after a hash of the server's
+// command and environment, so changing either lists the tools again.
+func toolCachePath(serverConfig config.MCPServerConfig) (string, error) {
+	dir, err := models.DataDir()
+	if err != nil {
+		return "", err
+	}
+	key, err := json.Marshal(struct {
+		Command     []string          `json:"command"`
+		Args        []string          `json:"args"`
+		Environment map[string]string `json:"environment"`
+	}{serverConfig.Command, serverConfig.Args, serverConfig.Environment})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(key)
+	return filepath.Join(dir, toolCacheDir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// cachedTools returns the cached tools of a lazy server, or false when
+// there are none.
+func cachedTools(serverConfig config.MCPServerConfig) ([]mcp.Tool, bool) {
+	path, err := toolCachePath(serverConfig)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var cache toolCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, false
+	}
+	return cache.Tools, true
+}
+
+// cacheServerTools caches the tools a lazy server listed. Failures are
+// logged; the server is then started to list its tools next time too.
+func (m *MCPToolManager) cacheServerTools(serverName string, serverConfig config.MCPServerConfig, tools []mcp.Tool) {
+	if err := writeToolCache(serverConfig, tools); err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Failed to cache the tools of %s: %v", serverName, err))
+	}
+}
+
+// writeToolCache replaces the cached tools of a lazy server when they differ
+// from tools, so that tools the server dropped are no longer advertised.
+func writeToolCache(serverConfig config.MCPServerConfig, tools []mcp.Tool) error {
+	path, err := toolCachePath(serverConfig)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(toolCache{Tools: tools})
+	if err != nil {
+		return err
+	}
+	if cached, err := os.ReadFile(path); err == nil && bytes.Equal(cached, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create tool cache directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// advertiseCachedTools adds the cached tools of the lazy server serverName
+// without starting it. It returns false when there are none, and the server
+// is started to list them.
+func (m *MCPToolManager) advertiseCachedTools(serverName string, serverConfig config.MCPServerConfig) bool {
+	tools, ok := cachedTools(serverConfig)
+	if !ok {
+		return false
+	}
+	if err := m.addServerTools(serverName, serverConfig, tools); err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Ignoring the cached tools of %s: %v", serverName, err))
+		return false
+	}
+	m.setServerStatus(ServerStatus{Name: serverName, State: ServerLazy, Tools: m.serverToolCount(serverName)})
+	return true
+}
+
+// startLazyServer starts the lazy server serverName unless it was started
+// already, and waits until its live tools replaced the cached ones or ctx
+// ends. The server itself is bound to the pool rather than to ctx, so it
+// keeps running after the call that started it. A failed start is tried
+// again by the next call.
+func (m *MCPToolManager) startLazyServer(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) error {
+	m.lazyMu.Lock()
+	start, started := m.lazyStarts[serverName]
+	if !started {
+		if m.lazyStarts == nil {
+			m.lazyStarts = make(map[string]*lazyStart)
+		}
+		start = &lazyStart{done: make(chan struct{})}
+		m.lazyStarts[serverName] = start
+	}
+	m.lazyMu.Unlock()
+
+	if !started {
+		go func() {
+			m.setServerStatus(ServerStatus{Name: serverName, State: ServerConnecting})
+			start.err = m.startServer(serverName, serverConfig)
+			if start.err != nil {
+				m.lazyMu.Lock()
+				delete(m.lazyStarts, serverName)
+				m.lazyMu.Unlock()
+			}
+			close(start.done)
+		}()
+	}
+
+	select {
+	case <-start.done:
+		return start.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// The test binary runs as a stdio MCP server when started with these
+// variables: it offers the tools named in the tools file and creates the
+// marker file, which tells the tests that it was started.
+const (
+	envTestServerTools  = "MCPHOST_TEST_SERVER_TOOLS"
+	envTestServerMarker = "MCPHOST_TEST_SERVER_MARKER"
+)
+
+func TestMain(m *testing.M) {
+	if toolsFile := os.Getenv(envTestServerTools); toolsFile != "" {
+		serveTestServer(toolsFile, os.Getenv(envTestServerMarker))
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// serveTestServer serves the tools named in toolsFile on stdio.
+func serveTestServer(toolsFile, marker string) {
+	names, _ := os.ReadFile(toolsFile)
+	_ = os.WriteFile(marker, nil, 0o644)
+
+	srv := server.NewMCPServer("heavy", "1.0.0", server.WithToolCapabilities(true))
+	for _, name := range strings.Fields(string(names)) {
+		srv.AddTool(mcp.NewTool(name, mcp.WithDescription("Answers with its name")),
+			func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText(name), nil
+			})
+	}
+	_ = server.ServeStdio(srv)
+}
+
+// loadLazyServer loads cfg into a fresh tool manager.
+func loadLazyServer(t *testing.T, cfg *config.Config) *MCPToolManager {
+	t.Helper()
+	manager := NewMCPToolManager()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := manager.LoadTools(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	return manager
+}
+
+// TestLoadTools_lazyServer verifies that a lazy server is started once to
+// cache its tools, then advertised from the cache until one of its tools is
+// called, and that the live tools replace the cached ones.
+func TestLoadTools_lazyServer(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	toolsFile := filepath.Join(dir, "tools")
+	marker := filepath.Join(dir, "started")
+	if err := os.WriteFile(toolsFile, []byte("echo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := config.MCPServerConfig{
+		Type:        "local",
+		Command:     []string{os.Args[0]},
+		Environment: map[string]string{envTestServerTools: toolsFile, envTestServerMarker: marker},
+		Lazy:        true,
+	}
+	cfg := &config.Config{MCPServers: map[string]config.MCPServerConfig{"heavy": serverConfig}}
+	started := func() bool {
+		_, err := os.Stat(marker)
+		return err == nil
+	}
+
+	// Without cached tools the server is started to list them
+	first := loadLazyServer(t, cfg)
+	findTool(t, first, "heavy__echo")
+	if !started() {
+		t.Fatal("Expected the server to be started to list its tools")
+	}
+	_ = first.Close()
+	_ = os.Remove(marker)
+
+	// Then the cached tools are advertised without starting it
+	if err := os.WriteFile(toolsFile, []byte("echo ping"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manager := loadLazyServer(t, cfg)
+	echo := findTool(t, manager, "heavy__echo")
+	if started() {
+		t.Fatal("Expected the lazy server not to be started")
+	}
+	if statuses := manager.ServerStatuses(); len(statuses) != 1 || statuses[0].State != ServerLazy || statuses[0].Tools != 1 {
+		t.Errorf("Expected heavy to be lazy with one cached tool, got %+v", statuses)
+	}
+
+	// The first call starts it, and its live tools replace the cached ones
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := echo.Run(ctx, fantasy.ToolCall{ID: "1", Name: "heavy__echo", Input: "{}"})
+	if err != nil || resp.IsError {
+		t.Fatalf("Expected the call to succeed, got %+v, %v", resp, err)
+	}
+	if !started() {
+		t.Error("Expected the call to start the server")
+	}
+	findTool(t, manager, "heavy__ping")
+	if tools, ok := cachedTools(serverConfig); !ok || len(tools) != 2 {
+		t.Errorf("Expected the cache to hold the live tools, got %v", tools)
+	}
+}
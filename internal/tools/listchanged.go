@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ListKind names a list a server may change during the session.
+type ListKind string
+
+const (
+	ListTools     ListKind = "tools"
+	ListResources ListKind = "resources"
+	ListPrompts   ListKind = "prompts"
+)
+
+// listReloadTimeout bounds listing a server's tools, resources or prompts
+// again after it reported a change.
+const listReloadTimeout = 30 * time.Second
+
+// ListChangedFunc is told that the tools, resources or prompts of serverName
+// were listed again after the server reported that they changed. It is
+// called on a goroutine of its own.
+type ListChangedFunc func(serverName string, kind ListKind)
+
+// SetListChangedFunc sets the callback told about reloaded lists. It can be
+// set before or after LoadTools.
+func (m *MCPToolManager) SetListChangedFunc(fn ListChangedFunc) {
+	m.listsMu.Lock()
+	defer m.listsMu.Unlock()
+	m.listChanged = fn
+}
+
+// handleNotification reloads the tools, resources or prompts of serverName
+// when its notification reports that they changed, and passes on progress
+// updates and log messages. Other notifications are ignored.
+func (m *MCPToolManager) handleNotification(serverName string, notification mcp.JSONRPCNotification) {
+	var kind ListKind
+	switch notification.Method {
+	case methodNotificationProgress:
+		m.handleProgress(notification)
+		return
+	case methodNotificationMessage:
+		m.handleLog(serverName, notification)
+		return
+	case mcp.MethodNotificationToolsListChanged:
+		kind = ListTools
+	case mcp.MethodNotificationResourcesListChanged:
+		kind = ListResources
+	case mcp.MethodNotificationPromptsListChanged:
+		kind = ListPrompts
+	default:
+		return
+	}
+
+	// Notifications are delivered by the client's reader, which listing the
+	// server again needs, so the reload must not block it.
+	go m.reloadList(serverName, kind)
+}
+
+// reloadList lists the tools, resources or prompts of serverName again and
+// tells the ListChangedFunc. Tools reloaded while a step runs are sent to the
+// model from its next step on. Failures are logged.
+func (m *MCPToolManager) reloadList(serverName string, kind ListKind) {
+	serverConfig, ok := m.config.MCPServers[serverName]
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(m.connectionPool.ctx, listReloadTimeout)
+	defer cancel()
+
+	conn, err := m.connectionPool.GetConnection(ctx, serverName, serverConfig)
+	if err == nil {
+		switch kind {
+		case ListTools:
+			err = m.listServerTools(ctx, serverName, serverConfig, conn)
+		case ListResources:
+			m.loadServerResources(ctx, serverName, conn)
+		case ListPrompts:
+			m.loadServerPrompts(ctx, serverName, conn)
+		}
+	}
+	if err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Failed to reload the %s of %s: %v", kind, serverName, err))
+		return
+	}
+
+	m.listsMu.RLock()
+	changed := m.listChanged
+	m.listsMu.RUnlock()
+	if changed != nil {
+		changed(serverName, kind)
+	}
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetServerTools(t *testing.T) {
+	m := newSearchTestManager(searchTestTools)
+	for _, name := range toolNames(m.tools) {
+		server, _, _ := strings.Cut(name, "__")
+		m.toolMap[name] = &toolMapping{serverName: server}
+	}
+	m.EnableToolSearch(nil)
+	m.loadTool("github__list_issues")
+	m.loadTool("fs__read_file")
+
+	replacement := newSearchTestManager(map[string]string{"github__create_issue": "Open an issue"})
+	m.setServerTools("github", replacement.tools, map[string]*toolMapping{
+		"github__create_issue": {serverName: "github", originalName: "create_issue"},
+	})
+
+	got := toolNames(m.GetTools())
+	want := []string{"fs__read_file", "fs__write_file", "todo__todo_write", "github__create_issue"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected tools %v, got %v", want, got)
+	}
+	if _, ok := m.toolMap["github__list_issues"]; ok {
+		t.Error("expected the removed tool's mapping dropped")
+	}
+	if mapping := m.toolMap["github__create_issue"]; mapping == nil || mapping.originalName != "create_issue" {
+		t.Errorf("expected the new mapping, got %+v", mapping)
+	}
+	if !slices.Equal(m.loadOrder, []string{"fs__read_file"}) || m.loadedTools["github__list_issues"] {
+		t.Errorf("expected the removed tool unloaded, got %v", m.loadOrder)
+	}
+}
+
+func TestSetServerResources_resourceTool(t *testing.T) {
+	m := NewMCPToolManager()
+	m.EnableResourceTool()
+
+	m.setServerResources("fs", []Resource{{Server: "fs", URI: "file:///a.txt"}})
+	if toolNamed(m.GetTools(), ReadResourceName) == nil {
+		t.Fatal("expected read_resource once a server offers resources")
+	}
+	m.setServerResources("db", []Resource{{Server: "db", URI: "postgres://orders"}})
+	if n := len(m.GetTools()); n != 1 {
+		t.Errorf("expected a single read_resource tool, got %d tools", n)
+	}
+	if len(m.Resources()) != 2 {
+		t.Errorf("expected the resources of both servers, got %v", m.Resources())
+	}
+
+	m.setServerResources("fs", nil)
+	m.setServerResources("db", nil)
+	if len(m.GetTools()) != 0 {
+		t.Errorf("expected read_resource removed with the last resource, got %v", toolNames(m.GetTools()))
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"path"
 	"slices"
 	"strings"
+	"sync"
 
 	"charm.land/fantasy"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/builtin"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/hooks"
+	"github.com/mark3labs/mcphost/internal/permissions"
 )
 
 // MCPToolManager manages MCP (Model Context Protocol) tools and clients across multiple servers.
@@ -18,15 +24,72 @@ import (
 // pooling, health checks, tool name prefixing to avoid conflicts, and sampling support for LLM interactions.
 // Thread-safe for concurrent tool invocations.
 type MCPToolManager struct {
-	connectionPool *MCPConnectionPool
-	tools          []fantasy.AgentTool
-	toolMap        map[string]*toolMapping // maps prefixed tool names to their server and original name
-	model          fantasy.LanguageModel   // LLM model for sampling
-	config         *config.Config
-	debug          bool
-	debugLogger    DebugLogger
+	connectionPool  *MCPConnectionPool
+	model           fantasy.LanguageModel // LLM model for sampling
+	config          *config.Config
+	debug           bool
+	debugLogger     DebugLogger
+	hookExecutor    *hooks.Executor      // optional; fires PreToolUse/PostToolUse hooks
+	approvalFunc    ToolApprovalFunc     // optional; asked before every tool call
+	permissions     *permissions.Ruleset // optional; allow/ask/deny rules checked before approval
+	taskRunner      builtin.TaskRunner   // optional; runs sub-agents for builtin task servers
+	samplingFunc    SamplingApprovalFunc // optional; asked before sampling requests
+	samplingUsage   SamplingUsageFunc    // optional; receives the usage of sampling requests
+	samplingBudget  SamplingBudgetFunc   // optional; refuses sampling requests over budget
+	elicitationFunc ElicitationFunc      // optional; answers elicitation requests
+
+	authorizationFunc AuthorizationURLFunc // optional; shows the URL authorizing mcphost for a server
+
+	// statusMu protects the startup status of the servers and the func told
+	// about its changes.
+	statusMu   sync.Mutex
+	statuses   map[string]ServerStatus
+	statusFunc ServerStatusFunc // optional
+
+	// lazyMu protects the starts of lazy servers, which happen on the first
+	// call of one of their tools.
+	lazyMu     sync.Mutex
+	lazyStarts map[string]*lazyStart
+
+	// listsMu protects the tools, resources and prompts of the servers, which
+	// are listed again when a server reports that they changed. The slices
+	// are replaced rather than modified, so readers may keep them.
+	listsMu      sync.RWMutex
+	tools        []fantasy.AgentTool
+	toolMap      map[string]*toolMapping // maps prefixed tool names to their server and original name
+	resources    []Resource              // resources and templates of the loaded servers
+	prompts      []Prompt                // prompts of the loaded servers
+	resourceTool bool                    // the read_resource tool is enabled
+	listChanged  ListChangedFunc         // optional; told about changed lists
+
+	// progressMu protects the progress handlers of the running tool calls,
+	// keyed by progress token, and the log func.
+	progressMu  sync.Mutex
+	progress    map[string]func(ToolProgress)
+	progressSeq int
+	logFunc     LogFunc // optional; receives the log messages of the servers
+
+	// hookStopMu protects hookStopped and hookStopReason, which record a
+	// "continue": false answer from a tool hook until the agent consumes it.
+	hookStopMu     sync.Mutex
+	hookStopped    bool
+	hookStopReason string
+
+	// searchMu protects the tool search state (see EnableToolSearch).
+	searchMu    sync.Mutex
+	toolSearch  bool
+	pinnedTools []string
+	metaTools   []fantasy.AgentTool
+	loadedTools map[string]bool
+	loadOrder   []string
+	searchIndex *toolIndex
 }
 
+// ToolApprovalFunc decides whether a tool call may run. It receives the prefixed
+// tool name and the JSON-encoded arguments, and may block until a decision is
+// made. Returning false denies the call; returning an error aborts the step.
+type ToolApprovalFunc func(ctx context.Context, toolName, toolArgs string) (bool, error)
+
 // toolMapping stores the mapping between prefixed tool names and their original details
 type toolMapping struct {
 	serverName   string
@@ -64,11 +127,32 @@ func (m *MCPToolManager) SetDebugLogger(logger DebugLogger) {
 	}
 }
 
+// SetToolApprovalFunc sets the callback consulted before every tool call.
+// A nil func (the default) lets every call run without asking.
+// It must be called before any tool is invoked.
+func (m *MCPToolManager) SetToolApprovalFunc(fn ToolApprovalFunc) {
+	m.approvalFunc = fn
+}
+
+// SetPermissions sets the permission rules checked before every tool call.
+// Calls a deny rule matches are rejected, calls an allow rule matches skip the
+// approval func, and all others go through it. Passing nil disables the rules.
+func (m *MCPToolManager) SetPermissions(rules *permissions.Ruleset) {
+	m.permissions = rules
+}
+
+// SetTaskRunner sets the function builtin task servers use to run sub-agents.
+// It must be called before LoadTools; without it task servers fail to load.
+func (m *MCPToolManager) SetTaskRunner(runner builtin.TaskRunner) {
+	m.taskRunner = runner
+}
+
 // LoadTools loads tools from all configured MCP servers based on the provided configuration.
-// It initializes the connection pool, connects to each configured server, and loads their tools.
+// It initializes the connection pool, connects to the configured servers concurrently, and loads their tools.
 // Tools from different servers are prefixed with the server name to avoid naming conflicts.
-// Returns an error only if all configured servers fail to load; partial failures are logged as warnings.
-// This method is thread-safe and idempotent.
+// Each server is waited for until its startup timeout; servers not ready by then keep connecting,
+// and their tools are added once they are. ServerStatuses reports how each server fared.
+// Returns an error only if all configured servers fail to load.
 func (m *MCPToolManager) LoadTools(ctx context.Context, config *config.Config) error {
 	// Initialize connection pool
 	m.config = config
@@ -78,19 +162,18 @@ func (m *MCPToolManager) LoadTools(ctx context.Context, config *config.Config) e
 	}
 	m.connectionPool = NewMCPConnectionPool(DefaultConnectionPoolConfig(), m.model, config.Debug)
 	m.connectionPool.SetDebugLogger(m.debugLogger)
+	m.connectionPool.SetTaskRunner(m.taskRunner)
+	m.connectionPool.SetSamplingApprovalFunc(m.samplingFunc)
+	m.connectionPool.SetSamplingUsageFunc(m.samplingUsage)
+	m.connectionPool.SetSamplingBudgetFunc(m.samplingBudget)
+	m.connectionPool.SetElicitationFunc(m.elicitationFunc)
+	m.connectionPool.SetAuthorizationURLFunc(m.authorizationFunc)
+	m.connectionPool.SetNotificationHandler(m.handleNotification)
 
-	var loadErrors []string
-
-	for serverName, serverConfig := range config.MCPServers {
-		if err := m.loadServerTools(ctx, serverName, serverConfig); err != nil {
-			loadErrors = append(loadErrors, fmt.Sprintf("server %s: %v", serverName, err))
-			fmt.Printf("Warning: Failed to load MCP server '%s': %v\n", serverName, err)
-			continue
-		}
-	}
+	loadErrors, connecting := m.startServers(ctx, config)
 
 	// If all servers failed to load, return an error
-	if len(loadErrors) == len(config.MCPServers) && len(config.MCPServers) > 0 {
+	if connecting == 0 && len(loadErrors) == len(config.MCPServers) && len(config.MCPServers) > 0 {
 		return fmt.Errorf("all MCP servers failed to load: %s", strings.Join(loadErrors, "; "))
 	}
 
@@ -108,6 +191,21 @@ func (m *MCPToolManager) loadServerTools(ctx context.Context, serverName string,
 		return fmt.Errorf("failed to get connection from pool: %v", err)
 	}
 
+	if err := m.listServerTools(ctx, serverName, serverConfig, conn); err != nil {
+		return err
+	}
+
+	// Resources and prompts are optional; a server that cannot list them
+	// still loads
+	m.loadServerResources(ctx, serverName, conn)
+	m.loadServerPrompts(ctx, serverName, conn)
+
+	return nil
+}
+
+// listServerTools lists the tools of a server and replaces the tools loaded
+// from it before.
+func (m *MCPToolManager) listServerTools(ctx context.Context, serverName string, serverConfig config.MCPServerConfig, conn *MCPConnection) error {
 	// Get tools from this server
 	listResults, err := conn.client.ListTools(ctx, mcp.ListToolsRequest{})
 	if err != nil {
@@ -116,6 +214,20 @@ func (m *MCPToolManager) loadServerTools(ctx context.Context, serverName string,
 		return fmt.Errorf("failed to list tools: %v", err)
 	}
 
+	// Lazy servers advertise these tools next time without being started
+	if serverConfig.Lazy {
+		m.cacheServerTools(serverName, serverConfig, listResults.Tools)
+	}
+
+	return m.addServerTools(serverName, serverConfig, listResults.Tools)
+}
+
+// addServerTools converts the MCP tools of a server, as listed by it or
+// cached, and replaces the tools loaded from it before.
+func (m *MCPToolManager) addServerTools(serverName string, serverConfig config.MCPServerConfig, mcpTools []mcp.Tool) error {
+	var serverTools []fantasy.AgentTool
+	mappings := make(map[string]*toolMapping)
+
 	// Create name set for allowed tools
 	var nameSet map[string]struct{}
 	if len(serverConfig.AllowedTools) > 0 {
@@ -126,7 +238,7 @@ func (m *MCPToolManager) loadServerTools(ctx context.Context, serverName string,
 	}
 
 	// Convert MCP tools to fantasy AgentTools with prefixed names
-	for _, mcpTool := range listResults.Tools {
+	for _, mcpTool := range mcpTools {
 		// Filter tools based on allowedTools/excludedTools
 		if len(serverConfig.AllowedTools) > 0 {
 			if _, ok := nameSet[mcpTool.Name]; !ok {
@@ -184,7 +296,7 @@ func (m *MCPToolManager) loadServerTools(ctx context.Context, serverName string,
 			serverConfig: serverConfig,
 			manager:      m,
 		}
-		m.toolMap[prefixedName] = mapping
+		mappings[prefixedName] = mapping
 
 		// Create fantasy AgentTool
 		fantasyTool := &mcpFantasyTool{
@@ -197,18 +309,77 @@ func (m *MCPToolManager) loadServerTools(ctx context.Context, serverName string,
 			mapping: mapping,
 		}
 
-		m.tools = append(m.tools, fantasyTool)
+		serverTools = append(serverTools, fantasyTool)
 	}
 
+	m.setServerTools(serverName, serverTools, mappings)
 	return nil
 }
 
+// setServerTools replaces the tools of serverName with serverTools, which
+// are added after the tools of the other servers.
+func (m *MCPToolManager) setServerTools(serverName string, serverTools []fantasy.AgentTool, mappings map[string]*toolMapping) {
+	m.listsMu.Lock()
+
+	toolMap := make(map[string]*toolMapping, len(m.toolMap)+len(mappings))
+	for name, mapping := range m.toolMap {
+		if mapping.serverName != serverName {
+			toolMap[name] = mapping
+		}
+	}
+	maps.Copy(toolMap, mappings)
+
+	tools := make([]fantasy.AgentTool, 0, len(m.tools)+len(serverTools))
+	for _, tool := range m.tools {
+		if mapping, ok := m.toolMap[tool.Info().Name]; !ok || mapping.serverName != serverName {
+			tools = append(tools, tool)
+		}
+	}
+	m.tools = append(tools, serverTools...)
+	m.toolMap = toolMap
+	m.listsMu.Unlock()
+
+	m.toolsChanged()
+}
+
 // GetTools returns all loaded tools as fantasy AgentTools from all configured MCP servers.
 // Tools are returned with their prefixed names (serverName__toolName) to ensure uniqueness.
 func (m *MCPToolManager) GetTools() []fantasy.AgentTool {
+	m.listsMu.RLock()
+	defer m.listsMu.RUnlock()
 	return m.tools
 }
 
+// SubAgentTools returns the tools a sub-agent started by the task tool may
+// use: every loaded tool except those of builtin task servers, so sub-agents
+// cannot start sub-agents of their own. When allowed is non-empty only tools
+// whose prefixed names match one of its globs (path.Match syntax) are returned.
+func (m *MCPToolManager) SubAgentTools(allowed []string) []fantasy.AgentTool {
+	m.listsMu.RLock()
+	defer m.listsMu.RUnlock()
+
+	var tools []fantasy.AgentTool
+	for _, tool := range m.tools {
+		name := tool.Info().Name
+		if mapping, ok := m.toolMap[name]; ok && isTaskServer(mapping.serverConfig) {
+			continue
+		}
+		if len(allowed) > 0 && !slices.ContainsFunc(allowed, func(pattern string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}) {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// isTaskServer reports whether serverConfig configures the builtin task server.
+func isTaskServer(serverConfig config.MCPServerConfig) bool {
+	return serverConfig.GetTransportType() == "inprocess" && serverConfig.Name == "task"
+}
+
 // GetLoadedServerNames returns the names of all successfully loaded MCP servers.
 // This includes servers that are currently connected and have had their tools loaded,
 // regardless of their current health status. Useful for debugging and status reporting.
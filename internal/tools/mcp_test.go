@@ -3,12 +3,38 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcphost/internal/builtin"
 	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/permissions"
 )
 
+// newTodoManager loads the builtin todo server into a fresh tool manager.
+func newTodoManager(t *testing.T) *MCPToolManager {
+	t.Helper()
+
+	manager := NewMCPToolManager()
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServerConfig{
+			"todo-server": {Type: "builtin", Name: "todo"},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := manager.LoadTools(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	return manager
+}
+
 func TestMCPToolManager_LoadTools_WithTimeout(t *testing.T) {
 	manager := NewMCPToolManager()
 
@@ -475,3 +501,141 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestMCPToolManager_ToolApproval(t *testing.T) {
+	tests := []struct {
+		name      string
+		approve   ToolApprovalFunc
+		wantErr   bool
+		wantError bool // error response returned to the model
+	}{
+		{
+			name:    "approved",
+			approve: func(context.Context, string, string) (bool, error) { return true, nil },
+		},
+		{
+			name:      "denied",
+			approve:   func(context.Context, string, string) (bool, error) { return false, nil },
+			wantError: true,
+		},
+		{
+			name:    "approval error aborts",
+			approve: func(context.Context, string, string) (bool, error) { return false, errors.New("no approver") },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTodoManager(t)
+
+			var asked string
+			manager.SetToolApprovalFunc(func(ctx context.Context, toolName, toolArgs string) (bool, error) {
+				asked = toolName
+				return tt.approve(ctx, toolName, toolArgs)
+			})
+
+			tool := findTool(t, manager, "todo-server__todoread")
+			resp, err := tool.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: "todo-server__todoread", Input: "{}"})
+			if asked != "todo-server__todoread" {
+				t.Errorf("Expected approval to be requested for todo-server__todoread, got %q", asked)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if resp.IsError != tt.wantError {
+				t.Fatalf("Expected IsError=%v, got %+v", tt.wantError, resp)
+			}
+			if tt.wantError && !strings.Contains(resp.Content, "denied") {
+				t.Errorf("Expected denial message, got %q", resp.Content)
+			}
+		})
+	}
+}
+
+func TestMCPToolManager_PermissionRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    permissions.Action
+		wantAsked bool
+		wantError bool
+	}{
+		{name: "allow skips approval", action: permissions.Allow},
+		{name: "ask uses approval", action: permissions.Ask, wantAsked: true},
+		{name: "deny blocks the call", action: permissions.Deny, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTodoManager(t)
+
+			rules, err := permissions.Load([]permissions.Rule{{Tool: "todo-server__*", Action: tt.action}}, "", "")
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			manager.SetPermissions(rules)
+
+			asked := false
+			manager.SetToolApprovalFunc(func(context.Context, string, string) (bool, error) {
+				asked = true
+				return true, nil
+			})
+
+			tool := findTool(t, manager, "todo-server__todoread")
+			resp, err := tool.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: "todo-server__todoread", Input: "{}"})
+			if err != nil {
+				t.Fatalf("Run returned error: %v", err)
+			}
+			if asked != tt.wantAsked {
+				t.Errorf("Expected approval asked=%v, got %v", tt.wantAsked, asked)
+			}
+			if resp.IsError != tt.wantError {
+				t.Fatalf("Expected IsError=%v, got %+v", tt.wantError, resp)
+			}
+			if tt.wantError && !strings.Contains(resp.Content, "permission rule") {
+				t.Errorf("Expected permission rule in denial message, got %q", resp.Content)
+			}
+		})
+	}
+}
+
+func TestMCPToolManager_SubAgentTools(t *testing.T) {
+	manager := NewMCPToolManager()
+	manager.SetTaskRunner(func(context.Context, builtin.TaskRequest) (string, error) {
+		return "", nil
+	})
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServerConfig{
+			"todo-server": {Type: "builtin", Name: "todo"},
+			"agents":      {Type: "builtin", Name: "task"},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := manager.LoadTools(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+
+	names := func(tools []fantasy.AgentTool) []string {
+		var out []string
+		for _, tool := range tools {
+			out = append(out, tool.Info().Name)
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	findTool(t, manager, "agents__task")
+
+	if got := names(manager.SubAgentTools(nil)); !slices.Equal(got, []string{"todo-server__todoread", "todo-server__todowrite"}) {
+		t.Errorf("Expected all tools except the task tool, got %v", got)
+	}
+	if got := names(manager.SubAgentTools([]string{"*__todoread", "agents__*"})); !slices.Equal(got, []string{"todo-server__todoread"}) {
+		t.Errorf("Expected only the allowed tool, got %v", got)
+	}
+}
@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcphost/internal/auth"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// authorizationTimeout bounds authorizing mcphost for a server, which is
+// mostly waiting for the user to log in.
+const authorizationTimeout = 5 * time.Minute
+
+// AuthorizationURLFunc shows the user the URL at which to authorize mcphost
+// for the remote MCP server serverName. The URL is opened in the browser too
+// when possible.
+type AuthorizationURLFunc func(serverName, authURL string)
+
+// openBrowser opens the authorization URL; tests replace it.
+var openBrowser = auth.TryOpenBrowser
+
+// SetAuthorizationURLFunc sets the callback showing the authorization URL of
+// remote servers that require OAuth. Without one (the default) such servers
+// fail to connect until authorized with `mcphost auth login --server`. It
+// must be set before LoadTools to cover the first connections.
+func (m *MCPToolManager) SetAuthorizationURLFunc(fn AuthorizationURLFunc) {
+	m.authorizationFunc = fn
+	if m.connectionPool != nil {
+		m.connectionPool.SetAuthorizationURLFunc(fn)
+	}
+}
+
+// needsAuthorization reports whether err is a remote server asking for an
+// OAuth token, or a better one.
+func needsAuthorization(err error) bool {
+	return errors.Is(err, transport.ErrUnauthorized) || errors.Is(err, transport.ErrOAuthAuthorizationRequired)
+}
+
+// serverTokenStore is the transport.TokenStore of a remote server's OAuth
+// tokens. They are kept in the credentials file under the server's name, so
+// they survive restarts; mcp-go saves the tokens it refreshes in it.
+type serverTokenStore struct {
+	credentials *auth.CredentialManager
+	serverName  string
+	serverURL   string
+}
+
+// GetToken implements transport.TokenStore.
+func (s *serverTokenStore) GetToken(ctx context.Context) (*transport.Token, error) {
+	creds, err := storedCredentials(s.credentials, s.serverName, s.serverURL)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil || creds.AccessToken == "" {
+		return nil, transport.ErrNoToken
+	}
+
+	token := &transport.Token{
+		AccessToken:  creds.AccessToken,
+		TokenType:    creds.TokenType,
+		RefreshToken: creds.RefreshToken,
+		Scope:        creds.Scope,
+	}
+	if creds.ExpiresAt != 0 {
+		token.ExpiresAt = time.Unix(creds.ExpiresAt, 0)
+	}
+	return token, nil
+}
+
+// SaveToken implements transport.TokenStore.
+func (s *serverTokenStore) SaveToken(ctx context.Context, token *transport.Token) error {
+	return s.credentials.UpdateMCPServerCredentials(s.serverName, func(creds *auth.MCPServerCredentials) {
+		creds.ServerURL = s.serverURL
+		creds.AccessToken = token.AccessToken
+		creds.TokenType = token.TokenType
+		creds.RefreshToken = token.RefreshToken
+		creds.Scope = token.Scope
+		creds.ExpiresAt = 0
+		if !token.ExpiresAt.IsZero() {
+			creds.ExpiresAt = token.ExpiresAt.Unix()
+		}
+	})
+}
+
+// storedCredentials returns the stored credentials of serverName, or nil
+// when there are none or they belong to another URL.
+func storedCredentials(credentials *auth.CredentialManager, serverName, serverURL string) (*auth.MCPServerCredentials, error) {
+	creds, err := credentials.GetMCPServerCredentials(serverName)
+	if err != nil || creds == nil || creds.ServerURL != serverURL {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// oauthConfig returns the OAuth settings of the remote server serverName: the
+// configured client, or else the one registered before, and the token store.
+func oauthConfig(credentials *auth.CredentialManager, serverName string, serverConfig config.MCPServerConfig, creds *auth.MCPServerCredentials) transport.OAuthConfig {
+	cfg := transport.OAuthConfig{
+		TokenStore:  &serverTokenStore{credentials: credentials, serverName: serverName, serverURL: serverConfig.URL},
+		PKCEEnabled: true,
+	}
+	if o := serverConfig.OAuth; o != nil {
+		cfg.ClientID = o.ClientID
+		cfg.ClientSecret = o.ClientSecret
+		cfg.Scopes = o.Scopes
+		cfg.RedirectURI = o.RedirectURI
+	}
+	if creds != nil && (cfg.ClientID == "" || cfg.ClientID == creds.ClientID) {
+		cfg.ClientID = creds.ClientID
+		if cfg.RedirectURI == "" {
+			cfg.RedirectURI = creds.RedirectURI
+		}
+	}
+	return cfg
+}
+
+// remoteOAuthConfig returns the OAuth settings the transport of the remote
+// server serverName is created with, or nil for servers that neither
+// configure OAuth nor were authorized before: they are sent no token, and
+// authorized once they answer 401.
+func remoteOAuthConfig(serverName string, serverConfig config.MCPServerConfig) (*transport.OAuthConfig, error) {
+	credentials, err := auth.NewCredentialManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize credential manager: %w", err)
+	}
+	creds, err := storedCredentials(credentials, serverName, serverConfig.URL)
+	if err != nil {
+		return nil, err
+	}
+	if serverConfig.OAuth == nil && (creds == nil || creds.AccessToken == "") {
+		return nil, nil
+	}
+
+	cfg := oauthConfig(credentials, serverName, serverConfig, creds)
+	return &cfg, nil
+}
+
+// AuthorizeServer authorizes mcphost for the remote MCP server serverName
+// with the OAuth authorization code flow and PKCE, and stores the tokens
+// obtained for its connections.
+//
+// The authorization server is found through the server's protected resource
+// metadata. Unless a client is configured, mcphost registers itself with it
+// dynamically, once per server. showURL is given the URL the user logs in at,
+// which is also opened in the browser; the browser is then redirected to a
+// listener on this machine.
+func AuthorizeServer(ctx context.Context, serverName string, serverConfig config.MCPServerConfig, showURL AuthorizationURLFunc) error {
+	if t := serverConfig.GetTransportType(); t != "sse" && t != "streamable" {
+		return fmt.Errorf("server %s is not a remote server", serverName)
+	}
+	serverURL, err := url.Parse(serverConfig.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL of server %s: %w", serverName, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authorizationTimeout)
+	defer cancel()
+
+	credentials, err := auth.NewCredentialManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize credential manager: %w", err)
+	}
+	creds, err := storedCredentials(credentials, serverName, serverConfig.URL)
+	if err != nil {
+		return err
+	}
+	cfg := oauthConfig(credentials, serverName, serverConfig, creds)
+
+	// The port of a registered redirect URI may be taken by now; a client
+	// registered dynamically is then registered again with another one.
+	registered := cfg.ClientID != "" && (serverConfig.OAuth == nil || serverConfig.OAuth.ClientID == "")
+	callback, err := listenForCallback(cfg.RedirectURI)
+	if err != nil && registered {
+		cfg.ClientID, cfg.RedirectURI = "", ""
+		callback, err = listenForCallback("")
+	}
+	if err != nil {
+		return err
+	}
+	defer callback.close()
+	cfg.RedirectURI = callback.uri
+
+	handler := transport.NewOAuthHandler(cfg)
+	handler.SetBaseURL(serverURL.Scheme + "://" + serverURL.Host)
+
+	if cfg.ClientID == "" {
+		if err := handler.RegisterClient(ctx, "mcphost"); err != nil {
+			return fmt.Errorf("failed to register with the authorization server of %s: %w", serverName, err)
+		}
+		// A client secret issued by the registration stays with the
+		// handler, which mcp-go does not expose; mcphost registers as a
+		// public client, which should not be issued one.
+		err := credentials.UpdateMCPServerCredentials(serverName, func(creds *auth.MCPServerCredentials) {
+			*creds = auth.MCPServerCredentials{
+				ServerURL:   serverConfig.URL,
+				ClientID:    handler.GetClientID(),
+				RedirectURI: cfg.RedirectURI,
+				CreatedAt:   time.Now(),
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to store the client registration: %w", err)
+		}
+	}
+
+	verifier, challenge, err := auth.GeneratePKCE()
+	if err != nil {
+		return fmt.Errorf("failed to generate PKCE: %w", err)
+	}
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+	authURL, err := handler.GetAuthorizationURL(ctx, state, challenge)
+	if err != nil {
+		return fmt.Errorf("failed to build the authorization URL: %w", err)
+	}
+	openBrowser(authURL)
+	if showURL != nil {
+		showURL(serverName, authURL)
+	}
+
+	result, err := callback.wait(ctx)
+	if err != nil {
+		return err
+	}
+	if err := handler.ProcessAuthorizationResponse(ctx, result.code, result.state, verifier); err != nil {
+		return fmt.Errorf("failed to exchange the authorization code: %w", err)
+	}
+	return nil
+}
+
+// randomState returns the state parameter protecting the authorization
+// callback against forgery.
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// oauthCallback listens for the browser coming back from the authorization
+// server with the authorization code.
+type oauthCallback struct {
+	uri     string // the redirect URI
+	server  *http.Server
+	results chan callbackResult
+}
+
+// callbackResult is the outcome of an authorization, as passed to the
+// redirect URI.
+type callbackResult struct {
+	code  string
+	state string
+	err   error
+}
+
+// listenForCallback starts listening on the loopback redirectURI, or on a
+// free port of 127.0.0.1 when redirectURI is empty.
+func listenForCallback(redirectURI string) (*oauthCallback, error) {
+	addr, path := "127.0.0.1:0", "/callback"
+	if redirectURI != "" {
+		u, err := url.Parse(redirectURI)
+		if err != nil || !config.IsLoopbackURI(redirectURI) {
+			return nil, fmt.Errorf("redirect URI %s is not an http URI of this machine", redirectURI)
+		}
+		addr = u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+		path = u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the authorization callback: %w", err)
+	}
+
+	c := &oauthCallback{uri: redirectURI, results: make(chan callbackResult, 1)}
+	if c.uri == "" {
+		c.uri = "http://" + listener.Addr().String() + path
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, c.handle)
+	c.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = c.server.Serve(listener) }()
+	return c, nil
+}
+
+// handle receives the redirect of the browser. Only the first one counts.
+func (c *oauthCallback) handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	result := callbackResult{code: query.Get("code"), state: query.Get("state")}
+	if e := query.Get("error"); e != "" {
+		result.err = fmt.Errorf("authorization failed: %s", strings.TrimSpace(e+" "+query.Get("error_description")))
+	} else if result.code == "" {
+		result.err = errors.New("authorization failed: no code in the callback")
+	}
+
+	select {
+	case c.results <- result:
+	default:
+	}
+
+	if result.err != nil {
+		http.Error(w, result.err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "mcphost is authorized. You can close this window.")
+}
+
+// wait returns the result of the authorization, or an error when ctx ends
+// first.
+func (c *oauthCallback) wait(ctx context.Context) (callbackResult, error) {
+	select {
+	case result := <-c.results:
+		return result, result.err
+	case <-ctx.Done():
+		return callbackResult{}, fmt.Errorf("authorization not completed: %w", ctx.Err())
+	}
+}
+
+// close stops listening.
+func (c *oauthCallback) close() {
+	_ = c.server.Close()
+}
@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/mark3labs/mcphost/internal/auth"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// oauthTestServer is an MCP server that only answers requests carrying an
+// access token of its own authorization server, which supports dynamic
+// client registration, PKCE and refresh tokens.
+type oauthTestServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	challenge string
+}
+
+func newOAuthTestServer(t *testing.T) *oauthTestServer {
+	t.Helper()
+	srv := server.NewMCPServer("secure", "1.0.0", server.WithToolCapabilities(true))
+	srv.AddTool(mcp.NewTool("echo", mcp.WithDescription("Echoes")),
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("echo"), nil
+		})
+	mcpHandler := server.NewStreamableHTTPServer(srv)
+
+	s := &oauthTestServer{}
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"resource": s.URL + "/mcp", "authorization_servers": []string{s.URL}})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                   s.URL,
+			"authorization_endpoint":   s.URL + "/authorize",
+			"token_endpoint":           s.URL + "/token",
+			"registration_endpoint":    s.URL + "/register",
+			"response_types_supported": []string{"code"},
+		})
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, map[string]any{"client_id": "client-1"})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("client_id") != "client-1" || query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad authorization request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.challenge = query.Get("code_challenge")
+		s.mu.Unlock()
+		redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {"code-1"}, "state": {query.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			s.mu.Lock()
+			valid := r.PostForm.Get("code") == "code-1" && base64.RawURLEncoding.EncodeToString(sum[:]) == s.challenge
+			s.mu.Unlock()
+			if !valid {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"access_token": "token-1", "token_type": "Bearer", "expires_in": 3600, "refresh_token": "refresh-1"})
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "refresh-1" {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"access_token": "token-2", "token_type": "Bearer", "expires_in": 3600})
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "unsupported_grant_type"})
+		}
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer token-1", "Bearer token-2":
+			mcpHandler.ServeHTTP(w, r)
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+s.URL+`/.well-known/oauth-protected-resource"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// loadSecureServer loads the tools of ts as server "secure" into manager.
+func loadSecureServer(t *testing.T, manager *MCPToolManager, ts *oauthTestServer) error {
+	t.Helper()
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServerConfig{
+			"secure": {Type: "remote", URL: ts.URL + "/mcp"},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := manager.LoadTools(ctx, cfg)
+	t.Cleanup(func() { _ = manager.Close() })
+	return err
+}
+
+// TestLoadTools_authorizesServer connects to a server answering 401 and
+// verifies that mcphost registers, goes through the browser flow and stores
+// the token it is issued.
+func TestLoadTools_authorizesServer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	ts := newOAuthTestServer(t)
+
+	// The "browser" follows the redirect back to mcphost's listener.
+	openBrowser = func(authURL string) {
+		resp, err := http.Get(authURL)
+		if err != nil {
+			t.Errorf("Failed to authorize: %v", err)
+			return
+		}
+		_ = resp.Body.Close()
+	}
+	t.Cleanup(func() { openBrowser = auth.TryOpenBrowser })
+
+	var shown string
+	manager := NewMCPToolManager()
+	manager.SetAuthorizationURLFunc(func(serverName, authURL string) { shown = serverName })
+	if err := loadSecureServer(t, manager, ts); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	findTool(t, manager, "secure__echo")
+	if shown != "secure" {
+		t.Errorf("Expected the authorization URL of secure to be shown, got %q", shown)
+	}
+
+	cm, err := auth.NewCredentialManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := cm.GetMCPServerCredentials("secure")
+	if err != nil || creds == nil {
+		t.Fatalf("Expected stored credentials, got %v, %v", creds, err)
+	}
+	if creds.ClientID != "client-1" || creds.AccessToken != "token-1" || creds.RefreshToken != "refresh-1" {
+		t.Errorf("Expected the registration and the tokens, got %+v", creds)
+	}
+}
+
+// TestLoadTools_refreshesExpiredToken verifies that an expired stored token
+// is refreshed without asking the user.
+func TestLoadTools_refreshesExpiredToken(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	ts := newOAuthTestServer(t)
+
+	cm, err := auth.NewCredentialManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cm.UpdateMCPServerCredentials("secure", func(creds *auth.MCPServerCredentials) {
+		creds.ServerURL = ts.URL + "/mcp"
+		creds.ClientID = "client-1"
+		creds.AccessToken = "expired"
+		creds.TokenType = "Bearer"
+		creds.RefreshToken = "refresh-1"
+		creds.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openBrowser = func(string) { t.Error("The browser should not be opened") }
+	t.Cleanup(func() { openBrowser = auth.TryOpenBrowser })
+
+	manager := NewMCPToolManager()
+	if err := loadSecureServer(t, manager, ts); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	findTool(t, manager, "secure__echo")
+
+	creds, _ := cm.GetMCPServerCredentials("secure")
+	if creds == nil || creds.AccessToken != "token-2" || creds.RefreshToken != "refresh-1" {
+		t.Errorf("Expected the refreshed token to be stored, got %+v", creds)
+	}
+}
+
+// TestLoadTools_unauthorizedWithoutURLFunc verifies that without a way to
+// show the authorization URL the server fails with a hint to log in.
+func TestLoadTools_unauthorizedWithoutURLFunc(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	ts := newOAuthTestServer(t)
+
+	err := loadSecureServer(t, NewMCPToolManager(), ts)
+	if err == nil || !strings.Contains(err.Error(), "mcphost auth login --server secure") {
+		t.Errorf("Expected a hint to authorize the server, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The methods of progress and log notifications, which mcp-go does not
+// declare.
+const (
+	methodNotificationProgress = "notifications/progress"
+	methodNotificationMessage  = "notifications/message"
+)
+
+// ToolProgress is a progress update of a running MCP tool call, as sent by
+// the server in a notifications/progress notification.
+type ToolProgress struct {
+	// Progress is the progress so far; it increases with every update.
+	Progress float64
+	// Total is the progress at which the call is done, or 0 when unknown.
+	Total float64
+	// Message describes the current progress; it may be empty.
+	Message string
+}
+
+// ProgressHandler receives the progress updates of the MCP tool calls made
+// with a context carrying it. toolName is the prefixed name of the tool.
+type ProgressHandler func(toolName string, progress ToolProgress)
+
+type progressHandlerKey struct{}
+
+// WithProgressHandler returns a context carrying h. MCP tool calls made with
+// this context send a progress token and report the server's updates to h.
+func WithProgressHandler(ctx context.Context, h ProgressHandler) context.Context {
+	return context.WithValue(ctx, progressHandlerKey{}, h)
+}
+
+// progressHandlerFrom returns the handler set with WithProgressHandler.
+func progressHandlerFrom(ctx context.Context) ProgressHandler {
+	h, _ := ctx.Value(progressHandlerKey{}).(ProgressHandler)
+	return h
+}
+
+// trackProgress registers fn for the progress updates of a tool call and
+// returns the call's progress token. done unregisters it.
+func (m *MCPToolManager) trackProgress(fn func(ToolProgress)) (token string, done func()) {
+	m.progressMu.Lock()
+	defer m.progressMu.Unlock()
+	m.progressSeq++
+	token = fmt.Sprintf("mcphost-%d", m.progressSeq)
+	if m.progress == nil {
+		m.progress = make(map[string]func(ToolProgress))
+	}
+	m.progress[token] = fn
+
+	return token, func() {
+		m.progressMu.Lock()
+		defer m.progressMu.Unlock()
+		delete(m.progress, token)
+	}
+}
+
+// handleProgress passes a notifications/progress notification to the tool
+// call it belongs to. Updates for calls that already finished are dropped.
+func (m *MCPToolManager) handleProgress(notification mcp.JSONRPCNotification) {
+	fields := notification.Params.AdditionalFields
+	token := fmt.Sprint(fields["progressToken"])
+
+	m.progressMu.Lock()
+	fn := m.progress[token]
+	m.progressMu.Unlock()
+	if fn == nil {
+		return
+	}
+
+	progress, _ := fields["progress"].(float64)
+	total, _ := fields["total"].(float64)
+	message, _ := fields["message"].(string)
+	fn(ToolProgress{Progress: progress, Total: total, Message: message})
+}
+
+// LogMessage is a log message sent by an MCP server in a
+// notifications/message notification.
+type LogMessage struct {
+	// Level is the syslog severity, such as "info" or "error".
+	Level string
+	// Logger names the part of the server logging; it may be empty.
+	Logger string
+	// Message is the logged data; data other than text is JSON-encoded.
+	Message string
+}
+
+// LogFunc receives the log messages of MCP servers. It is called on the
+// reader of the server's connection, so it should return quickly.
+type LogFunc func(serverName string, message LogMessage)
+
+// SetLogFunc sets the callback receiving the log messages of the servers.
+// It can be set before or after LoadTools.
+func (m *MCPToolManager) SetLogFunc(fn LogFunc) {
+	m.progressMu.Lock()
+	defer m.progressMu.Unlock()
+	m.logFunc = fn
+}
+
+// handleLog passes a notifications/message notification of serverName to the
+// LogFunc and the debug log.
+func (m *MCPToolManager) handleLog(serverName string, notification mcp.JSONRPCNotification) {
+	message := logMessage(notification.Params.AdditionalFields)
+	m.debugLogger.LogDebug(fmt.Sprintf("[MCP LOG] %s %s: %s", serverName, message.Level, message.Message))
+
+	m.progressMu.Lock()
+	fn := m.logFunc
+	m.progressMu.Unlock()
+	if fn != nil {
+		fn(serverName, message)
+	}
+}
+
+// logMessage decodes the params of a notifications/message notification.
+func logMessage(fields map[string]any) LogMessage {
+	message := LogMessage{}
+	message.Level, _ = fields["level"].(string)
+	message.Logger, _ = fields["logger"].(string)
+	switch data := fields["data"].(type) {
+	case string:
+		message.Message = data
+	case nil:
+	default:
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			encoded = []byte(fmt.Sprint(data))
+		}
+		message.Message = string(encoded)
+	}
+	return message
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// notification builds a notification as decoded from a server.
+func notification(method string, fields map[string]any) mcp.JSONRPCNotification {
+	var n mcp.JSONRPCNotification
+	n.Method = method
+	n.Params.AdditionalFields = fields
+	return n
+}
+
+func TestHandleProgress(t *testing.T) {
+	m := NewMCPToolManager()
+	var got []ToolProgress
+	token, done := m.trackProgress(func(p ToolProgress) { got = append(got, p) })
+
+	m.handleNotification("build", notification(methodNotificationProgress, map[string]any{
+		"progressToken": token, "progress": 2.0, "total": 5.0, "message": "linking",
+	}))
+	m.handleNotification("build", notification(methodNotificationProgress, map[string]any{
+		"progressToken": "other", "progress": 1.0,
+	}))
+	done()
+	m.handleNotification("build", notification(methodNotificationProgress, map[string]any{
+		"progressToken": token, "progress": 3.0,
+	}))
+
+	if len(got) != 1 || got[0] != (ToolProgress{Progress: 2, Total: 5, Message: "linking"}) {
+		t.Errorf("expected only the update of the running call, got %+v", got)
+	}
+
+	if next, _ := m.trackProgress(func(ToolProgress) {}); next == token {
+		t.Error("expected a new token for every call")
+	}
+}
+
+func TestWithProgressHandler(t *testing.T) {
+	if progressHandlerFrom(context.Background()) != nil {
+		t.Fatal("expected no handler by default")
+	}
+	var toolName string
+	ctx := WithProgressHandler(context.Background(), func(name string, _ ToolProgress) { toolName = name })
+	progressHandlerFrom(ctx)("build__run", ToolProgress{})
+	if toolName != "build__run" {
+		t.Errorf("expected the handler set, got %q", toolName)
+	}
+}
+
+func TestHandleLog(t *testing.T) {
+	m := NewMCPToolManager()
+	m.debugLogger = NewSimpleDebugLogger(false)
+	var got []LogMessage
+	m.SetLogFunc(func(serverName string, message LogMessage) {
+		if serverName != "build" {
+			t.Errorf("unexpected server %q", serverName)
+		}
+		got = append(got, message)
+	})
+
+	m.handleNotification("build", notification(methodNotificationMessage, map[string]any{
+		"level": "warning", "logger": "cache", "data": "cache miss",
+	}))
+	m.handleNotification("build", notification(methodNotificationMessage, map[string]any{
+		"level": "error", "data": map[string]any{"code": 2.0},
+	}))
+
+	want := []LogMessage{
+		{Level: "warning", Logger: "cache", Message: "cache miss"},
+		{Level: "error", Message: `{"code":2}`},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Prompt is a prompt template offered by an MCP server.
+type Prompt struct {
+	// Server is the name of the server in the configuration.
+	Server      string
+	Name        string
+	Description string
+	Arguments   []PromptArgument
+}
+
+// PromptArgument is an argument a prompt is filled in with.
+type PromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Command returns the slash command that runs the prompt, "/server:name".
+func (p Prompt) Command() string {
+	return "/" + p.Server + ":" + p.Name
+}
+
+// loadServerPrompts lists the prompts of a server that offers them, replacing
+// those listed before. Failures are logged but do not fail the server, whose
+// tools work without its prompts.
+func (m *MCPToolManager) loadServerPrompts(ctx context.Context, serverName string, conn *MCPConnection) {
+	if conn.Capabilities().Prompts == nil {
+		return
+	}
+
+	listResult, err := conn.client.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Failed to list prompts of %s: %v", serverName, err))
+		return
+	}
+	var prompts []Prompt
+	for _, p := range listResult.Prompts {
+		prompt := Prompt{Server: serverName, Name: p.Name, Description: p.Description}
+		for _, arg := range p.Arguments {
+			prompt.Arguments = append(prompt.Arguments, PromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	m.listsMu.Lock()
+	defer m.listsMu.Unlock()
+	m.prompts = append(slices.DeleteFunc(slices.Clone(m.prompts), func(p Prompt) bool {
+		return p.Server == serverName
+	}), prompts...)
+}
+
+// Prompts returns the prompts of all loaded servers, sorted by server, then
+// name.
+func (m *MCPToolManager) Prompts() []Prompt {
+	m.listsMu.RLock()
+	prompts := slices.Clone(m.prompts)
+	m.listsMu.RUnlock()
+	slices.SortStableFunc(prompts, func(a, b Prompt) int {
+		return cmp.Or(cmp.Compare(a.Server, b.Server), cmp.Compare(a.Name, b.Name))
+	})
+	return prompts
+}
+
+// GetPrompt fills in the prompt promptName of serverName with args and
+// returns its messages, converted with PromptMessages.
+func (m *MCPToolManager) GetPrompt(ctx context.Context, serverName, promptName string, args map[string]string) ([]fantasy.Message, error) {
+	serverConfig, ok := m.config.MCPServers[serverName]
+	if !ok || !slices.ContainsFunc(m.Prompts(), func(p Prompt) bool { return p.Server == serverName && p.Name == promptName }) {
+		return nil, fmt.Errorf("server %s offers no prompt %s", serverName, promptName)
+	}
+
+	conn, err := m.connectionPool.GetConnectionWithHealthCheck(ctx, serverName, serverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get healthy connection from pool: %w", err)
+	}
+	result, err := conn.client.GetPrompt(ctx, mcp.GetPromptRequest{
+		Params: mcp.GetPromptParams{Name: promptName, Arguments: args},
+	})
+	if err != nil {
+		m.connectionPool.HandleConnectionError(serverName, err)
+		return nil, fmt.Errorf("failed to get prompt %s from %s: %w", promptName, serverName, err)
+	}
+
+	messages := PromptMessages(result.Messages)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("prompt %s of %s has no messages", promptName, serverName)
+	}
+	return messages, nil
+}
+
+// PromptMessages converts the messages of an MCP prompt into conversation
+// messages. Consecutive messages of the same role are merged. Text, resource
+// links and embedded resources become text like in tool results. Images and
+// audio in user messages are attached as files; in assistant messages, which
+// cannot carry files, they become placeholders.
+func PromptMessages(promptMessages []mcp.PromptMessage) []fantasy.Message {
+	var messages []fantasy.Message
+	for _, pm := range promptMessages {
+		role := fantasy.MessageRoleUser
+		if pm.Role == mcp.RoleAssistant {
+			role = fantasy.MessageRoleAssistant
+		}
+
+		var part fantasy.MessagePart
+		switch c := pm.Content.(type) {
+		case mcp.TextContent:
+			part = fantasy.TextPart{Text: c.Text}
+		case mcp.ImageContent:
+			part = promptMediaPart(role, c.Data, c.MIMEType, imagePlaceholder(c))
+		case mcp.AudioContent:
+			part = promptMediaPart(role, c.Data, c.MIMEType,
+				fmt.Sprintf("[audio %s %s]", mediaSubtype(c.MIMEType), formatSize(base64.StdEncoding.DecodedLen(len(c.Data)))))
+		case mcp.ResourceLink:
+			part = fantasy.TextPart{Text: resourceLinkText(c)}
+		case mcp.EmbeddedResource:
+			part = fantasy.TextPart{Text: embeddedResourceText(c)}
+		default:
+			continue
+		}
+
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, part)
+			continue
+		}
+		messages = append(messages, fantasy.Message{Role: role, Content: []fantasy.MessagePart{part}})
+	}
+	return messages
+}
+
+// promptMediaPart returns base64-encoded media of a prompt message as a file
+// part, or placeholder when role cannot carry files or the data is invalid.
+func promptMediaPart(role fantasy.MessageRole, data, mediaType, placeholder string) fantasy.MessagePart {
+	if role == fantasy.MessageRoleUser {
+		if decoded, err := base64.StdEncoding.DecodeString(data); err == nil {
+			return fantasy.FilePart{Data: decoded, MediaType: mediaType}
+		}
+	}
+	return fantasy.TextPart{Text: placeholder}
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestPromptMessages(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n"))
+	messages := PromptMessages([]mcp.PromptMessage{
+		{Role: mcp.RoleUser, Content: mcp.TextContent{Type: "text", Text: "Review this diff"}},
+		{Role: mcp.RoleUser, Content: mcp.EmbeddedResource{Type: "resource", Resource: mcp.TextResourceContents{URI: "git://diff", Text: "+ added"}}},
+		{Role: mcp.RoleUser, Content: mcp.ImageContent{Type: "image", Data: png, MIMEType: "image/png"}},
+		{Role: mcp.RoleAssistant, Content: mcp.ImageContent{Type: "image", Data: png, MIMEType: "image/png"}},
+		{Role: mcp.RoleUser, Content: mcp.TextContent{Type: "text", Text: "Go on"}},
+	})
+
+	if len(messages) != 3 {
+		t.Fatalf("expected consecutive user messages merged into 3 messages, got %d", len(messages))
+	}
+	if messages[0].Role != fantasy.MessageRoleUser || len(messages[0].Content) != 3 {
+		t.Fatalf("unexpected first message: %+v", messages[0])
+	}
+	if text, ok := fantasy.AsMessagePart[fantasy.TextPart](messages[0].Content[1]); !ok || text.Text != "[resource git://diff]\n+ added" {
+		t.Errorf("expected the embedded resource as text, got %+v", messages[0].Content[1])
+	}
+	if file, ok := fantasy.AsMessagePart[fantasy.FilePart](messages[0].Content[2]); !ok || file.MediaType != "image/png" {
+		t.Errorf("expected the user image as a file, got %+v", messages[0].Content[2])
+	}
+	if _, ok := fantasy.AsMessagePart[fantasy.TextPart](messages[1].Content[0]); messages[1].Role != fantasy.MessageRoleAssistant || !ok {
+		t.Errorf("expected the assistant image as a placeholder, got %+v", messages[1])
+	}
+}
+
+func TestPromptCommand(t *testing.T) {
+	p := Prompt{Server: "github", Name: "review-pr"}
+	if got := p.Command(); got != "/github:review-pr" {
+		t.Errorf("Command() = %q", got)
+	}
+}
@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/attachments"
+)
+
+// ReadResourceName is the name of the tool that lets the model read MCP
+// resources (see EnableResourceTool).
+const ReadResourceName = "read_resource"
+
+// maxListedResources limits the resources listed in the read_resource tool
+// description, which is sent with every request.
+const maxListedResources = 50
+
+// Resource is a resource or resource template offered by an MCP server.
+type Resource struct {
+	// Server is the name of the server in the configuration.
+	Server string
+	// URI identifies the resource. For templates it is an RFC 6570 URI
+	// template, e.g. "file:///logs/{date}.log".
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+	// Template is set for resource templates, whose URI must be filled in
+	// before the resource can be read.
+	Template bool
+}
+
+// Mention returns the form a prompt mentions the resource in,
+// "@server:uri".
+func (r Resource) Mention() string {
+	return "@" + r.Server + ":" + r.URI
+}
+
+// loadServerResources lists the resources and resource templates of a
+// server that offers them, replacing those listed before. Failures are logged
+// but do not fail the server, whose tools work without its resources.
+func (m *MCPToolManager) loadServerResources(ctx context.Context, serverName string, conn *MCPConnection) {
+	if conn.Capabilities().Resources == nil {
+		return
+	}
+
+	var resources []Resource
+	defer func() { m.setServerResources(serverName, resources) }()
+
+	listResult, err := conn.client.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Failed to list resources of %s: %v", serverName, err))
+	} else {
+		for _, r := range listResult.Resources {
+			resources = append(resources, Resource{
+				Server:      serverName,
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MIMEType:    r.MIMEType,
+			})
+		}
+	}
+
+	templateResult, err := conn.client.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Failed to list resource templates of %s: %v", serverName, err))
+		return
+	}
+	for _, t := range templateResult.ResourceTemplates {
+		if t.URITemplate == nil || t.URITemplate.Template == nil {
+			continue
+		}
+		resources = append(resources, Resource{
+			Server:      serverName,
+			URI:         t.URITemplate.Raw(),
+			Name:        t.Name,
+			Description: t.Description,
+			MIMEType:    t.MIMEType,
+			Template:    true,
+		})
+	}
+}
+
+// setServerResources replaces the resources of serverName, and updates the
+// read_resource tool's list of them.
+func (m *MCPToolManager) setServerResources(serverName string, resources []Resource) {
+	m.listsMu.Lock()
+	m.resources = append(slices.DeleteFunc(slices.Clone(m.resources), func(r Resource) bool {
+		return r.Server == serverName
+	}), resources...)
+	m.listsMu.Unlock()
+
+	m.updateResourceTool()
+}
+
+// Resources returns the resources and resource templates of all loaded
+// servers, sorted by server, then resources before templates, then URI.
+func (m *MCPToolManager) Resources() []Resource {
+	m.listsMu.RLock()
+	resources := slices.Clone(m.resources)
+	m.listsMu.RUnlock()
+	slices.SortStableFunc(resources, func(a, b Resource) int {
+		if c := cmp.Compare(a.Server, b.Server); c != 0 {
+			return c
+		}
+		if a.Template != b.Template {
+			if a.Template {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.URI, b.URI)
+	})
+	return resources
+}
+
+// hasResources reports whether serverName offers any resources or templates.
+func (m *MCPToolManager) hasResources(serverName string) bool {
+	m.listsMu.RLock()
+	defer m.listsMu.RUnlock()
+	return slices.ContainsFunc(m.resources, func(r Resource) bool { return r.Server == serverName })
+}
+
+// ReadResource reads the resource with the given URI from serverName. A
+// resource template's URI must be filled in first.
+func (m *MCPToolManager) ReadResource(ctx context.Context, serverName, uri string) ([]mcp.ResourceContents, error) {
+	serverConfig, ok := m.config.MCPServers[serverName]
+	if !ok || !m.hasResources(serverName) {
+		return nil, fmt.Errorf("server %s offers no resources", serverName)
+	}
+
+	conn, err := m.connectionPool.GetConnectionWithHealthCheck(ctx, serverName, serverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get healthy connection from pool: %w", err)
+	}
+	result, err := conn.client.ReadResource(ctx, mcp.ReadResourceRequest{
+		Params: mcp.ReadResourceParams{URI: uri},
+	})
+	if err != nil {
+		m.connectionPool.HandleConnectionError(serverName, err)
+		return nil, fmt.Errorf("failed to read resource %s from %s: %w", uri, serverName, err)
+	}
+	return result.Contents, nil
+}
+
+// ResourceAttachments converts the contents of a resource read from
+// serverName into prompt attachments, named "server:uri". Text contents are
+// inlined into the prompt; binary contents are attached as media when the
+// model can take their type.
+func ResourceAttachments(serverName string, contents []mcp.ResourceContents) ([]*attachments.Attachment, error) {
+	var atts []*attachments.Attachment
+	for _, content := range contents {
+		var att *attachments.Attachment
+		var err error
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			att, err = attachments.New(serverName+":"+c.URI, "text/plain", []byte(c.Text))
+		case mcp.BlobResourceContents:
+			data, decodeErr := base64.StdEncoding.DecodeString(c.Blob)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("invalid contents of resource %s: %w", c.URI, decodeErr)
+			}
+			att, err = attachments.New(serverName+":"+c.URI, c.MIMEType, data)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, att)
+	}
+	return atts, nil
+}
+
+// EnableResourceTool adds the read_resource tool, which lets the model read
+// the resources of every loaded server. Its description lists the available
+// resources and templates. The tool is only offered while a server offers
+// resources.
+func (m *MCPToolManager) EnableResourceTool() {
+	m.listsMu.Lock()
+	m.resourceTool = true
+	m.listsMu.Unlock()
+
+	m.updateResourceTool()
+}
+
+// updateResourceTool adds, replaces or removes the read_resource tool so
+// that its description lists the current resources.
+func (m *MCPToolManager) updateResourceTool() {
+	m.listsMu.RLock()
+	enabled := m.resourceTool
+	m.listsMu.RUnlock()
+	if !enabled {
+		return
+	}
+	description := m.resourceToolDescription()
+
+	m.listsMu.Lock()
+	tools := slices.DeleteFunc(slices.Clone(m.tools), func(tool fantasy.AgentTool) bool {
+		return tool.Info().Name == ReadResourceName
+	})
+	if len(m.resources) > 0 {
+		tools = append(tools, fantasy.NewAgentTool(ReadResourceName, description, m.runReadResource))
+	}
+	m.tools = tools
+	m.listsMu.Unlock()
+
+	m.toolsChanged()
+}
+
+// resourceToolDescription describes the read_resource tool along with the
+// resources it can read.
+func (m *MCPToolManager) resourceToolDescription() string {
+	var b strings.Builder
+	b.WriteString("Read a resource offered by an MCP server, such as a file, document or record, by its server and URI. " +
+		"For a resource template, fill in the placeholders in braces to build the URI.\n\nAvailable resources:")
+	resources := m.Resources()
+	for _, r := range resources[:min(len(resources), maxListedResources)] {
+		fmt.Fprintf(&b, "\n- server %q, uri %q", r.Server, r.URI)
+		if r.Template {
+			b.WriteString(" (template)")
+		}
+		if desc := summarizeDescription(cmp.Or(r.Description, r.Name)); desc != "" {
+			b.WriteString(": " + desc)
+		}
+	}
+	if len(resources) > maxListedResources {
+		fmt.Fprintf(&b, "\n- and %d more", len(resources)-maxListedResources)
+	}
+	return b.String()
+}
+
+type readResourceInput struct {
+	Server string `json:"server" description:"Name of the MCP server offering the resource"`
+	URI    string `json:"uri" description:"URI of the resource"`
+}
+
+// runReadResource implements the read_resource tool. The contents are
+// converted like a tool result, so images reach models that accept them.
+func (m *MCPToolManager) runReadResource(ctx context.Context, input readResourceInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	if input.Server == "" || input.URI == "" {
+		return fantasy.NewTextErrorResponse("server and uri must not be empty"), nil
+	}
+	contents, err := m.ReadResource(ctx, input.Server, input.URI)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+
+	result := &mcp.CallToolResult{}
+	for _, content := range contents {
+		if blob, ok := content.(mcp.BlobResourceContents); ok && strings.HasPrefix(blob.MIMEType, "image/") {
+			result.Content = append(result.Content, mcp.ImageContent{Type: "image", Data: blob.Blob, MIMEType: blob.MIMEType})
+			continue
+		}
+		result.Content = append(result.Content, mcp.EmbeddedResource{Type: "resource", Resource: content})
+	}
+	if len(result.Content) == 0 {
+		return fantasy.NewTextResponse(fmt.Sprintf("Resource %s is empty.", input.URI)), nil
+	}
+	return convertToolResult(result, m.acceptsToolResultMedia), nil
+}
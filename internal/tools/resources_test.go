@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestResources_sorted(t *testing.T) {
+	m := NewMCPToolManager()
+	m.resources = []Resource{
+		{Server: "fs", URI: "file:///{path}", Template: true},
+		{Server: "fs", URI: "file:///b.txt"},
+		{Server: "db", URI: "postgres://orders"},
+		{Server: "fs", URI: "file:///a.txt"},
+	}
+
+	var got []string
+	for _, r := range m.Resources() {
+		got = append(got, r.Mention())
+	}
+	want := []string{"@db:postgres://orders", "@fs:file:///a.txt", "@fs:file:///b.txt", "@fs:file:///{path}"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Resources() = %v, want %v", got, want)
+	}
+}
+
+func TestResourceAttachments(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	atts, err := ResourceAttachments("fs", []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: "file:///notes.md", MIMEType: "text/markdown", Text: "# Notes"},
+		mcp.BlobResourceContents{URI: "file:///logo.png", MIMEType: "image/png", Blob: base64.StdEncoding.EncodeToString(png)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(atts) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(atts))
+	}
+	if atts[0].Name != "fs:file:///notes.md" || string(atts[0].Data) != "# Notes" {
+		t.Errorf("unexpected text attachment: %+v", atts[0])
+	}
+	if atts[1].MediaType != "image/png" || string(atts[1].Data) != string(png) {
+		t.Errorf("unexpected image attachment: %+v", atts[1])
+	}
+
+	_, err = ResourceAttachments("fs", []mcp.ResourceContents{
+		mcp.BlobResourceContents{URI: "file:///bad", MIMEType: "image/png", Blob: "not base64!"},
+	})
+	if err == nil {
+		t.Error("expected an error for invalid base64 contents")
+	}
+}
+
+func TestResourceToolDescription(t *testing.T) {
+	m := NewMCPToolManager()
+	m.EnableResourceTool()
+	if len(m.tools) != 0 {
+		t.Fatal("expected no read_resource tool without resources")
+	}
+
+	m.resources = []Resource{
+		{Server: "fs", URI: "file:///{path}", Description: "Any file", Template: true},
+		{Server: "fs", URI: "file:///README.md", Name: "Readme"},
+	}
+	m.EnableResourceTool()
+	if len(m.tools) != 1 || m.tools[0].Info().Name != ReadResourceName {
+		t.Fatalf("expected the read_resource tool, got %d tools", len(m.tools))
+	}
+	desc := m.tools[0].Info().Description
+	for _, want := range []string{`server "fs", uri "file:///README.md": Readme`, `uri "file:///{path}" (template): Any file`} {
+		if !strings.Contains(desc, want) {
+			t.Errorf("description missing %q:\n%s", want, desc)
+		}
+	}
+}
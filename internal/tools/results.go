@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image.DecodeConfig
+	_ "image/jpeg" // register JPEG for image.DecodeConfig
+	_ "image/png"  // register PNG for image.DecodeConfig
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// acceptsToolResultMedia reports whether the model can receive media of the
+// given type in a tool result. With fallback models, media is sent when any of
+// them accepts it; each request gives the others the result's text instead
+// (see models.AcceptsToolResultMedia).
+func (m *MCPToolManager) acceptsToolResultMedia(mediaType string) bool {
+	if m.model == nil {
+		return false
+	}
+	return models.AcceptsToolResultMedia(m.model, mediaType)
+}
+
+// convertToolResult converts an MCP tool result into a fantasy tool response.
+// Text content stays text, resource links and embedded resources are rendered
+// as readable text, and images and audio become placeholders such as
+// "[image 1280x720 png]". The first image or audio clip the model accepts
+// (see accepts) is also sent as media; fantasy carries one per response.
+func convertToolResult(result *mcp.CallToolResult, accepts func(mediaType string) bool) fantasy.ToolResponse {
+	var parts []string
+	var mediaData, mediaType string
+	var mediaIsAudio bool
+	// send picks the media sent to the model and reports whether it was picked
+	send := func(data, dataType string, audio bool) bool {
+		if mediaData != "" || result.IsError || !accepts(dataType) {
+			return false
+		}
+		mediaData, mediaType, mediaIsAudio = data, dataType, audio
+		return true
+	}
+
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, c.Text)
+		case mcp.ImageContent:
+			placeholder := imagePlaceholder(c)
+			if !send(c.Data, c.MIMEType, false) {
+				placeholder += " (not shown to the model)"
+			}
+			parts = append(parts, placeholder)
+		case mcp.AudioContent:
+			placeholder := fmt.Sprintf("[audio %s %s]", mediaSubtype(c.MIMEType), formatSize(base64.StdEncoding.DecodedLen(len(c.Data))))
+			if !send(c.Data, c.MIMEType, true) {
+				placeholder += " (not shown to the model)"
+			}
+			parts = append(parts, placeholder)
+		case mcp.ResourceLink:
+			parts = append(parts, resourceLinkText(c))
+		case mcp.EmbeddedResource:
+			parts = append(parts, embeddedResourceText(c))
+		default:
+			if data, err := json.Marshal(content); err == nil {
+				parts = append(parts, string(data))
+			}
+		}
+	}
+
+	// Servers should also return structured content as text, but not all do
+	if len(parts) == 0 && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	text := strings.Join(parts, "\n")
+
+	switch {
+	case result.IsError:
+		return fantasy.NewTextErrorResponse(text)
+	case mediaData == "":
+		return fantasy.NewTextResponse(text)
+	}
+
+	// fantasy expects media data base64-encoded, as MCP sends it
+	var response fantasy.ToolResponse
+	if mediaIsAudio {
+		response = fantasy.NewMediaResponse([]byte(mediaData), mediaType)
+	} else {
+		response = fantasy.NewImageResponse([]byte(mediaData), mediaType)
+	}
+	response.Content = text
+	return response
+}
+
+// imagePlaceholder describes an image by its size and format, e.g.
+// "[image 1280x720 png]". The size is left out when the image cannot be
+// decoded.
+func imagePlaceholder(c mcp.ImageContent) string {
+	data, err := base64.StdEncoding.DecodeString(c.Data)
+	if err != nil {
+		return fmt.Sprintf("[image %s]", mediaSubtype(c.MIMEType))
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Sprintf("[image %s %s]", mediaSubtype(c.MIMEType), formatSize(len(data)))
+	}
+	return fmt.Sprintf("[image %dx%d %s]", config.Width, config.Height, format)
+}
+
+// resourceLinkText renders a resource link, e.g.
+// "[resource link: report (file:///tmp/report.pdf, application/pdf) - Q3 report]".
+func resourceLinkText(c mcp.ResourceLink) string {
+	details := c.URI
+	if c.MIMEType != "" {
+		details += ", " + c.MIMEType
+	}
+	text := fmt.Sprintf("[resource link: %s (%s)", c.Name, details)
+	if c.Description != "" {
+		text += " - " + c.Description
+	}
+	return text + "]"
+}
+
+// embeddedResourceText renders an embedded resource. Text resources are
+// shown in full under a header; binary ones as a placeholder.
+func embeddedResourceText(c mcp.EmbeddedResource) string {
+	switch r := c.Resource.(type) {
+	case mcp.TextResourceContents:
+		header := "[resource " + r.URI
+		if r.MIMEType != "" {
+			header += " (" + r.MIMEType + ")"
+		}
+		return header + "]\n" + r.Text
+	case mcp.BlobResourceContents:
+		return fmt.Sprintf("[resource %s (%s, %s)]", r.URI, r.MIMEType, formatSize(base64.StdEncoding.DecodedLen(len(r.Blob))))
+	}
+	return "[resource]"
+}
+
+// mediaSubtype returns the subtype of a media type ("png" for "image/png").
+func mediaSubtype(mediaType string) string {
+	if _, subtype, ok := strings.Cut(mediaType, "/"); ok {
+		return subtype
+	}
+	return mediaType
+}
+
+// formatSize formats a byte count for display, e.g. "12.3 KB".
+func formatSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
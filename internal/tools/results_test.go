@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// testPNG returns a base64-encoded PNG of the given size.
+func testPNG(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func acceptImages(mediaType string) bool { return strings.HasPrefix(mediaType, "image/") }
+
+func acceptNothing(string) bool { return false }
+
+func TestConvertToolResult_Text(t *testing.T) {
+	resp := convertToolResult(&mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent("line one"), mcp.NewTextContent("line two")},
+	}, acceptImages)
+
+	if resp.Type != "text" || resp.Content != "line one\nline two" {
+		t.Errorf("Expected plain text, got %+v", resp)
+	}
+}
+
+func TestConvertToolResult_Image(t *testing.T) {
+	screenshot := testPNG(t, 64, 32)
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent("Took a screenshot"),
+			mcp.NewImageContent(screenshot, "image/png"),
+			mcp.NewImageContent(testPNG(t, 8, 8), "image/png"),
+		},
+	}
+
+	resp := convertToolResult(result, acceptImages)
+	if resp.Type != "image" || resp.MediaType != "image/png" || string(resp.Data) != screenshot {
+		t.Fatalf("Expected the first image as media, got type %q (%s)", resp.Type, resp.MediaType)
+	}
+	want := "Took a screenshot\n[image 64x32 png]\n[image 8x8 png] (not shown to the model)"
+	if resp.Content != want {
+		t.Errorf("Expected text %q, got %q", want, resp.Content)
+	}
+
+	// Models that cannot receive images only get the placeholders
+	resp = convertToolResult(result, acceptNothing)
+	if resp.Type != "text" || resp.Data != nil {
+		t.Errorf("Expected a text response, got type %q with %d bytes", resp.Type, len(resp.Data))
+	}
+	if !strings.Contains(resp.Content, "[image 64x32 png] (not shown to the model)") {
+		t.Errorf("Expected an image placeholder, got %q", resp.Content)
+	}
+}
+
+func TestConvertToolResult_Resources(t *testing.T) {
+	resp := convertToolResult(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewAudioContent(base64.StdEncoding.EncodeToString(make([]byte, 2048)), "audio/wav"),
+			mcp.NewResourceLink("file:///tmp/report.pdf", "report", "Q3 report", "application/pdf"),
+			mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "file:///tmp/notes.md", MIMEType: "text/markdown", Text: "# Notes"}),
+		},
+	}, acceptImages)
+
+	want := "[audio wav 2.0 KB] (not shown to the model)\n" +
+		"[resource link: report (file:///tmp/report.pdf, application/pdf) - Q3 report]\n" +
+		"[resource file:///tmp/notes.md (text/markdown)]\n# Notes"
+	if resp.Type != "text" || resp.Content != want {
+		t.Errorf("Expected text %q, got %q", want, resp.Content)
+	}
+}
+
+func TestConvertToolResult_Error(t *testing.T) {
+	resp := convertToolResult(&mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent("page not found"), mcp.NewImageContent(testPNG(t, 4, 4), "image/png")},
+		IsError: true,
+	}, acceptImages)
+
+	if !resp.IsError || resp.Data != nil || !strings.HasPrefix(resp.Content, "page not found") {
+		t.Errorf("Expected a text error response, got %+v", resp)
+	}
+}
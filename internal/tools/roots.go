@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// rootsNotifier is implemented by clients that can tell their server that
+// the roots changed.
+type rootsNotifier interface {
+	RootListChanges(ctx context.Context) error
+}
+
+// rootsHandler answers the roots/list requests of one MCP server: its
+// configured roots, or the working directory, followed by the roots added
+// during the session.
+type rootsHandler struct {
+	pool       *MCPConnectionPool
+	configured []string // absolute paths; nil means the working directory
+}
+
+// newRootsHandler creates the roots handler of a server with the configured
+// roots, given as directories or file:// URIs. Relative directories are
+// resolved against the working directory once, when the server is first
+// connected.
+func newRootsHandler(pool *MCPConnectionPool, roots []string) *rootsHandler {
+	h := &rootsHandler{pool: pool}
+	for _, root := range roots {
+		if path, ok := strings.CutPrefix(root, "file://"); ok {
+			if unescaped, err := url.PathUnescape(path); err == nil {
+				path = unescaped
+			}
+			root = filepath.FromSlash(path)
+		}
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		h.configured = append(h.configured, root)
+	}
+	return h
+}
+
+// ListRoots implements client.RootsHandler.
+func (h *rootsHandler) ListRoots(context.Context, mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	result := &mcp.ListRootsResult{Roots: []mcp.Root{}}
+	for _, path := range h.paths() {
+		result.Roots = append(result.Roots, mcp.Root{URI: rootURI(path), Name: filepath.Base(path)})
+	}
+	return result, nil
+}
+
+// paths returns the directories of the roots.
+func (h *rootsHandler) paths() []string {
+	h.pool.rootsMu.Lock()
+	defer h.pool.rootsMu.Unlock()
+
+	paths := slices.Clone(h.configured)
+	if paths == nil {
+		paths = []string{h.pool.workDir}
+	}
+	for _, added := range h.pool.addedRoots {
+		if !slices.Contains(paths, added) {
+			paths = append(paths, added)
+		}
+	}
+	return paths
+}
+
+// rootsHandlerFor returns the roots handler of serverName, creating it on
+// first use. Handlers are kept across reconnections.
+func (p *MCPConnectionPool) rootsHandlerFor(serverName string, serverConfig config.MCPServerConfig) *rootsHandler {
+	p.rootsMu.Lock()
+	h, ok := p.rootsHandlers[serverName]
+	p.rootsMu.Unlock()
+	if ok {
+		return h
+	}
+
+	h = newRootsHandler(p, serverConfig.Roots)
+	p.rootsMu.Lock()
+	defer p.rootsMu.Unlock()
+	if p.rootsHandlers == nil {
+		p.rootsHandlers = make(map[string]*rootsHandler)
+	}
+	p.rootsHandlers[serverName] = h
+	return h
+}
+
+// setWorkingDirectory changes the default root and tells the connected
+// servers using it.
+func (p *MCPConnectionPool) setWorkingDirectory(dir string) {
+	p.rootsMu.Lock()
+	changed := p.workDir != dir
+	p.workDir = dir
+	p.rootsMu.Unlock()
+
+	if changed {
+		p.notifyRootsChanged(func(h *rootsHandler) bool { return h.configured == nil })
+	}
+}
+
+// addRoot adds dir to the roots of every server and tells the connected
+// servers. It reports whether dir was not a root yet.
+func (p *MCPConnectionPool) addRoot(dir string) bool {
+	p.rootsMu.Lock()
+	if slices.Contains(p.addedRoots, dir) {
+		p.rootsMu.Unlock()
+		return false
+	}
+	p.addedRoots = append(p.addedRoots, dir)
+	p.rootsMu.Unlock()
+
+	p.notifyRootsChanged(func(*rootsHandler) bool { return true })
+	return true
+}
+
+// notifyRootsChanged sends notifications/roots/list_changed to the connected
+// servers whose roots handler matches affected. Failures are only logged: the
+// server gets the new roots the next time it lists them.
+func (p *MCPConnectionPool) notifyRootsChanged(affected func(*rootsHandler) bool) {
+	p.mu.RLock()
+	connections := maps.Clone(p.connections)
+	p.mu.RUnlock()
+	p.rootsMu.Lock()
+	handlers := maps.Clone(p.rootsHandlers)
+	p.rootsMu.Unlock()
+
+	for serverName, conn := range connections {
+		h, ok := handlers[serverName]
+		notifier, canNotify := conn.client.(rootsNotifier)
+		if !ok || !canNotify || !affected(h) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(p.ctx, 5*time.Second)
+		err := notifier.RootListChanges(ctx)
+		cancel()
+		if err != nil && p.debugLogger != nil && p.debugLogger.IsDebugEnabled() {
+			p.debugLogger.LogDebug(fmt.Sprintf("[POOL] Failed to notify %s of changed roots: %v", serverName, err))
+		}
+	}
+}
+
+// rootURI returns the file:// URI of the directory path.
+func rootURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// Roots returns the root directories advertised to each server that supports
+// roots, by server name.
+func (m *MCPToolManager) Roots() map[string][]string {
+	roots := make(map[string][]string)
+	if m.connectionPool == nil {
+		return roots
+	}
+	m.connectionPool.rootsMu.Lock()
+	handlers := maps.Clone(m.connectionPool.rootsHandlers)
+	m.connectionPool.rootsMu.Unlock()
+	for serverName, h := range handlers {
+		roots[serverName] = h.paths()
+	}
+	return roots
+}
+
+// AddRoot adds the directory dir, relative to the working directory, to the
+// roots of every server and notifies the servers. It returns the absolute
+// path, and an error when dir is not a directory or already a root.
+func (m *MCPToolManager) AddRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(abs); err != nil {
+		return "", err
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", abs)
+	}
+	if m.connectionPool == nil {
+		return "", errors.New("no MCP servers are loaded")
+	}
+	if !m.connectionPool.addRoot(abs) {
+		return abs, fmt.Errorf("%s is already a root", abs)
+	}
+	return abs, nil
+}
+
+// SetWorkingDirectory makes dir the root of the servers that configure no
+// roots of their own, and notifies them. The caller changes the process's
+// working directory.
+func (m *MCPToolManager) SetWorkingDirectory(dir string) {
+	if m.connectionPool != nil {
+		m.connectionPool.setWorkingDirectory(dir)
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+func TestRootsHandler(t *testing.T) {
+	workDir := t.TempDir()
+	pool := &MCPConnectionPool{workDir: workDir}
+
+	cwdServer := pool.rootsHandlerFor("git", config.MCPServerConfig{})
+	configured := pool.rootsHandlerFor("fs", config.MCPServerConfig{Roots: []string{"/srv/data", "file:///srv/my%20docs"}})
+
+	result, err := cwdServer.ListRoots(context.Background(), mcp.ListRootsRequest{})
+	if err != nil {
+		t.Fatalf("ListRoots: %v", err)
+	}
+	if len(result.Roots) != 1 || result.Roots[0].URI != rootURI(workDir) || result.Roots[0].Name != filepath.Base(workDir) {
+		t.Errorf("expected the working directory as root, got %+v", result.Roots)
+	}
+
+	got := configured.paths()
+	want := []string{filepath.FromSlash("/srv/data"), filepath.FromSlash("/srv/my docs")}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected the configured roots %v, got %v", want, got)
+	}
+
+	other := t.TempDir()
+	pool.setWorkingDirectory(other)
+	if !pool.addRoot("/opt/shared") || pool.addRoot("/opt/shared") {
+		t.Error("expected the root added once")
+	}
+	if got := cwdServer.paths(); len(got) != 2 || got[0] != other || got[1] != "/opt/shared" {
+		t.Errorf("expected the new working directory and the added root, got %v", got)
+	}
+	if got := configured.paths(); len(got) != 3 || got[2] != "/opt/shared" {
+		t.Errorf("expected the added root after the configured ones, got %v", got)
+	}
+}
+
+func TestRootURI(t *testing.T) {
+	if got := rootURI("/home/me/my project"); got != "file:///home/me/my%20project" {
+		t.Errorf("rootURI() = %q", got)
+	}
+}
+
+func TestAddRoot_errors(t *testing.T) {
+	m := NewMCPToolManager()
+	m.connectionPool = &MCPConnectionPool{workDir: t.TempDir()}
+
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AddRoot(file); err == nil {
+		t.Error("expected an error for a file")
+	}
+	dir := t.TempDir()
+	if got, err := m.AddRoot(dir); err != nil || got != dir {
+		t.Fatalf("AddRoot() = %q, %v", got, err)
+	}
+	if _, err := m.AddRoot(dir); err == nil {
+		t.Error("expected an error for a root added twice")
+	}
+}
@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/config"
+	"github.com/mark3labs/mcphost/internal/models"
+)
+
+// SamplingRequest is a request of an MCP server to have the model generate a
+// message, as shown for approval.
+type SamplingRequest struct {
+	SystemPrompt string
+	Messages     []fantasy.Message
+	MaxTokens    int
+	// Model is the ID of the model that will answer.
+	Model string
+}
+
+// SamplingApprovalFunc decides whether a sampling request of serverName may
+// run. It may block until a decision is made. Returning false denies the
+// request; so does returning an error, which is passed on to the server.
+type SamplingApprovalFunc func(ctx context.Context, serverName string, request SamplingRequest) (bool, error)
+
+// SamplingUsageFunc receives the token usage of every fulfilled sampling
+// request.
+type SamplingUsageFunc func(usage fantasy.Usage)
+
+// SamplingBudgetFunc is consulted before every sampling request, whatever
+// the server's approval. An error, such as a used-up budget, refuses the
+// request and is passed on to the server.
+type SamplingBudgetFunc func() error
+
+// SetSamplingApprovalFunc sets the callback consulted before the sampling
+// requests of servers whose approval is "ask". A nil func (the default) lets
+// them run. Servers may sample as soon as they are loaded, so it can be set
+// before or after LoadTools.
+func (m *MCPToolManager) SetSamplingApprovalFunc(fn SamplingApprovalFunc) {
+	m.samplingFunc = fn
+	if m.connectionPool != nil {
+		m.connectionPool.SetSamplingApprovalFunc(fn)
+	}
+}
+
+// SetSamplingUsageFunc sets the callback receiving the token usage of
+// sampling requests. Like SetSamplingApprovalFunc it can be set at any time.
+func (m *MCPToolManager) SetSamplingUsageFunc(fn SamplingUsageFunc) {
+	m.samplingUsage = fn
+	if m.connectionPool != nil {
+		m.connectionPool.SetSamplingUsageFunc(fn)
+	}
+}
+
+// SetSamplingBudgetFunc sets the callback consulted before every sampling
+// request. Like SetSamplingApprovalFunc it can be set at any time.
+func (m *MCPToolManager) SetSamplingBudgetFunc(fn SamplingBudgetFunc) {
+	m.samplingBudget = fn
+	if m.connectionPool != nil {
+		m.connectionPool.SetSamplingBudgetFunc(fn)
+	}
+}
+
+// errSamplingDenied is returned to servers whose request was not approved.
+var errSamplingDenied = errors.New("sampling request denied by the user")
+
+// sampler fulfils the sampling requests of one MCP server with the pool's
+// model. It lives as long as the pool, so its rate limit holds across
+// reconnections of the server.
+type sampler struct {
+	pool       *MCPConnectionPool
+	serverName string
+	approval   string
+	maxTokens  int
+	limit      int // requests per minute
+
+	mu     sync.Mutex
+	recent []time.Time // start times of the requests of the last minute
+	now    func() time.Time
+}
+
+// newSampler creates the sampler of serverName configured by cfg, which may
+// be nil.
+func newSampler(pool *MCPConnectionPool, serverName string, cfg *config.SamplingConfig) *sampler {
+	s := &sampler{
+		pool:       pool,
+		serverName: serverName,
+		approval:   config.SamplingApprovalAsk,
+		limit:      config.DefaultSamplingRequestsPerMinute,
+		now:        time.Now,
+	}
+	if cfg != nil {
+		if cfg.Approval != "" {
+			s.approval = cfg.Approval
+		}
+		if cfg.MaxRequestsPerMinute > 0 {
+			s.limit = cfg.MaxRequestsPerMinute
+		}
+		s.maxTokens = cfg.MaxTokens
+	}
+	return s
+}
+
+// CreateMessage implements client.SamplingHandler. The request is rate
+// limited, checked against the budget and, unless the server is trusted with "auto" approval, approved
+// before the model is called. The model preferences' hints select a model of
+// the fallback chain; the priorities are not used.
+func (s *sampler) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	if check := s.pool.samplingBudget(); check != nil {
+		if err := check(); err != nil {
+			return nil, err
+		}
+	}
+
+	model := s.model(request.ModelPreferences)
+	if model == nil {
+		return nil, errors.New("no model available for sampling")
+	}
+	maxTokens := request.MaxTokens
+	if s.maxTokens > 0 && (maxTokens <= 0 || maxTokens > s.maxTokens) {
+		maxTokens = s.maxTokens
+	}
+
+	messages := samplingMessages(request.Messages)
+	if len(messages) == 0 {
+		return nil, errors.New("sampling request has no messages")
+	}
+
+	if s.approval != config.SamplingApprovalAuto {
+		if approve := s.pool.samplingApproval(); approve != nil {
+			ok, err := approve(ctx, s.serverName, SamplingRequest{
+				SystemPrompt: request.SystemPrompt,
+				Messages:     messages,
+				MaxTokens:    maxTokens,
+				Model:        model.Model(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, errSamplingDenied
+			}
+		}
+	}
+
+	call := fantasy.Call{Prompt: messages}
+	if request.SystemPrompt != "" {
+		call.Prompt = append([]fantasy.Message{fantasy.NewSystemMessage(request.SystemPrompt)}, messages...)
+	}
+	if maxTokens > 0 {
+		n := int64(maxTokens)
+		call.MaxOutputTokens = &n
+	}
+	if request.Temperature > 0 {
+		temperature := request.Temperature
+		call.Temperature = &temperature
+	}
+
+	resp, err := model.Generate(ctx, call)
+	if err != nil {
+		return nil, fmt.Errorf("sampling failed: %w", err)
+	}
+	if record := s.pool.samplingUsage(); record != nil {
+		record(resp.Usage)
+	}
+
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(resp.Content.Text()),
+		},
+		Model:      model.Model(),
+		StopReason: samplingStopReason(resp.FinishReason),
+	}, nil
+}
+
+// allow counts a request against the rate limit, or returns an error when the
+// server made too many requests in the last minute.
+func (s *sampler) allow() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	recent := s.recent[:0]
+	for _, t := range s.recent {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	s.recent = recent
+	if len(s.recent) >= s.limit {
+		return fmt.Errorf("sampling rate limit of %d requests per minute exceeded", s.limit)
+	}
+	s.recent = append(s.recent, now)
+	return nil
+}
+
+// model returns the model answering a request with prefs: the model of the
+// fallback chain its hints prefer, or the pool's model.
+func (s *sampler) model(prefs *mcp.ModelPreferences) fantasy.LanguageModel {
+	model := s.pool.model
+	fallback, ok := model.(*models.FallbackModel)
+	if !ok || prefs == nil {
+		return model
+	}
+	var hints []string
+	for _, hint := range prefs.Hints {
+		hints = append(hints, hint.Name)
+	}
+	return fallback.Prefer(hints...)
+}
+
+// samplingMessages converts the messages of a sampling request like prompt
+// messages, which carry the same content.
+func samplingMessages(messages []mcp.SamplingMessage) []fantasy.Message {
+	promptMessages := make([]mcp.PromptMessage, 0, len(messages))
+	for _, m := range messages {
+		content, ok := m.Content.(mcp.Content)
+		if !ok {
+			continue
+		}
+		promptMessages = append(promptMessages, mcp.PromptMessage{Role: m.Role, Content: content})
+	}
+	return PromptMessages(promptMessages)
+}
+
+// samplingStopReason maps a finish reason to the stop reasons of the MCP
+// specification, passing others through.
+func samplingStopReason(reason fantasy.FinishReason) string {
+	switch reason {
+	case fantasy.FinishReasonStop:
+		return "endTurn"
+	case fantasy.FinishReasonLength:
+		return "maxTokens"
+	default:
+		return string(reason)
+	}
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// samplingModel is a fantasy.LanguageModel that records the call it answers.
+type samplingModel struct {
+	call fantasy.Call
+}
+
+func (s *samplingModel) Generate(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	s.call = call
+	return &fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "A haiku"}},
+		FinishReason: fantasy.FinishReasonLength,
+		Usage:        fantasy.Usage{InputTokens: 12, OutputTokens: 5},
+	}, nil
+}
+
+func (s *samplingModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *samplingModel) GenerateObject(context.Context, fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *samplingModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *samplingModel) Provider() string { return "stub" }
+func (s *samplingModel) Model() string    { return "stub-model" }
+
+func newSamplingRequest(text string, maxTokens int) mcp.CreateMessageRequest {
+	var request mcp.CreateMessageRequest
+	request.Messages = []mcp.SamplingMessage{{Role: mcp.RoleUser, Content: mcp.NewTextContent(text)}}
+	request.SystemPrompt = "You are a poet."
+	request.MaxTokens = maxTokens
+	return request
+}
+
+func TestSampler_CreateMessage(t *testing.T) {
+	model := &samplingModel{}
+	pool := &MCPConnectionPool{model: model}
+	var usage fantasy.Usage
+	pool.SetSamplingUsageFunc(func(u fantasy.Usage) { usage = u })
+	var asked SamplingRequest
+	pool.SetSamplingApprovalFunc(func(_ context.Context, serverName string, request SamplingRequest) (bool, error) {
+		if serverName != "poems" {
+			t.Errorf("expected the server name, got %q", serverName)
+		}
+		asked = request
+		return true, nil
+	})
+
+	s := newSampler(pool, "poems", &config.SamplingConfig{MaxTokens: 100})
+	result, err := s.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 500))
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+
+	if asked.MaxTokens != 100 || asked.SystemPrompt != "You are a poet." || asked.Model != "stub-model" {
+		t.Errorf("unexpected approval request: %+v", asked)
+	}
+	if len(model.call.Prompt) != 2 || model.call.Prompt[0].Role != fantasy.MessageRoleSystem {
+		t.Fatalf("expected the system prompt and the message, got %+v", model.call.Prompt)
+	}
+	if model.call.MaxOutputTokens == nil || *model.call.MaxOutputTokens != 100 {
+		t.Errorf("expected maxTokens capped at 100, got %v", model.call.MaxOutputTokens)
+	}
+	if text, ok := result.Content.(mcp.TextContent); !ok || text.Text != "A haiku" {
+		t.Errorf("unexpected content: %#v", result.Content)
+	}
+	if result.Role != mcp.RoleAssistant || result.Model != "stub-model" || result.StopReason != "maxTokens" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if usage.InputTokens != 12 || usage.OutputTokens != 5 {
+		t.Errorf("expected the usage recorded, got %+v", usage)
+	}
+}
+
+func TestSampler_denied(t *testing.T) {
+	model := &samplingModel{}
+	pool := &MCPConnectionPool{model: model}
+	pool.SetSamplingApprovalFunc(func(context.Context, string, SamplingRequest) (bool, error) {
+		return false, nil
+	})
+
+	s := newSampler(pool, "poems", nil)
+	if _, err := s.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 50)); !errors.Is(err, errSamplingDenied) {
+		t.Fatalf("expected the request denied, got %v", err)
+	}
+	if model.call.Prompt != nil {
+		t.Error("expected the model not called")
+	}
+
+	auto := newSampler(pool, "poems", &config.SamplingConfig{Approval: config.SamplingApprovalAuto})
+	if _, err := auto.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 50)); err != nil {
+		t.Fatalf("expected auto approval to skip the approval func, got %v", err)
+	}
+}
+
+func TestSampler_overBudget(t *testing.T) {
+	model := &samplingModel{}
+	pool := &MCPConnectionPool{model: model}
+	overBudget := errors.New("budget exceeded")
+	pool.SetSamplingBudgetFunc(func() error { return overBudget })
+
+	s := newSampler(pool, "poems", &config.SamplingConfig{Approval: config.SamplingApprovalAuto})
+	if _, err := s.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 50)); !errors.Is(err, overBudget) {
+		t.Fatalf("expected the request refused, got %v", err)
+	}
+	if model.call.Prompt != nil {
+		t.Error("expected the model not called")
+	}
+}
+
+func TestSampler_rateLimit(t *testing.T) {
+	pool := &MCPConnectionPool{model: &samplingModel{}}
+	s := newSampler(pool, "poems", &config.SamplingConfig{Approval: config.SamplingApprovalAuto, MaxRequestsPerMinute: 2})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if _, err := s.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 50)); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if _, err := s.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 50)); err == nil {
+		t.Fatal("expected the third request within a minute to be refused")
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := s.CreateMessage(context.Background(), newSamplingRequest("Write a haiku", 50)); err != nil {
+		t.Fatalf("expected requests allowed again after a minute, got %v", err)
+	}
+}
+
+func TestClientOptions_sampling(t *testing.T) {
+	pool := &MCPConnectionPool{model: &samplingModel{}}
+	// Roots and elicitation are always offered.
+	if len(pool.clientOptions("poems", config.MCPServerConfig{})) != 3 {
+		t.Error("expected sampling offered by default")
+	}
+	deny := config.MCPServerConfig{Sampling: &config.SamplingConfig{Approval: config.SamplingApprovalDeny}}
+	if len(pool.clientOptions("secret", deny)) != 2 {
+		t.Error("expected no sampling with approval deny")
+	}
+
+	first := pool.samplers["poems"]
+	pool.clientOptions("poems", config.MCPServerConfig{})
+	if pool.samplers["poems"] != first {
+		t.Error("expected the sampler kept across reconnections")
+	}
+}
@@ -0,0 +1,370 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"charm.land/fantasy"
+)
+
+// Names of the meta-tools registered in tool search mode.
+const (
+	SearchToolsName = "search_tools"
+	LoadToolName    = "load_tool"
+)
+
+const (
+	defaultSearchLimit = 5
+	maxSearchLimit     = 20
+)
+
+// EnableToolSearch switches the manager to tool search mode. Instead of every
+// loaded tool, ActiveTools then returns the search_tools and load_tool
+// meta-tools, the tools whose prefixed names match one of the pinned globs
+// (path.Match syntax), and the tools the model has loaded so far. Loaded tools
+// stay active for the rest of the session. It must be called before the
+// agent's first step.
+func (m *MCPToolManager) EnableToolSearch(pinned []string) {
+	m.searchMu.Lock()
+	defer m.searchMu.Unlock()
+	m.toolSearch = true
+	m.pinnedTools = pinned
+	m.loadedTools = make(map[string]bool)
+	m.searchIndex = nil
+	m.metaTools = []fantasy.AgentTool{
+		fantasy.NewAgentTool(SearchToolsName,
+			"Search the available tools by keywords describing what you want to do. "+
+				"Only tools you have loaded with load_tool can be called; use this first "+
+				"whenever none of your tools fits the task.",
+			m.runSearchTools),
+		fantasy.NewAgentTool(LoadToolName,
+			"Load tools found with search_tools by their exact names. "+
+				"Loaded tools can be called from your next step on.",
+			m.runLoadTool),
+	}
+}
+
+// ToolSearchEnabled reports whether the manager is in tool search mode.
+func (m *MCPToolManager) ToolSearchEnabled() bool {
+	m.searchMu.Lock()
+	defer m.searchMu.Unlock()
+	return m.toolSearch
+}
+
+// ActiveTools returns the tools sent to the model. Without tool search these
+// are all loaded tools. In tool search mode they are the meta-tools, then the
+// pinned tools, then the loaded tools in the order they were loaded, so the
+// list only grows at its end.
+func (m *MCPToolManager) ActiveTools() []fantasy.AgentTool {
+	tools := m.GetTools()
+	m.searchMu.Lock()
+	defer m.searchMu.Unlock()
+	if !m.toolSearch {
+		return tools
+	}
+
+	active := slices.Clone(m.metaTools)
+	for _, tool := range tools {
+		if m.isPinned(tool.Info().Name) {
+			active = append(active, tool)
+		}
+	}
+	for _, name := range m.loadOrder {
+		if tool := toolNamed(tools, name); tool != nil {
+			active = append(active, tool)
+		}
+	}
+	return active
+}
+
+// LoadCalledTools loads every tool called in messages, so that a resumed
+// conversation can keep using the tools it loaded before.
+func (m *MCPToolManager) LoadCalledTools(messages []fantasy.Message) {
+	m.searchMu.Lock()
+	defer m.searchMu.Unlock()
+	if !m.toolSearch {
+		return
+	}
+	for _, msg := range messages {
+		for _, part := range msg.Content {
+			if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+				m.loadTool(call.ToolName)
+			}
+		}
+	}
+}
+
+// loadTool activates the named tool and reports whether it exists. The caller
+// holds m.searchMu.
+func (m *MCPToolManager) loadTool(name string) bool {
+	if toolNamed(m.GetTools(), name) == nil {
+		return false
+	}
+	if !m.loadedTools[name] && !m.isPinned(name) {
+		m.loadedTools[name] = true
+		m.loadOrder = append(m.loadOrder, name)
+	}
+	return true
+}
+
+// toolsChanged updates the tool search state after the tools changed: the
+// index is rebuilt on the next search, and loaded tools that are gone are
+// forgotten.
+func (m *MCPToolManager) toolsChanged() {
+	tools := m.GetTools()
+	m.searchMu.Lock()
+	defer m.searchMu.Unlock()
+
+	m.searchIndex = nil
+	m.loadOrder = slices.DeleteFunc(m.loadOrder, func(name string) bool {
+		if toolNamed(tools, name) != nil {
+			return false
+		}
+		delete(m.loadedTools, name)
+		return true
+	})
+}
+
+// isPinned reports whether name matches one of the pinned globs.
+func (m *MCPToolManager) isPinned(name string) bool {
+	return slices.ContainsFunc(m.pinnedTools, func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	})
+}
+
+// toolNamed returns the tool of tools with the given prefixed name, or nil.
+func toolNamed(tools []fantasy.AgentTool, name string) fantasy.AgentTool {
+	for _, tool := range tools {
+		if tool.Info().Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+type searchToolsInput struct {
+	Query string `json:"query" description:"Keywords describing the task, e.g. \"create github issue\""`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of tools to return (default 5, at most 20)"`
+}
+
+// runSearchTools implements the search_tools meta-tool.
+func (m *MCPToolManager) runSearchTools(_ context.Context, input searchToolsInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	if strings.TrimSpace(input.Query) == "" {
+		return fantasy.NewTextErrorResponse("query must not be empty"), nil
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	tools := m.GetTools()
+	m.searchMu.Lock()
+	if m.searchIndex == nil {
+		m.searchIndex = newToolIndex(tools)
+	}
+	results := m.searchIndex.search(input.Query, limit)
+	var b strings.Builder
+	for _, tool := range results {
+		info := tool.Info()
+		fmt.Fprintf(&b, "- %s", info.Name)
+		if m.loadedTools[info.Name] || m.isPinned(info.Name) {
+			b.WriteString(" (loaded)")
+		}
+		if desc := summarizeDescription(info.Description); desc != "" {
+			b.WriteString(": " + desc)
+		}
+		if len(info.Parameters) > 0 {
+			params := make([]string, 0, len(info.Parameters))
+			for name := range info.Parameters {
+				params = append(params, name)
+			}
+			sort.Strings(params)
+			fmt.Fprintf(&b, "\n  parameters: %s", strings.Join(params, ", "))
+		}
+		b.WriteString("\n")
+	}
+	m.searchMu.Unlock()
+
+	if len(results) == 0 {
+		return fantasy.NewTextResponse(fmt.Sprintf("No tools match %q. Try other keywords.", input.Query)), nil
+	}
+	return fantasy.NewTextResponse(fmt.Sprintf("Found %d tools. Load the ones you need with %s.\n%s",
+		len(results), LoadToolName, b.String())), nil
+}
+
+type loadToolInput struct {
+	Names []string `json:"names" description:"Exact names of the tools to load, as returned by search_tools"`
+}
+
+// runLoadTool implements the load_tool meta-tool.
+func (m *MCPToolManager) runLoadTool(_ context.Context, input loadToolInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	if len(input.Names) == 0 {
+		return fantasy.NewTextErrorResponse("names must not be empty"), nil
+	}
+
+	m.searchMu.Lock()
+	var loaded, unknown []string
+	for _, name := range input.Names {
+		if m.loadTool(name) {
+			loaded = append(loaded, name)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	m.searchMu.Unlock()
+
+	var parts []string
+	if len(loaded) > 0 {
+		parts = append(parts, fmt.Sprintf("Loaded %s. They can be called from your next step on.", strings.Join(loaded, ", ")))
+	}
+	if len(unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("Unknown tools: %s. Use %s to find tool names.", strings.Join(unknown, ", "), SearchToolsName))
+	}
+	if len(loaded) == 0 {
+		return fantasy.NewTextErrorResponse(strings.Join(parts, " ")), nil
+	}
+	return fantasy.NewTextResponse(strings.Join(parts, " ")), nil
+}
+
+// summarizeDescription returns the first line of a tool description, cut to
+// a length that keeps search results short.
+func summarizeDescription(desc string) string {
+	desc, _, _ = strings.Cut(strings.TrimSpace(desc), "\n")
+	if len(desc) > 200 {
+		desc = strings.TrimSpace(desc[:200]) + "..."
+	}
+	return desc
+}
+
+// BM25 parameters: term frequency saturation and document length normalization.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// toolIndex ranks tools against a query with BM25 over their names and
+// descriptions. Name terms count twice, as names are short and telling.
+type toolIndex struct {
+	tools   []fantasy.AgentTool
+	terms   []map[string]int // term frequencies of each tool
+	lengths []int
+	df      map[string]int // number of tools containing each term
+	avgLen  float64
+}
+
+// newToolIndex indexes tools.
+func newToolIndex(tools []fantasy.AgentTool) *toolIndex {
+	ix := &toolIndex{tools: tools, df: make(map[string]int)}
+	total := 0
+	for _, tool := range tools {
+		info := tool.Info()
+		nameTerms := tokenize(info.Name)
+		terms := make(map[string]int)
+		for _, term := range nameTerms {
+			terms[term] += 2
+		}
+		descTerms := tokenize(info.Description)
+		for _, term := range descTerms {
+			terms[term]++
+		}
+		for term := range terms {
+			ix.df[term]++
+		}
+		length := 2*len(nameTerms) + len(descTerms)
+		ix.terms = append(ix.terms, terms)
+		ix.lengths = append(ix.lengths, length)
+		total += length
+	}
+	if len(tools) > 0 {
+		ix.avgLen = float64(total) / float64(len(tools))
+	}
+	return ix
+}
+
+// search returns up to limit tools matching query, best first.
+func (ix *toolIndex) search(query string, limit int) []fantasy.AgentTool {
+	queryTerms := tokenize(query)
+	n := float64(len(ix.tools))
+
+	type scored struct {
+		index int
+		score float64
+	}
+	var matches []scored
+	for i, terms := range ix.terms {
+		// An exact tool name always ranks first
+		if strings.EqualFold(ix.tools[i].Info().Name, strings.TrimSpace(query)) {
+			matches = append(matches, scored{i, math.Inf(1)})
+			continue
+		}
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(terms[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(ix.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(ix.lengths[i])/ix.avgLen)
+			score += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+		if score > 0 {
+			matches = append(matches, scored{i, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool { return matches[a].score > matches[b].score })
+	var results []fantasy.AgentTool
+	for _, match := range matches[:min(limit, len(matches))] {
+		results = append(results, ix.tools[match.index])
+	}
+	return results
+}
+
+// stopWords are left out of the index and queries.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "to": true, "of": true, "or": true,
+	"for": true, "in": true, "on": true, "with": true, "is": true, "it": true, "by": true,
+}
+
+// tokenize splits text into lower-case terms at non-alphanumeric characters
+// and camelCase boundaries, dropping stop words and plural "s" endings, so
+// "listOpenIssues" and "github__list_issues" share "list" and "issue".
+func tokenize(text string) []string {
+	var terms []string
+	var current []rune
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		term := strings.ToLower(string(current))
+		current = current[:0]
+		if len(term) > 3 && strings.HasSuffix(term, "s") && !strings.HasSuffix(term, "ss") {
+			term = term[:len(term)-1]
+		}
+		if !stopWords[term] {
+			terms = append(terms, term)
+		}
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		}
+		current = append(current, r)
+	}
+	flush()
+	return terms
+}
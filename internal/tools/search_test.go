@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// newSearchTestManager returns a manager holding stub tools with the given
+// names and descriptions.
+func newSearchTestManager(tools map[string]string) *MCPToolManager {
+	m := NewMCPToolManager()
+	for _, name := range []string{"github__create_issue", "github__list_issues", "fs__read_file", "fs__write_file", "todo__todo_write"} {
+		desc, ok := tools[name]
+		if !ok {
+			continue
+		}
+		m.tools = append(m.tools, fantasy.NewAgentTool(name, desc,
+			func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+				return fantasy.NewTextResponse("ok"), nil
+			}))
+	}
+	return m
+}
+
+var searchTestTools = map[string]string{
+	"github__create_issue": "Create a new issue in a GitHub repository",
+	"github__list_issues":  "List issues in a GitHub repository, optionally filtered by state",
+	"fs__read_file":        "Read the complete contents of a file from the file system",
+	"fs__write_file":       "Create a new file or overwrite an existing file with new content",
+	"todo__todo_write":     "Update the task list",
+}
+
+func toolNames(tools []fantasy.AgentTool) []string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Info().Name)
+	}
+	return names
+}
+
+func TestTokenize(t *testing.T) {
+	got := strings.Join(tokenize("github__listOpenIssues: List the issues"), ",")
+	if want := "github,list,open,issue,list,issue"; got != want {
+		t.Errorf("Expected terms %q, got %q", want, got)
+	}
+}
+
+func TestToolIndexSearch(t *testing.T) {
+	ix := newToolIndex(newSearchTestManager(searchTestTools).tools)
+
+	if got := toolNames(ix.search("read a file", 2)); len(got) == 0 || got[0] != "fs__read_file" {
+		t.Errorf("Expected fs__read_file first, got %v", got)
+	}
+	if got := toolNames(ix.search("open issues on github", 5)); len(got) < 2 || !strings.Contains(got[0]+got[1], "issue") {
+		t.Errorf("Expected the issue tools first, got %v", got)
+	}
+	if got := toolNames(ix.search("fs__write_file", 1)); len(got) != 1 || got[0] != "fs__write_file" {
+		t.Errorf("Expected an exact name match, got %v", got)
+	}
+	if got := ix.search("kubernetes deployment", 5); len(got) != 0 {
+		t.Errorf("Expected no matches, got %v", toolNames(got))
+	}
+}
+
+func TestToolSearchActiveTools(t *testing.T) {
+	m := newSearchTestManager(searchTestTools)
+	if got := len(m.ActiveTools()); got != len(searchTestTools) {
+		t.Fatalf("Expected all %d tools without tool search, got %d", len(searchTestTools), got)
+	}
+
+	m.EnableToolSearch([]string{"todo__*"})
+	want := "search_tools,load_tool,todo__todo_write"
+	if got := strings.Join(toolNames(m.ActiveTools()), ","); got != want {
+		t.Fatalf("Expected active tools %q, got %q", want, got)
+	}
+
+	resp, err := m.runSearchTools(context.Background(), searchToolsInput{Query: "create issue"}, fantasy.ToolCall{})
+	if err != nil || resp.IsError || !strings.Contains(resp.Content, "- github__create_issue: Create a new issue") {
+		t.Fatalf("Unexpected search result %q (%v)", resp.Content, err)
+	}
+
+	resp, err = m.runLoadTool(context.Background(), loadToolInput{Names: []string{"github__create_issue", "jira__create_ticket"}}, fantasy.ToolCall{})
+	if err != nil || resp.IsError || !strings.Contains(resp.Content, "Unknown tools: jira__create_ticket") {
+		t.Fatalf("Unexpected load result %q (%v)", resp.Content, err)
+	}
+
+	// Tools called earlier in the conversation are loaded too, after the others
+	m.LoadCalledTools([]fantasy.Message{{
+		Role:    fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{fantasy.ToolCallPart{ToolCallID: "1", ToolName: "fs__read_file", Input: "{}"}},
+	}})
+	want += ",github__create_issue,fs__read_file"
+	if got := strings.Join(toolNames(m.ActiveTools()), ","); got != want {
+		t.Errorf("Expected active tools %q, got %q", want, got)
+	}
+
+	resp, _ = m.runLoadTool(context.Background(), loadToolInput{Names: []string{"nope"}}, fantasy.ToolCall{})
+	if !resp.IsError {
+		t.Errorf("Expected an error when no tool could be loaded, got %q", resp.Content)
+	}
+}
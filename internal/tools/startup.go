@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// DefaultStartupTimeout is how long LoadTools waits for a server that
+// configures no startupTimeout.
+const DefaultStartupTimeout = 10 * time.Second
+
+// ServerState is the state of an MCP server's startup.
+type ServerState string
+
+const (
+	// ServerConnecting is a server still being connected to; its tools are
+	// added once it is ready.
+	ServerConnecting ServerState = "connecting"
+	// ServerReady is a server whose tools are loaded.
+	ServerReady ServerState = "ready"
+	// ServerFailed is a server that could not be connected to.
+	ServerFailed ServerState = "failed"
+	// ServerLazy is a lazy server not started yet; its cached tools are
+	// advertised, and the first call of one of them starts it.
+	ServerLazy ServerState = "lazy"
+)
+
+// ServerStatus is the startup status of an MCP server.
+type ServerStatus struct {
+	// Name is the configured name of the server.
+	Name string
+	// State is where the server's startup is at.
+	State ServerState
+	// Tools is the number of tools of a ready or lazy server.
+	Tools int
+	// Err is why a failed server failed.
+	Err error
+}
+
+// String describes the status in one line, as shown on the startup screen.
+func (s ServerStatus) String() string {
+	switch s.State {
+	case ServerReady:
+		return fmt.Sprintf("MCP server %s: ready (%d tools)", s.Name, s.Tools)
+	case ServerFailed:
+		return fmt.Sprintf("MCP server %s: failed: %v", s.Name, s.Err)
+	case ServerLazy:
+		return fmt.Sprintf("MCP server %s: starts on first use (%d tools)", s.Name, s.Tools)
+	default:
+		return fmt.Sprintf("MCP server %s: %s", s.Name, s.State)
+	}
+}
+
+// ServerStatusFunc is told about every change of a server's status,
+// including servers that become ready or fail after LoadTools returned. It
+// is called on the goroutine connecting to the server.
+type ServerStatusFunc func(status ServerStatus)
+
+// SetServerStatusFunc sets the callback told about the servers' status
+// changes. It can be set before or after LoadTools; ServerStatuses returns
+// the statuses so far.
+func (m *MCPToolManager) SetServerStatusFunc(fn ServerStatusFunc) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	m.statusFunc = fn
+}
+
+// ServerStatuses returns the status of every configured server, sorted by
+// name.
+func (m *MCPToolManager) ServerStatuses() []ServerStatus {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	statuses := make([]ServerStatus, 0, len(m.statuses))
+	for _, name := range slices.Sorted(maps.Keys(m.statuses)) {
+		statuses = append(statuses, m.statuses[name])
+	}
+	return statuses
+}
+
+// setServerStatus records status and tells the ServerStatusFunc.
+func (m *MCPToolManager) setServerStatus(status ServerStatus) {
+	m.statusMu.Lock()
+	if m.statuses == nil {
+		m.statuses = make(map[string]ServerStatus)
+	}
+	m.statuses[status.Name] = status
+	fn := m.statusFunc
+	m.statusMu.Unlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+// startupTimeout returns how long LoadTools waits for a server.
+func startupTimeout(serverConfig config.MCPServerConfig) time.Duration {
+	if serverConfig.StartupTimeout > 0 {
+		return time.Duration(serverConfig.StartupTimeout) * time.Second
+	}
+	return DefaultStartupTimeout
+}
+
+// startServers connects to all servers of cfg concurrently and waits for
+// each until its startup timeout or until ctx ends. Servers not ready by
+// then keep connecting in the background, bound to the pool rather than to
+// ctx, and their tools are added once they are. Lazy servers with cached
+// tools are not started. It returns the errors of the servers that failed
+// meanwhile, and the number of servers still connecting.
+func (m *MCPToolManager) startServers(ctx context.Context, cfg *config.Config) (loadErrors []string, connecting int) {
+	start := time.Now()
+	results := make(map[string]chan error, len(cfg.MCPServers))
+	for serverName, serverConfig := range cfg.MCPServers {
+		if serverConfig.Lazy && m.advertiseCachedTools(serverName, serverConfig) {
+			continue
+		}
+		m.setServerStatus(ServerStatus{Name: serverName, State: ServerConnecting})
+		result := make(chan error, 1)
+		results[serverName] = result
+		go func() {
+			if serverConfig.Lazy {
+				// Started to list its tools; the calls of its tools find it
+				// started.
+				result <- m.startLazyServer(m.connectionPool.ctx, serverName, serverConfig)
+				return
+			}
+			result <- m.startServer(serverName, serverConfig)
+		}()
+	}
+
+	for _, serverName := range slices.Sorted(maps.Keys(results)) {
+		timer := time.NewTimer(time.Until(start.Add(startupTimeout(cfg.MCPServers[serverName]))))
+		select {
+		case err := <-results[serverName]:
+			if err != nil {
+				loadErrors = append(loadErrors, fmt.Sprintf("server %s: %v", serverName, err))
+			}
+		case <-timer.C:
+			connecting++
+		case <-ctx.Done():
+			connecting++
+		}
+		timer.Stop()
+	}
+	return loadErrors, connecting
+}
+
+// startServer loads the tools, resources and prompts of serverName and
+// records whether it is ready or failed.
+func (m *MCPToolManager) startServer(serverName string, serverConfig config.MCPServerConfig) error {
+	err := m.loadServerTools(m.connectionPool.ctx, serverName, serverConfig)
+	if err != nil {
+		m.debugLogger.LogDebug(fmt.Sprintf("[DEBUG] Failed to load MCP server %s: %v", serverName, err))
+		m.setServerStatus(ServerStatus{Name: serverName, State: ServerFailed, Err: err})
+		return err
+	}
+	m.setServerStatus(ServerStatus{Name: serverName, State: ServerReady, Tools: m.serverToolCount(serverName)})
+	return nil
+}
+
+// serverToolCount returns the number of loaded tools of serverName.
+func (m *MCPToolManager) serverToolCount(serverName string) int {
+	m.listsMu.RLock()
+	defer m.listsMu.RUnlock()
+	count := 0
+	for _, mapping := range m.toolMap {
+		if mapping.serverName == serverName {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/mark3labs/mcphost/internal/config"
+)
+
+// newDelayedServer starts a streamable HTTP server with a tool named name
+// that does not answer before release is closed.
+func newDelayedServer(t *testing.T, name string, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	srv := server.NewMCPServer(name, "1.0.0", server.WithToolCapabilities(true))
+	srv.AddTool(mcp.NewTool(name, mcp.WithDescription("Answers")),
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		})
+	handler := server.NewStreamableHTTPServer(srv)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestLoadTools_slowServerConnectsInBackground verifies that startup goes on
+// without a server that misses its startup timeout, and that its tools are
+// added once it is ready.
+func TestLoadTools_slowServerConnectsInBackground(t *testing.T) {
+	ready := make(chan struct{})
+	close(ready)
+	release := make(chan struct{})
+	fast := newDelayedServer(t, "fast", ready)
+	slow := newDelayedServer(t, "slow", release)
+
+	manager := NewMCPToolManager()
+	statuses := make(chan ServerStatus, 10)
+	manager.SetServerStatusFunc(func(status ServerStatus) { statuses <- status })
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServerConfig{
+			"fast": {Type: "remote", URL: fast.URL + "/mcp"},
+			"slow": {Type: "remote", URL: slow.URL + "/mcp", StartupTimeout: 1},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := manager.LoadTools(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected startup to end after the slow server's timeout, took %v", elapsed)
+	}
+
+	findTool(t, manager, "fast__fast")
+	got := manager.ServerStatuses()
+	if len(got) != 2 || got[0].State != ServerReady || got[0].Tools != 1 || got[1].State != ServerConnecting {
+		t.Fatalf("Expected fast to be ready and slow connecting, got %+v", got)
+	}
+
+	close(release)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case status := <-statuses:
+			if status.Name != "slow" || status.State == ServerConnecting {
+				continue
+			}
+			if status.State != ServerReady || status.Tools != 1 {
+				t.Fatalf("Expected slow to be ready with one tool, got %+v", status)
+			}
+			findTool(t, manager, "slow__slow")
+			return
+		case <-deadline:
+			t.Fatal("The slow server never became ready")
+		}
+	}
+}
+
+// TestLoadTools_reportsFailedServers verifies that a failing server is
+// reported with its error while the others load.
+func TestLoadTools_reportsFailedServers(t *testing.T) {
+	manager := NewMCPToolManager()
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServerConfig{
+			"broken":      {Command: []string{"non-existent-command"}},
+			"todo-server": {Type: "builtin", Name: "todo"},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := manager.LoadTools(ctx, cfg); err != nil {
+		t.Fatalf("Failed to load tools: %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+
+	got := manager.ServerStatuses()
+	if len(got) != 2 || got[0].Name != "broken" || got[0].State != ServerFailed || got[0].Err == nil {
+		t.Fatalf("Expected broken to have failed, got %+v", got)
+	}
+	if got[1].State != ServerReady || got[1].Tools == 0 {
+		t.Errorf("Expected todo-server to be ready, got %+v", got[1])
+	}
+}
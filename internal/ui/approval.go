@@ -0,0 +1,215 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
+	"charm.land/lipgloss/v2"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// maxApprovalArgsLen caps how much of the tool arguments the dialog shows so a
+// large payload (e.g. a file write) does not push the prompt off screen.
+const maxApprovalArgsLen = 500
+
+// maxSamplingMessageLen caps how much of the last message of a sampling
+// request the dialog shows.
+const maxSamplingMessageLen = 300
+
+// ApprovalComponent is the tool approval dialog shown by the parent AppModel
+// while the agent waits for the user to allow or deny a tool call. The same
+// dialog asks whether an MCP server may have the model answer a sampling
+// request (see NewSamplingApprovalComponent). On a decision it returns an approvalResultMsg tea.Cmd instead of tea.Quit — the
+// parent sends the result back to the app layer and owns the lifecycle.
+//
+// Keys: y/n decide directly, a answers "always allow" (when offered),
+// left/right/tab move the highlight, enter confirms the highlighted option,
+// and esc denies.
+type ApprovalComponent struct {
+	title    string // e.g. "Allow tool execution"
+	details  string // what is asked for, one "Label: value" per line
+	width    int
+	options  []approvalOption
+	selected int  // index into options of the highlighted option
+	always   bool // the "always allow" option is offered
+	done     bool // a decision was made; further keys are ignored
+}
+
+// approvalOption is one answer the dialog offers.
+type approvalOption struct {
+	label  string // rendered label, with the shortcut key in brackets
+	result approvalResultMsg
+}
+
+// NewApprovalComponent creates an approval dialog for the given tool call.
+// canAlwaysAllow adds an "always" option that saves a permission rule.
+// "Yes" is highlighted initially.
+func NewApprovalComponent(toolName, toolArgs string, width int, canAlwaysAllow bool) *ApprovalComponent {
+	options := []approvalOption{{label: "[y]es", result: approvalResultMsg{Approved: true}}}
+	if canAlwaysAllow {
+		options = append(options, approvalOption{label: "[a]lways", result: approvalResultMsg{Approved: true, Always: true}})
+	}
+	options = append(options, approvalOption{label: "[n]o", result: approvalResultMsg{Approved: false}})
+
+	args := toolArgs
+	if len(args) > maxApprovalArgsLen {
+		args = args[:maxApprovalArgsLen] + "…"
+	}
+
+	return &ApprovalComponent{
+		title:   "Allow tool execution",
+		details: fmt.Sprintf("Tool: %s\nArguments: %s", toolName, args),
+		width:   width,
+		options: options,
+		always:  canAlwaysAllow,
+	}
+}
+
+// NewSamplingApprovalComponent creates an approval dialog for a sampling
+// request of serverName. It shows the model, the token limit, the system
+// prompt and the last message, and offers no "always" option: servers are
+// trusted with sampling in the configuration instead.
+func NewSamplingApprovalComponent(serverName string, request tools.SamplingRequest, width int) *ApprovalComponent {
+	var details strings.Builder
+	fmt.Fprintf(&details, "Server: %s\nModel: %s", serverName, request.Model)
+	if request.MaxTokens > 0 {
+		fmt.Fprintf(&details, " (up to %d tokens)", request.MaxTokens)
+	}
+	if request.SystemPrompt != "" {
+		fmt.Fprintf(&details, "\nSystem prompt: %s", truncateText(request.SystemPrompt, maxSamplingMessageLen))
+	}
+	if n := len(request.Messages); n > 0 {
+		last := request.Messages[n-1]
+		fmt.Fprintf(&details, "\nLast message (%s): %s", last.Role, truncateText(messageText(last), maxSamplingMessageLen))
+	}
+
+	return &ApprovalComponent{
+		title:   "Allow sampling request",
+		details: details.String(),
+		width:   width,
+		options: []approvalOption{
+			{label: "[y]es", result: approvalResultMsg{Approved: true}},
+			{label: "[n]o", result: approvalResultMsg{Approved: false}},
+		},
+	}
+}
+
+// messageText returns the text of msg, with files shown as placeholders.
+func messageText(msg fantasy.Message) string {
+	var parts []string
+	for _, part := range msg.Content {
+		switch p := part.(type) {
+		case fantasy.TextPart:
+			parts = append(parts, p.Text)
+		case fantasy.FilePart:
+			parts = append(parts, fmt.Sprintf("[%s file]", p.MediaType))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// truncateText shortens s to at most n runes, marking the cut with "…".
+func truncateText(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "…"
+	}
+	return s
+}
+
+// Init implements tea.Model.
+func (t *ApprovalComponent) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model. Key presses either move the highlight or
+// produce the decision as an approvalResultMsg.
+func (t *ApprovalComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		t.width = msg.Width
+	case tea.KeyPressMsg:
+		if t.done {
+			return t, nil
+		}
+		switch msg.String() {
+		case "y", "Y":
+			return t, t.decide(approvalResultMsg{Approved: true})
+		case "a", "A":
+			if t.always {
+				return t, t.decide(approvalResultMsg{Approved: true, Always: true})
+			}
+		case "n", "N", "esc":
+			return t, t.decide(approvalResultMsg{Approved: false})
+		case "left":
+			t.selected = max(t.selected-1, 0)
+		case "right":
+			t.selected = min(t.selected+1, len(t.options)-1)
+		case "tab":
+			t.selected = (t.selected + 1) % len(t.options)
+		case "enter":
+			return t, t.decide(t.options[t.selected].result)
+		}
+	}
+	return t, nil
+}
+
+// decide records the decision and returns the cmd that reports it to the parent.
+func (t *ApprovalComponent) decide(result approvalResultMsg) tea.Cmd {
+	t.done = true
+	return func() tea.Msg {
+		return result
+	}
+}
+
+// View implements tea.Model. Renders what is asked for and the yes/no choice.
+func (t *ApprovalComponent) View() tea.View {
+	// PaddingLeft(3) aligns with message content: border(1) + paddingLeft(2).
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("252")).
+		MarginBottom(1).
+		PaddingLeft(3)
+
+	// Input box with huh-like styling
+	inputBoxStyle := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderLeft(true).
+		BorderRight(false).
+		BorderTop(false).
+		BorderBottom(false).
+		BorderForeground(lipgloss.Color("39")).
+		PaddingLeft(2).    // match message block paddingLeft
+		Width(t.width - 1) // full width minus left border
+
+	// Style for the currently selected/highlighted option
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("42")). // Bright green
+		Bold(true).
+		Underline(true)
+
+	// Style for the unselected/unhighlighted option
+	unselectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")) // Dark gray
+
+	// Build the view
+	var view strings.Builder
+	view.WriteString(titleStyle.Render(t.title))
+	view.WriteString("\n")
+	view.WriteString(t.details + "\n\n")
+	view.WriteString(t.title + ": ")
+
+	labels := make([]string, len(t.options))
+	for i, opt := range t.options {
+		if i == t.selected {
+			labels[i] = selectedStyle.Render(opt.label)
+		} else {
+			labels[i] = unselectedStyle.Render(opt.label)
+		}
+	}
+	view.WriteString(strings.Join(labels, "/") + "\n")
+
+	return tea.NewView(inputBoxStyle.Render(view.String()))
+}
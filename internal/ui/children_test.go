@@ -1,11 +1,14 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
 	tea "charm.land/bubbletea/v2"
+	"charm.land/fantasy"
 	"github.com/mark3labs/mcphost/internal/app"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // ==========================================================================
@@ -258,6 +261,48 @@ func TestInputComponent_UnknownSlashCommand_ForwardsAsSubmit(t *testing.T) {
 	}
 }
 
+// TestInputComponent_ResourceMention verifies that typing an "@" mention
+// offers matching resources and that tab completes the mention without
+// submitting.
+func TestInputComponent_ResourceMention(t *testing.T) {
+	c := newTestInput(&stubAppController{})
+	c.SetResources([]tools.Resource{
+		{Server: "docs", URI: "file:///guide.md", Name: "User guide"},
+		{Server: "db", URI: "postgres://orders/schema"},
+	})
+	c.textarea.SetValue("summarize @gui")
+	c.lastValue = "summarize @gui"
+
+	c, _ = sendInputMsg(c, tea.KeyPressMsg{Code: 'd', Text: "d"})
+	if !c.showPopup || len(c.mentions) != 1 || c.mentions[0].Resource.Server != "docs" {
+		t.Fatalf("expected the docs resource offered, got popup=%v mentions=%v", c.showPopup, c.mentions)
+	}
+
+	c, cmd := sendInputMsg(c, tea.KeyPressMsg{Code: tea.KeyTab})
+	if cmd != nil {
+		t.Fatal("expected completing a mention not to submit")
+	}
+	if got := c.textarea.Value(); got != "summarize @docs:file:///guide.md " {
+		t.Fatalf("expected the mention completed, got %q", got)
+	}
+	if c.showPopup {
+		t.Fatal("expected the popup dismissed after completing")
+	}
+}
+
+// TestInputComponent_MentionWithoutResources verifies that "@" does not open
+// the popup when no server offers resources, so file mentions submit as usual.
+func TestInputComponent_MentionWithoutResources(t *testing.T) {
+	c := newTestInput(&stubAppController{})
+	c.textarea.SetValue("read @README.m")
+	c.lastValue = "read @README.m"
+
+	c, _ = sendInputMsg(c, tea.KeyPressMsg{Code: 'd', Text: "d"})
+	if c.showPopup {
+		t.Fatal("expected no popup without resources")
+	}
+}
+
 // ==========================================================================
 // StreamComponent tests
 // ==========================================================================
@@ -425,6 +470,40 @@ func TestStreamComponent_ToolExecution_NotStarting_KeepsSpinning(t *testing.T) {
 	}
 }
 
+// TestStreamComponent_ToolProgressAndLogs verifies that the running tool's
+// progress and recent log lines are shown under the spinner and cleared when
+// the tool finishes.
+func TestStreamComponent_ToolProgressAndLogs(t *testing.T) {
+	c := newTestStream()
+
+	// Updates between tool calls are not shown.
+	c = sendStreamMsg(c, app.ToolProgressEvent{ToolName: "build__run", Progress: 1, Total: 4})
+	if c.toolProgress != nil {
+		t.Fatal("expected progress outside a tool call ignored")
+	}
+
+	c = sendStreamMsg(c, app.ToolExecutionEvent{ToolName: "build__run", IsStarting: true})
+	c = sendStreamMsg(c, app.ToolProgressEvent{ToolName: "build__run", Progress: 1, Total: 4, Message: "compiling"})
+	for i := range maxToolLogLines + 1 {
+		c = sendStreamMsg(c, app.ServerLogEvent{ServerName: "build", Level: "info", Message: fmt.Sprintf("step %d", i)})
+	}
+
+	view := c.render()
+	for _, want := range []string{"25%", "compiling", "build info: step 3"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the view, got:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "step 0") {
+		t.Errorf("expected only the last %d log lines, got:\n%s", maxToolLogLines, view)
+	}
+
+	c = sendStreamMsg(c, app.ToolExecutionEvent{ToolName: "build__run", IsStarting: false})
+	if c.toolProgress != nil || c.toolLogs != nil {
+		t.Fatal("expected progress and log lines cleared when the tool finished")
+	}
+}
+
 // --------------------------------------------------------------------------
 // TestStreamComponent_GetRenderedContent verifies the method returns rendered
 // text when content is accumulated, and empty string when not.
@@ -451,6 +530,44 @@ func TestStreamComponent_GetRenderedContent_WithText(t *testing.T) {
 	}
 }
 
+// TestStreamComponent_Thinking verifies reasoning is rendered as a thinking
+// block that is collapsed to a summary until toggled.
+func TestStreamComponent_Thinking(t *testing.T) {
+	c := newTestStream()
+	c = sendStreamMsg(c, app.ReasoningChunkEvent{Content: "Let me weigh "})
+	c = sendStreamMsg(c, app.ReasoningChunkEvent{Content: "the options."})
+
+	got := c.GetRenderedContent()
+	if !strings.Contains(got, "Thought for 5 words") {
+		t.Fatalf("expected thinking summary in rendered content, got %q", got)
+	}
+	if strings.Contains(got, "options") {
+		t.Fatalf("expected collapsed thinking to hide the text, got %q", got)
+	}
+	if view := c.render(); !strings.Contains(view, "Thinking") || !strings.Contains(view, "ctrl+t") {
+		t.Fatalf("expected live thinking header with hint, got %q", view)
+	}
+
+	c.ToggleThinking()
+	c = sendStreamMsg(c, app.StreamChunkEvent{Content: "hello world"})
+	got = c.GetRenderedContent()
+	if !strings.Contains(got, "options") || !strings.Contains(got, "hello") {
+		t.Fatalf("expected expanded thinking followed by text, got %q", got)
+	}
+	if strings.Index(got, "options") > strings.Index(got, "hello") {
+		t.Fatalf("expected thinking above the text, got %q", got)
+	}
+
+	// The toggle survives Reset; the reasoning does not.
+	c.Reset()
+	if !c.showThinking {
+		t.Fatal("expected showThinking to survive Reset()")
+	}
+	if c.thinkingContent.Len() != 0 {
+		t.Fatalf("expected empty thinkingContent after Reset(), got %q", c.thinkingContent.String())
+	}
+}
+
 // --------------------------------------------------------------------------
 // TestStreamComponent_Reset clears all accumulated state.
 // --------------------------------------------------------------------------
@@ -540,3 +657,182 @@ func TestStreamComponent_SpinnerTick_NoReschedule_WhenNotSpinning(t *testing.T)
 		t.Fatal("expected no tick reschedule when not spinning")
 	}
 }
+
+// ==========================================================================
+// ApprovalComponent tests
+// ==========================================================================
+
+// approvalDecision presses key on a fresh ApprovalComponent and returns the
+// resulting approvalResultMsg, failing if none was produced.
+func approvalDecision(t *testing.T, c *ApprovalComponent, key tea.KeyPressMsg) approvalResultMsg {
+	t.Helper()
+	_, cmd := c.Update(key)
+	msg := runCmd(cmd)
+	result, ok := msg.(approvalResultMsg)
+	if !ok {
+		t.Fatalf("expected approvalResultMsg, got %T", msg)
+	}
+	return result
+}
+
+// TestApprovalComponent_Keys verifies that each decision key emits the expected
+// approvalResultMsg (and never tea.Quit).
+func TestApprovalComponent_Keys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  tea.KeyPressMsg
+		want bool
+	}{
+		{"y approves", tea.KeyPressMsg{Code: 'y', Text: "y"}, true},
+		{"n denies", tea.KeyPressMsg{Code: 'n', Text: "n"}, false},
+		{"esc denies", tea.KeyPressMsg{Code: tea.KeyEscape}, false},
+		{"enter confirms default yes", tea.KeyPressMsg{Code: tea.KeyEnter}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewApprovalComponent("bash", `{"command":"ls"}`, 80, false)
+			if got := approvalDecision(t, c, tt.key); got.Approved != tt.want {
+				t.Fatalf("expected Approved=%v, got %v", tt.want, got.Approved)
+			}
+		})
+	}
+}
+
+// TestApprovalComponent_MoveSelection verifies that moving the highlight to
+// "no" makes enter deny the call.
+func TestApprovalComponent_MoveSelection(t *testing.T) {
+	c := NewApprovalComponent("bash", "{}", 80, false)
+
+	if _, cmd := c.Update(tea.KeyPressMsg{Code: tea.KeyRight}); cmd != nil {
+		t.Fatal("moving the selection should not produce a decision")
+	}
+	if got := approvalDecision(t, c, tea.KeyPressMsg{Code: tea.KeyEnter}); got.Approved {
+		t.Fatal("expected enter on [n]o to deny")
+	}
+}
+
+// TestApprovalComponent_IgnoresKeysAfterDecision verifies that only one
+// decision is ever emitted.
+func TestApprovalComponent_IgnoresKeysAfterDecision(t *testing.T) {
+	c := NewApprovalComponent("bash", "{}", 80, false)
+	approvalDecision(t, c, tea.KeyPressMsg{Code: 'y', Text: "y"})
+
+	if _, cmd := c.Update(tea.KeyPressMsg{Code: 'n', Text: "n"}); cmd != nil {
+		t.Fatal("expected no second decision")
+	}
+}
+
+// TestApprovalComponent_ViewShowsTool verifies that the dialog names the tool
+// and truncates long arguments.
+func TestApprovalComponent_ViewShowsTool(t *testing.T) {
+	longArgs := strings.Repeat("x", maxApprovalArgsLen+100)
+	c := NewApprovalComponent("fs__write_file", longArgs, 80, false)
+
+	view := c.View().Content
+	if !strings.Contains(view, "fs__write_file") {
+		t.Fatalf("expected tool name in view, got %q", view)
+	}
+	if strings.Contains(view, longArgs) {
+		t.Fatal("expected long arguments to be truncated")
+	}
+}
+
+// TestSamplingApprovalComponent verifies that the sampling dialog shows the
+// request and offers no "always" option.
+func TestSamplingApprovalComponent(t *testing.T) {
+	c := NewSamplingApprovalComponent("poems", tools.SamplingRequest{
+		SystemPrompt: "You are a poet.",
+		Messages:     []fantasy.Message{fantasy.NewUserMessage("Write a haiku about " + strings.Repeat("rain ", 100))},
+		MaxTokens:    100,
+		Model:        "claude-sonnet-4-5",
+	}, 120)
+
+	view := c.View().Content
+	for _, want := range []string{"Allow sampling request", "poems", "claude-sonnet-4-5", "100 tokens", "You are a poet.", "Write a haiku"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view, got %q", want, view)
+		}
+	}
+	if strings.Contains(view, "[a]lways") {
+		t.Error("expected no always option")
+	}
+	if _, cmd := c.Update(tea.KeyPressMsg{Code: 'a', Text: "a"}); cmd != nil {
+		t.Fatal("expected no decision for a")
+	}
+	if got := approvalDecision(t, c, tea.KeyPressMsg{Code: 'y', Text: "y"}); !got.Approved || got.Always {
+		t.Fatalf("expected a plain approval, got %+v", got)
+	}
+}
+
+// TestApprovalComponent_Always verifies that the "always" option is only
+// available when offered, via its shortcut and via the highlight.
+func TestApprovalComponent_Always(t *testing.T) {
+	c := NewApprovalComponent("bash", "{}", 80, false)
+	if _, cmd := c.Update(tea.KeyPressMsg{Code: 'a', Text: "a"}); cmd != nil {
+		t.Fatal("expected no decision for [a]lways when it is not offered")
+	}
+
+	c = NewApprovalComponent("bash", "{}", 80, true)
+	if got := approvalDecision(t, c, tea.KeyPressMsg{Code: 'a', Text: "a"}); !got.Approved || !got.Always {
+		t.Fatalf("expected always-allow decision, got %+v", got)
+	}
+
+	c = NewApprovalComponent("bash", "{}", 80, true)
+	c.Update(tea.KeyPressMsg{Code: tea.KeyRight})
+	if got := approvalDecision(t, c, tea.KeyPressMsg{Code: tea.KeyEnter}); !got.Approved || !got.Always {
+		t.Fatalf("expected enter on [a]lways to always allow, got %+v", got)
+	}
+}
+
+// --------------------------------------------------------------------------
+// TestElicitationFormComponent verifies that the form asks for each field in
+// turn, validates typed values and submits typed content.
+// --------------------------------------------------------------------------
+func TestElicitationFormComponent(t *testing.T) {
+	request := tools.ElicitationRequest{
+		Message: "Where should we deploy?",
+		Fields: []tools.ElicitationField{
+			{Name: "target", Type: "string", Required: true, Enum: []string{"staging", "production"}, EnumNames: []string{"Staging", "Production"}},
+			{Name: "confirm", Type: "boolean", Required: true},
+			{Name: "replicas", Type: "integer", Required: true},
+		},
+	}
+	f := NewElicitationFormComponent("deployer", request, 80)
+	view := f.View().Content
+	for _, want := range []string{"deployer asks for input", "Where should we deploy?", "Staging", "Production"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view, got %q", want, view)
+		}
+	}
+
+	f.Update(tea.KeyPressMsg{Code: tea.KeyRight})
+	if _, cmd := f.Update(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd != nil {
+		t.Fatal("expected the next field, not a result")
+	}
+	f.Update(tea.KeyPressMsg{Code: tea.KeyEnter}) // confirm: Yes
+	f.input.SetValue("many")
+	if _, cmd := f.Update(tea.KeyPressMsg{Code: tea.KeyEnter}); cmd != nil || f.err == "" {
+		t.Fatal("expected an error for a value that is not a number")
+	}
+	f.input.SetValue("3")
+	_, cmd := f.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	result, ok := runCmd(cmd).(elicitationResultMsg)
+	if !ok || result.Response.Action != tools.ElicitationAccept {
+		t.Fatalf("expected the request accepted, got %#v", result)
+	}
+	content := result.Response.Content
+	if content["target"] != "production" || content["confirm"] != true || content["replicas"] != int64(3) {
+		t.Errorf("unexpected content: %v", content)
+	}
+
+	f = NewElicitationFormComponent("deployer", request, 80)
+	_, cmd = f.Update(tea.KeyPressMsg{Code: 'd', Mod: tea.ModCtrl})
+	if result, _ := runCmd(cmd).(elicitationResultMsg); result.Response.Action != tools.ElicitationDecline {
+		t.Errorf("expected ctrl+d to decline, got %+v", result)
+	}
+	f = NewElicitationFormComponent("deployer", request, 80)
+	_, cmd = f.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if result, _ := runCmd(cmd).(elicitationResultMsg); result.Response.Action != tools.ElicitationCancel {
+		t.Errorf("expected esc to cancel, got %+v", result)
+	}
+}
@@ -130,34 +130,50 @@ func (c *CLI) DisplayAssistantMessageWithModel(message, modelName string) error
 // is being executed. Shows the tool name and its arguments formatted appropriately
 // for the current display mode. This is typically shown while a tool is running.
 func (c *CLI) DisplayToolCallMessage(toolName, toolArgs string) {
-	var msg UIMessage
-	if c.compactMode {
-		msg = c.compactRenderer.RenderToolCallMessage(toolName, toolArgs, time.Now())
-	} else {
-		msg = c.messageRenderer.RenderToolCallMessage(toolName, toolArgs, time.Now())
-	}
-
 	// Always display immediately - spinner management is handled externally
-	c.messageContainer.AddMessage(msg)
+	c.messageContainer.AddMessage(c.renderToolCallMessage(toolName, toolArgs))
 	c.displayContainer()
 }
 
+// DisplayNestedToolCallMessage is DisplayToolCallMessage for a tool call made
+// by a sub-agent of the task tool; the message is indented under the task call.
+func (c *CLI) DisplayNestedToolCallMessage(toolName, toolArgs string) {
+	c.messageContainer.AddMessage(nestMessage(c.renderToolCallMessage(toolName, toolArgs)))
+	c.displayContainer()
+}
+
+// renderToolCallMessage renders a tool call message for the current display mode.
+func (c *CLI) renderToolCallMessage(toolName, toolArgs string) UIMessage {
+	if c.compactMode {
+		return c.compactRenderer.RenderToolCallMessage(toolName, toolArgs, time.Now())
+	}
+	return c.messageRenderer.RenderToolCallMessage(toolName, toolArgs, time.Now())
+}
+
 // DisplayToolMessage renders and displays the complete result of a tool execution,
 // including the tool name, arguments, and result. The isError parameter determines
 // whether the result should be displayed as an error or success message.
 func (c *CLI) DisplayToolMessage(toolName, toolArgs, toolResult string, isError bool) {
-	var msg UIMessage
-	if c.compactMode {
-		msg = c.compactRenderer.RenderToolMessage(toolName, toolArgs, toolResult, isError)
-	} else {
-		msg = c.messageRenderer.RenderToolMessage(toolName, toolArgs, toolResult, isError)
-	}
-
 	// Always display immediately - spinner management is handled externally
-	c.messageContainer.AddMessage(msg)
+	c.messageContainer.AddMessage(c.renderToolMessage(toolName, toolArgs, toolResult, isError))
 	c.displayContainer()
 }
 
+// DisplayNestedToolMessage is DisplayToolMessage for a tool result of a
+// sub-agent of the task tool; the message is indented under the task call.
+func (c *CLI) DisplayNestedToolMessage(toolName, toolArgs, toolResult string, isError bool) {
+	c.messageContainer.AddMessage(nestMessage(c.renderToolMessage(toolName, toolArgs, toolResult, isError)))
+	c.displayContainer()
+}
+
+// renderToolMessage renders a tool result message for the current display mode.
+func (c *CLI) renderToolMessage(toolName, toolArgs, toolResult string, isError bool) UIMessage {
+	if c.compactMode {
+		return c.compactRenderer.RenderToolMessage(toolName, toolArgs, toolResult, isError)
+	}
+	return c.messageRenderer.RenderToolMessage(toolName, toolArgs, toolResult, isError)
+}
+
 // DisplayError renders and displays an error message with distinctive formatting
 // to ensure visibility. The error is timestamped and styled according to the
 // current display mode's error theme.
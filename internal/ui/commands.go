@@ -1,6 +1,12 @@
 package ui
 
-import "slices"
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
 
 // SlashCommand represents a user-invokable slash command with its metadata.
 // Commands can have multiple aliases and are organized by category for better
@@ -10,6 +16,7 @@ type SlashCommand struct {
 	Description string
 	Aliases     []string
 	Category    string // e.g., "Navigation", "System", "Info"
+	Args        string // argument placeholder, e.g. "[instructions]"; empty if the command takes none
 }
 
 // SlashCommands provides the global registry of all available slash commands
@@ -34,13 +41,37 @@ var SlashCommands = []SlashCommand{
 		Category:    "Info",
 		Aliases:     []string{"/s"},
 	},
+	{
+		Name:        "/resources",
+		Description: "List resources offered by MCP servers",
+		Category:    "Info",
+		Aliases:     []string{"/r"},
+	},
 
+	{
+		Name:        "/roots",
+		Description: "List the roots of MCP servers, or add a directory to them",
+		Category:    "System",
+		Args:        "[add <dir>]",
+	},
+	{
+		Name:        "/cd",
+		Description: "Show or change the working directory",
+		Category:    "System",
+		Args:        "[dir]",
+	},
 	{
 		Name:        "/clear",
 		Description: "Clear conversation and start fresh",
 		Category:    "System",
 		Aliases:     []string{"/c", "/cls"},
 	},
+	{
+		Name:        "/compact",
+		Description: "Summarize older messages to free up context",
+		Category:    "System",
+		Args:        "[instructions]",
+	},
 	{
 		Name:        "/usage",
 		Description: "Show token usage statistics",
@@ -83,6 +114,24 @@ func GetCommandByName(name string) *SlashCommand {
 	return nil
 }
 
+// ParseSlashCommand resolves input to a slash command and its arguments. Input
+// matches when it is exactly a command name or alias, or when it starts with
+// the name of a command that takes arguments followed by a space. It returns
+// nil for anything else, which is then treated as a regular prompt.
+func ParseSlashCommand(input string) (*SlashCommand, string) {
+	if cmd := GetCommandByName(input); cmd != nil {
+		return cmd, ""
+	}
+	name, args, found := strings.Cut(input, " ")
+	if !found {
+		return nil, ""
+	}
+	if cmd := GetCommandByName(name); cmd != nil && cmd.Args != "" {
+		return cmd, strings.TrimSpace(args)
+	}
+	return nil, ""
+}
+
 // GetAllCommandNames returns a complete list of all command names and their aliases.
 // This is useful for command completion, validation, and help display. The returned
 // slice contains both primary command names and all alternative aliases.
@@ -94,3 +143,137 @@ func GetAllCommandNames() []string {
 	}
 	return names
 }
+
+// PromptCommands returns the slash commands running MCP server prompts, such
+// as "/github:review-pr". Each server's prompts get their own category.
+func PromptCommands(prompts []tools.Prompt) []SlashCommand {
+	commands := make([]SlashCommand, len(prompts))
+	for i, p := range prompts {
+		var args []string
+		for _, arg := range p.Arguments {
+			if arg.Required {
+				args = append(args, "<"+arg.Name+">")
+			} else {
+				args = append(args, "["+arg.Name+"]")
+			}
+		}
+		commands[i] = SlashCommand{
+			Name:        p.Command(),
+			Description: p.Description,
+			Category:    p.Server + " prompts",
+			Args:        strings.Join(args, " "),
+		}
+	}
+	return commands
+}
+
+// ParsePromptCommand resolves input to the server prompt it runs and the
+// arguments given inline, or returns nil when input does not start with the
+// command of one of prompts.
+func ParsePromptCommand(input string, prompts []tools.Prompt) (*tools.Prompt, string) {
+	name, args, _ := strings.Cut(input, " ")
+	for i := range prompts {
+		if prompts[i].Command() == name {
+			return &prompts[i], strings.TrimSpace(args)
+		}
+	}
+	return nil, ""
+}
+
+// ParsePromptArgs parses the arguments of a server prompt given inline.
+// Arguments are given by name as name=value, or by position in the order the
+// prompt declares them; values with spaces are quoted. The whole input is the
+// value of a prompt with a single argument. Missing arguments are left out.
+func ParsePromptArgs(prompt tools.Prompt, input string) (map[string]string, error) {
+	args := make(map[string]string)
+	if input == "" {
+		return args, nil
+	}
+	if len(prompt.Arguments) == 1 {
+		name := prompt.Arguments[0].Name
+		args[name] = unquote(strings.TrimPrefix(input, name+"="))
+		return args, nil
+	}
+
+	tokens, err := splitArgs(input)
+	if err != nil {
+		return nil, err
+	}
+	var positional []string
+	for _, token := range tokens {
+		name, value, found := strings.Cut(token, "=")
+		if found && slices.ContainsFunc(prompt.Arguments, func(a tools.PromptArgument) bool { return a.Name == name }) {
+			args[name] = value
+			continue
+		}
+		positional = append(positional, token)
+	}
+	for _, arg := range prompt.Arguments {
+		if len(positional) == 0 {
+			break
+		}
+		if _, ok := args[arg.Name]; !ok {
+			args[arg.Name] = positional[0]
+			positional = positional[1:]
+		}
+	}
+	if len(positional) > 0 {
+		return nil, fmt.Errorf("too many arguments for %s: %s", prompt.Command(), strings.Join(positional, " "))
+	}
+	return args, nil
+}
+
+// MissingPromptArgs returns the required arguments of prompt not in args.
+func MissingPromptArgs(prompt tools.Prompt, args map[string]string) []tools.PromptArgument {
+	var missing []tools.PromptArgument
+	for _, arg := range prompt.Arguments {
+		if _, ok := args[arg.Name]; arg.Required && !ok {
+			missing = append(missing, arg)
+		}
+	}
+	return missing
+}
+
+// splitArgs splits input at spaces outside of single or double quotes, and
+// removes the quotes.
+func splitArgs(input string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	var quote rune
+	inToken := false
+	for _, r := range input {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			token.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", input)
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// unquote removes the quotes around a value given in quotes.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
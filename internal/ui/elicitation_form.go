@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// ElicitationFormComponent is the form shown by the parent AppModel when an
+// MCP server asks the user for input. Like PromptFormComponent it asks for
+// one field at a time: text and numbers are typed, booleans and enums are
+// chosen from their options. When the user answers it returns an
+// elicitationResultMsg tea.Cmd; the parent sends the response to the server.
+//
+// Keys: enter accepts the current field and submits the form after the last,
+// left/right (or up/down) move between options, ctrl+d declines the request
+// and esc cancels it.
+type ElicitationFormComponent struct {
+	serverName string
+	request    tools.ElicitationRequest
+	values     map[string]any
+	current    int
+	input      textinput.Model
+	options    []elicitationOption // choices of the current field; nil for typed fields
+	selected   int                 // index into options of the highlighted option
+	err        string
+	width      int
+	done       bool // the form was answered; further keys are ignored
+}
+
+// elicitationOption is one choice of a boolean or enum field.
+type elicitationOption struct {
+	label string
+	value any // nil leaves an optional field out
+}
+
+// elicitationResultMsg reports the answer of an ElicitationFormComponent.
+type elicitationResultMsg struct {
+	Response tools.ElicitationResponse
+}
+
+// NewElicitationFormComponent creates a form for the request of serverName.
+func NewElicitationFormComponent(serverName string, request tools.ElicitationRequest, width int) *ElicitationFormComponent {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.SetWidth(width - 12)
+	input.Focus()
+
+	f := &ElicitationFormComponent{
+		serverName: serverName,
+		request:    request,
+		values:     make(map[string]any),
+		input:      input,
+		width:      width,
+	}
+	f.showField()
+	return f
+}
+
+// Init implements tea.Model.
+func (f *ElicitationFormComponent) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (f *ElicitationFormComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		f.width = msg.Width
+		f.input.SetWidth(msg.Width - 12)
+		return f, nil
+	case tea.KeyPressMsg:
+		if f.done {
+			return f, nil
+		}
+		switch msg.String() {
+		case "esc":
+			return f, f.answer(tools.ElicitationResponse{Action: tools.ElicitationCancel})
+		case "ctrl+d":
+			return f, f.answer(tools.ElicitationResponse{Action: tools.ElicitationDecline})
+		case "enter":
+			return f, f.accept()
+		}
+		if f.options != nil {
+			switch msg.String() {
+			case "left", "up", "shift+tab":
+				f.selected = max(f.selected-1, 0)
+			case "right", "down", "tab":
+				f.selected = min(f.selected+1, len(f.options)-1)
+			}
+			return f, nil
+		}
+	}
+
+	if f.options != nil {
+		return f, nil
+	}
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return f, cmd
+}
+
+// accept records the current field and moves to the next one, or submits the
+// form after the last.
+func (f *ElicitationFormComponent) accept() tea.Cmd {
+	if f.current < len(f.request.Fields) {
+		field := f.request.Fields[f.current]
+		var value any
+		if f.options != nil {
+			value = f.options[f.selected].value
+		} else {
+			v, err := field.Value(f.input.Value())
+			if err != nil {
+				f.err = err.Error()
+				return nil
+			}
+			value = v
+		}
+		if value == nil && field.Required {
+			f.err = fmt.Sprintf("%s is required", field.Label())
+			return nil
+		}
+		if value != nil {
+			f.values[field.Name] = value
+		}
+		f.err = ""
+		f.current++
+		if f.current < len(f.request.Fields) {
+			f.showField()
+			return nil
+		}
+	}
+
+	return f.answer(tools.ElicitationResponse{Action: tools.ElicitationAccept, Content: f.values})
+}
+
+// answer ends the form with response.
+func (f *ElicitationFormComponent) answer(response tools.ElicitationResponse) tea.Cmd {
+	f.done = true
+	return func() tea.Msg { return elicitationResultMsg{Response: response} }
+}
+
+// showField prepares the input or the options of the current field, with its
+// default value filled in or highlighted.
+func (f *ElicitationFormComponent) showField() {
+	f.options = nil
+	f.selected = 0
+	f.input.SetValue("")
+	if f.current >= len(f.request.Fields) {
+		return
+	}
+	field := f.request.Fields[f.current]
+
+	switch {
+	case field.Type == "boolean":
+		f.options = []elicitationOption{{label: "Yes", value: true}, {label: "No", value: false}}
+	case len(field.Enum) > 0:
+		for i, value := range field.Enum {
+			label := value
+			if i < len(field.EnumNames) && field.EnumNames[i] != "" {
+				label = field.EnumNames[i]
+			}
+			f.options = append(f.options, elicitationOption{label: label, value: value})
+		}
+	default:
+		if field.Default != nil {
+			f.input.SetValue(fmt.Sprint(field.Default))
+		}
+		f.input.Placeholder = field.Description
+		if !field.Required {
+			f.input.Placeholder = strings.TrimSpace(field.Description + " (optional)")
+		}
+		return
+	}
+
+	if !field.Required {
+		f.options = append(f.options, elicitationOption{label: "(none)"})
+	}
+	if field.Default != nil {
+		f.selected = max(slices.IndexFunc(f.options, func(o elicitationOption) bool {
+			return o.value == field.Default
+		}), 0)
+	}
+}
+
+// View implements tea.Model. Renders the server's message, the current field
+// and its input or options.
+func (f *ElicitationFormComponent) View() tea.View {
+	// PaddingLeft(3) aligns with message content: border(1) + paddingLeft(2).
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("252")).
+		MarginBottom(1).
+		PaddingLeft(3)
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderLeft(true).
+		BorderRight(false).
+		BorderTop(false).
+		BorderBottom(false).
+		BorderForeground(lipgloss.Color("39")).
+		PaddingLeft(2).
+		Width(f.width - 1)
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true)
+	hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
+
+	var view strings.Builder
+	view.WriteString(titleStyle.Render(f.serverName + " asks for input"))
+	view.WriteString("\n")
+	if f.request.Message != "" {
+		view.WriteString(f.request.Message + "\n\n")
+	}
+
+	hint := "enter submit • ctrl+d decline • esc cancel"
+	if f.current < len(f.request.Fields) {
+		field := f.request.Fields[f.current]
+		fmt.Fprintf(&view, "%s %s\n", labelStyle.Render(field.Label()),
+			hintStyle.Render(fmt.Sprintf("(%d of %d)", f.current+1, len(f.request.Fields))))
+		if f.options != nil {
+			if field.Description != "" {
+				view.WriteString(hintStyle.Render(field.Description) + "\n")
+			}
+			labels := make([]string, len(f.options))
+			for i, opt := range f.options {
+				if i == f.selected {
+					labels[i] = selectedStyle.Render(opt.label)
+				} else {
+					labels[i] = hintStyle.Render(opt.label)
+				}
+			}
+			view.WriteString(strings.Join(labels, " / ") + "\n")
+		} else {
+			view.WriteString(f.input.View() + "\n")
+		}
+		if f.current < len(f.request.Fields)-1 {
+			hint = "enter next • ctrl+d decline • esc cancel"
+		}
+	}
+	if f.err != "" {
+		view.WriteString(errStyle.Render(f.err) + "\n")
+	}
+	view.WriteString(hintStyle.Render(hint))
+
+	return tea.NewView(boxStyle.Render(view.String()))
+}
@@ -105,7 +105,11 @@ func (h *CLIEventHandler) Handle(msg tea.Msg) {
 		h.stopSpinner()
 		// End any active stream before tool call output.
 		h.endStream()
-		h.cli.DisplayToolCallMessage(e.ToolName, e.ToolArgs)
+		if e.Nested {
+			h.cli.DisplayNestedToolCallMessage(e.ToolName, e.ToolArgs)
+		} else {
+			h.cli.DisplayToolCallMessage(e.ToolName, e.ToolArgs)
+		}
 
 	case app.ToolExecutionEvent:
 		if e.IsStarting {
@@ -116,7 +120,11 @@ func (h *CLIEventHandler) Handle(msg tea.Msg) {
 
 	case app.ToolResultEvent:
 		h.stopSpinner()
-		h.cli.DisplayToolMessage(e.ToolName, e.ToolArgs, e.Result, e.IsError)
+		if e.Nested {
+			h.cli.DisplayNestedToolMessage(e.ToolName, e.ToolArgs, e.Result, e.IsError)
+		} else {
+			h.cli.DisplayToolMessage(e.ToolName, e.ToolArgs, e.Result, e.IsError)
+		}
 		h.startSpinner()
 
 	case app.ResponseCompleteEvent:
@@ -128,6 +136,39 @@ func (h *CLIEventHandler) Handle(msg tea.Msg) {
 			h.lastDisplayed = e.Content
 		}
 
+	case app.HookBlockedEvent:
+		h.stopSpinner()
+		h.endStream()
+		h.cli.DisplayInfo(hookStoppedMessage(e))
+
+	case app.CompactionEvent:
+		h.stopSpinner()
+		h.cli.DisplayInfo(compactionMessage(e))
+
+	case app.ModelRetryEvent:
+		h.stopSpinner()
+		h.endStream()
+		h.cli.DisplayInfo(modelRetryMessage(e))
+		h.startSpinner()
+
+	case app.ModelFallbackEvent:
+		h.stopSpinner()
+		h.endStream()
+		h.cli.DisplayInfo(modelFallbackMessage(e))
+		h.startSpinner()
+
+	case app.ModelAnsweredEvent:
+		h.stopSpinner()
+		h.endStream()
+		h.cli.DisplayInfo(modelAnsweredMessage(e))
+		h.startSpinner()
+
+	case app.BudgetWarningEvent:
+		h.stopSpinner()
+		h.endStream()
+		h.cli.DisplayInfo(budgetWarningMessage(e))
+		h.startSpinner()
+
 	case app.StepCompleteEvent:
 		h.stopSpinner()
 
@@ -11,3 +11,13 @@ type submitMsg struct {
 // presses ESC once during stateWorking. If this message arrives before the user
 // presses ESC a second time, the canceling state is reset to false.
 type cancelTimerExpiredMsg struct{}
+
+// approvalResultMsg is sent by the ApprovalComponent when the user allows or
+// denies a pending tool call. The parent forwards the decision to the app layer
+// on the channel it received with app.ToolApprovalNeededEvent.
+type approvalResultMsg struct {
+	// Approved is true when the user allowed the tool call.
+	Approved bool
+	// Always is true when the user chose to always allow this tool.
+	Always bool
+}
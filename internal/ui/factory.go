@@ -6,6 +6,7 @@ import (
 
 	"github.com/mark3labs/mcphost/internal/auth"
 	"github.com/mark3labs/mcphost/internal/models"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // AgentInterface defines the minimal interface required from the agent package
@@ -14,6 +15,7 @@ type AgentInterface interface {
 	GetLoadingMessage() string
 	GetTools() []any                // Using any to avoid importing tool types
 	GetLoadedServerNames() []string // Add this method for debug config
+	GetServerStatuses() []tools.ServerStatus
 }
 
 // CLISetupOptions encapsulates all configuration parameters needed to initialize
@@ -120,6 +122,11 @@ func SetupCLI(opts *CLISetupOptions) (*CLI, error) {
 		cli.DisplayInfo(loadingMessage)
 	}
 
+	// Display how each MCP server started
+	for _, status := range opts.Agent.GetServerStatuses() {
+		cli.DisplayInfo(status.String())
+	}
+
 	// Display tool count
 	tools := opts.Agent.GetTools()
 	cli.DisplayInfo(fmt.Sprintf("Loaded %d tools from MCP servers", len(tools)))
@@ -2,6 +2,8 @@ package ui
 
 import (
 	"strings"
+
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // FuzzyMatch represents the result of a fuzzy string matching operation,
@@ -35,7 +37,7 @@ func FuzzyMatchCommands(query string, commands []SlashCommand) []FuzzyMatch {
 
 	for i := range commands {
 		cmd := &commands[i]
-		score := fuzzyScore(query, cmd)
+		score := fuzzyScore(query, strings.TrimPrefix(cmd.Name, "/"), cmd.Aliases, cmd.Description)
 		if score > 0 {
 			matches = append(matches, FuzzyMatch{
 				Command: cmd,
@@ -56,53 +58,90 @@ func FuzzyMatchCommands(query string, commands []SlashCommand) []FuzzyMatch {
 	return matches
 }
 
-// fuzzyScore calculates the fuzzy match score for a command
-func fuzzyScore(query string, cmd *SlashCommand) int {
+// ResourceMatch is a resource matched by FuzzyMatchResources, with its
+// relevance score. Higher scores indicate better matches.
+type ResourceMatch struct {
+	Resource tools.Resource
+	Score    int
+}
+
+// FuzzyMatchResources matches MCP resources against query, the part of an
+// "@server:uri" mention after the "@". A resource matches on its mention, its
+// name or its description. Returns matches sorted by relevance score in
+// descending order; an empty query returns all resources with zero scores.
+func FuzzyMatchResources(query string, resources []tools.Resource) []ResourceMatch {
+	query = strings.ToLower(strings.TrimPrefix(query, "@"))
+
+	var matches []ResourceMatch
+	for _, r := range resources {
+		score := 0
+		if query != "" {
+			score = fuzzyScore(query, r.Server+":"+r.URI, []string{r.Name}, r.Description)
+			if score == 0 {
+				continue
+			}
+		}
+		matches = append(matches, ResourceMatch{Resource: r, Score: score})
+	}
+
+	// Sort by score (highest first), keeping the order of equal scores
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0 && matches[j].Score > matches[j-1].Score; j-- {
+			matches[j], matches[j-1] = matches[j-1], matches[j]
+		}
+	}
+
+	return matches
+}
+
+// fuzzyScore calculates the fuzzy match score of a lower-case query against
+// an item's name, its aliases and its description
+func fuzzyScore(query, name string, aliases []string, description string) int {
 	// Check exact match first
-	cmdName := strings.ToLower(strings.TrimPrefix(cmd.Name, "/"))
-	if cmdName == query {
+	name = strings.ToLower(name)
+	if name == query {
 		return 1000
 	}
 
 	// Check aliases for exact match
-	for _, alias := range cmd.Aliases {
+	for _, alias := range aliases {
 		aliasName := strings.ToLower(strings.TrimPrefix(alias, "/"))
 		if aliasName == query {
 			return 900
 		}
 	}
 
-	// Check if command starts with query
-	if strings.HasPrefix(cmdName, query) {
-		return 800 - len(cmdName) + len(query)
+	// Check if name starts with query
+	if strings.HasPrefix(name, query) {
+		return 800 - len(name) + len(query)
 	}
 
 	// Check if any alias starts with query
-	for _, alias := range cmd.Aliases {
+	for _, alias := range aliases {
 		aliasName := strings.ToLower(strings.TrimPrefix(alias, "/"))
 		if strings.HasPrefix(aliasName, query) {
 			return 700 - len(aliasName) + len(query)
 		}
 	}
 
-	// Check if command contains query
-	if strings.Contains(cmdName, query) {
+	// Check if name contains query
+	if strings.Contains(name, query) {
 		return 500
 	}
 
 	// Check if description contains query
-	if strings.Contains(strings.ToLower(cmd.Description), query) {
+	if strings.Contains(strings.ToLower(description), query) {
 		return 300
 	}
 
 	// Fuzzy character matching
-	score := fuzzyCharacterMatch(query, cmdName)
+	score := fuzzyCharacterMatch(query, name)
 	if score > 0 {
 		return score
 	}
 
 	// Try fuzzy matching on aliases
-	for _, alias := range cmd.Aliases {
+	for _, alias := range aliases {
 		aliasName := strings.ToLower(strings.TrimPrefix(alias, "/"))
 		score = fuzzyCharacterMatch(query, aliasName)
 		if score > 0 {
@@ -1,12 +1,16 @@
 package ui
 
 import (
+	"cmp"
+	"slices"
 	"strings"
 
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/textarea"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // InputComponent is the interactive text input field for the parent AppModel.
@@ -22,13 +26,19 @@ import (
 // update queueCount directly (calling ClearQueue from within Update would
 // require prog.Send which deadlocks).
 //
+// Typing "@" at the end of the input opens the same popup with the MCP
+// resources matching the mention, if any server offers resources; choosing
+// one completes the mention to "@server:uri".
+//
 // All other input is returned via submitMsg for the parent to forward to
 // app.Run().
 type InputComponent struct {
 	textarea    textarea.Model
 	commands    []SlashCommand
+	resources   []tools.Resource
 	showPopup   bool
 	filtered    []FuzzyMatch
+	mentions    []ResourceMatch // set instead of filtered while completing a mention
 	selected    int
 	width       int
 	lastValue   string
@@ -80,6 +90,17 @@ func NewInputComponent(width int, title string, appCtrl AppController) *InputCom
 	}
 }
 
+// SetPromptCommands sets the commands running server prompts, which the
+// autocomplete popup offers after the built-in commands.
+func (s *InputComponent) SetPromptCommands(commands []SlashCommand) {
+	s.commands = slices.Concat(SlashCommands, commands)
+}
+
+// SetResources sets the MCP resources offered when completing an "@" mention.
+func (s *InputComponent) SetResources(resources []tools.Resource) {
+	s.resources = resources
+}
+
 // Init implements tea.Model. Starts the cursor blink animation.
 func (s *InputComponent) Init() tea.Cmd {
 	return textarea.Blink
@@ -131,11 +152,19 @@ func (s *InputComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return s, nil
 
 			case key.Matches(msg, key.NewBinding(key.WithKeys("down"), key.WithHelp("↓", "down"))):
-				if s.selected < len(s.filtered)-1 {
+				if s.selected < s.popupLen()-1 {
 					s.selected++
 				}
 				return s, nil
 
+			case s.mentions != nil && key.Matches(msg, key.NewBinding(key.WithKeys("tab", "enter"))):
+				// Complete the mention without submitting, so the user can
+				// go on typing the prompt.
+				if s.selected < len(s.mentions) {
+					s.completeMention(s.mentions[s.selected].Resource)
+				}
+				return s, nil
+
 			case key.Matches(msg, key.NewBinding(key.WithKeys("tab"))):
 				if s.selected < len(s.filtered) {
 					s.textarea.SetValue(s.filtered[s.selected].Command.Name)
@@ -172,10 +201,15 @@ func (s *InputComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if value != s.lastValue {
 			s.lastValue = value
 			lines := strings.Split(value, "\n")
+			s.mentions = nil
 			if len(lines) == 1 && strings.HasPrefix(lines[0], "/") && !strings.Contains(lines[0], " ") {
 				s.showPopup = true
 				s.filtered = FuzzyMatchCommands(lines[0], s.commands)
 				s.selected = 0
+			} else if mention, ok := trailingMention(value); ok && len(s.resources) > 0 {
+				s.mentions = FuzzyMatchResources(mention, s.resources)
+				s.showPopup = len(s.mentions) > 0
+				s.selected = 0
 			} else {
 				s.showPopup = false
 			}
@@ -188,6 +222,33 @@ func (s *InputComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// trailingMention returns the "@" mention being typed at the end of value.
+func trailingMention(value string) (string, bool) {
+	token := value[strings.LastIndexAny(value, " \t\n")+1:]
+	return token, strings.HasPrefix(token, "@")
+}
+
+// completeMention replaces the mention being typed with the mention of r.
+func (s *InputComponent) completeMention(r tools.Resource) {
+	value := s.textarea.Value()
+	mention, _ := trailingMention(value)
+	value = value[:len(value)-len(mention)] + r.Mention() + " "
+	s.textarea.SetValue(value)
+	s.textarea.CursorEnd()
+	s.lastValue = value
+	s.showPopup = false
+	s.mentions = nil
+	s.selected = 0
+}
+
+// popupLen returns the number of items in the autocomplete popup.
+func (s *InputComponent) popupLen() int {
+	if s.mentions != nil {
+		return len(s.mentions)
+	}
+	return len(s.filtered)
+}
+
 // handleSubmit processes the submitted text. Slash commands that affect app
 // state are executed here; /quit returns tea.Quit; everything else returns a
 // submitMsg tea.Cmd for the parent to forward to app.Run().
@@ -249,7 +310,7 @@ func (s *InputComponent) View() tea.View {
 	view.WriteString("\n")
 	view.WriteString(inputBoxStyle.Render(s.textarea.View()))
 
-	if s.showPopup && len(s.filtered) > 0 {
+	if s.showPopup && s.popupLen() > 0 {
 		view.WriteString("\n")
 		view.WriteString(s.renderPopup())
 	}
@@ -265,7 +326,38 @@ func (s *InputComponent) View() tea.View {
 	return tea.NewView(containerStyle.Render(view.String()))
 }
 
-// renderPopup renders the autocomplete popup for slash command suggestions.
+// popupItem is a row of the autocomplete popup.
+type popupItem struct {
+	name, description string
+}
+
+// popupItems returns the rows of the autocomplete popup and the width of its
+// name column.
+func (s *InputComponent) popupItems() ([]popupItem, int) {
+	if s.mentions == nil {
+		items := make([]popupItem, len(s.filtered))
+		for i, match := range s.filtered {
+			items[i] = popupItem{name: match.Command.Name, description: match.Command.Description}
+		}
+		return items, 15
+	}
+
+	items := make([]popupItem, len(s.mentions))
+	nameWidth := 15
+	for i, match := range s.mentions {
+		r := match.Resource
+		desc := cmp.Or(r.Description, r.Name)
+		if r.Template {
+			desc = "(template) " + desc
+		}
+		items[i] = popupItem{name: r.Mention(), description: desc}
+		nameWidth = max(nameWidth, len(items[i].name)+2)
+	}
+	return items, min(nameWidth, max(15, s.width/2))
+}
+
+// renderPopup renders the autocomplete popup for slash command and resource
+// mention suggestions.
 func (s *InputComponent) renderPopup() string {
 	popupStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -276,16 +368,17 @@ func (s *InputComponent) renderPopup() string {
 
 	var items []string
 
-	visibleItems := min(len(s.filtered), s.popupHeight)
+	rows, nameWidth := s.popupItems()
+
+	visibleItems := min(len(rows), s.popupHeight)
 	startIdx := 0
 	if s.selected >= s.popupHeight {
 		startIdx = s.selected - s.popupHeight + 1
 	}
-	endIdx := min(startIdx+visibleItems, len(s.filtered))
+	endIdx := min(startIdx+visibleItems, len(rows))
 
 	for i := startIdx; i < endIdx; i++ {
-		match := s.filtered[i]
-		sc := match.Command
+		row := rows[i]
 
 		var indicator string
 		if i == s.selected {
@@ -301,10 +394,13 @@ func (s *InputComponent) renderPopup() string {
 			descStyle = descStyle.Foreground(lipgloss.Color("250"))
 		}
 
-		nameWidth := 15
-		name := nameStyle.Width(nameWidth - 2).Render(sc.Name)
+		name := row.name
+		if len(name) > nameWidth-2 {
+			name = name[:nameWidth-5] + "..."
+		}
+		name = nameStyle.Width(nameWidth - 2).Render(name)
 
-		desc := sc.Description
+		desc := row.description
 		maxDescLen := s.width - nameWidth - 14
 		if len(desc) > maxDescLen && maxDescLen > 3 {
 			desc = desc[:maxDescLen-3] + "..."
@@ -316,7 +412,7 @@ func (s *InputComponent) renderPopup() string {
 	if startIdx > 0 {
 		items = append([]string{lipgloss.NewStyle().Foreground(lipgloss.Color("238")).Render("  ↑ more above")}, items...)
 	}
-	if endIdx < len(s.filtered) {
+	if endIdx < len(rows) {
 		items = append(items, lipgloss.NewStyle().Foreground(lipgloss.Color("238")).Render("  ↓ more below"))
 	}
 
@@ -610,6 +610,20 @@ func (r *MessageRenderer) renderMarkdownWithBg(content string, width int, bgHex
 	return strings.TrimSuffix(rendered, "\n")
 }
 
+// nestedIndent is prepended to every line of a message produced by a
+// sub-agent of the task tool, so its tool calls appear under the task call.
+const nestedIndent = "    "
+
+// nestMessage returns msg indented as a sub-agent message.
+func nestMessage(msg UIMessage) UIMessage {
+	lines := strings.Split(msg.Content, "\n")
+	for i, line := range lines {
+		lines[i] = nestedIndent + line
+	}
+	msg.Content = strings.Join(lines, "\n")
+	return msg
+}
+
 // MessageContainer manages a collection of UI messages, handling their display,
 // updates, and layout within the terminal. It supports both standard and compact
 // display modes and maintains state for streaming message updates.
@@ -1,13 +1,18 @@
 package ui
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 	"github.com/mark3labs/mcphost/internal/app"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // appState represents the current state of the parent TUI model.
@@ -21,6 +26,10 @@ const (
 	// stateWorking means the agent is running. The stream component is active.
 	// The input component remains visible and editable for queueing messages.
 	stateWorking
+
+	// stateApproval means the agent is blocked on a tool approval, or an MCP
+	// server on a sampling approval, and the approval dialog has keyboard focus.
+	stateApproval
 )
 
 // AppController is the interface the parent TUI model uses to interact with the
@@ -45,6 +54,23 @@ type AppController interface {
 	ClearQueue()
 	// ClearMessages clears the conversation history.
 	ClearMessages()
+	// Compact summarizes the older part of the conversation in the background,
+	// with optional extra instructions for the summary. It returns an error
+	// instead of starting when the agent is busy; the outcome is reported with
+	// an app.CompactionEvent.
+	Compact(instructions string) error
+	// RunServerPrompt runs a prompt of an MCP server, filled in with args, in
+	// the background. Like Compact it returns an error instead of starting
+	// when the agent is busy.
+	RunServerPrompt(serverName, promptName string, args map[string]string) error
+	// Roots returns the root directories of each MCP server supporting roots.
+	Roots() map[string][]string
+	// AddRoot adds a directory to the roots of every MCP server and returns
+	// its absolute path.
+	AddRoot(dir string) (string, error)
+	// ChangeDirectory changes the working directory and returns the new one.
+	// It returns an error instead of changing it when the agent is busy.
+	ChangeDirectory(dir string) (string, error)
 }
 
 // AppModelOptions holds configuration passed to NewAppModel.
@@ -75,6 +101,19 @@ type AppModelOptions struct {
 	// ToolNames holds available tool names for the /tools command.
 	ToolNames []string
 
+	// ServerStatuses holds the startup status of the MCP servers, shown by
+	// PrintStartupInfo. Servers still connecting report through
+	// app.ServerStatusEvent once they are ready or failed.
+	ServerStatuses []tools.ServerStatus
+
+	// Resources holds the MCP resources and resource templates for the
+	// /resources command and "@" mention completion.
+	Resources []tools.Resource
+
+	// Prompts holds the MCP server prompts, which run as slash commands such
+	// as "/github:review-pr".
+	Prompts []tools.Prompt
+
 	// UsageTracker provides token usage statistics for /usage and /reset-usage.
 	// May be nil if usage tracking is unavailable for the current model.
 	UsageTracker *UsageTracker
@@ -112,6 +151,20 @@ type AppModel struct {
 	// Placeholder until StreamComponent is implemented in TAS-16.
 	stream streamComponentIface
 
+	// approval is the tool or sampling approval dialog. Non-nil only in
+	// stateApproval.
+	approval *ApprovalComponent
+
+	// approvalChan receives the user's decision for the pending request.
+	// It comes from app.ToolApprovalNeededEvent or
+	// app.SamplingApprovalNeededEvent and is buffered by the app layer, so
+	// sending on it never blocks Update().
+	approvalChan chan<- app.ToolApprovalDecision
+
+	// approvalReturn is the state to return to once the approval is decided:
+	// sampling requests may also arrive while no step runs.
+	approvalReturn appState
+
 	// renderer renders completed assistant messages for tea.Println output.
 	renderer *MessageRenderer
 
@@ -139,9 +192,29 @@ type AppModel struct {
 	// loadingMessage is an optional agent startup message (e.g. GPU fallback).
 	loadingMessage string
 
-	// serverNames, toolNames are used by /servers and /tools commands.
+	// serverStatuses are the startup statuses of the MCP servers.
+	serverStatuses []tools.ServerStatus
+
+	// serverNames, toolNames, resources are used by /servers, /tools and
+	// /resources commands.
 	serverNames []string
 	toolNames   []string
+	resources   []tools.Resource
+
+	// prompts are the MCP server prompts run as slash commands.
+	prompts []tools.Prompt
+
+	// promptForm collects the missing arguments of a server prompt. While it
+	// is non-nil it has keyboard focus.
+	promptForm *PromptFormComponent
+
+	// elicitation is the form answering an MCP server's request for input.
+	// While it is non-nil it has keyboard focus, also while a step runs.
+	elicitation *ElicitationFormComponent
+
+	// elicitationChan receives the answer to the pending elicitation. It
+	// comes from app.ElicitationNeededEvent and is buffered by the app layer.
+	elicitationChan chan<- tools.ElicitationResponse
 
 	// usageTracker provides token usage stats for /usage and /reset-usage.
 	// May be nil when usage tracking is unavailable.
@@ -173,6 +246,8 @@ type streamComponentIface interface {
 	// GetRenderedContent returns the rendered assistant message from accumulated
 	// streaming text, or empty string if nothing has been accumulated.
 	GetRenderedContent() string
+	// ToggleThinking expands or collapses the model's reasoning.
+	ToggleThinking()
 }
 
 // --------------------------------------------------------------------------
@@ -207,14 +282,20 @@ func NewAppModel(appCtrl AppController, opts AppModelOptions) *AppModel {
 		providerName:   opts.ProviderName,
 		loadingMessage: opts.LoadingMessage,
 		serverNames:    opts.ServerNames,
+		serverStatuses: opts.ServerStatuses,
 		toolNames:      opts.ToolNames,
+		resources:      opts.Resources,
+		prompts:        opts.Prompts,
 		usageTracker:   opts.UsageTracker,
 		width:          width,
 		height:         height,
 	}
 
 	// Wire up child components now that we have the concrete implementations.
-	m.input = NewInputComponent(width, "Enter your prompt (Type /help for commands, Ctrl+C to quit)", appCtrl)
+	input := NewInputComponent(width, "Enter your prompt (Type /help for commands, Ctrl+C to quit)", appCtrl)
+	input.SetResources(opts.Resources)
+	input.SetPromptCommands(PromptCommands(opts.Prompts))
+	m.input = input
 	m.stream = NewStreamComponent(opts.CompactMode, width, opts.ModelName)
 
 	// Propagate initial height distribution to children.
@@ -263,6 +344,10 @@ func (m *AppModel) PrintStartupInfo() {
 		fmt.Println(render(m.loadingMessage))
 	}
 
+	for _, status := range m.serverStatuses {
+		fmt.Println(render(status.String()))
+	}
+
 	fmt.Println(render(fmt.Sprintf("Loaded %d tools from MCP servers", len(m.toolNames))))
 }
 
@@ -277,6 +362,15 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		if m.approval != nil {
+			m.approval.Update(msg)
+		}
+		if m.promptForm != nil {
+			m.promptForm.Update(msg)
+		}
+		if m.elicitation != nil {
+			m.elicitation.Update(msg)
+		}
 		m.distributeHeight()
 		// Propagate to children.
 		if m.input != nil {
@@ -290,6 +384,14 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// ── Keyboard input ───────────────────────────────────────────────────────
 	case tea.KeyPressMsg:
+		// An MCP server waiting for input has the form's keyboard focus,
+		// unless an approval is pending; ESC answers the form rather than
+		// cancelling the step.
+		if m.elicitation != nil && m.state != stateApproval && msg.String() != "ctrl+c" {
+			_, cmd := m.elicitation.Update(msg)
+			return m, cmd
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
 			// Graceful quit: app.Close() is deferred in cmd/root.go.
@@ -311,6 +413,25 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(cmds...)
 			}
 			// In other states pass ESC through to children below.
+
+		case "ctrl+t":
+			if m.stream != nil {
+				m.stream.ToggleThinking()
+			}
+			return m, nil
+		}
+
+		// While a tool approval is pending the dialog has keyboard focus.
+		if m.state == stateApproval && m.approval != nil {
+			_, cmd := m.approval.Update(msg)
+			return m, cmd
+		}
+
+		// While a server prompt's arguments are asked for the form has
+		// keyboard focus.
+		if m.promptForm != nil {
+			_, cmd := m.promptForm.Update(msg)
+			return m, cmd
 		}
 
 		// Route key events to the focused child.
@@ -324,11 +445,45 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case cancelTimerExpiredMsg:
 		m.canceling = false
 
+	// ── Tool approval decided ────────────────────────────────────────────────
+	case approvalResultMsg:
+		if m.approvalChan != nil {
+			m.approvalChan <- app.ToolApprovalDecision{Approved: msg.Approved, Always: msg.Always}
+		}
+		m.approvalChan = nil // answered; clearApproval must not deny it
+		m.clearApproval()
+		if m.state == stateApproval {
+			m.state = m.approvalReturn
+		}
+
+	// ── Elicitation answered ─────────────────────────────────────────────────
+	case elicitationResultMsg:
+		if m.elicitationChan != nil {
+			m.elicitationChan <- msg.Response
+		}
+		m.elicitationChan = nil // answered; clearElicitation must not cancel it
+		m.clearElicitation()
+
+	// ── Server prompt arguments collected ────────────────────────────────────
+	case promptFormResultMsg:
+		m.promptForm = nil
+		m.distributeHeight()
+		if !msg.Cancelled {
+			if cmd := m.startServerPrompt(msg.Prompt, msg.Args); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+
 	// ── Input submitted ──────────────────────────────────────────────────────
 	case submitMsg:
+		// Server prompts run as slash commands, asking for missing arguments.
+		if p, args := ParsePromptCommand(msg.Text, m.prompts); p != nil {
+			return m, m.runPromptCommand(*p, args)
+		}
+
 		// Handle slash commands locally — they should never reach app.Run().
-		if sc := GetCommandByName(msg.Text); sc != nil {
-			if cmd := m.handleSlashCommand(sc); cmd != nil {
+		if sc, args := ParseSlashCommand(msg.Text); sc != nil {
+			if cmd := m.handleSlashCommand(sc, args); cmd != nil {
 				cmds = append(cmds, cmd)
 			}
 			return m, tea.Batch(cmds...)
@@ -353,7 +508,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			cmds = append(cmds, m.printUserMessage(msg.Text))
 		}
-		if m.state != stateWorking {
+		if m.state == stateInput {
 			m.state = stateWorking
 		}
 
@@ -374,7 +529,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
-	case app.StreamChunkEvent:
+	case app.ReasoningChunkEvent, app.StreamChunkEvent:
 		if m.stream != nil {
 			_, cmd := m.stream.Update(msg)
 			cmds = append(cmds, cmd)
@@ -386,7 +541,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, m.flushStreamContent())
 		cmds = append(cmds, m.printToolCall(msg))
 
-	case app.ToolExecutionEvent:
+	case app.ToolExecutionEvent, app.ToolProgressEvent, app.ServerLogEvent:
 		// Pass to stream component for execution spinner display.
 		if m.stream != nil {
 			_, cmd := m.stream.Update(msg)
@@ -417,9 +572,89 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.stream.Reset() // stop spinner
 		}
 
+	case app.ToolApprovalNeededEvent:
+		// The agent is blocked until the user decides; show the dialog.
+		cmds = append(cmds, m.flushStreamContent())
+		if m.stream != nil {
+			m.stream.Reset() // stop spinner while waiting on the user
+		}
+		m.showApproval(NewApprovalComponent(msg.ToolName, msg.ToolArgs, m.width, msg.CanAlwaysAllow), msg.ResponseChan)
+
+	case app.SamplingApprovalNeededEvent:
+		// An MCP server waits for the model to answer it, usually while one
+		// of its tools runs.
+		cmds = append(cmds, m.flushStreamContent())
+		m.showApproval(NewSamplingApprovalComponent(msg.ServerName, msg.Request, m.width), msg.ResponseChan)
+
+	case app.ElicitationNeededEvent:
+		// An MCP server waits for the user's input, usually while one of its
+		// tools runs.
+		cmds = append(cmds, m.flushStreamContent())
+		m.showElicitation(NewElicitationFormComponent(msg.ServerName, msg.Request, m.width), msg.ResponseChan)
+
+	case app.ServerListChangedEvent:
+		// The server's new tools reach the model from the agent's next step;
+		// the commands and completions follow here.
+		m.toolNames = msg.ToolNames
+		m.resources = msg.Resources
+		m.prompts = msg.Prompts
+		if input, ok := m.input.(*InputComponent); ok {
+			input.SetResources(msg.Resources)
+			input.SetPromptCommands(PromptCommands(msg.Prompts))
+		}
+		cmds = append(cmds, m.printSystemMessage(fmt.Sprintf("Server %s %s updated", msg.ServerName, msg.Kind)))
+
+	case app.ServerStatusEvent:
+		// A server still connecting at startup, or a lazy one started by a
+		// tool call, is ready or failed; the tools of a ready one reach the
+		// model from the agent's next step.
+		m.toolNames = msg.ToolNames
+		m.resources = msg.Resources
+		m.prompts = msg.Prompts
+		if input, ok := m.input.(*InputComponent); ok {
+			input.SetResources(msg.Resources)
+			input.SetPromptCommands(PromptCommands(msg.Prompts))
+		}
+		cmds = append(cmds, m.printSystemMessage(msg.Status.String()))
+
+	case app.PermissionRuleAddedEvent:
+		cmds = append(cmds, m.printSystemMessage(permissionRuleMessage(msg)))
+
+	case app.CompactionEvent:
+		cmds = append(cmds, m.printSystemMessage(compactionMessage(msg)))
+		if !msg.Auto {
+			// A manual compaction is a step of its own; it is finished now.
+			if m.stream != nil {
+				m.stream.Reset()
+			}
+			m.state = stateInput
+			m.canceling = false
+		}
+
 	case app.MessageCreatedEvent:
 		// Informational — no action needed by parent.
 
+	case app.ModelRetryEvent:
+		cmds = append(cmds, m.flushStreamContent())
+		cmds = append(cmds, m.printSystemMessage(modelRetryMessage(msg)))
+
+	case app.ModelFallbackEvent:
+		cmds = append(cmds, m.flushStreamContent())
+		cmds = append(cmds, m.printSystemMessage(modelFallbackMessage(msg)))
+
+	case app.ModelAnsweredEvent:
+		cmds = append(cmds, m.flushStreamContent())
+		cmds = append(cmds, m.printSystemMessage(modelAnsweredMessage(msg)))
+
+	case app.BudgetWarningEvent:
+		cmds = append(cmds, m.flushStreamContent())
+		cmds = append(cmds, m.printSystemMessage(budgetWarningMessage(msg)))
+
+	case app.HookBlockedEvent:
+		// A hook ended the step early; explain why before the step completes.
+		cmds = append(cmds, m.flushStreamContent())
+		cmds = append(cmds, m.printSystemMessage(hookStoppedMessage(msg)))
+
 	case app.QueueUpdatedEvent:
 		// drainQueue popped item(s) from the queue. Move consumed messages
 		// from the anchored display to scrollback (they are now being processed
@@ -440,6 +675,8 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.stream != nil {
 			m.stream.Reset()
 		}
+		m.clearApproval()
+		m.clearElicitation()
 		m.state = stateInput
 		m.canceling = false
 
@@ -450,6 +687,8 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.stream != nil {
 			m.stream.Reset()
 		}
+		m.clearApproval()
+		m.clearElicitation()
 		m.state = stateInput
 		m.canceling = false
 
@@ -462,6 +701,8 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.stream != nil {
 			m.stream.Reset()
 		}
+		m.clearApproval()
+		m.clearElicitation()
 		m.state = stateInput
 		m.canceling = false
 
@@ -489,6 +730,16 @@ func (m *AppModel) View() tea.View {
 
 	parts := []string{streamView}
 
+	if m.approval != nil {
+		parts = append(parts, m.approval.View().Content)
+	}
+	if m.promptForm != nil {
+		parts = append(parts, m.promptForm.View().Content)
+	}
+	if m.elicitation != nil {
+		parts = append(parts, m.elicitation.View().Content)
+	}
+
 	// Sticky usage info sits between the stream and separator so it is
 	// always visible at the bottom of the messages area and updates in place.
 	if usageView := m.renderUsageInfo(); usageView != "" {
@@ -629,29 +880,33 @@ func (m *AppModel) printAssistantMessage(text string) tea.Cmd {
 }
 
 // printToolCall renders a tool call message and emits it above the BT region.
+// Calls made by a sub-agent of the task tool are indented under the task call.
 func (m *AppModel) printToolCall(evt app.ToolCallStartedEvent) tea.Cmd {
-	var rendered string
+	var msg UIMessage
 	if m.compactMode {
-		msg := m.compactRdr.RenderToolCallMessage(evt.ToolName, evt.ToolArgs, time.Now())
-		rendered = msg.Content
+		msg = m.compactRdr.RenderToolCallMessage(evt.ToolName, evt.ToolArgs, time.Now())
 	} else {
-		msg := m.renderer.RenderToolCallMessage(evt.ToolName, evt.ToolArgs, time.Now())
-		rendered = msg.Content
+		msg = m.renderer.RenderToolCallMessage(evt.ToolName, evt.ToolArgs, time.Now())
 	}
-	return tea.Println(rendered)
+	if evt.Nested {
+		msg = nestMessage(msg)
+	}
+	return tea.Println(msg.Content)
 }
 
 // printToolResult renders a tool result message and emits it above the BT region.
+// Results of sub-agent tool calls are indented like their calls.
 func (m *AppModel) printToolResult(evt app.ToolResultEvent) tea.Cmd {
-	var rendered string
+	var msg UIMessage
 	if m.compactMode {
-		msg := m.compactRdr.RenderToolMessage(evt.ToolName, evt.ToolArgs, evt.Result, evt.IsError)
-		rendered = msg.Content
+		msg = m.compactRdr.RenderToolMessage(evt.ToolName, evt.ToolArgs, evt.Result, evt.IsError)
 	} else {
-		msg := m.renderer.RenderToolMessage(evt.ToolName, evt.ToolArgs, evt.Result, evt.IsError)
-		rendered = msg.Content
+		msg = m.renderer.RenderToolMessage(evt.ToolName, evt.ToolArgs, evt.Result, evt.IsError)
 	}
-	return tea.Println(rendered)
+	if evt.Nested {
+		msg = nestMessage(msg)
+	}
+	return tea.Println(msg.Content)
 }
 
 // printErrorResponse renders an error message and emits it above the BT region.
@@ -674,10 +929,11 @@ func (m *AppModel) printErrorResponse(evt app.StepErrorEvent) tea.Cmd {
 // Slash command handlers
 // --------------------------------------------------------------------------
 
-// handleSlashCommand executes a recognized slash command and returns a tea.Cmd
-// that emits the appropriate output to scrollback. Returns tea.Quit for /quit,
-// nil for commands with no visible output, or a tea.Println cmd for display.
-func (m *AppModel) handleSlashCommand(sc *SlashCommand) tea.Cmd {
+// handleSlashCommand executes a recognized slash command with its arguments and
+// returns a tea.Cmd that emits the appropriate output to scrollback. Returns
+// tea.Quit for /quit, nil for commands with no visible output, or a tea.Println
+// cmd for display.
+func (m *AppModel) handleSlashCommand(sc *SlashCommand, args string) tea.Cmd {
 	switch sc.Name {
 	case "/quit":
 		return tea.Quit
@@ -687,6 +943,8 @@ func (m *AppModel) handleSlashCommand(sc *SlashCommand) tea.Cmd {
 		return m.printToolsMessage()
 	case "/servers":
 		return m.printServersMessage()
+	case "/resources":
+		return m.printResourcesMessage()
 	case "/usage":
 		return m.printUsageMessage()
 	case "/reset-usage":
@@ -696,6 +954,12 @@ func (m *AppModel) handleSlashCommand(sc *SlashCommand) tea.Cmd {
 			m.appCtrl.ClearMessages()
 		}
 		return m.printSystemMessage("Conversation cleared. Starting fresh.")
+	case "/compact":
+		return m.compactConversation(args)
+	case "/roots":
+		return m.rootsCommand(args)
+	case "/cd":
+		return m.changeDirectory(args)
 	case "/clear-queue":
 		if m.appCtrl != nil {
 			m.appCtrl.ClearQueue()
@@ -721,19 +985,206 @@ func (m *AppModel) printSystemMessage(text string) tea.Cmd {
 	return tea.Println(rendered)
 }
 
+// showApproval shows dialog, whose decision is sent on ch. A request still
+// pending is denied.
+func (m *AppModel) showApproval(dialog *ApprovalComponent, ch chan<- app.ToolApprovalDecision) {
+	m.clearApproval()
+	if m.state != stateApproval {
+		m.approvalReturn = m.state
+	}
+	m.approval = dialog
+	m.approvalChan = ch
+	m.state = stateApproval
+	m.canceling = false
+	m.distributeHeight()
+}
+
+// clearApproval dismisses the approval dialog, if any. A pending request is
+// denied: a tool call whose step ended is no longer waited on, but an MCP
+// server may still wait for its sampling request.
+func (m *AppModel) clearApproval() {
+	if m.approval == nil && m.approvalChan == nil {
+		return
+	}
+	if m.approvalChan != nil {
+		select {
+		case m.approvalChan <- app.ToolApprovalDecision{}:
+		default:
+		}
+	}
+	m.approval = nil
+	m.approvalChan = nil
+	m.distributeHeight()
+}
+
+// showElicitation shows form, whose answer is sent on ch. A request still
+// pending is cancelled.
+func (m *AppModel) showElicitation(form *ElicitationFormComponent, ch chan<- tools.ElicitationResponse) {
+	m.clearElicitation()
+	m.elicitation = form
+	m.elicitationChan = ch
+	m.canceling = false
+	m.distributeHeight()
+}
+
+// clearElicitation dismisses the elicitation form, if any, cancelling a
+// pending request.
+func (m *AppModel) clearElicitation() {
+	if m.elicitation == nil && m.elicitationChan == nil {
+		return
+	}
+	if m.elicitationChan != nil {
+		select {
+		case m.elicitationChan <- tools.ElicitationResponse{Action: tools.ElicitationCancel}:
+		default:
+		}
+	}
+	m.elicitation = nil
+	m.elicitationChan = nil
+	m.distributeHeight()
+}
+
+// permissionRuleMessage formats the notice shown after an "always allow" answer.
+func permissionRuleMessage(evt app.PermissionRuleAddedEvent) string {
+	if evt.Err != nil {
+		return fmt.Sprintf("Added rule %q for this session, but could not save it: %v", evt.Rule, evt.Err)
+	}
+	return fmt.Sprintf("Added rule %q to %s", evt.Rule, evt.Path)
+}
+
+// compactConversation starts a manual compaction. The app reports the result
+// with a CompactionEvent, which returns the model to stateInput.
+func (m *AppModel) compactConversation(instructions string) tea.Cmd {
+	if m.appCtrl == nil {
+		return nil
+	}
+	if err := m.appCtrl.Compact(instructions); err != nil {
+		return m.printSystemMessage(fmt.Sprintf("Cannot compact right now: %v", err))
+	}
+	m.state = stateWorking
+	return m.printSystemMessage("Compacting conversation…")
+}
+
+// runPromptCommand runs a server prompt invoked as a slash command with the
+// arguments given inline. Without inline arguments every argument is asked
+// for; otherwise only the required ones that are missing.
+func (m *AppModel) runPromptCommand(p tools.Prompt, input string) tea.Cmd {
+	args, err := ParsePromptArgs(p, input)
+	if err != nil {
+		return m.printSystemMessage(err.Error())
+	}
+	fields := MissingPromptArgs(p, args)
+	if input == "" {
+		fields = p.Arguments
+	}
+	if len(fields) > 0 {
+		m.promptForm = NewPromptFormComponent(p, args, fields, m.width)
+		m.distributeHeight()
+		return nil
+	}
+	return m.startServerPrompt(p, args)
+}
+
+// startServerPrompt asks the app layer to run a server prompt and shows the
+// invocation as the user's message.
+func (m *AppModel) startServerPrompt(p tools.Prompt, args map[string]string) tea.Cmd {
+	if m.appCtrl == nil {
+		return nil
+	}
+	if err := m.appCtrl.RunServerPrompt(p.Server, p.Name, args); err != nil {
+		return m.printSystemMessage(fmt.Sprintf("Cannot run %s right now: %v", p.Command(), err))
+	}
+	m.state = stateWorking
+
+	invocation := p.Command()
+	for _, arg := range p.Arguments {
+		if value, ok := args[arg.Name]; ok {
+			invocation += fmt.Sprintf(" %s=%q", arg.Name, value)
+		}
+	}
+	return m.printUserMessage(invocation)
+}
+
+// compactionMessage formats the notice shown after a compaction.
+func compactionMessage(evt app.CompactionEvent) string {
+	if evt.Err != nil {
+		return fmt.Sprintf("Could not compact the conversation: %v", evt.Err)
+	}
+	msg := fmt.Sprintf("Compacted %d earlier messages into a summary (kept %d recent messages).", evt.Summarized, evt.Kept)
+	if evt.Auto {
+		return "Context window is nearly full. " + msg
+	}
+	return msg
+}
+
+// modelRetryMessage formats the notice shown when a model request is retried.
+func modelRetryMessage(evt app.ModelRetryEvent) string {
+	return fmt.Sprintf("%s failed: %v. Retrying in %s (attempt %d).",
+		evt.Model, evt.Err, evt.Delay.Round(100*time.Millisecond), evt.Attempt)
+}
+
+// modelFallbackMessage formats the notice shown when the next model of the
+// fallback chain is tried instead of the failing one.
+func modelFallbackMessage(evt app.ModelFallbackEvent) string {
+	return fmt.Sprintf("%s failed: %v. Trying %s next.", evt.From, evt.Err, evt.To)
+}
+
+// modelAnsweredMessage formats the notice shown when a model other than the
+// primary one answers.
+func modelAnsweredMessage(evt app.ModelAnsweredEvent) string {
+	return fmt.Sprintf("Answered by %s instead of %s.", evt.Model, evt.Primary)
+}
+
+// budgetWarningMessage formats the notice shown when a budget is nearly used up.
+func budgetWarningMessage(evt app.BudgetWarningEvent) string {
+	return fmt.Sprintf("Budget warning: %s. The agent stops before a step that would exceed it.", evt.Warning)
+}
+
+// hookStoppedMessage formats the notice shown when a hook stops the agent.
+func hookStoppedMessage(evt app.HookBlockedEvent) string {
+	if evt.Reason == "" {
+		return "Stopped by hook"
+	}
+	return fmt.Sprintf("Stopped by hook: %s", evt.Reason)
+}
+
 // printHelpMessage renders the help text listing all available slash commands.
 func (m *AppModel) printHelpMessage() tea.Cmd {
 	help := "## Available Commands\n\n" +
 		"- `/help`: Show this help message\n" +
 		"- `/tools`: List all available tools\n" +
 		"- `/servers`: List configured MCP servers\n" +
+		"- `/resources`: List the resources of MCP servers\n" +
 		"- `/usage`: Show token usage and cost statistics\n" +
 		"- `/reset-usage`: Reset usage statistics\n" +
 		"- `/clear`: Clear message history\n" +
+		"- `/compact [instructions]`: Summarize older messages to free up context\n" +
+		"- `/roots [add <dir>]`: List the roots of MCP servers, or add a directory to them\n" +
+		"- `/cd [dir]`: Show or change the working directory\n" +
 		"- `/quit`: Exit the application\n" +
 		"- `Ctrl+C`: Exit at any time\n" +
+		"- `Ctrl+T`: Expand or collapse the model's thinking\n" +
 		"- `ESC` (x2): Cancel ongoing LLM generation\n\n" +
-		"You can also just type your message to chat with the AI assistant."
+		"You can also just type your message to chat with the AI assistant. " +
+		"Mention a file as `@path` or an MCP resource as `@server:uri` to attach it to your message."
+
+	// Server prompts are listed by server.
+	category := ""
+	for _, sc := range PromptCommands(m.prompts) {
+		if sc.Category != category {
+			category = sc.Category
+			help += fmt.Sprintf("\n\n## %s\n\n", strings.ToUpper(category[:1])+category[1:])
+		}
+		usage := sc.Name
+		if sc.Args != "" {
+			usage += " " + sc.Args
+		}
+		help += fmt.Sprintf("- `%s`", usage)
+		if desc, _, _ := strings.Cut(sc.Description, "\n"); desc != "" {
+			help += ": " + desc
+		}
+		help += "\n"
+	}
 	return m.printSystemMessage(help)
 }
 
@@ -765,6 +1216,98 @@ func (m *AppModel) printServersMessage() tea.Cmd {
 	return m.printSystemMessage(content)
 }
 
+// printResourcesMessage renders the resources and resource templates of MCP
+// servers, grouped by server.
+func (m *AppModel) printResourcesMessage() tea.Cmd {
+	var content strings.Builder
+	content.WriteString("## MCP Resources\n\n")
+	if len(m.resources) == 0 {
+		content.WriteString("No MCP server offers resources.")
+		return m.printSystemMessage(content.String())
+	}
+	server := ""
+	for _, r := range m.resources {
+		if r.Server != server {
+			server = r.Server
+			fmt.Fprintf(&content, "\n**%s**\n\n", server)
+		}
+		fmt.Fprintf(&content, "- `%s`", r.Mention())
+		if r.Template {
+			content.WriteString(" (template)")
+		}
+		if desc := cmp.Or(r.Description, r.Name); desc != "" {
+			content.WriteString(": " + desc)
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString("\nMention a resource as `@server:uri` to attach it to your message. " +
+		"Fill in the placeholders in braces of a template first.")
+	return m.printSystemMessage(content.String())
+}
+
+// rootsCommand lists the roots of the MCP servers, or adds a directory to
+// them for "/roots add <dir>".
+func (m *AppModel) rootsCommand(args string) tea.Cmd {
+	if args == "" {
+		return m.printRootsMessage()
+	}
+	verb, dir, _ := strings.Cut(args, " ")
+	dir = strings.TrimSpace(dir)
+	if verb != "add" || dir == "" {
+		return m.printSystemMessage("Usage: /roots [add <dir>]")
+	}
+	if m.appCtrl == nil {
+		return nil
+	}
+	added, err := m.appCtrl.AddRoot(dir)
+	if err != nil {
+		return m.printSystemMessage(fmt.Sprintf("Cannot add root: %v", err))
+	}
+	return m.printSystemMessage(fmt.Sprintf("Added root %s. MCP servers were notified.", added))
+}
+
+// printRootsMessage renders the roots of each MCP server.
+func (m *AppModel) printRootsMessage() tea.Cmd {
+	var roots map[string][]string
+	if m.appCtrl != nil {
+		roots = m.appCtrl.Roots()
+	}
+	var content strings.Builder
+	content.WriteString("## MCP Roots\n\n")
+	if len(roots) == 0 {
+		content.WriteString("No connected MCP server supports roots.")
+		return m.printSystemMessage(content.String())
+	}
+	for _, server := range slices.Sorted(maps.Keys(roots)) {
+		fmt.Fprintf(&content, "**%s**\n\n", server)
+		for _, dir := range roots[server] {
+			fmt.Fprintf(&content, "- `%s`\n", dir)
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString("Add a directory with `/roots add <dir>`; `/cd` moves the roots of servers that configure none.")
+	return m.printSystemMessage(content.String())
+}
+
+// changeDirectory shows the working directory, or changes it for "/cd <dir>".
+func (m *AppModel) changeDirectory(dir string) tea.Cmd {
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return m.printSystemMessage(fmt.Sprintf("Cannot get the working directory: %v", err))
+		}
+		return m.printSystemMessage("Working directory: " + cwd)
+	}
+	if m.appCtrl == nil {
+		return nil
+	}
+	cwd, err := m.appCtrl.ChangeDirectory(dir)
+	if err != nil {
+		return m.printSystemMessage(fmt.Sprintf("Cannot change directory: %v", err))
+	}
+	return m.printSystemMessage("Working directory: " + cwd)
+}
+
 // printUsageMessage renders token usage statistics.
 func (m *AppModel) printUsageMessage() tea.Cmd {
 	if m.usageTracker == nil {
@@ -781,6 +1324,10 @@ func (m *AppModel) printUsageMessage() tea.Cmd {
 	}
 	content += fmt.Sprintf("**Session Total:** %d input + %d output tokens = $%.6f (%d requests)\n",
 		sessionStats.TotalInputTokens, sessionStats.TotalOutputTokens, sessionStats.TotalCost, sessionStats.RequestCount)
+	if rate, ok := m.usageTracker.CacheHitRate(); ok {
+		content += fmt.Sprintf("**Prompt Cache:** %d read + %d written tokens (%.0f%% hit rate)\n",
+			sessionStats.TotalCacheReadTokens, sessionStats.TotalCacheWriteTokens, rate*100)
+	}
 
 	return m.printSystemMessage(content)
 }
@@ -816,8 +1363,11 @@ func (m *AppModel) flushStreamContent() tea.Cmd {
 //
 // Layout (line counts):
 //
-//	stream region  = total - usage(0-1) - separator(1) - queued(N*5) - input(5)
+//	stream region  = total - usage(0-1) - approval(0-N) - separator(1) - queued(N*5) - input(5)
 //	usage info     = 0 or 1 line (visible only after first response)
+//	approval       = height of the approval dialog while a tool call is pending,
+//	                 plus that of the server prompt and elicitation forms while
+//	                 they are open
 //	separator      = 1 line
 //	queued msgs    = ~5 lines per message (padding + text + badge + padding)
 //	input region   = 5 lines: title(1) + textarea(3) + help(1)
@@ -833,7 +1383,20 @@ func (m *AppModel) distributeHeight() {
 		usageLines = 1
 	}
 
-	streamHeight := max(m.height-usageLines-separatorLines-queuedLines-inputLines, 0)
+	// The approval dialog sits below the stream while a tool call is pending.
+	approvalLines := 0
+	if m.approval != nil {
+		approvalLines = lipgloss.Height(m.approval.View().Content)
+	}
+	// So does the form asking for the arguments of a server prompt.
+	if m.promptForm != nil {
+		approvalLines += lipgloss.Height(m.promptForm.View().Content)
+	}
+	if m.elicitation != nil {
+		approvalLines += lipgloss.Height(m.elicitation.View().Content)
+	}
+
+	streamHeight := max(m.height-usageLines-approvalLines-separatorLines-queuedLines-inputLines, 0)
 
 	if m.stream != nil {
 		m.stream.SetHeight(streamHeight)
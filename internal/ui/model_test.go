@@ -2,11 +2,13 @@ package ui
 
 import (
 	"errors"
+	"maps"
 	"testing"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/fantasy"
 	"github.com/mark3labs/mcphost/internal/app"
+	"github.com/mark3labs/mcphost/internal/tools"
 )
 
 // --------------------------------------------------------------------------
@@ -20,6 +22,14 @@ type stubAppController struct {
 	cancelCalled     int
 	clearQueueCalled int
 	clearMsgCalled   int
+	compactCalls     []string
+	compactErr       error
+	promptCalls      []string
+	promptArgs       map[string]string
+	promptErr        error
+	roots            map[string][]string
+	addedRoots       []string
+	cdCalls          []string
 	queueLen         int
 }
 
@@ -45,6 +55,31 @@ func (s *stubAppController) ClearMessages() {
 	s.clearMsgCalled++
 }
 
+func (s *stubAppController) Compact(instructions string) error {
+	s.compactCalls = append(s.compactCalls, instructions)
+	return s.compactErr
+}
+
+func (s *stubAppController) RunServerPrompt(serverName, promptName string, args map[string]string) error {
+	s.promptCalls = append(s.promptCalls, serverName+":"+promptName)
+	s.promptArgs = args
+	return s.promptErr
+}
+
+func (s *stubAppController) Roots() map[string][]string {
+	return s.roots
+}
+
+func (s *stubAppController) AddRoot(dir string) (string, error) {
+	s.addedRoots = append(s.addedRoots, dir)
+	return "/abs/" + dir, nil
+}
+
+func (s *stubAppController) ChangeDirectory(dir string) (string, error) {
+	s.cdCalls = append(s.cdCalls, dir)
+	return "/abs/" + dir, nil
+}
+
 // --------------------------------------------------------------------------
 // Stub child components
 // --------------------------------------------------------------------------
@@ -52,6 +87,7 @@ func (s *stubAppController) ClearMessages() {
 // stubStreamComponent satisfies streamComponentIface without rendering anything.
 type stubStreamComponent struct {
 	resetCalled     int
+	toggleCalled    int
 	height          int
 	lastMsg         tea.Msg
 	renderedContent string // returned by GetRenderedContent
@@ -66,6 +102,7 @@ func (s *stubStreamComponent) View() tea.View             { return tea.NewView("
 func (s *stubStreamComponent) Reset()                     { s.resetCalled++; s.renderedContent = "" }
 func (s *stubStreamComponent) SetHeight(h int)            { s.height = h }
 func (s *stubStreamComponent) GetRenderedContent() string { return s.renderedContent }
+func (s *stubStreamComponent) ToggleThinking()            { s.toggleCalled++ }
 
 // stubInputComponent satisfies inputComponentIface without rendering anything.
 type stubInputComponent struct {
@@ -641,6 +678,23 @@ func TestCtrlC_producesQuit(t *testing.T) {
 	}
 }
 
+// TestCtrlT_togglesThinking verifies that ctrl+t toggles the thinking block in
+// any state without reaching the input.
+func TestCtrlT_togglesThinking(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, stream, input := newTestAppModel(ctrl)
+	m.state = stateWorking
+
+	m = sendMsg(m, tea.KeyPressMsg{Code: 't', Mod: tea.ModCtrl})
+
+	if stream.toggleCalled != 1 {
+		t.Fatalf("expected ToggleThinking called once, got %d", stream.toggleCalled)
+	}
+	if input.lastMsg != nil {
+		t.Fatalf("expected ctrl+t not to reach the input, got %T", input.lastMsg)
+	}
+}
+
 // --------------------------------------------------------------------------
 // submitMsg during stateWorking (queue path)
 // --------------------------------------------------------------------------
@@ -661,3 +715,403 @@ func TestSubmit_duringWorking_stays(t *testing.T) {
 		t.Fatalf("expected Run('queued prompt') called, got %v", ctrl.runCalls)
 	}
 }
+
+// --------------------------------------------------------------------------
+// Tool approval
+// --------------------------------------------------------------------------
+
+// TestToolApproval_showsDialogAndSendsDecision verifies the approval handshake:
+// ToolApprovalNeededEvent enters stateApproval, keys go to the dialog, and the
+// decision is sent on the event's channel before returning to stateWorking.
+func TestToolApproval_showsDialogAndSendsDecision(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, input := newTestAppModel(ctrl)
+	m.state = stateWorking
+
+	ch := make(chan app.ToolApprovalDecision, 1)
+	m = sendMsg(m, app.ToolApprovalNeededEvent{ToolName: "bash", ToolArgs: "{}", ResponseChan: ch})
+	if m.state != stateApproval {
+		t.Fatalf("expected stateApproval, got %v", m.state)
+	}
+	if m.approval == nil {
+		t.Fatal("expected approval dialog to be shown")
+	}
+
+	// The key goes to the dialog, not the input.
+	_, cmd := m.Update(tea.KeyPressMsg{Code: 'n', Text: "n"})
+	if input.lastMsg != nil {
+		t.Fatalf("expected input to receive no keys during approval, got %T", input.lastMsg)
+	}
+	m = sendMsg(m, runCmd(cmd))
+
+	select {
+	case decision := <-ch:
+		if decision.Approved {
+			t.Fatal("expected denial to be sent")
+		}
+	default:
+		t.Fatal("expected a decision on the response channel")
+	}
+	if m.state != stateWorking {
+		t.Fatalf("expected stateWorking after decision, got %v", m.state)
+	}
+	if m.approval != nil {
+		t.Fatal("expected approval dialog to be dismissed")
+	}
+}
+
+// TestToolApproval_clearedOnStepCancelled verifies that cancelling the step
+// while a tool approval is pending dismisses the dialog.
+func TestToolApproval_clearedOnStepCancelled(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+	m.state = stateWorking
+
+	m = sendMsg(m, app.ToolApprovalNeededEvent{ToolName: "bash", ResponseChan: make(chan app.ToolApprovalDecision, 1)})
+	m = sendMsg(m, app.StepCancelledEvent{})
+
+	if m.state != stateInput {
+		t.Fatalf("expected stateInput, got %v", m.state)
+	}
+	if m.approval != nil || m.approvalChan != nil {
+		t.Fatal("expected pending approval to be cleared")
+	}
+}
+
+// TestSamplingApproval_whileIdle verifies that a sampling request arriving
+// while no step runs is asked for and returns to stateInput, and that one
+// pending when a step ends is denied.
+func TestSamplingApproval_whileIdle(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+
+	ch := make(chan app.ToolApprovalDecision, 1)
+	m = sendMsg(m, app.SamplingApprovalNeededEvent{ServerName: "poems", ResponseChan: ch})
+	if m.state != stateApproval || m.approval == nil {
+		t.Fatalf("expected the approval dialog, got state %v", m.state)
+	}
+	_, cmd := m.Update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+	m = sendMsg(m, runCmd(cmd))
+	if decision := <-ch; !decision.Approved {
+		t.Fatal("expected approval to be sent")
+	}
+	if m.state != stateInput {
+		t.Fatalf("expected stateInput after decision, got %v", m.state)
+	}
+
+	m.state = stateWorking
+	ch = make(chan app.ToolApprovalDecision, 1)
+	m = sendMsg(m, app.SamplingApprovalNeededEvent{ServerName: "poems", ResponseChan: ch})
+	m = sendMsg(m, app.StepCompleteEvent{})
+	select {
+	case decision := <-ch:
+		if decision.Approved {
+			t.Fatal("expected the pending request denied")
+		}
+	default:
+		t.Fatal("expected a decision for the pending request")
+	}
+}
+
+// TestElicitation_whileWorking verifies that the elicitation form takes the
+// keys while a step runs, sends the answer, and that a request still pending
+// when the step ends is cancelled.
+func TestElicitation_whileWorking(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+	m.state = stateWorking
+
+	request := tools.ElicitationRequest{Fields: []tools.ElicitationField{{Name: "confirm", Type: "boolean", Required: true}}}
+	ch := make(chan tools.ElicitationResponse, 1)
+	m = sendMsg(m, app.ElicitationNeededEvent{ServerName: "deployer", Request: request, ResponseChan: ch})
+	if m.elicitation == nil {
+		t.Fatal("expected the elicitation form")
+	}
+	_, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.canceling || ctrl.cancelCalled != 0 {
+		t.Fatal("expected ESC to answer the form, not cancel the step")
+	}
+	m = sendMsg(m, runCmd(cmd))
+	if response := <-ch; response.Action != tools.ElicitationCancel {
+		t.Fatalf("expected the request cancelled, got %+v", response)
+	}
+	if m.elicitation != nil || m.state != stateWorking {
+		t.Fatalf("expected the form closed and the step still running, got state %v", m.state)
+	}
+
+	ch = make(chan tools.ElicitationResponse, 1)
+	m = sendMsg(m, app.ElicitationNeededEvent{ServerName: "deployer", Request: request, ResponseChan: ch})
+	m = sendMsg(m, app.StepErrorEvent{})
+	select {
+	case response := <-ch:
+		if response.Action != tools.ElicitationCancel {
+			t.Fatalf("expected the pending request cancelled, got %+v", response)
+		}
+	default:
+		t.Fatal("expected an answer for the pending request")
+	}
+	if m.elicitation != nil {
+		t.Fatal("expected the form closed")
+	}
+}
+
+// --------------------------------------------------------------------------
+// Roots
+// --------------------------------------------------------------------------
+
+// TestRootsCommands verifies that /roots add and /cd reach the app layer and
+// are not sent as prompts.
+func TestRootsCommands(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+
+	m = sendMsg(m, submitMsg{Text: "/roots add ../shared"})
+	m = sendMsg(m, submitMsg{Text: "/roots remove ../shared"})
+	m = sendMsg(m, submitMsg{Text: "/cd ~/src/app"})
+	sendMsg(m, submitMsg{Text: "/roots"})
+
+	if len(ctrl.addedRoots) != 1 || ctrl.addedRoots[0] != "../shared" {
+		t.Errorf("expected AddRoot(../shared), got %v", ctrl.addedRoots)
+	}
+	if len(ctrl.cdCalls) != 1 || ctrl.cdCalls[0] != "~/src/app" {
+		t.Errorf("expected ChangeDirectory(~/src/app), got %v", ctrl.cdCalls)
+	}
+	if len(ctrl.runCalls) != 0 {
+		t.Errorf("expected no prompts, got %v", ctrl.runCalls)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Compaction
+// --------------------------------------------------------------------------
+
+// TestCompactCommand_startsCompaction verifies that /compact passes its
+// instructions to the app layer instead of sending them as a prompt, and that
+// the manual compaction's CompactionEvent returns the model to stateInput.
+func TestCompactCommand_startsCompaction(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, stream, _ := newTestAppModel(ctrl)
+
+	m = sendMsg(m, submitMsg{Text: "/compact keep the file list"})
+
+	if len(ctrl.compactCalls) != 1 || ctrl.compactCalls[0] != "keep the file list" {
+		t.Fatalf("expected Compact called with instructions, got %v", ctrl.compactCalls)
+	}
+	if len(ctrl.runCalls) != 0 {
+		t.Fatalf("expected /compact not to reach Run, got %v", ctrl.runCalls)
+	}
+	if m.state != stateWorking {
+		t.Fatalf("expected stateWorking while compacting, got %v", m.state)
+	}
+
+	m = sendMsg(m, app.CompactionEvent{Summarized: 6, Kept: 2})
+
+	if m.state != stateInput {
+		t.Fatalf("expected stateInput after CompactionEvent, got %v", m.state)
+	}
+	if stream.resetCalled != 1 {
+		t.Fatalf("expected stream.Reset() called once, got %d", stream.resetCalled)
+	}
+}
+
+// TestCompactCommand_busy verifies that a refused compaction leaves the state
+// untouched.
+func TestCompactCommand_busy(t *testing.T) {
+	ctrl := &stubAppController{compactErr: app.ErrBusy}
+	m, _, _ := newTestAppModel(ctrl)
+
+	_, cmd := m.Update(submitMsg{Text: "/compact"})
+
+	if len(ctrl.compactCalls) != 1 || ctrl.compactCalls[0] != "" {
+		t.Fatalf("expected Compact called without instructions, got %v", ctrl.compactCalls)
+	}
+	if cmd == nil {
+		t.Fatal("expected a message explaining why compaction did not start")
+	}
+	if m.state != stateInput {
+		t.Fatalf("expected stateInput to persist, got %v", m.state)
+	}
+}
+
+// TestCompactionEvent_autoKeepsWorking verifies that an automatic compaction,
+// which happens at the start of a step, does not end the step.
+func TestCompactionEvent_autoKeepsWorking(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+	m.state = stateWorking
+
+	m = sendMsg(m, app.CompactionEvent{Auto: true, Summarized: 10, Kept: 4})
+
+	if m.state != stateWorking {
+		t.Fatalf("expected stateWorking after automatic compaction, got %v", m.state)
+	}
+}
+
+func TestParseSlashCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantName string
+		wantArgs string
+	}{
+		{"/compact", "/compact", ""},
+		{"/compact  focus on tests ", "/compact", "focus on tests"},
+		{"/h", "/help", ""},
+		{"/help me please", "", ""}, // /help takes no arguments: a regular prompt
+		{"/unknown arg", "", ""},
+		{"hello", "", ""},
+	}
+	for _, tt := range tests {
+		sc, args := ParseSlashCommand(tt.input)
+		name := ""
+		if sc != nil {
+			name = sc.Name
+		}
+		if name != tt.wantName || args != tt.wantArgs {
+			t.Errorf("ParseSlashCommand(%q) = %q, %q; want %q, %q", tt.input, name, args, tt.wantName, tt.wantArgs)
+		}
+	}
+}
+
+var testReviewPrompt = tools.Prompt{
+	Server:      "github",
+	Name:        "review-pr",
+	Description: "Review a pull request",
+	Arguments: []tools.PromptArgument{
+		{Name: "pr", Required: true},
+		{Name: "focus"},
+	},
+}
+
+// TestServerPrompt_inlineArgs verifies that a server prompt given its
+// required arguments inline runs right away.
+func TestServerPrompt_inlineArgs(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+	m.prompts = []tools.Prompt{testReviewPrompt}
+
+	m = sendMsg(m, submitMsg{Text: `/github:review-pr 42 focus="error handling"`})
+
+	if len(ctrl.promptCalls) != 1 || ctrl.promptCalls[0] != "github:review-pr" {
+		t.Fatalf("expected the prompt run, got %v", ctrl.promptCalls)
+	}
+	if ctrl.promptArgs["pr"] != "42" || ctrl.promptArgs["focus"] != "error handling" {
+		t.Fatalf("unexpected arguments %v", ctrl.promptArgs)
+	}
+	if len(ctrl.runCalls) != 0 {
+		t.Fatalf("expected the prompt command not to reach Run, got %v", ctrl.runCalls)
+	}
+	if m.state != stateWorking {
+		t.Fatalf("expected stateWorking, got %v", m.state)
+	}
+}
+
+// TestServerPrompt_formCollectsArgs verifies that a server prompt invoked
+// without arguments asks for them in a form before it runs.
+func TestServerPrompt_formCollectsArgs(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+	m.prompts = []tools.Prompt{testReviewPrompt}
+
+	m = sendMsg(m, submitMsg{Text: "/github:review-pr"})
+	if m.promptForm == nil {
+		t.Fatal("expected the argument form to open")
+	}
+
+	// The required argument cannot be skipped.
+	_, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if cmd != nil || m.promptForm.current != 0 {
+		t.Fatal("expected an empty required argument to be refused")
+	}
+
+	m.promptForm.input.SetValue("7")
+	_, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if cmd != nil {
+		t.Fatal("expected the form to ask for the optional argument next")
+	}
+	_, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	m = sendMsg(m, runCmd(cmd))
+
+	if m.promptForm != nil {
+		t.Fatal("expected the form closed")
+	}
+	if len(ctrl.promptCalls) != 1 || ctrl.promptArgs["pr"] != "7" {
+		t.Fatalf("expected the prompt run with pr=7, got %v %v", ctrl.promptCalls, ctrl.promptArgs)
+	}
+	if _, ok := ctrl.promptArgs["focus"]; ok {
+		t.Fatal("expected the skipped optional argument left out")
+	}
+}
+
+// TestServerListChanged_updatesLists verifies that a server's changed lists
+// replace those used by /tools and the server prompt commands.
+func TestServerListChanged_updatesLists(t *testing.T) {
+	ctrl := &stubAppController{}
+	m, _, _ := newTestAppModel(ctrl)
+	m.toolNames = []string{"github__list_issues"}
+
+	_, cmd := m.Update(app.ServerListChangedEvent{
+		ServerName: "github",
+		Kind:       tools.ListPrompts,
+		ToolNames:  []string{"github__create_issue"},
+		Prompts:    []tools.Prompt{testReviewPrompt},
+	})
+
+	if cmd == nil {
+		t.Fatal("expected a message about the updated server")
+	}
+	if len(m.toolNames) != 1 || m.toolNames[0] != "github__create_issue" {
+		t.Errorf("expected the new tools, got %v", m.toolNames)
+	}
+	sendMsg(m, submitMsg{Text: "/github:review-pr 42"})
+	if len(ctrl.promptCalls) != 1 {
+		t.Errorf("expected the new prompt to run, got %v", ctrl.promptCalls)
+	}
+}
+
+// TestServerStatus_addsLateServer verifies that a server ready after startup
+// adds its tools to those used by /tools.
+func TestServerStatus_addsLateServer(t *testing.T) {
+	m, _, _ := newTestAppModel(&stubAppController{})
+	m.toolNames = []string{"fs__read_file"}
+
+	_, cmd := m.Update(app.ServerStatusEvent{
+		Status:    tools.ServerStatus{Name: "github", State: tools.ServerReady, Tools: 1},
+		ToolNames: []string{"fs__read_file", "github__create_issue"},
+	})
+
+	if cmd == nil {
+		t.Fatal("expected a message about the ready server")
+	}
+	if len(m.toolNames) != 2 || m.toolNames[1] != "github__create_issue" {
+		t.Errorf("expected the server's tools added, got %v", m.toolNames)
+	}
+}
+
+func TestParsePromptArgs(t *testing.T) {
+	single := tools.Prompt{Server: "s", Name: "p", Arguments: []tools.PromptArgument{{Name: "topic", Required: true}}}
+	tests := []struct {
+		prompt  tools.Prompt
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{testReviewPrompt, "", map[string]string{}, false},
+		{testReviewPrompt, "42", map[string]string{"pr": "42"}, false},
+		{testReviewPrompt, "focus=tests 42", map[string]string{"pr": "42", "focus": "tests"}, false},
+		{testReviewPrompt, `42 'the parser'`, map[string]string{"pr": "42", "focus": "the parser"}, false},
+		{testReviewPrompt, "1 2 3", nil, true},
+		{testReviewPrompt, `42 "open`, nil, true},
+		{single, "rate limiting in Go", map[string]string{"topic": "rate limiting in Go"}, false},
+		{single, `topic="caching"`, map[string]string{"topic": "caching"}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParsePromptArgs(tt.prompt, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePromptArgs(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("ParsePromptArgs(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/mark3labs/mcphost/internal/tools"
+)
+
+// PromptFormComponent is the small form shown by the parent AppModel to
+// collect the arguments of a server prompt that were not given inline. It
+// asks for one argument at a time. When every argument is answered it returns
+// a promptFormResultMsg tea.Cmd; the parent runs the prompt.
+//
+// Keys: enter accepts the current argument (optional ones may be left empty),
+// and esc cancels the form.
+type PromptFormComponent struct {
+	prompt  tools.Prompt
+	args    map[string]string
+	fields  []tools.PromptArgument // arguments still asked for, in order
+	current int
+	input   textinput.Model
+	err     string
+	width   int
+	done    bool // the form was submitted or cancelled; further keys are ignored
+}
+
+// promptFormResultMsg reports the outcome of a PromptFormComponent.
+type promptFormResultMsg struct {
+	Prompt    tools.Prompt
+	Args      map[string]string
+	Cancelled bool
+}
+
+// NewPromptFormComponent creates a form asking for fields, the arguments of
+// prompt not yet in args.
+func NewPromptFormComponent(prompt tools.Prompt, args map[string]string, fields []tools.PromptArgument, width int) *PromptFormComponent {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.SetWidth(width - 12)
+	input.Focus()
+
+	f := &PromptFormComponent{
+		prompt: prompt,
+		args:   maps.Clone(args),
+		fields: fields,
+		input:  input,
+		width:  width,
+	}
+	f.setPlaceholder()
+	return f
+}
+
+// Init implements tea.Model.
+func (f *PromptFormComponent) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (f *PromptFormComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		f.width = msg.Width
+		f.input.SetWidth(msg.Width - 12)
+		return f, nil
+	case tea.KeyPressMsg:
+		if f.done {
+			return f, nil
+		}
+		switch msg.String() {
+		case "esc":
+			f.done = true
+			return f, func() tea.Msg { return promptFormResultMsg{Prompt: f.prompt, Cancelled: true} }
+		case "enter":
+			return f, f.accept()
+		}
+	}
+
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return f, cmd
+}
+
+// accept records the current argument and moves to the next one, or submits
+// the form after the last.
+func (f *PromptFormComponent) accept() tea.Cmd {
+	field := f.fields[f.current]
+	value := strings.TrimSpace(f.input.Value())
+	if value == "" && field.Required {
+		f.err = fmt.Sprintf("%s is required", field.Name)
+		return nil
+	}
+	if value != "" {
+		if f.args == nil {
+			f.args = make(map[string]string)
+		}
+		f.args[field.Name] = value
+	}
+	f.err = ""
+	f.current++
+	if f.current < len(f.fields) {
+		f.input.SetValue("")
+		f.setPlaceholder()
+		return nil
+	}
+
+	f.done = true
+	result := promptFormResultMsg{Prompt: f.prompt, Args: f.args}
+	return func() tea.Msg { return result }
+}
+
+// setPlaceholder shows the description of the current argument in the input.
+func (f *PromptFormComponent) setPlaceholder() {
+	field := f.fields[f.current]
+	f.input.Placeholder = field.Description
+	if !field.Required {
+		f.input.Placeholder = strings.TrimSpace(field.Description + " (optional)")
+	}
+}
+
+// View implements tea.Model. Renders the prompt, the current argument and
+// its input.
+func (f *PromptFormComponent) View() tea.View {
+	// PaddingLeft(3) aligns with message content: border(1) + paddingLeft(2).
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("252")).
+		MarginBottom(1).
+		PaddingLeft(3)
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderLeft(true).
+		BorderRight(false).
+		BorderTop(false).
+		BorderBottom(false).
+		BorderForeground(lipgloss.Color("39")).
+		PaddingLeft(2).
+		Width(f.width - 1)
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Bold(true)
+	hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	var view strings.Builder
+	view.WriteString(titleStyle.Render("Run " + f.prompt.Command()))
+	view.WriteString("\n")
+	if f.prompt.Description != "" {
+		view.WriteString(hintStyle.Render(f.prompt.Description) + "\n\n")
+	}
+	if f.current < len(f.fields) {
+		field := f.fields[f.current]
+		fmt.Fprintf(&view, "%s %s\n", labelStyle.Render(field.Name),
+			hintStyle.Render(fmt.Sprintf("(%d of %d)", f.current+1, len(f.fields))))
+		view.WriteString(f.input.View() + "\n")
+	}
+	if f.err != "" {
+		view.WriteString(errStyle.Render(f.err) + "\n")
+	}
+	view.WriteString(hintStyle.Render("enter next • esc cancel"))
+
+	return tea.NewView(boxStyle.Render(view.String()))
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -69,6 +70,13 @@ func streamSpinnerTickCmd() tea.Cmd {
 	})
 }
 
+// maxToolLogLines is the number of recent MCP server log lines shown under
+// the running tool.
+const maxToolLogLines = 3
+
+// progressBarWidth is the width in cells of the running tool's progress bar.
+const progressBarWidth = 20
+
 // streamPhase tracks what the StreamComponent is currently displaying.
 type streamPhase int
 
@@ -97,8 +105,11 @@ const (
 //
 // Events handled:
 //   - app.SpinnerEvent{Show:true}  → start spinner tick loop
+//   - app.ReasoningChunkEvent      → append reasoning ("thinking") text
 //   - app.StreamChunkEvent         → append text
 //   - app.ToolExecutionEvent       → show execution label on spinner
+//   - app.ToolProgressEvent        → show a progress bar under the spinner
+//   - app.ServerLogEvent           → show recent log lines under the spinner
 type StreamComponent struct {
 	// phase tracks whether the component is idle or active.
 	phase streamPhase
@@ -118,9 +129,25 @@ type StreamComponent struct {
 	// "Executing tool_name…"). Empty string means no label.
 	spinnerMsg string
 
+	// toolProgress is the last progress update of the running tool, or nil.
+	toolProgress *app.ToolProgressEvent
+
+	// toolLogs holds the most recent log lines of MCP servers while a tool
+	// runs, at most maxToolLogLines.
+	toolLogs []string
+
 	// streamContent accumulates all streaming text chunks.
 	streamContent strings.Builder
 
+	// thinkingContent accumulates the model's reasoning, shown as a thinking
+	// block above the text.
+	thinkingContent strings.Builder
+
+	// showThinking expands the thinking block to the full reasoning text;
+	// otherwise it is collapsed to a one-line summary. Toggled with ctrl+t
+	// and kept across steps.
+	showThinking bool
+
 	// messageRenderer renders assistant messages in standard mode.
 	messageRenderer *MessageRenderer
 
@@ -176,19 +203,31 @@ func (s *StreamComponent) Reset() {
 	s.spinning = false
 	s.spinnerFrame = 0
 	s.spinnerMsg = ""
+	s.toolProgress = nil
+	s.toolLogs = nil
 	s.streamContent.Reset()
+	s.thinkingContent.Reset()
 	s.timestamp = time.Time{}
 }
 
+// ToggleThinking expands or collapses the thinking block.
+func (s *StreamComponent) ToggleThinking() {
+	s.showThinking = !s.showThinking
+}
+
 // GetRenderedContent returns the rendered assistant message from the accumulated
-// streaming text. Returns empty string if no text has been accumulated. Used by
-// the parent AppModel to flush content via tea.Println() before resetting.
+// streaming text, preceded by the thinking block if the model reasoned. Returns
+// empty string if nothing has been accumulated. Used by the parent AppModel to
+// flush content via tea.Println() before resetting.
 func (s *StreamComponent) GetRenderedContent() string {
-	text := s.streamContent.String()
-	if text == "" {
-		return ""
+	var parts []string
+	if thinking := s.thinkingContent.String(); thinking != "" {
+		parts = append(parts, s.renderThinking(thinking, false))
+	}
+	if text := s.streamContent.String(); text != "" {
+		parts = append(parts, s.renderStreamingText(text))
 	}
-	return s.renderStreamingText(text)
+	return strings.Join(parts, "\n")
 }
 
 // --------------------------------------------------------------------------
@@ -230,6 +269,13 @@ func (s *StreamComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return s, streamSpinnerTickCmd()
 		}
 
+	case app.ReasoningChunkEvent:
+		s.phase = streamPhaseActive
+		if s.timestamp.IsZero() {
+			s.timestamp = time.Now()
+		}
+		s.thinkingContent.WriteString(msg.Content)
+
 	case app.StreamChunkEvent:
 		s.phase = streamPhaseActive
 		if s.timestamp.IsZero() {
@@ -238,6 +284,8 @@ func (s *StreamComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		s.streamContent.WriteString(msg.Content)
 
 	case app.ToolExecutionEvent:
+		s.toolProgress = nil
+		s.toolLogs = nil
 		if msg.IsStarting {
 			// Show the tool name on the spinner while the tool executes.
 			s.spinnerMsg = "Executing " + msg.ToolName + "…"
@@ -251,6 +299,19 @@ func (s *StreamComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Tool finished — clear execution label but keep spinning.
 			s.spinnerMsg = ""
 		}
+
+	// Progress and log lines belong to the running tool; those arriving
+	// between tool calls are not shown.
+	case app.ToolProgressEvent:
+		if s.spinnerMsg != "" {
+			s.toolProgress = &msg
+		}
+
+	case app.ServerLogEvent:
+		if s.spinnerMsg != "" {
+			s.toolLogs = append(s.toolLogs, s.renderLogLine(msg))
+			s.toolLogs = s.toolLogs[max(len(s.toolLogs)-maxToolLogLines, 0):]
+		}
 	}
 
 	return s, nil
@@ -273,14 +334,24 @@ func (s *StreamComponent) render() string {
 
 	var parts []string
 
+	// Render the thinking block above the text.
+	if thinking := s.thinkingContent.String(); thinking != "" {
+		parts = append(parts, s.renderThinking(thinking, true))
+	}
+
 	// Render streaming text if present.
 	if text := s.streamContent.String(); text != "" {
 		parts = append(parts, s.renderStreamingText(text))
 	}
 
-	// Render spinner below streaming text (or alone if no text yet).
+	// Render spinner below streaming text (or alone if no text yet), and the
+	// running tool's progress and log lines below it.
 	if s.spinning {
 		parts = append(parts, s.renderSpinner())
+		if s.toolProgress != nil {
+			parts = append(parts, s.renderProgress(*s.toolProgress))
+		}
+		parts = append(parts, s.toolLogs...)
 	}
 
 	if len(parts) == 0 {
@@ -315,6 +386,47 @@ func (s *StreamComponent) renderSpinner() string {
 	return "  " + frame + " " + msgStyle.Render(s.spinnerMsg)
 }
 
+// renderProgress renders a progress update of the running tool as a bar with
+// its percentage, or as the bare progress when the total is unknown, followed
+// by the update's message.
+func (s *StreamComponent) renderProgress(p app.ToolProgressEvent) string {
+	theme := GetTheme()
+	var line string
+	if p.Total > 0 {
+		ratio := min(max(p.Progress/p.Total, 0), 1)
+		filled := int(ratio * progressBarWidth)
+		line = lipgloss.NewStyle().Foreground(theme.Tool).Render(strings.Repeat("━", filled)) +
+			lipgloss.NewStyle().Foreground(theme.VeryMuted).Render(strings.Repeat("─", progressBarWidth-filled)) +
+			fmt.Sprintf(" %3.0f%%", ratio*100)
+	} else {
+		line = fmt.Sprintf("%g", p.Progress)
+	}
+	if p.Message != "" {
+		line += " " + lipgloss.NewStyle().Foreground(theme.Muted).Render(p.Message)
+	}
+	return lipgloss.NewStyle().PaddingLeft(4).MaxWidth(s.width).Render(line)
+}
+
+// renderLogLine renders a log message of an MCP server as a single muted
+// line; warnings and more severe messages are highlighted.
+func (s *StreamComponent) renderLogLine(msg app.ServerLogEvent) string {
+	theme := GetTheme()
+	color := theme.VeryMuted
+	switch msg.Level {
+	case "warning":
+		color = theme.Warning
+	case "error", "critical", "alert", "emergency":
+		color = theme.Error
+	}
+	source := msg.ServerName
+	if msg.Logger != "" {
+		source += "/" + msg.Logger
+	}
+	text := strings.Join(strings.Fields(msg.Message), " ")
+	line := fmt.Sprintf("%s %s: %s", source, msg.Level, text)
+	return lipgloss.NewStyle().Foreground(color).PaddingLeft(4).MaxWidth(s.width).Render(line)
+}
+
 // renderStreamingText renders the accumulated streaming text as a live assistant
 // message using the configured renderer.
 func (s *StreamComponent) renderStreamingText(text string) string {
@@ -330,3 +442,36 @@ func (s *StreamComponent) renderStreamingText(text string) string {
 	msg := s.messageRenderer.RenderAssistantMessage(text, ts, s.modelName)
 	return msg.Content
 }
+
+// renderThinking renders the model's reasoning as a muted thinking block.
+// Collapsed, it is a one-line summary; expanded, the summary is followed by
+// the full reasoning text. live adds the ctrl+t hint shown while streaming.
+func (s *StreamComponent) renderThinking(thinking string, live bool) string {
+	theme := GetTheme()
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Italic(true)
+
+	header := "✻ Thinking…"
+	if !live || s.streamContent.Len() > 0 {
+		header = fmt.Sprintf("✻ Thought for %d words", len(strings.Fields(thinking)))
+	}
+	if live {
+		if s.showThinking {
+			header += " (ctrl+t to collapse)"
+		} else {
+			header += " (ctrl+t to expand)"
+		}
+	}
+	header = "  " + headerStyle.Render(header)
+
+	if !s.showThinking {
+		return header
+	}
+
+	bodyStyle := lipgloss.NewStyle().
+		Foreground(theme.VeryMuted).
+		Width(max(s.width-4, 20)).
+		PaddingLeft(4)
+	return header + "\n" + bodyStyle.Render(strings.TrimSpace(thinking))
+}
@@ -234,6 +234,28 @@ func (ut *UsageTracker) GetSessionStats() SessionStats {
 	return ut.sessionStats
 }
 
+// CacheHitRate returns the share of the session's prompt tokens that were read
+// from the provider's prompt cache, between 0 and 1. Anthropic providers count
+// cached tokens separately from the input tokens; other providers include them.
+// ok is false when the provider reported no cache activity.
+func (ut *UsageTracker) CacheHitRate() (rate float64, ok bool) {
+	ut.mu.RLock()
+	defer ut.mu.RUnlock()
+
+	stats := ut.sessionStats
+	if stats.TotalCacheReadTokens == 0 && stats.TotalCacheWriteTokens == 0 {
+		return 0, false
+	}
+	promptTokens := stats.TotalInputTokens
+	if models.IsAnthropicProvider(ut.provider) {
+		promptTokens += stats.TotalCacheReadTokens + stats.TotalCacheWriteTokens
+	}
+	if promptTokens == 0 {
+		return 0, false
+	}
+	return float64(stats.TotalCacheReadTokens) / float64(promptTokens), true
+}
+
 // GetLastRequestStats returns a copy of the usage statistics from the most recent
 // request, or nil if no requests have been made. The returned copy is safe to use
 // without additional synchronization.
@@ -108,3 +108,29 @@ func TestUsageTracker_OAuthSessionStats(t *testing.T) {
 		t.Errorf("Expected request count to be 2, got %d", sessionStats.RequestCount)
 	}
 }
+
+func TestUsageTracker_CacheHitRate(t *testing.T) {
+	modelInfo := &models.ModelInfo{ID: "claude-sonnet-4-5", Cost: models.Cost{Input: 3.0, Output: 15.0}}
+
+	tracker := NewUsageTracker(modelInfo, "anthropic", 80, false)
+	tracker.UpdateUsage(100, 50, 0, 0)
+	if _, ok := tracker.CacheHitRate(); ok {
+		t.Error("Expected no hit rate without cache activity")
+	}
+
+	// Anthropic reports cached tokens on top of the input tokens
+	tracker.UpdateUsage(100, 50, 0, 800)
+	tracker.UpdateUsage(100, 50, 800, 100)
+	rate, ok := tracker.CacheHitRate()
+	if !ok || rate != 0.4 {
+		t.Errorf("Expected a 40%% hit rate, got %v (ok %v)", rate, ok)
+	}
+
+	// Other providers include cached tokens in the input tokens
+	tracker = NewUsageTracker(modelInfo, "openai", 80, false)
+	tracker.UpdateUsage(1000, 50, 250, 0)
+	rate, ok = tracker.CacheHitRate()
+	if !ok || rate != 0.25 {
+		t.Errorf("Expected a 25%% hit rate, got %v (ok %v)", rate, ok)
+	}
+}
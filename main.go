@@ -21,6 +21,6 @@ func main() {
 
 	rootCmd := cmd.GetRootCommand(version)
 	if err := fang.Execute(context.Background(), rootCmd); err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -146,6 +146,7 @@ func (m *MCPHost) PromptWithCallbacks(
 		nil, // onResponse
 		nil, // onToolCallContent
 		onStreaming,
+		nil, // onReasoning
 	)
 	if err != nil {
 		return "", err